@@ -2,9 +2,11 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	_ "lms_back/api/docs"
 	"lms_back/api/models"
+	"lms_back/pkg/errs"
 	"lms_back/pkg/password"
 	"lms_back/config"
 	"net/http"
@@ -14,6 +16,7 @@ import (
 )
 
 // CreateAdmin godoc
+// @Security ApiKeyAuth
 // @Router     /admin [POST]
 // @Summary    create a admin
 // Description This api creates a new admin return its id
@@ -21,31 +24,43 @@ import (
 // @Accept     json
 // @Produce    json
 // @Param      admin body   models.CreateAdmin true "admin"
-// @Success    200 {object} models.Admin
+// @Param      Idempotency-Key header string false "retries with the same key return the first response"
+// @Success    200 {object} models.GetAdmin
 // @Failure    400 {object} models.Response
 // @Failure    404 {object} models.Response
+// @Failure    422 {object} models.Response
 // @Failure    500 {object} models.Response
 func (h Handler) CreateAdmin(c *gin.Context) {
-	admin := models.Admin{}
+	createAdmin := models.CreateAdmin{}
 
-	if err := c.ShouldBindJSON(&admin); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	if err := bindJSON(c, &createAdmin); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
 
+	admin := models.Admin{
+		Full_Name: createAdmin.Full_Name,
+		Email:     createAdmin.Email,
+		Age:       createAdmin.Age,
+		Status:    createAdmin.Status,
+		Login:     createAdmin.Login,
+		Password:  createAdmin.Password,
+		Role:      createAdmin.Role,
+	}
+
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	hashedPass, err := password.HashPassword(admin.Password)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while generating customer password", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while generating customer password", http.StatusInternalServerError, err)
 		return
 	}
 	admin.Password = string(hashedPass)
 
 	id, err := h.Service.Admin().Create(ctx, admin)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while creating admin", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while creating admin", http.StatusInternalServerError, err)
 		return
 	}
 
@@ -53,6 +68,7 @@ func (h Handler) CreateAdmin(c *gin.Context) {
 }
 
 // UpdateAdmin godoc
+// @Security ApiKeyAuth
 // @Router                /admin/{id} [PUT]
 // @Summary               Update Admin
 // @Description           This API Updates admin Information
@@ -60,43 +76,186 @@ func (h Handler) CreateAdmin(c *gin.Context) {
 // @Accept     	          json
 // @Produce               json
 // @Param				  id path string true "Admin Id"
+// @Param				  If-Match header string true "ETag of the row"
 // @Param                 admin body models.UpdateAdmin true "admin"
-// @Success 		      200 {object} models.Admin
+// @Success 		      200 {object} models.GetAdmin
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure 		      400 {object} models.Response
 // @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
 // @Failure 		      500 {object} models.Response
 func (h Handler) UpdateAdmin(c *gin.Context) {
-	admin := models.Admin{}
-	if err := c.ShouldBindJSON(&admin); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	updateAdmin := models.UpdateAdmin{}
+	if err := bindJSON(c, &updateAdmin); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
-	admin.Id = c.Param("id")
+	admin := models.Admin{
+		Id:        c.Param("id"),
+		Full_Name: updateAdmin.Full_Name,
+		Email:     updateAdmin.Email,
+		Age:       updateAdmin.Age,
+		Status:    updateAdmin.Status,
+		Login:     updateAdmin.Login,
+		Role:      updateAdmin.Role,
+	}
 	err := uuid.Validate(admin.Id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
 		return
 	}
-
-	hashedPass, err := password.HashPassword(admin.Password)
+	version, err := parseIfMatch(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while generating customer password", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
-	admin.Password = string(hashedPass)
+	admin.Version = version
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	id, err := h.Service.Admin().Update(ctx, admin)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while updating admin", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while updating admin", serviceErrorStatus(err), err)
 		return
 	}
+	setETag(c, id.Version)
 	handleResponseLog(c, h.Log, "updated successfully", http.StatusOK, id)
 }
 
+// PatchAdmin godoc
+// @Security ApiKeyAuth
+// @Router                /admin/{id} [PATCH]
+// @Summary 			  patch a admin
+// @Description           this api changes only the admin fields in the body, a JSON merge patch
+// @Tags 			      admin
+// @Accept 			      json
+// @Produce 		      json
+// @Param 			      id path string true "Admin Id"
+// @Param 			      If-Match header string true "ETag of the row"
+// @Param       		  patch body object true "fields to change by their json names"
+// @Success 		      200 {object} models.GetAdmin
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure 		      400 {object} models.Response
+// @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
+// @Failure 		      500 {object} models.Response
+func (h Handler) PatchAdmin(c *gin.Context) {
+
+	patch := models.Patch{}
+	if err := bindPatch(c, &patch, models.UpdateAdmin{}); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+	if raw, ok := patch["role"]; ok {
+		var role string
+		if err := json.Unmarshal(raw, &role); err != nil || !isAdminRole(role) {
+			err := errs.Validation("", errs.FieldError{Field: "role", Message: "must be one of admin, superadmin"})
+			handleResponseLog(c, h.Log, "error while validating role", http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	admin, err := h.Service.Admin().Patch(ctx, id, version, patch)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while patching admin", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, admin.Version)
+	handleResponseLog(c, h.Log, "patched successfully", http.StatusOK, admin)
+}
+
+// ChangeAdminPassword godoc
+// @Security ApiKeyAuth
+// @Router                /admin/{id}/password [PUT]
+// @Summary               Change admin password
+// @Description           Admins change their own password by confirming the old one, superadmins may set a new one directly
+// @Tags   	  			  admin
+// @Accept     	          json
+// @Produce               json
+// @Param				  id path string true "Admin Id"
+// @Param                 password body models.ChangePasswordRequest true "password"
+// @Success 		      200 {object} models.Response
+// @Failure 		      400 {object} models.Response
+// @Failure               403 {object} models.Response
+// @Failure 		      500 {object} models.Response
+func (h Handler) ChangeAdminPassword(c *gin.Context) {
+	h.changePassword(c, config.ADMIN_ROLE, h.Service.Admin().ChangePassword)
+}
+
+// UpdateAdminBranches godoc
+// @Security ApiKeyAuth
+// @Router                /admin/{id}/branch [PUT]
+// @Summary               Assign branches to admin
+// @Description           Replaces the branches an admin manages, admins only see and change data of their branches
+// @Tags   	  			  admin
+// @Accept     	          json
+// @Produce               json
+// @Param				  id path string true "Admin Id"
+// @Param                 branches body models.UpdateAdminBranches true "branches"
+// @Success 		      200 {object} models.GetAdmin
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure 		      400 {object} models.Response
+// @Failure 		      422 {object} models.Response
+// @Failure               403 {object} models.Response
+// @Failure 		      500 {object} models.Response
+func (h Handler) UpdateAdminBranches(c *gin.Context) {
+	req := models.UpdateAdminBranches{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+	for _, branchID := range req.BranchIDs {
+		if err := uuid.Validate(branchID); err != nil {
+			handleResponseLog(c, h.Log, "error while validating branch id", http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	admin, err := h.Service.Admin().SetBranches(ctx, id, req.BranchIDs)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while assigning branches to admin", http.StatusInternalServerError, err)
+		return
+	}
+
+	setETag(c, admin.Version)
+	handleResponseLog(c, h.Log, "updated successfully", http.StatusOK, admin)
+}
+
+func isAdminRole(role string) bool {
+	return role == "" || role == config.ADMIN_ROLE || role == config.SUPERADMIN_ROLE
+}
+
 // GetAllAdmin godoc
+// @Security ApiKeyAuth
 // @Router 			/admin [GET]
 // @Summary 		get all admin
 // @Description 	This API returns admin list
@@ -105,7 +264,12 @@ func (h Handler) UpdateAdmin(c *gin.Context) {
 // @Produce 		json
 // @Param 			page query int false "page number"
 // @Param 			limit query int false "limit per page"
-// @Param 			search query string false "search keyword"
+// @Param 			sort query string false "comma separated sort fields, - for descending, e.g. -created_at"
+// @Param 			cursor query string false "next_cursor of the previous page, empty for the first page of cursor pagination"
+// @Param 			deleted query string false "only to list the deleted ones"
+// @Param 			search query string false "search by full name, email or login"
+// @Param 			status query string false "active or inactive"
+// @Param 			role query string false "admin or superadmin"
 // @Success 		200 {object} models.GetAllAdminsResponse
 // @Failure 		400 {object} models.Response
 // @Failure         404 {object} models.Response
@@ -116,15 +280,17 @@ func (h Handler) GetAllAdmins(c *gin.Context) {
 	)
 
 	request.Search = c.Query("search")
+	request.Status = c.Query("status")
+	request.Role = c.Query("role")
 
 	page, err := ParsePageQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err)
 		return
 	}
 	limit, err := ParseLimitQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err)
 		return
 	}
 	fmt.Println("page: ", page)
@@ -132,19 +298,24 @@ func (h Handler) GetAllAdmins(c *gin.Context) {
 
 	request.Page = page
 	request.Limit = limit
+	if request.ListParams, err = parseListParams(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing list params", http.StatusBadRequest, err)
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	admins, err := h.Service.Admin().GetAll(ctx, request)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting admins", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting admins", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "", http.StatusOK, admins)
 }
 
 // GetByIDAdmin godoc
+// @Security ApiKeyAuth
 // @Router       /admin/{id} [GET]
 // @Summary      return a admin by ID
 // @Description  Retrieves a admin by its ID
@@ -153,6 +324,7 @@ func (h Handler) GetAllAdmins(c *gin.Context) {
 // @Produce      json
 // @Param        id path string true "Admin ID"
 // @Success      200 {object} models.GetAdmin
+// @Header       200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure      400 {object} models.Response
 // @Failure      404 {object} models.Response
 // @Failure      500 {object} models.Response
@@ -169,10 +341,12 @@ func (h Handler) GetByIDAdmin(c *gin.Context) {
 		handleResponseLog(c, h.Log, "error while getting admin by id", http.StatusInternalServerError, err)
 		return
 	}
+	setETag(c, admin.Version)
 	handleResponseLog(c, h.Log, "", http.StatusOK, admin)
 }
 
 // DeleteAdmin godoc
+// @Security ApiKeyAuth
 // @Router          /admin/{id} [DELETE]
 // @Summary         delete a admin by ID
 // @Description     Deletes a admin by its ID
@@ -180,9 +354,12 @@ func (h Handler) GetByIDAdmin(c *gin.Context) {
 // @Accept          json
 // @Produce         json
 // @Param           id path string true "Admin ID"
+// @Param           If-Match header string true "ETag of the row"
 // @Success         200 {string} models.Response
 // @Failure         400 {object} models.Response
 // @Failure         404 {object} models.Response
+// @Failure         412 {object} models.Response
+// @Failure         428 {object} models.Response
 // @Failure         500 {object} models.Response
 func (h Handler) DeleteAdmin(c *gin.Context) {
 
@@ -191,22 +368,65 @@ func (h Handler) DeleteAdmin(c *gin.Context) {
 
 	err := uuid.Validate(id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
-	err = h.Service.Admin().Delete(ctx, id)
+	err = h.Service.Admin().Delete(ctx, id, version)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while deleting admin", http.StatusInternalServerError, err)
+		handleResponseLog(c, h.Log, "error while deleting admin", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "deleted admin", http.StatusOK, id)
 }
 
+// RestoreAdmin godoc
+// @Security ApiKeyAuth
+// @Router          /admin/{id}/restore [POST]
+// @Summary         restore a deleted admin
+// @Description     Takes a deleted admin out of the trash
+// @Tags            admin
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Admin ID"
+// @Success         200 {object} models.GetAdmin
+// @Header          200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure         400 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) RestoreAdmin(c *gin.Context) {
+
+	id := c.Param("id")
+
+	err := uuid.Validate(id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	admin, err := h.Service.Admin().Restore(ctx, id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while restoring admin", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, admin.Version)
+	handleResponseLog(c, h.Log, "restored admin", http.StatusOK, admin)
+}
+
 // GetById AdminPayment godoc
+// @Security ApiKeyAuth
 // @Router       /adminPay/{idAdmin} [GET]
 // @Summary      return a admin by payments
 // @Description  Retrieves a admin by its payments
@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// MarkAttendance godoc
+// @Security ApiKeyAuth
+// @Router          /lesson/{id}/attendance [PUT]
+// @Summary         mark the attendance of a lesson
+// @Description     Marks the listed students present, absent, late or excused, earlier marks of the students are replaced. Teachers may only mark the lessons of their own groups
+// @Tags            attendance
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Lesson ID"
+// @Param           attendance body models.MarkAttendanceRequest true "marks"
+// @Success         200 {object} []models.Attendance
+// @Failure         400 {object} models.Response
+// @Failure         403 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         422 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) MarkAttendance(c *gin.Context) {
+	req := models.MarkAttendanceRequest{}
+	if err := bindJSON(c, &req); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	attendance, err := h.Service.Attendance().Mark(ctx, id, req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while marking attendance", serviceErrorStatus(err), err)
+		return
+	}
+	handleResponseLog(c, h.Log, "marked attendance", http.StatusOK, attendance)
+}
+
+// GetStudentAttendance godoc
+// @Security ApiKeyAuth
+// @Router          /student/{id}/attendance [GET]
+// @Summary         get the attendance of a student
+// @Description     Returns the marks of the student, latest lesson first, and the attendance rate of all the marks matching the filters
+// @Tags            attendance
+// @Produce         json
+// @Param           id path string true "Student ID"
+// @Param           page query int false "page number"
+// @Param           limit query int false "limit per page"
+// @Param           group_id query string false "group id"
+// @Param           from query string false "lessons from the date, e.g. 2024-01-01"
+// @Param           to query string false "lessons up to the date, e.g. 2024-01-31"
+// @Success         200 {object} models.StudentAttendanceResponse
+// @Failure         400 {object} models.Response
+// @Failure         403 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) GetStudentAttendance(c *gin.Context) {
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err)
+		return
+	}
+	if authInfo.UserRole == config.STUDENT_ROLE && authInfo.UserID != id {
+		handleResponseLog(c, h.Log, "student can only get own attendance", http.StatusForbidden, "forbidden")
+		return
+	}
+
+	request := models.GetAllAttendanceRequest{
+		StudentID: id,
+		GroupID:   c.Query("group_id"),
+	}
+	if request.GroupID != "" {
+		if err = uuid.Validate(request.GroupID); err != nil {
+			handleResponseLog(c, h.Log, "error while validating group_id", http.StatusBadRequest, err)
+			return
+		}
+	}
+	if request.From, err = parseDateQueryParam(c, "from"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing from", http.StatusBadRequest, err)
+		return
+	}
+	if request.To, err = parseDateQueryParam(c, "to"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing to", http.StatusBadRequest, err)
+		return
+	}
+	if request.Page, err = ParsePageQueryParam(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusBadRequest, err)
+		return
+	}
+	if request.Limit, err = ParseLimitQueryParam(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	attendance, err := h.Service.Attendance().GetStudentAttendance(ctx, request)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting attendance of student", serviceErrorStatus(err), err)
+		return
+	}
+	handleResponseLog(c, h.Log, "", http.StatusOK, attendance)
+}
+
+// GetGroupAttendance godoc
+// @Security ApiKeyAuth
+// @Router          /group/{id}/attendance [GET]
+// @Summary         get the attendance rates of a group
+// @Description     Returns the attendance rate of every student enrolled in the group or marked in its lessons between from and to
+// @Tags            attendance
+// @Produce         json
+// @Param           id path string true "Group ID"
+// @Param           from query string false "lessons from the date, e.g. 2024-01-01"
+// @Param           to query string false "lessons up to the date, e.g. 2024-01-31"
+// @Success         200 {object} models.GroupAttendanceResponse
+// @Failure         400 {object} models.Response
+// @Failure         403 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) GetGroupAttendance(c *gin.Context) {
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	var (
+		request = models.GetAllAttendanceRequest{GroupID: id}
+		err     error
+	)
+	if request.From, err = parseDateQueryParam(c, "from"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing from", http.StatusBadRequest, err)
+		return
+	}
+	if request.To, err = parseDateQueryParam(c, "to"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing to", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	rates, err := h.Service.Attendance().GetGroupAttendance(ctx, request)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting attendance of group", serviceErrorStatus(err), err)
+		return
+	}
+	handleResponseLog(c, h.Log, "", http.StatusOK, rates)
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/config"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const auditTimeLayout = "2006-01-02 15:04:05"
+
+// GetAllAuditLogs godoc
+// @Security ApiKeyAuth
+// @Router 			/audit [GET]
+// @Summary 		get audit log
+// @Description 	This API returns the audit log of mutating operations, newest first
+// @Tags 			audit
+// @Produce 		json
+// @Param 			page query int false "page number"
+// @Param 			limit query int false "limit per page"
+// @Param 			entity_type query string false "entity type, e.g. student"
+// @Param 			entity_id query string false "entity id"
+// @Param 			actor_id query string false "id of the user who made the change"
+// @Param 			action query string false "action, e.g. create, update or delete"
+// @Param 			from query string false "start of the date range, 2006-01-02 or RFC3339"
+// @Param 			to query string false "end of the date range, 2006-01-02 (inclusive) or RFC3339"
+// @Success 		200 {object} models.GetAllAuditLogsResponse
+// @Failure 		400 {object} models.Response
+// @Failure 		500 {object} models.Response
+func (h Handler) GetAllAuditLogs(c *gin.Context) {
+	request := models.GetAllAuditLogsRequest{
+		EntityType: c.Query("entity_type"),
+		EntityID:   c.Query("entity_id"),
+		ActorID:    c.Query("actor_id"),
+		Action:     c.Query("action"),
+	}
+
+	from, err := parseAuditTime(c.Query("from"), false)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing from", http.StatusBadRequest, "from must be a date or an RFC3339 time")
+		return
+	}
+	to, err := parseAuditTime(c.Query("to"), true)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing to", http.StatusBadRequest, "to must be a date or an RFC3339 time")
+		return
+	}
+
+	page, err := ParsePageQueryParam(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusBadRequest, err)
+		return
+	}
+	limit, err := ParseLimitQueryParam(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusBadRequest, err)
+		return
+	}
+
+	request.From = from
+	request.To = to
+	request.Page = page
+	request.Limit = limit
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	logs, err := h.Service.Audit().GetAll(ctx, request)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting audit log", http.StatusInternalServerError, err)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "", http.StatusOK, logs)
+}
+
+// parseAuditTime converts a date or an RFC3339 time to the layout the storage
+// filters by. A date given as the end of the range includes the whole day.
+func parseAuditTime(value string, end bool) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC().Format(auditTimeLayout), nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return "", err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t.Format(auditTimeLayout), nil
+}
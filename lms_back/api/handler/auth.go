@@ -1,11 +1,16 @@
 package handler
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"lms_back/api/models"
+	"lms_back/config"
+	"lms_back/service"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // StudentLogin godoc
@@ -15,26 +20,304 @@ import (
 // @Tags         auth
 // @Accept       json
 // @Produce      json
-// @Param        login body     models.StudentLoginRequest true "login"
-// @Success      201  {object}  models.StudentLoginResponse
+// @Param        login body     models.LoginRequest true "login"
+// @Success      200  {object}  models.LoginResponse
 // @Failure      400  {object}  models.Response
-// @Failure      404  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      429  {object}  models.Response
 // @Failure      500  {object}  models.Response
 func (h *Handler) StudentLogin(c *gin.Context) {
-	loginReq := models.StudentLoginRequest{}
+	h.login(c, config.STUDENT_ROLE)
+}
+
+// AdminLogin godoc
+// @Router       /admin/login [POST]
+// @Summary      Admin login
+// @Description  Admin login
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        login body     models.LoginRequest true "login"
+// @Success      200  {object}  models.LoginResponse
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      429  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h *Handler) AdminLogin(c *gin.Context) {
+	h.login(c, config.ADMIN_ROLE)
+}
+
+// TeacherLogin godoc
+// @Router       /teacher/login [POST]
+// @Summary      Teacher login
+// @Description  Teacher login
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        login body     models.LoginRequest true "login"
+// @Success      200  {object}  models.LoginResponse
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      429  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h *Handler) TeacherLogin(c *gin.Context) {
+	h.login(c, config.TEACHER_ROLE)
+}
+
+func (h *Handler) login(c *gin.Context, role string) {
+	loginReq := models.LoginRequest{}
 
 	if err := c.ShouldBindJSON(&loginReq); err != nil {
 		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err)
 		return
 	}
-	fmt.Println("loginReq: ", loginReq)
 
-	loginResp, err := h.Service.Auth().StudentLogin(c.Request.Context(), loginReq)
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	loginResp, err := h.Service.Auth().Login(ctx, role, loginReq, c.ClientIP())
 	if err != nil {
+		var locked service.LoginLockedError
+		if errors.As(err, &locked) {
+			c.Header("Retry-After", strconv.Itoa(int(locked.RetryAfter.Seconds())))
+			handleResponseLog(c, h.Log, "too many login attempts", http.StatusTooManyRequests, err)
+			return
+		}
 		handleResponseLog(c, h.Log, "unauthorized", http.StatusUnauthorized, err)
 		return
 	}
 
 	handleResponseLog(c, h.Log, "Succes", http.StatusOK, loginResp)
+}
+
+// RefreshToken godoc
+// @Router       /auth/refresh [POST]
+// @Summary      Refresh tokens
+// @Description  Exchanges a refresh token for a new access and refresh token pair
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        token body     models.RefreshTokenRequest true "refresh token"
+// @Success      200  {object}  models.LoginResponse
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h *Handler) RefreshToken(c *gin.Context) {
+	req := models.RefreshTokenRequest{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err)
+		return
+	}
+
+	resp, err := h.Service.Auth().RefreshToken(c.Request.Context(), req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "unauthorized", http.StatusUnauthorized, err)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "tokens refreshed", http.StatusOK, resp)
+}
+
+// ForgotPassword godoc
+// @Router       /auth/forgot-password [POST]
+// @Summary      Forgot password
+// @Description  Sends a one-time password reset code to the email of the user
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body     models.ForgotPasswordRequest true "role and login of the user"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	req := models.ForgotPasswordRequest{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err)
+		return
+	}
+
+	if !isUserRole(req.Role) {
+		handleResponseLog(c, h.Log, "error while validating role", http.StatusBadRequest, "role must be admin, teacher or student")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	if err := h.Service.Auth().ForgotPassword(ctx, req); err != nil {
+		handleResponseLog(c, h.Log, "error while requesting password reset", http.StatusInternalServerError, err)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "if the account exists, a reset code has been sent", http.StatusOK, "")
+}
+
+// ResetPassword godoc
+// @Router       /auth/reset-password [POST]
+// @Summary      Reset password
+// @Description  Sets a new password of the user with a reset code
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body     models.ResetPasswordRequest true "reset code and new password"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h *Handler) ResetPassword(c *gin.Context) {
+	req := models.ResetPasswordRequest{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err)
+		return
+	}
+
+	if !isUserRole(req.Role) {
+		handleResponseLog(c, h.Log, "error while validating role", http.StatusBadRequest, "role must be admin, teacher or student")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	if err := h.Service.Auth().ResetPassword(ctx, req); err != nil {
+		if errors.Is(err, service.ErrInvalidResetCode) || errors.Is(err, service.ErrWeakPassword) {
+			handleResponseLog(c, h.Log, "error while resetting password", http.StatusBadRequest, err)
+			return
+		}
+		handleResponseLog(c, h.Log, "error while resetting password", http.StatusInternalServerError, err)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "password has been reset", http.StatusOK, "")
+}
+
+// UnlockLogin godoc
+// @Security ApiKeyAuth
+// @Router       /auth/unlock [POST]
+// @Summary      Unlock login
+// @Description  Clears the failed login attempts of a user, so they can log in again right away
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body     models.UnlockLoginRequest true "role and login of the user"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h *Handler) UnlockLogin(c *gin.Context) {
+	req := models.UnlockLoginRequest{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err)
+		return
+	}
+
+	if !isUserRole(req.Role) {
+		handleResponseLog(c, h.Log, "error while validating role", http.StatusBadRequest, "role must be admin, teacher or student")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	if err := h.Service.Auth().UnlockLogin(ctx, req); err != nil {
+		handleResponseLog(c, h.Log, "error while unlocking login", http.StatusInternalServerError, err)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "login unlocked", http.StatusOK, "")
+}
+
+func isUserRole(role string) bool {
+	return role == config.ADMIN_ROLE || role == config.TEACHER_ROLE || role == config.STUDENT_ROLE
+}
+
+// Logout godoc
+// @Security ApiKeyAuth
+// @Router       /auth/logout [POST]
+// @Summary      Logout
+// @Description  Revokes the access token of the request and the given refresh token
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        token body     models.LogoutRequest false "refresh token"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h *Handler) Logout(c *gin.Context) {
+	req := models.LogoutRequest{}
+
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err)
+		return
+	}
+
+	if err = h.Service.Auth().Logout(c.Request.Context(), authInfo, req); err != nil {
+		handleResponseLog(c, h.Log, "error while logging out", http.StatusBadRequest, err)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "logged out", http.StatusOK, "logged out")
+}
+
+type changePasswordFunc func(ctx context.Context, id string, req models.ChangePasswordRequest, checkOldPassword bool) error
+
+// changePassword lets users change their own password after confirming the
+// old one, while admins reset passwords of students and teachers and
+// superadmins reset anyone's password without it.
+func (h Handler) changePassword(c *gin.Context, role string, change changePasswordFunc) {
+	req := models.ChangePasswordRequest{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err)
+		return
+	}
+
+	checkOldPassword := true
+	switch {
+	case service.AccountRole(authInfo.UserRole) == role && authInfo.UserID == id:
+	case authInfo.UserRole == config.SUPERADMIN_ROLE:
+		checkOldPassword = false
+	case authInfo.UserRole == config.ADMIN_ROLE && role != config.ADMIN_ROLE:
+		checkOldPassword = false
+	default:
+		handleResponseLog(c, h.Log, "not allowed to change password of "+role, http.StatusForbidden, "forbidden")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	if err = change(ctx, id, req, checkOldPassword); err != nil {
+		if errors.Is(err, service.ErrForbidden) {
+			handleResponseLog(c, h.Log, "error while changing password", http.StatusForbidden, err)
+			return
+		}
+		handleResponseLog(c, h.Log, "error while changing password", http.StatusBadRequest, err)
+		return
+	}
 
+	handleResponseLog(c, h.Log, "password changed", http.StatusOK, id)
 }
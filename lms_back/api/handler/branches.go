@@ -13,6 +13,7 @@ import (
 )
 
 // CreateBranch godoc
+// @Security ApiKeyAuth
 // @Router 		   /branch [POST]
 // @Summary 	   create a branch
 // @Description    This api is creates a new branch and returns its id
@@ -20,30 +21,34 @@ import (
 // @Accept		   json
 // @Produce		   json
 // @Param		   branch body    models.CreateBranch true "car"
+// @Param		   Idempotency-Key header string false "retries with the same key return the first response"
 // @Success		   200  {object}  models.Branch
 // @Failure		   400  {object}  models.Response
 // @Failure		   404  {object}  models.Response
+// @Failure		   422  {object}  models.Response
 // @Failure		   500  {object}  models.Response
 func (h Handler) CreateBranch(c *gin.Context) {
-	branch := models.Branch{}
+	createBranch := models.CreateBranch{}
 
-	if err := c.ShouldBindJSON(&branch); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	if err := bindJSON(c, &createBranch); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
+	branch := models.Branch{Name: createBranch.Name, Address: createBranch.Address}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	id, err := h.Service.Branch().Create(ctx, branch)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while creating branch", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while creating branch", http.StatusInternalServerError, err)
 		return
 	}
 	handleResponseLog(c, h.Log, "created successfully", http.StatusOK, id)
 }
 
 // UpdateBranch godoc
+// @Security ApiKeyAuth
 // @Router                /branch/{id} [PUT]
 // @Summary 			  update a branch
 // @Description:          this api updates branch information
@@ -51,39 +56,104 @@ func (h Handler) CreateBranch(c *gin.Context) {
 // @Accept 			      json
 // @Produce 		      json
 // @Param 			      id path string true "Branch ID"
+// @Param 			      If-Match header string true "ETag of the row"
 // @Param       		  car body models.UpdateBranch true "branch"
 // @Success 		      200 {object} models.Branch
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure 		      400 {object} models.Response
 // @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
 // @Failure 		      500 {object} models.Response
 func (h Handler) UpdateBranch(c *gin.Context) {
 
-	branch := models.Branch{}
-	if err := c.ShouldBindJSON(&branch); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	updateBranch := models.UpdateBranch{}
+	if err := bindJSON(c, &updateBranch); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
 
-	branch.Id = c.Param("id")
+	branch := models.Branch{Id: c.Param("id"), Name: updateBranch.Name, Address: updateBranch.Address}
 	err := uuid.Validate(branch.Id)
 
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
 		return
 	}
 
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
+		return
+	}
+	branch.Version = version
+
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	id, err := h.Service.Branch().Update(ctx, branch)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while updating branch", http.StatusInternalServerError, err)
+		handleResponseLog(c, h.Log, "error while updating branch", serviceErrorStatus(err), err)
 		return
 	}
+	setETag(c, id.Version)
 	handleResponseLog(c, h.Log, "updated successfully", http.StatusOK, id)
 }
 
+// PatchBranch godoc
+// @Security ApiKeyAuth
+// @Router                /branch/{id} [PATCH]
+// @Summary 			  patch a branch
+// @Description           this api changes only the branch fields in the body, a JSON merge patch
+// @Tags 			      branch
+// @Accept 			      json
+// @Produce 		      json
+// @Param 			      id path string true "Branch ID"
+// @Param 			      If-Match header string true "ETag of the row"
+// @Param       		  patch body object true "fields to change by their json names"
+// @Success 		      200 {object} models.Branch
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure 		      400 {object} models.Response
+// @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
+// @Failure 		      500 {object} models.Response
+func (h Handler) PatchBranch(c *gin.Context) {
+
+	patch := models.Patch{}
+	if err := bindPatch(c, &patch, models.UpdateBranch{}); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	branch, err := h.Service.Branch().Patch(ctx, id, version, patch)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while patching branch", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, branch.Version)
+	handleResponseLog(c, h.Log, "patched successfully", http.StatusOK, branch)
+}
+
 // GetAllBranch godoc
+// @Security ApiKeyAuth
 // @Router 			/branch [GET]
 // @Summary 		get all branch
 // @Description 	This API returns branch list
@@ -92,7 +162,10 @@ func (h Handler) UpdateBranch(c *gin.Context) {
 // @Produce 		json
 // @Param 			page query int false "page number"
 // @Param 			limit query int false "limit per page"
-// @Param 			search query string false "search keyword"
+// @Param 			sort query string false "comma separated sort fields, - for descending, e.g. -created_at"
+// @Param 			cursor query string false "next_cursor of the previous page, empty for the first page of cursor pagination"
+// @Param 			deleted query string false "only to list the deleted ones"
+// @Param 			search query string false "search by name or address"
 // @Success 		200 {object} models.GetAllBranchesResponse
 // @Failure 		400 {object} models.Response
 // @Failure         404 {object} models.Response
@@ -105,12 +178,12 @@ func (h Handler) GetAllBranches(c *gin.Context) {
 
 	page, err := ParsePageQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err)
 		return
 	}
 	limit, err := ParseLimitQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err)
 		return
 	}
 	fmt.Println("page: ", page)
@@ -118,19 +191,24 @@ func (h Handler) GetAllBranches(c *gin.Context) {
 
 	request.Page = page
 	request.Limit = limit
+	if request.ListParams, err = parseListParams(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing list params", http.StatusBadRequest, err)
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	branches, err := h.Service.Branch().GetAll(ctx, request)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting branches", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting branches", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "", http.StatusOK, branches)
 }
 
 // GetByIDBranch godoc
+// @Security ApiKeyAuth
 // @Router       /branch/{id} [GET]
 // @Summary      return a branch by ID
 // @Description  Retrieves a branch by its ID
@@ -139,6 +217,7 @@ func (h Handler) GetAllBranches(c *gin.Context) {
 // @Produce      json
 // @Param        id path string true "Branch ID"
 // @Success      200 {object} models.GetBranch
+// @Header       200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure      400 {object} models.Response
 // @Failure      404 {object} models.Response
 // @Failure      500 {object} models.Response
@@ -155,10 +234,12 @@ func (h Handler) GetByIDBranch(c *gin.Context) {
 		handleResponseLog(c, h.Log, "error while getting branch by id", http.StatusInternalServerError, err)
 		return
 	}
+	setETag(c, Branch.Version)
 	handleResponseLog(c, h.Log, "", http.StatusOK, Branch)
 }
 
 // DeleteBranch godoc
+// @Security ApiKeyAuth
 // @Router          /branch/{id} [DELETE]
 // @Summary         delete a branch by ID
 // @Description     Deletes a branch by its ID
@@ -166,9 +247,12 @@ func (h Handler) GetByIDBranch(c *gin.Context) {
 // @Accept          json
 // @Produce         json
 // @Param           id path string true "Branch ID"
+// @Param           If-Match header string true "ETag of the row"
 // @Success         200 {string} models.Response
 // @Failure         400 {object} models.Response
 // @Failure         404 {object} models.Response
+// @Failure         412 {object} models.Response
+// @Failure         428 {object} models.Response
 // @Failure         500 {object} models.Response
 func (h Handler) DeleteBranch(c *gin.Context) {
 
@@ -177,17 +261,59 @@ func (h Handler) DeleteBranch(c *gin.Context) {
 
 	err := uuid.Validate(id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
-	err = h.Service.Branch().Delete(ctx, id)
+	err = h.Service.Branch().Delete(ctx, id, version)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while deleting branch", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while deleting branch", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "", http.StatusOK, id)
 }
+
+// RestoreBranch godoc
+// @Security ApiKeyAuth
+// @Router          /branch/{id}/restore [POST]
+// @Summary         restore a deleted branch
+// @Description     Takes a deleted branch out of the trash
+// @Tags            branch
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Branch ID"
+// @Success         200 {object} models.Branch
+// @Header          200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure         400 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) RestoreBranch(c *gin.Context) {
+
+	id := c.Param("id")
+
+	err := uuid.Validate(id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	branch, err := h.Service.Branch().Restore(ctx, id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while restoring branch", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, branch.Version)
+	handleResponseLog(c, h.Log, "restored branch", http.StatusOK, branch)
+}
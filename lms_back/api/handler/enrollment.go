@@ -0,0 +1,250 @@
+package handler
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// EnrollStudent godoc
+// @Security ApiKeyAuth
+// @Router          /student/{id}/enroll [POST]
+// @Summary         enroll a student in a group
+// @Description     Enrolls the student in another group, a student without a group gets it as its primary group
+// @Tags            enrollment
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Student ID"
+// @Param           enrollment body models.EnrollRequest true "group"
+// @Success         200 {object} models.Enrollment
+// @Failure         400 {object} models.Response
+// @Failure         403 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         409 {object} models.Response
+// @Failure         422 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) EnrollStudent(c *gin.Context) {
+	req := models.EnrollRequest{}
+	if err := bindJSON(c, &req); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	enrollment, err := h.Service.Enrollment().Enroll(ctx, id, req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while enrolling student", serviceErrorStatus(err), err)
+		return
+	}
+	handleResponseLog(c, h.Log, "enrolled student", http.StatusOK, enrollment)
+}
+
+// TransferStudent godoc
+// @Security ApiKeyAuth
+// @Router          /student/{id}/transfer [POST]
+// @Summary         transfer a student to another group
+// @Description     Ends the enrollment in from_group_id as transferred and enrolls the student in to_group_id, the history keeps both
+// @Tags            enrollment
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Student ID"
+// @Param           transfer body models.TransferRequest true "groups"
+// @Success         200 {object} models.Enrollment
+// @Failure         400 {object} models.Response
+// @Failure         403 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         409 {object} models.Response
+// @Failure         422 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) TransferStudent(c *gin.Context) {
+	req := models.TransferRequest{}
+	if err := bindJSON(c, &req); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	enrollment, err := h.Service.Enrollment().Transfer(ctx, id, req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while transferring student", serviceErrorStatus(err), err)
+		return
+	}
+	handleResponseLog(c, h.Log, "transferred student", http.StatusOK, enrollment)
+}
+
+// WithdrawStudent godoc
+// @Security ApiKeyAuth
+// @Router          /student/{id}/withdraw [POST]
+// @Summary         withdraw a student from a group
+// @Description     Ends the enrollment of the student in the group as withdrawn
+// @Tags            enrollment
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Student ID"
+// @Param           withdraw body models.WithdrawRequest true "group"
+// @Success         200 {object} models.Enrollment
+// @Failure         400 {object} models.Response
+// @Failure         403 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         422 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) WithdrawStudent(c *gin.Context) {
+	req := models.WithdrawRequest{}
+	if err := bindJSON(c, &req); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	enrollment, err := h.Service.Enrollment().Withdraw(ctx, id, req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while withdrawing student", serviceErrorStatus(err), err)
+		return
+	}
+	handleResponseLog(c, h.Log, "withdrew student", http.StatusOK, enrollment)
+}
+
+// GetStudentEnrollments godoc
+// @Security ApiKeyAuth
+// @Router          /student/{id}/enrollments [GET]
+// @Summary         get the enrollments of a student
+// @Description     Returns the groups the student is and was in, newest first
+// @Tags            enrollment
+// @Produce         json
+// @Param           id path string true "Student ID"
+// @Param           page query int false "page number"
+// @Param           limit query int false "limit per page"
+// @Param           group_id query string false "group id"
+// @Param           status query string false "active, transferred or withdrawn"
+// @Success         200 {object} models.GetAllEnrollmentsResponse
+// @Failure         400 {object} models.Response
+// @Failure         403 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) GetStudentEnrollments(c *gin.Context) {
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err)
+		return
+	}
+	if authInfo.UserRole == config.STUDENT_ROLE && authInfo.UserID != id {
+		handleResponseLog(c, h.Log, "student can only get own enrollments", http.StatusForbidden, "forbidden")
+		return
+	}
+
+	request := models.GetAllEnrollmentsRequest{
+		StudentID: id,
+		GroupID:   c.Query("group_id"),
+		Status:    c.Query("status"),
+	}
+	if request.Page, err = ParsePageQueryParam(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusBadRequest, err)
+		return
+	}
+	if request.Limit, err = ParseLimitQueryParam(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	enrollments, err := h.Service.Enrollment().GetAll(ctx, request)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting enrollments of student", serviceErrorStatus(err), err)
+		return
+	}
+	handleResponseLog(c, h.Log, "", http.StatusOK, enrollments)
+}
+
+// GetGroupStudents godoc
+// @Security ApiKeyAuth
+// @Router          /group/{id}/students [GET]
+// @Summary         get the students of a group
+// @Description     Returns the students actively enrolled in the group, whether it is their primary group or not
+// @Tags            enrollment
+// @Produce         json
+// @Param           id path string true "Group ID"
+// @Param           page query int false "page number"
+// @Param           limit query int false "limit per page"
+// @Param           sort query string false "comma separated sort fields, - for descending, e.g. -created_at"
+// @Param           cursor query string false "next_cursor of the previous page, empty for the first page of cursor pagination"
+// @Param           search query string false "search by full name, email or login"
+// @Param           status query string false "status"
+// @Success         200 {object} models.GetAllStudentsResponse
+// @Failure         400 {object} models.Response
+// @Failure         403 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) GetGroupStudents(c *gin.Context) {
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	var (
+		request = models.GetAllStudentsRequest{
+			EnrolledGroupID: id,
+			Search:          c.Query("search"),
+			Status:          c.Query("status"),
+		}
+		err error
+	)
+	if request.Page, err = ParsePageQueryParam(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusBadRequest, err)
+		return
+	}
+	if request.Limit, err = ParseLimitQueryParam(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusBadRequest, err)
+		return
+	}
+	if request.ListParams, err = parseListParams(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing list params", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	students, err := h.Service.Enrollment().GetGroupStudents(ctx, request)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting students of group", serviceErrorStatus(err), err)
+		return
+	}
+	handleResponseLog(c, h.Log, "", http.StatusOK, students)
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ifMatchHeader = "If-Match"
+
+var (
+	// errPreconditionRequired is returned for a write of a row without If-Match.
+	errPreconditionRequired = errors.New("If-Match header with the ETag of the row is required")
+
+	// errPreconditionFailed is returned for an If-Match that is not an ETag of the api.
+	errPreconditionFailed = errors.New("If-Match must be the ETag of the row, like \"3\"")
+)
+
+// setETag returns the version of the row in the ETag header, clients send it
+// back in If-Match to update or delete the row.
+func setETag(c *gin.Context, version int) {
+	c.Header("ETag", strconv.Quote(strconv.Itoa(version)))
+}
+
+// parseIfMatch returns the version of the row in the If-Match header. Weak
+// and wildcard tags are not accepted, a write must name the version it read.
+func parseIfMatch(c *gin.Context) (int, error) {
+	value := c.GetHeader(ifMatchHeader)
+	if value == "" {
+		return 0, errPreconditionRequired
+	}
+
+	tag, err := strconv.Unquote(value)
+	if err != nil {
+		return 0, errPreconditionFailed
+	}
+	version, err := strconv.Atoi(tag)
+	if err != nil || version < 1 {
+		return 0, errPreconditionFailed
+	}
+
+	return version, nil
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetGroupGradebook godoc
+// @Security ApiKeyAuth
+// @Router          /group/{id}/gradebook [GET]
+// @Summary         get the gradebook of a group
+// @Description     Returns the scores of the students actively enrolled in the group for the tasks of its lessons between from and to, with the average and attendance rate of every student and the average of every task
+// @Tags            gradebook
+// @Produce         json
+// @Param           id path string true "Group ID"
+// @Param           from query string false "lessons from the date, e.g. 2024-01-01"
+// @Param           to query string false "lessons up to the date, e.g. 2024-01-31"
+// @Success         200 {object} models.Gradebook
+// @Failure         400 {object} models.Response
+// @Failure         403 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) GetGroupGradebook(c *gin.Context) {
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	var (
+		request = models.GradebookRequest{GroupID: id}
+		err     error
+	)
+	if request.From, err = parseDateQueryParam(c, "from"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing from", http.StatusBadRequest, err)
+		return
+	}
+	if request.To, err = parseDateQueryParam(c, "to"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing to", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	gradebook, err := h.Service.Gradebook().Gradebook(ctx, request)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting gradebook of group", serviceErrorStatus(err), err)
+		return
+	}
+	handleResponseLog(c, h.Log, "", http.StatusOK, gradebook)
+}
+
+// GetStudentProgress godoc
+// @Security ApiKeyAuth
+// @Router          /student/{id}/progress [GET]
+// @Summary         get the progress of a student
+// @Description     Returns the completed lessons, average score, attendance rate, trend and rank of the student in the group, the primary group of the student if group_id is not given
+// @Tags            gradebook
+// @Produce         json
+// @Param           id path string true "Student ID"
+// @Param           group_id query string false "group id"
+// @Param           from query string false "lessons from the date, e.g. 2024-01-01"
+// @Param           to query string false "lessons up to the date, e.g. 2024-01-31"
+// @Success         200 {object} models.StudentProgress
+// @Failure         400 {object} models.Response
+// @Failure         403 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) GetStudentProgress(c *gin.Context) {
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err)
+		return
+	}
+	if authInfo.UserRole == config.STUDENT_ROLE && authInfo.UserID != id {
+		handleResponseLog(c, h.Log, "student can only get own progress", http.StatusForbidden, "forbidden")
+		return
+	}
+
+	request := models.GradebookRequest{GroupID: c.Query("group_id")}
+	if request.GroupID != "" {
+		if err = uuid.Validate(request.GroupID); err != nil {
+			handleResponseLog(c, h.Log, "error while validating group_id", http.StatusBadRequest, err)
+			return
+		}
+	}
+	if request.From, err = parseDateQueryParam(c, "from"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing from", http.StatusBadRequest, err)
+		return
+	}
+	if request.To, err = parseDateQueryParam(c, "to"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing to", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	progress, err := h.Service.Gradebook().Progress(ctx, id, request)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting progress of student", serviceErrorStatus(err), err)
+		return
+	}
+	handleResponseLog(c, h.Log, "", http.StatusOK, progress)
+}
@@ -13,6 +13,7 @@ import (
 )
 
 // CreateGroup godoc
+// @Security ApiKeyAuth
 // @Router 		   /group [POST]
 // @Summary 	   create a group
 // @Description    This api is creates a new group and returns its id
@@ -20,24 +21,31 @@ import (
 // @Accept		   json
 // @Produce		   json
 // @Param		   group body    models.CreateGroup true "group"
+// @Param		   Idempotency-Key header string false "retries with the same key return the first response"
 // @Success		   200  {object}  models.Group
 // @Failure		   400  {object}  models.Response
 // @Failure		   404  {object}  models.Response
+// @Failure		   422  {object}  models.Response
 // @Failure		   500  {object}  models.Response
 func (h Handler) CreateGroup(c *gin.Context) {
-	group := models.Group{}
+	createGroup := models.CreateGroup{}
 
-	if err := c.ShouldBindJSON(&group); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	if err := bindJSON(c, &createGroup); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
+	group := models.Group{
+		Branch_id:  createGroup.Branch_id,
+		Teacher_id: createGroup.Teacher_id,
+		Type:       createGroup.Type,
+	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	id, err := h.Service.Group().Create(ctx, group)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while creating group", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while creating group", serviceErrorStatus(err), err)
 		return
 	}
 
@@ -45,6 +53,7 @@ func (h Handler) CreateGroup(c *gin.Context) {
 }
 
 // UpdateGroup godoc
+// @Security ApiKeyAuth
 // @Router                /group/{id} [PUT]
 // @Summary 			  update a group
 // @Description:          this api updates group information
@@ -52,36 +61,104 @@ func (h Handler) CreateGroup(c *gin.Context) {
 // @Accept 			      json
 // @Produce 		      json
 // @Param 			      id path string true "group ID"
+// @Param 			      If-Match header string true "ETag of the row"
 // @Param       		  group body models.UpdateGroup true "group"
 // @Success 		      200 {object} models.Group
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure 		      400 {object} models.Response
 // @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
 // @Failure 		      500 {object} models.Response
 func (h Handler) UpdateGroup(c *gin.Context) {
-	group := models.Group{}
-	if err := c.ShouldBindJSON(&group); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	updateGroup := models.UpdateGroup{}
+	if err := bindJSON(c, &updateGroup); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
-	group.Id = c.Param("id")
+	group := models.Group{
+		Id:   c.Param("id"),
+		Type: updateGroup.Type,
+	}
 	err := uuid.Validate(group.Id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
+	group.Version = version
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	id, err := h.Service.Group().Update(ctx, group)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while updating group", http.StatusInternalServerError, err)
+		handleResponseLog(c, h.Log, "error while updating group", serviceErrorStatus(err), err)
 		return
 	}
+	setETag(c, id.Version)
 	handleResponseLog(c, h.Log, "updated successfully", http.StatusOK, id)
 }
 
+// PatchGroup godoc
+// @Security ApiKeyAuth
+// @Router                /group/{id} [PATCH]
+// @Summary 			  patch a group
+// @Description           this api changes only the group fields in the body, a JSON merge patch
+// @Tags 			      group
+// @Accept 			      json
+// @Produce 		      json
+// @Param 			      id path string true "Group ID"
+// @Param 			      If-Match header string true "ETag of the row"
+// @Param       		  patch body object true "fields to change by their json names"
+// @Success 		      200 {object} models.Group
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure 		      400 {object} models.Response
+// @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
+// @Failure 		      500 {object} models.Response
+func (h Handler) PatchGroup(c *gin.Context) {
+
+	patch := models.Patch{}
+	if err := bindPatch(c, &patch, models.UpdateGroup{}); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	group, err := h.Service.Group().Patch(ctx, id, version, patch)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while patching group", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, group.Version)
+	handleResponseLog(c, h.Log, "patched successfully", http.StatusOK, group)
+}
+
 // GetAllGroup godoc
+// @Security ApiKeyAuth
 // @Router 			/group [GET]
 // @Summary 		get all Group
 // @Description 	This API returns group list
@@ -90,7 +167,13 @@ func (h Handler) UpdateGroup(c *gin.Context) {
 // @Produce 		json
 // @Param 			page query int false "page number"
 // @Param 			limit query int false "limit per page"
-// @Param 			search query string false "search keyword"
+// @Param 			sort query string false "comma separated sort fields, - for descending, e.g. -created_at"
+// @Param 			cursor query string false "next_cursor of the previous page, empty for the first page of cursor pagination"
+// @Param 			deleted query string false "only to list the deleted ones"
+// @Param 			search query string false "search by group code, e.g. GR-001"
+// @Param 			branch_id query string false "branch id"
+// @Param 			teacher_id query string false "teacher id"
+// @Param 			type query string false "group type"
 // @Success 		200 {object} models.GetAllGroupsResponse
 // @Failure 		400 {object} models.Response
 // @Failure         404 {object} models.Response
@@ -101,15 +184,18 @@ func (h Handler) GetAllGroups(c *gin.Context) {
 	)
 
 	request.Search = c.Query("search")
+	request.BranchID = c.Query("branch_id")
+	request.TeacherID = c.Query("teacher_id")
+	request.Type = c.Query("type")
 
 	page, err := ParsePageQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err)
 		return
 	}
 	limit, err := ParseLimitQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err)
 		return
 	}
 	fmt.Println("page: ", page)
@@ -117,19 +203,24 @@ func (h Handler) GetAllGroups(c *gin.Context) {
 
 	request.Page = page
 	request.Limit = limit
+	if request.ListParams, err = parseListParams(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing list params", http.StatusBadRequest, err)
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	groups, err := h.Service.Group().GetAll(ctx, request)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting groups", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting groups", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "", http.StatusOK, groups)
 }
 
 // GetByIDGroup godoc
+// @Security ApiKeyAuth
 // @Router       /group/{id} [GET]
 // @Summary      return a group by ID
 // @Description  Retrieves a group by its ID
@@ -138,6 +229,7 @@ func (h Handler) GetAllGroups(c *gin.Context) {
 // @Produce      json
 // @Param        id path string true "Group ID"
 // @Success      200 {object} models.GetGroup
+// @Header       200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure      400 {object} models.Response
 // @Failure      404 {object} models.Response
 // @Failure      500 {object} models.Response
@@ -152,13 +244,15 @@ func (h Handler) GetByIDGroup(c *gin.Context) {
 	group, err := h.Service.Group().GetByID(ctx, id)
 	if err != nil {
 		fmt.Println("error while getting group by id")
-		handleResponseLog(c, h.Log, "", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "", serviceErrorStatus(err), err)
 		return
 	}
+	setETag(c, group.Version)
 	handleResponseLog(c, h.Log, "", http.StatusOK, group)
 }
 
 // DeleteGroup godoc
+// @Security ApiKeyAuth
 // @Router          /group/{id} [DELETE]
 // @Summary         delete a group by ID
 // @Description     Deletes a group by its ID
@@ -166,9 +260,12 @@ func (h Handler) GetByIDGroup(c *gin.Context) {
 // @Accept          json
 // @Produce         json
 // @Param           id path string true "Group ID"
+// @Param           If-Match header string true "ETag of the row"
 // @Success         200 {string} models.Response
 // @Failure         400 {object} models.Response
 // @Failure         404 {object} models.Response
+// @Failure         412 {object} models.Response
+// @Failure         428 {object} models.Response
 // @Failure         500 {object} models.Response
 func (h Handler) DeleteGroup(c *gin.Context) {
 
@@ -177,17 +274,59 @@ func (h Handler) DeleteGroup(c *gin.Context) {
 
 	err := uuid.Validate(id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
-	err = h.Service.Group().Delete(ctx, id)
+	err = h.Service.Group().Delete(ctx, id, version)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while deleting group", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while deleting group", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "deleted successfully", http.StatusOK, id)
 }
+
+// RestoreGroup godoc
+// @Security ApiKeyAuth
+// @Router          /group/{id}/restore [POST]
+// @Summary         restore a deleted group
+// @Description     Takes a deleted group out of the trash
+// @Tags            group
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Group ID"
+// @Success         200 {object} models.Group
+// @Header          200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure         400 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) RestoreGroup(c *gin.Context) {
+
+	id := c.Param("id")
+
+	err := uuid.Validate(id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	group, err := h.Service.Group().Restore(ctx, id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while restoring group", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, group.Version)
+	handleResponseLog(c, h.Log, "restored group", http.StatusOK, group)
+}
@@ -1,11 +1,17 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"lms_back/api/models"
 	"lms_back/config"
+	"lms_back/pkg/errs"
 	"lms_back/pkg/logger"
 	"lms_back/service"
+	"lms_back/storage"
+	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,6 +22,7 @@ type Handler struct {
 }
 
 func NewStrg(service service.IServiceManager, log logger.ILogger) Handler {
+	registerValidators()
 	return Handler{
 		Service: service,
 		Log: log,
@@ -25,6 +32,16 @@ func NewStrg(service service.IServiceManager, log logger.ILogger) Handler {
 func handleResponseLog(c *gin.Context, log logger.ILogger, msg string, statusCode int, data interface{}) {
 	resp := models.Response{}
 
+	// errors are answered with their message, domain errors with the status
+	// of their kind, their code and invalid fields
+	if err, ok := data.(error); ok {
+		if domainErr, ok := errs.As(err); ok {
+			statusCode = kindStatus(domainErr.Kind)
+			resp.Code, resp.Errors = domainErr.Code, domainErr.Fields
+		}
+		data = err.Error()
+	}
+
 	if statusCode >= 100 && statusCode <= 199 {
 		resp.Description = config.ERR_INFORMATION
 	} else if statusCode >= 200 && statusCode <= 299 {
@@ -33,6 +50,21 @@ func handleResponseLog(c *gin.Context, log logger.ILogger, msg string, statusCod
 
 	} else if statusCode >= 300 && statusCode <= 399 {
 		resp.Description = config.ERR_REDIRECTION
+	} else if statusCode == 401 {
+		resp.Description = config.ERR_UNAUTHORIZED
+		log.Error("!!!!!!!! UNAUTHORIZED !!!!!!!!", logger.Any("error: ", msg), logger.Int("status: ", statusCode))
+	} else if statusCode == 403 {
+		resp.Description = config.ERR_FORBIDDEN
+		log.Error("!!!!!!!! FORBIDDEN !!!!!!!!", logger.Any("error: ", msg), logger.Int("status: ", statusCode))
+	} else if statusCode == 404 {
+		resp.Description = config.ERR_NOT_FOUND
+		log.Error("!!!!!!!! NOT FOUND !!!!!!!!", logger.Any("error: ", msg), logger.Int("status: ", statusCode))
+	} else if statusCode == 409 {
+		resp.Description = config.ERR_CONFLICT
+		log.Error("!!!!!!!! CONFLICT !!!!!!!!", logger.Any("error: ", msg), logger.Int("status: ", statusCode))
+	} else if statusCode == 422 {
+		resp.Description = config.ERR_VALIDATION
+		log.Error("!!!!!!!! VALIDATION FAILED !!!!!!!!", logger.Any("error: ", msg), logger.Int("status: ", statusCode))
 	} else if statusCode >= 400 && statusCode <= 499 {
 		resp.Description = config.ERR_BADREQUEST
 		log.Error("!!!!!!!! BAD REQUEST !!!!!!!!", logger.Any("error: ", msg), logger.Int("status: ", statusCode))
@@ -47,6 +79,41 @@ func handleResponseLog(c *gin.Context, log logger.ILogger, msg string, statusCod
 	c.JSON(resp.StatusCode, resp)
 }
 
+// serviceErrorStatus maps an error of the service layer to the response
+// status. Domain errors get the status of their kind in handleResponseLog.
+func serviceErrorStatus(err error) int {
+	if errors.Is(err, storage.ErrInvalidSort) || errors.Is(err, storage.ErrInvalidCursor) {
+		return http.StatusBadRequest
+	}
+	var conflict *storage.VersionConflictError
+	if errors.As(err, &conflict) || errors.Is(err, errPreconditionFailed) {
+		return http.StatusPreconditionFailed
+	}
+	if errors.Is(err, errPreconditionRequired) {
+		return http.StatusPreconditionRequired
+	}
+
+	return http.StatusInternalServerError
+}
+
+// kindStatus is the response status of the domain errors of the kind.
+func kindStatus(kind errs.Kind) int {
+	switch kind {
+	case errs.KindNotFound:
+		return http.StatusNotFound
+	case errs.KindConflict:
+		return http.StatusConflict
+	case errs.KindValidation:
+		return http.StatusUnprocessableEntity
+	case errs.KindForbidden:
+		return http.StatusForbidden
+	case errs.KindUnauthorized:
+		return http.StatusUnauthorized
+	}
+
+	return http.StatusInternalServerError
+}
+
 func ParsePageQueryParam(c *gin.Context) (uint64, error) {
 	pageStr := c.Query("page")
 	if pageStr == "" {
@@ -77,3 +144,49 @@ func ParseLimitQueryParam(c *gin.Context) (uint64, error) {
 	}
 	return limit, nil
 }
+
+// parseDateQueryParam returns the "2006-01-02" date of the query param, or an
+// empty string if it is not set.
+func parseDateQueryParam(c *gin.Context, name string) (string, error) {
+	value := c.Query(name)
+	if value == "" {
+		return "", nil
+	}
+
+	if _, err := time.Parse(time.DateOnly, value); err != nil {
+		return "", fmt.Errorf("%s must be a date like 2006-01-02", name)
+	}
+
+	return value, nil
+}
+
+// parseFloatQueryParam returns the number of the query param, or 0 if it is not set.
+func parseFloatQueryParam(c *gin.Context, name string) (float64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+
+	return number, nil
+}
+
+// parseListParams returns the sort, cursor and deleted query params. Passing
+// the cursor param, even empty, switches the list to cursor pagination.
+func parseListParams(c *gin.Context) (models.ListParams, error) {
+	params := models.ListParams{
+		Sort:    c.Query("sort"),
+		Deleted: c.Query("deleted"),
+	}
+	params.Cursor, params.UseCursor = c.GetQuery("cursor")
+
+	if params.Deleted != "" && params.Deleted != models.DeletedOnly {
+		return models.ListParams{}, fmt.Errorf("deleted must be %s", models.DeletedOnly)
+	}
+
+	return params, nil
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"lms_back/api/models"
+	"lms_back/pkg/logger"
+	"lms_back/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// responseRecorder keeps a copy of the response body for storing it with the idempotency key.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware makes retries of a request with the same Idempotency-Key
+// header return the response of the first request instead of running it again.
+// Reusing a key for a different request is rejected with 422. Requests without
+// the header are not affected.
+func (h Handler) IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keyValue := c.GetHeader(idempotencyKeyHeader)
+		if keyValue == "" {
+			c.Next()
+			return
+		}
+		if len(keyValue) > 255 {
+			handleResponseLog(c, h.Log, "idempotency key is too long", http.StatusBadRequest, "Idempotency-Key must be at most 255 characters long")
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			handleResponseLog(c, h.Log, "error while reading request body", http.StatusBadRequest, err)
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		key, replay, err := h.Service.Idempotency().Start(c.Request.Context(), models.IdempotencyKey{
+			Key:    keyValue,
+			Method: c.Request.Method,
+			Path:   c.Request.URL.Path,
+		}, body)
+		if err != nil {
+			handleResponseLog(c, h.Log, "error while checking idempotency key", idempotencyErrorStatus(err), err)
+			c.Abort()
+			return
+		}
+		if replay {
+			c.Header("Idempotent-Replayed", "true")
+			if key.ETag != "" {
+				c.Header("ETag", key.ETag)
+			}
+			c.Data(key.StatusCode, key.ContentType, key.Response)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		c.Next()
+
+		// server errors are not stored, so the request can be retried with the same key
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			if err = h.Service.Idempotency().Release(c.Request.Context(), key); err != nil {
+				h.Log.Error("error while releasing idempotency key", logger.Error(err))
+			}
+			return
+		}
+
+		key.StatusCode = c.Writer.Status()
+		key.ContentType = c.Writer.Header().Get("Content-Type")
+		key.ETag = c.Writer.Header().Get("ETag")
+		key.Response = recorder.body.Bytes()
+		if err = h.Service.Idempotency().Complete(c.Request.Context(), key); err != nil {
+			h.Log.Error("error while storing idempotent response", logger.Error(err))
+		}
+	}
+}
+
+func idempotencyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -13,6 +13,7 @@ import (
 )
 
 // CreateLesson godoc
+// @Security ApiKeyAuth
 // @Router 		   /lesson [POST]
 // @Summary 	   create a lesson
 // @Description    This api is creates a new lesson and returns its id
@@ -20,30 +21,40 @@ import (
 // @Accept		   json
 // @Produce		   json
 // @Param		   lesson body    models.CreateLesson true "lesson"
+// @Param		   Idempotency-Key header string false "retries with the same key return the first response"
 // @Success		   200  {object}  models.Lesson
 // @Failure		   400  {object}  models.Response
 // @Failure		   404  {object}  models.Response
+// @Failure		   422  {object}  models.Response
 // @Failure		   500  {object}  models.Response
 func (h Handler) CreateLesson(c *gin.Context) {
-	lesson := models.Lesson{}
+	createLesson := models.CreateLesson{}
 
-	if err := c.ShouldBindJSON(&lesson); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	if err := bindJSON(c, &createLesson); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
+	lesson := models.Lesson{
+		ScheduleId: createLesson.ScheduleId,
+		GroupId:    createLesson.GroupId,
+		From:       createLesson.From,
+		To:         createLesson.To,
+		Theme:      createLesson.Theme,
+	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	id, err := h.Service.Lesson().Create(ctx, lesson)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while creating lesson", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while creating lesson", http.StatusInternalServerError, err)
 		return
 	}
 	handleResponseLog(c, h.Log, "created successfully", http.StatusOK, id)
 }
 
 // UpdateLesson godoc
+// @Security ApiKeyAuth
 // @Router                /lesson/{id} [PUT]
 // @Summary 			  update a lesson
 // @Description:          this api updates lesson information
@@ -51,39 +62,111 @@ func (h Handler) CreateLesson(c *gin.Context) {
 // @Accept 			      json
 // @Produce 		      json
 // @Param 			      id path string true "Lesson ID"
+// @Param 			      If-Match header string true "ETag of the row"
 // @Param       		  car body models.UpdateLesson true "Lesson"
 // @Success 		      200 {object} models.Lesson
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure 		      400 {object} models.Response
 // @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
 // @Failure 		      500 {object} models.Response
 func (h Handler) UpdateLesson(c *gin.Context) {
 
-	lesson := models.Lesson{}
-	if err := c.ShouldBindJSON(&lesson); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	updateLesson := models.UpdateLesson{}
+	if err := bindJSON(c, &updateLesson); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
 
-	lesson.Id = c.Param("id")
+	lesson := models.Lesson{
+		Id:         c.Param("id"),
+		ScheduleId: updateLesson.ScheduleId,
+		GroupId:    updateLesson.GroupId,
+		From:       updateLesson.From,
+		To:         updateLesson.To,
+		Theme:      updateLesson.Theme,
+	}
 	err := uuid.Validate(lesson.Id)
 
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
 		return
 	}
 
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
+		return
+	}
+	lesson.Version = version
+
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	id, err := h.Service.Lesson().Update(ctx, lesson)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while updating lesson", http.StatusInternalServerError, err)
+		handleResponseLog(c, h.Log, "error while updating lesson", serviceErrorStatus(err), err)
 		return
 	}
+	setETag(c, id.Version)
 	handleResponseLog(c, h.Log, "updated successfully", http.StatusOK, id)
 }
 
+// PatchLesson godoc
+// @Security ApiKeyAuth
+// @Router                /lesson/{id} [PATCH]
+// @Summary 			  patch a lesson
+// @Description           this api changes only the lesson fields in the body, a JSON merge patch
+// @Tags 			      lesson
+// @Accept 			      json
+// @Produce 		      json
+// @Param 			      id path string true "Lesson ID"
+// @Param 			      If-Match header string true "ETag of the row"
+// @Param       		  patch body object true "fields to change by their json names"
+// @Success 		      200 {object} models.Lesson
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure 		      400 {object} models.Response
+// @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
+// @Failure 		      500 {object} models.Response
+func (h Handler) PatchLesson(c *gin.Context) {
+
+	patch := models.Patch{}
+	if err := bindPatch(c, &patch, models.UpdateLesson{}); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	lesson, err := h.Service.Lesson().Patch(ctx, id, version, patch)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while patching lesson", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, lesson.Version)
+	handleResponseLog(c, h.Log, "patched successfully", http.StatusOK, lesson)
+}
+
 // GetAllLessons godoc
+// @Security ApiKeyAuth
 // @Router 			/lesson [GET]
 // @Summary 		Get All Lessons
 // @Description 	This API returns lesson list
@@ -92,7 +175,14 @@ func (h Handler) UpdateLesson(c *gin.Context) {
 // @Produce 		json
 // @Param 			page query int false "page number"
 // @Param 			limit query int false "limit per page"
-// @Param 			search query string false "search keyword"
+// @Param 			sort query string false "comma separated sort fields, - for descending, e.g. -created_at"
+// @Param 			cursor query string false "next_cursor of the previous page, empty for the first page of cursor pagination"
+// @Param 			deleted query string false "only to list the deleted ones"
+// @Param 			search query string false "search by theme"
+// @Param 			group_id query string false "group id"
+// @Param 			schedule_id query string false "schedule id"
+// @Param 			from_date query string false "starts on or after, 2006-01-02"
+// @Param 			to_date query string false "starts on or before, 2006-01-02"
 // @Success 		200 {object} models.GetAllLessonsResponse
 // @Failure 		400 {object} models.Response
 // @Failure         404 {object} models.Response
@@ -100,17 +190,28 @@ func (h Handler) UpdateLesson(c *gin.Context) {
 func (h Handler) GetAllLessons(c *gin.Context) {
 
 	request := models.GetAllLessonsRequest{}
+	var err error
 
 	request.Search = c.Query("search")
+	request.GroupID = c.Query("group_id")
+	request.ScheduleID = c.Query("schedule_id")
+	if request.FromDate, err = parseDateQueryParam(c, "from_date"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing from_date", http.StatusBadRequest, err)
+		return
+	}
+	if request.ToDate, err = parseDateQueryParam(c, "to_date"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing to_date", http.StatusBadRequest, err)
+		return
+	}
 
 	page, err := ParsePageQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err)
 		return
 	}
 	limit, err := ParseLimitQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err)
 		return
 	}
 	fmt.Println("page: ", page)
@@ -118,19 +219,24 @@ func (h Handler) GetAllLessons(c *gin.Context) {
 
 	request.Page = page
 	request.Limit = limit
+	if request.ListParams, err = parseListParams(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing list params", http.StatusBadRequest, err)
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	branches, err := h.Service.Lesson().GetAll(ctx, request)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting branches", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting branches", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "", http.StatusOK, branches)
 }
 
 // GetByIDLesson godoc
+// @Security ApiKeyAuth
 // @Router       /lesson/{id} [GET]
 // @Summary      return a lesson by ID
 // @Description  Retrieves a lesson by its ID
@@ -139,6 +245,7 @@ func (h Handler) GetAllLessons(c *gin.Context) {
 // @Produce      json
 // @Param        id path string true "Lesson ID"
 // @Success      200 {object} models.GetLesson
+// @Header       200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure      400 {object} models.Response
 // @Failure      404 {object} models.Response
 // @Failure      500 {object} models.Response
@@ -155,10 +262,12 @@ func (h Handler) GetByIDLesson(c *gin.Context) {
 		handleResponseLog(c, h.Log, "error while getting lesson by id", http.StatusInternalServerError, err)
 		return
 	}
+	setETag(c, Branch.Version)
 	handleResponseLog(c, h.Log, "", http.StatusOK, Branch)
 }
 
 // DeleteLessson godoc
+// @Security ApiKeyAuth
 // @Router          /lesson/{id} [DELETE]
 // @Summary         Deletes a lesson by ID
 // @Description     Deletes a lesson by its ID
@@ -166,9 +275,12 @@ func (h Handler) GetByIDLesson(c *gin.Context) {
 // @Accept          json
 // @Produce         json
 // @Param           id path string true "Lesson ID"
+// @Param           If-Match header string true "ETag of the row"
 // @Success         200 {string} models.Response
 // @Failure         400 {object} models.Response
 // @Failure         404 {object} models.Response
+// @Failure         412 {object} models.Response
+// @Failure         428 {object} models.Response
 // @Failure         500 {object} models.Response
 func (h Handler) DeleteLessson(c *gin.Context) {
 
@@ -177,17 +289,59 @@ func (h Handler) DeleteLessson(c *gin.Context) {
 
 	err := uuid.Validate(id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
-	err = h.Service.Lesson().Delete(ctx, id)
+	err = h.Service.Lesson().Delete(ctx, id, version)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while deleting lesson", http.StatusInternalServerError, err)
+		handleResponseLog(c, h.Log, "error while deleting lesson", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "", http.StatusOK, id)
 }
+
+// RestoreLesson godoc
+// @Security ApiKeyAuth
+// @Router          /lesson/{id}/restore [POST]
+// @Summary         restore a deleted lesson
+// @Description     Takes a deleted lesson out of the trash
+// @Tags            lesson
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Lesson ID"
+// @Success         200 {object} models.Lesson
+// @Header          200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure         400 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) RestoreLesson(c *gin.Context) {
+
+	id := c.Param("id")
+
+	err := uuid.Validate(id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	lesson, err := h.Service.Lesson().Restore(ctx, id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while restoring lesson", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, lesson.Version)
+	handleResponseLog(c, h.Log, "restored lesson", http.StatusOK, lesson)
+}
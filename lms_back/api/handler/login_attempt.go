@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAllLoginAttempts godoc
+// @Security ApiKeyAuth
+// @Router 			/login-attempt [GET]
+// @Summary 		get all login attempts
+// @Description 	This API returns successful and failed login attempts, newest first
+// @Tags 			auth
+// @Produce 		json
+// @Param 			page query int false "page number"
+// @Param 			limit query int false "limit per page"
+// @Param 			login query string false "login"
+// @Param 			role query string false "user role"
+// @Param 			branch_id query string false "branch id"
+// @Param 			ip query string false "ip address"
+// @Param 			success query bool false "only successful or failed attempts"
+// @Success 		200 {object} models.GetAllLoginAttemptsResponse
+// @Failure 		400 {object} models.Response
+// @Failure 		500 {object} models.Response
+func (h Handler) GetAllLoginAttempts(c *gin.Context) {
+	request := models.GetAllLoginAttemptsRequest{
+		Login:    c.Query("login"),
+		UserRole: c.Query("role"),
+		BranchID: c.Query("branch_id"),
+		IP:       c.Query("ip"),
+		Success:  c.Query("success"),
+	}
+
+	if request.Success != "" && request.Success != "true" && request.Success != "false" {
+		handleResponseLog(c, h.Log, "error while parsing success", http.StatusBadRequest, "success must be true or false")
+		return
+	}
+
+	page, err := ParsePageQueryParam(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusBadRequest, err)
+		return
+	}
+	limit, err := ParseLimitQueryParam(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusBadRequest, err)
+		return
+	}
+
+	request.Page = page
+	request.Limit = limit
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	attempts, err := h.Service.Auth().GetAllLoginAttempts(ctx, request)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting login attempts", http.StatusInternalServerError, err)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "", http.StatusOK, attempts)
+}
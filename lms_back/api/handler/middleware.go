@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"lms_back/api/models"
+	"lms_back/config"
+	"lms_back/pkg/jwt"
+	"lms_back/service"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cast"
+)
+
+// AuthMiddleware validates the bearer token of the request and only lets
+// the listed roles through. Superadmins are allowed on every route.
+func (h Handler) AuthMiddleware(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimSpace(c.GetHeader("Authorization"))
+		if token == "" {
+			handleResponseLog(c, h.Log, "authorization header is missing", http.StatusUnauthorized, "unauthorized")
+			c.Abort()
+			return
+		}
+		if len(token) > 7 && strings.EqualFold(token[:7], "Bearer ") {
+			token = strings.TrimSpace(token[7:])
+		}
+
+		claims, err := jwt.ExtractClaims(token)
+		if err != nil {
+			handleResponseLog(c, h.Log, "error while extracting token claims", http.StatusUnauthorized, err)
+			c.Abort()
+			return
+		}
+
+		if cast.ToString(claims["token_type"]) != jwt.AccessTokenType {
+			handleResponseLog(c, h.Log, "token is not an access token", http.StatusUnauthorized, "invalid token")
+			c.Abort()
+			return
+		}
+
+		authInfo := models.AuthInfo{
+			UserID:    cast.ToString(claims["user_id"]),
+			UserRole:  cast.ToString(claims["user_role"]),
+			BranchIDs: cast.ToStringSlice(claims["branch_ids"]),
+			TokenID:   cast.ToString(claims["jti"]),
+			ExpiresAt: cast.ToInt64(claims["exp"]),
+		}
+		if authInfo.UserID == "" || authInfo.UserRole == "" || authInfo.TokenID == "" {
+			handleResponseLog(c, h.Log, "token does not carry user info", http.StatusUnauthorized, "invalid token")
+			c.Abort()
+			return
+		}
+
+		revoked, err := h.Service.Auth().IsTokenRevoked(c.Request.Context(), authInfo.TokenID)
+		if err != nil {
+			handleResponseLog(c, h.Log, "error while checking token revocation", http.StatusInternalServerError, err)
+			c.Abort()
+			return
+		}
+		if revoked {
+			handleResponseLog(c, h.Log, "token is revoked", http.StatusUnauthorized, "token is revoked")
+			c.Abort()
+			return
+		}
+
+		if !hasRole(authInfo.UserRole, roles) {
+			handleResponseLog(c, h.Log, "role is not allowed: "+authInfo.UserRole, http.StatusForbidden, "forbidden")
+			c.Abort()
+			return
+		}
+
+		c.Set(config.AUTH_INFO_KEY, authInfo)
+		// the service layer reads the caller from the request context to scope data by branch
+		c.Request = c.Request.WithContext(service.ContextWithAuthInfo(c.Request.Context(), authInfo))
+		c.Next()
+	}
+}
+
+// ClientIPMiddleware puts the ip address of the request into the request
+// context, so the service layer can record it in the audit log.
+func (h Handler) ClientIPMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(service.ContextWithClientIP(c.Request.Context(), c.ClientIP()))
+		c.Next()
+	}
+}
+
+func hasRole(role string, roles []string) bool {
+	if role == config.SUPERADMIN_ROLE {
+		return true
+	}
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func getAuthInfo(c *gin.Context) (models.AuthInfo, error) {
+	value, exists := c.Get(config.AUTH_INFO_KEY)
+	if !exists {
+		return models.AuthInfo{}, errors.New("unauthorized")
+	}
+
+	authInfo, ok := value.(models.AuthInfo)
+	if !ok {
+		return models.AuthInfo{}, errors.New("unauthorized")
+	}
+
+	return authInfo, nil
+}
@@ -13,6 +13,7 @@ import (
 )
 
 // CreatePayment godoc
+// @Security ApiKeyAuth
 // @Router 		/payment [POST]
 // @Summary 	create a payment
 // @Description This api is creates a new payment and returns its id
@@ -20,15 +21,17 @@ import (
 // @Accept		json
 // @Produce		json
 // @Param		payment body models.CreatePayment true "payment"
+// @Param		Idempotency-Key header string false "retries with the same key return the first response"
 // @Success		200  {object}  models.Payment
 // @Failure		400  {object}  models.Response
 // @Failure		404  {object}  models.Response
+// @Failure		422  {object}  models.Response
 // @Failure		500  {object}  models.Response
 func (h Handler) CreatePayment(c *gin.Context) {
 	payment := models.CreatePayment{}
 
-	if err := c.ShouldBindJSON(&payment); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	if err := bindJSON(c, &payment); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
 
@@ -37,7 +40,7 @@ func (h Handler) CreatePayment(c *gin.Context) {
 
 	id, err := h.Service.Payment().Create(ctx, payment)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while creating payment", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while creating payment", serviceErrorStatus(err), err)
 		return
 	}
 
@@ -45,6 +48,7 @@ func (h Handler) CreatePayment(c *gin.Context) {
 }
 
 // UpdatePayment godoc
+// @Security ApiKeyAuth
 // @Router                /payment/{id} [PUT]
 // @Summary 			  update a payment
 // @Description:          this api updates payment information
@@ -52,37 +56,108 @@ func (h Handler) CreatePayment(c *gin.Context) {
 // @Accept 			      json
 // @Produce 		      json
 // @Param 			      id path string true "Payment ID"
+// @Param 			      If-Match header string true "ETag of the row"
 // @Param       		  payment body models.UpdatePayment true "payment"
 // @Success 		      200 {object} models.Payment
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure 		      400 {object} models.Response
 // @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
 // @Failure 		      500 {object} models.Response
 func (h Handler) UpdatePayment(c *gin.Context) {
-	payment := models.Payment{}
-	if err := c.ShouldBindJSON(&payment); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	updatePayment := models.UpdatePayment{}
+	if err := bindJSON(c, &updatePayment); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
 
-	payment.Id = c.Param("id")
+	payment := models.Payment{
+		Id:         c.Param("id"),
+		Price:      updatePayment.Price,
+		Student_id: updatePayment.Student_id,
+		Branch_id:  updatePayment.Branch_id,
+		Admin_id:   updatePayment.Admin_id,
+	}
 	err := uuid.Validate(payment.Id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
+	payment.Version = version
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	id, err := h.Service.Payment().Update(ctx, payment)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while updating payment", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while updating payment", serviceErrorStatus(err), err)
 		return
 	}
+	setETag(c, id.Version)
 	handleResponseLog(c, h.Log, "updated payment", http.StatusOK, id)
 }
 
+// PatchPayment godoc
+// @Security ApiKeyAuth
+// @Router                /payment/{id} [PATCH]
+// @Summary 			  patch a payment
+// @Description           this api changes only the payment fields in the body, a JSON merge patch
+// @Tags 			      payment
+// @Accept 			      json
+// @Produce 		      json
+// @Param 			      id path string true "Payment ID"
+// @Param 			      If-Match header string true "ETag of the row"
+// @Param       		  patch body object true "fields to change by their json names"
+// @Success 		      200 {object} models.Payment
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure 		      400 {object} models.Response
+// @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
+// @Failure 		      500 {object} models.Response
+func (h Handler) PatchPayment(c *gin.Context) {
+
+	patch := models.Patch{}
+	if err := bindPatch(c, &patch, models.UpdatePayment{}); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	payment, err := h.Service.Payment().Patch(ctx, id, version, patch)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while patching payment", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, payment.Version)
+	handleResponseLog(c, h.Log, "patched successfully", http.StatusOK, payment)
+}
+
 // GetAllPayment godoc
+// @Security ApiKeyAuth
 // @Router 			/payment [GET]
 // @Summary 		get all payment
 // @Description 	This API returns payment list
@@ -91,7 +166,17 @@ func (h Handler) UpdatePayment(c *gin.Context) {
 // @Produce 		json
 // @Param 			page query int false "page number"
 // @Param 			limit query int false "limit per page"
-// @Param 			search query string false "search keyword"
+// @Param 			sort query string false "comma separated sort fields, - for descending, e.g. -created_at"
+// @Param 			cursor query string false "next_cursor of the previous page, empty for the first page of cursor pagination"
+// @Param 			deleted query string false "only to list the deleted ones"
+// @Param 			search query string false "search by student full name or login"
+// @Param 			student_id query string false "student id"
+// @Param 			branch_id query string false "branch id"
+// @Param 			admin_id query string false "admin id"
+// @Param 			min_price query number false "minimum price"
+// @Param 			max_price query number false "maximum price"
+// @Param 			created_from query string false "created on or after, 2006-01-02"
+// @Param 			created_to query string false "created on or before, 2006-01-02"
 // @Success 		200 {object} models.GetAllPaymentsResponse
 // @Failure 		400 {object} models.Response
 // @Failure         404 {object} models.Response
@@ -99,18 +184,38 @@ func (h Handler) UpdatePayment(c *gin.Context) {
 func (h Handler) GetAllPayment(c *gin.Context) {
 	var (
 		request = models.GetAllPaymentsRequest{}
+		err     error
 	)
 
 	request.Search = c.Query("search")
+	request.StudentID = c.Query("student_id")
+	request.BranchID = c.Query("branch_id")
+	request.AdminID = c.Query("admin_id")
+	if request.MinPrice, err = parseFloatQueryParam(c, "min_price"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing min_price", http.StatusBadRequest, err)
+		return
+	}
+	if request.MaxPrice, err = parseFloatQueryParam(c, "max_price"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing max_price", http.StatusBadRequest, err)
+		return
+	}
+	if request.CreatedFrom, err = parseDateQueryParam(c, "created_from"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing created_from", http.StatusBadRequest, err)
+		return
+	}
+	if request.CreatedTo, err = parseDateQueryParam(c, "created_to"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing created_to", http.StatusBadRequest, err)
+		return
+	}
 
 	page, err := ParsePageQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err)
 		return
 	}
 	limit, err := ParseLimitQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err)
 		return
 	}
 	fmt.Println("page: ", page)
@@ -118,19 +223,24 @@ func (h Handler) GetAllPayment(c *gin.Context) {
 
 	request.Page = page
 	request.Limit = limit
+	if request.ListParams, err = parseListParams(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing list params", http.StatusBadRequest, err)
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	payment, err := h.Service.Payment().GetAll(ctx, request)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting payment", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting payment", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "", http.StatusOK, payment)
 }
 
 // GetByIDpayment godoc
+// @Security ApiKeyAuth
 // @Router       /payment/{id} [GET]
 // @Summary      return a payment by ID
 // @Description  Retrieves a payment by its ID
@@ -139,6 +249,7 @@ func (h Handler) GetAllPayment(c *gin.Context) {
 // @Produce      json
 // @Param        id path string true "payment ID"
 // @Success      200 {object} models.GetPayment
+// @Header       200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure      400 {object} models.Response
 // @Failure      404 {object} models.Response
 // @Failure      500 {object} models.Response
@@ -152,13 +263,15 @@ func (h Handler) GetByIDPayment(c *gin.Context) {
 
 	payment, err := h.Service.Payment().GetByID(ctx, id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting payment by id", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting payment by id", serviceErrorStatus(err), err)
 		return
 	}
+	setETag(c, payment.Version)
 	handleResponseLog(c, h.Log, "", http.StatusOK, payment)
 }
 
 // DeletePayment godoc
+// @Security ApiKeyAuth
 // @Router          /payment/{id} [DELETE]
 // @Summary         delete a payment by ID
 // @Description     Deletes a payment by its ID
@@ -166,9 +279,12 @@ func (h Handler) GetByIDPayment(c *gin.Context) {
 // @Accept          json
 // @Produce         json
 // @Param           id path string true "Payment ID"
+// @Param           If-Match header string true "ETag of the row"
 // @Success         200 {string} models.Response
 // @Failure         400 {object} models.Response
 // @Failure         404 {object} models.Response
+// @Failure         412 {object} models.Response
+// @Failure         428 {object} models.Response
 // @Failure         500 {object} models.Response
 func (h Handler) DeletePayment(c *gin.Context) {
 
@@ -177,17 +293,59 @@ func (h Handler) DeletePayment(c *gin.Context) {
 
 	err := uuid.Validate(id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
-	err = h.Service.Payment().Delete(ctx, id)
+	err = h.Service.Payment().Delete(ctx, id, version)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while deleting payment", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while deleting payment", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "successfully deletes", http.StatusOK, id)
 }
+
+// RestorePayment godoc
+// @Security ApiKeyAuth
+// @Router          /payment/{id}/restore [POST]
+// @Summary         restore a deleted payment
+// @Description     Takes a deleted payment out of the trash
+// @Tags            payment
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Payment ID"
+// @Success         200 {object} models.Payment
+// @Header          200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure         400 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) RestorePayment(c *gin.Context) {
+
+	id := c.Param("id")
+
+	err := uuid.Validate(id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	payment, err := h.Service.Payment().Restore(ctx, id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while restoring payment", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, payment.Version)
+	handleResponseLog(c, h.Log, "restored payment", http.StatusOK, payment)
+}
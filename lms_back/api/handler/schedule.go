@@ -13,6 +13,7 @@ import (
 )
 
 // CreateSchedule godoc
+// @Security ApiKeyAuth
 // @Router 		/schedule [POST]
 // @Summary 	create a schedule
 // @Description This api is creates a new schedule and returns its id
@@ -20,24 +21,35 @@ import (
 // @Accept		json
 // @Produce		json
 // @Param		schedule body  models.CreateSchedule true "schedule"
+// @Param		Idempotency-Key header string false "retries with the same key return the first response"
 // @Success		200  {object}  models.Schedule
 // @Failure		400  {object}  models.Response
 // @Failure		404  {object}  models.Response
+// @Failure		422  {object}  models.Response
 // @Failure		500  {object}  models.Response
 func (h Handler) CreateSchedule(c *gin.Context) {
-	schedule := models.Schedule{}
+	createSchedule := models.CreateSchedule{}
 
-	if err := c.ShouldBindJSON(&schedule); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	if err := bindJSON(c, &createSchedule); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
+	schedule := models.Schedule{
+		Group_id:   createSchedule.Group_id,
+		Group_type: createSchedule.Group_type,
+		Start_time: createSchedule.Start_time,
+		End_time:   createSchedule.End_time,
+		Date:       createSchedule.Date,
+		Branch_id:  createSchedule.Branch_id,
+		Teacher_id: createSchedule.Teacher_id,
+	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	id, err := h.Service.Schedule().Create(ctx, schedule)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while creating schedule", http.StatusInternalServerError, err)
+		handleResponseLog(c, h.Log, "error while creating schedule", serviceErrorStatus(err), err)
 		return
 	}
 
@@ -45,6 +57,7 @@ func (h Handler) CreateSchedule(c *gin.Context) {
 }
 
 // UpdateSchedule godoc
+// @Security ApiKeyAuth
 // @Router                /schedule/{id} [PUT]
 // @Summary 			  update a schedule
 // @Description:          this api updates schedule information
@@ -52,38 +65,112 @@ func (h Handler) CreateSchedule(c *gin.Context) {
 // @Accept 			      json
 // @Produce 		      json
 // @Param 			      id path string true "Schedule ID"
+// @Param 			      If-Match header string true "ETag of the row"
 // @Param       		  schedule body models.UpdateSchedule true "schedule"
 // @Success 		      200 {object} models.Schedule
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure 		      400 {object} models.Response
 // @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
 // @Failure 		      500 {object} models.Response
 func (h Handler) UpdateSchedule(c *gin.Context) {
 
-	schedule := models.Schedule{}
-	if err := c.ShouldBindJSON(&schedule); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	updateSchedule := models.UpdateSchedule{}
+	if err := bindJSON(c, &updateSchedule); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
 
-	schedule.Id = c.Param("id")
+	schedule := models.Schedule{
+		Id:         c.Param("id"),
+		Group_id:   updateSchedule.Group_id,
+		Group_type: updateSchedule.Group_type,
+		Start_time: updateSchedule.Start_time,
+		End_time:   updateSchedule.End_time,
+		Date:       updateSchedule.Date,
+		Branch_id:  updateSchedule.Branch_id,
+		Teacher_id: updateSchedule.Teacher_id,
+	}
 	err := uuid.Validate(schedule.Id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
+	schedule.Version = version
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	id, err := h.Service.Schedule().Update(ctx, schedule)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while updating schedule", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while updating schedule", serviceErrorStatus(err), err)
 		return
 	}
+	setETag(c, id.Version)
 	handleResponseLog(c, h.Log, "", http.StatusOK, id)
 }
 
+// PatchSchedule godoc
+// @Security ApiKeyAuth
+// @Router                /schedule/{id} [PATCH]
+// @Summary 			  patch a schedule
+// @Description           this api changes only the schedule fields in the body, a JSON merge patch
+// @Tags 			      schedule
+// @Accept 			      json
+// @Produce 		      json
+// @Param 			      id path string true "Schedule ID"
+// @Param 			      If-Match header string true "ETag of the row"
+// @Param       		  patch body object true "fields to change by their json names"
+// @Success 		      200 {object} models.Schedule
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure 		      400 {object} models.Response
+// @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
+// @Failure 		      500 {object} models.Response
+func (h Handler) PatchSchedule(c *gin.Context) {
+
+	patch := models.Patch{}
+	if err := bindPatch(c, &patch, models.UpdateSchedule{}); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	schedule, err := h.Service.Schedule().Patch(ctx, id, version, patch)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while patching schedule", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, schedule.Version)
+	handleResponseLog(c, h.Log, "patched successfully", http.StatusOK, schedule)
+}
+
 // GetAllSchedules godoc
+// @Security ApiKeyAuth
 // @Router 			/schedule [GET]
 // @Summary 		get all schedules
 // @Description 	This API returns schedule list
@@ -92,7 +179,14 @@ func (h Handler) UpdateSchedule(c *gin.Context) {
 // @Produce 		json
 // @Param 			page query int false "page number"
 // @Param 			limit query int false "limit per page"
-// @Param 			search query string false "search keyword"
+// @Param 			sort query string false "comma separated sort fields, - for descending, e.g. -created_at"
+// @Param 			cursor query string false "next_cursor of the previous page, empty for the first page of cursor pagination"
+// @Param 			deleted query string false "only to list the deleted ones"
+// @Param 			search query string false "search by group type"
+// @Param 			group_id query string false "group id"
+// @Param 			branch_id query string false "branch id"
+// @Param 			teacher_id query string false "teacher id"
+// @Param 			date query string false "date"
 // @Success 		200 {object} models.GetAllSchedulesResponse
 // @Failure 		400 {object} models.Response
 // @Failure         404 {object} models.Response
@@ -102,15 +196,19 @@ func (h Handler) GetAllSchedule(c *gin.Context) {
 		request = models.GetAllSchedulesRequest{}
 	)
 	request.Search = c.Query("search")
+	request.GroupID = c.Query("group_id")
+	request.BranchID = c.Query("branch_id")
+	request.TeacherID = c.Query("teacher_id")
+	request.Date = c.Query("date")
 
 	page, err := ParsePageQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err)
 		return
 	}
 	limit, err := ParseLimitQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err)
 		return
 	}
 	fmt.Println("page: ", page)
@@ -118,19 +216,24 @@ func (h Handler) GetAllSchedule(c *gin.Context) {
 
 	request.Page = page
 	request.Limit = limit
+	if request.ListParams, err = parseListParams(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing list params", http.StatusBadRequest, err)
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	schedule, err := h.Service.Schedule().GetAll(ctx, request)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting schedule", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting schedule", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "", http.StatusOK, schedule)
 }
 
 // GetByIDSchedule godoc
+// @Security ApiKeyAuth
 // @Router       /schedule/{id} [GET]
 // @Summary      Return a schedule by ID
 // @Description  Retrieves a schedule by its ID
@@ -139,6 +242,7 @@ func (h Handler) GetAllSchedule(c *gin.Context) {
 // @Produce      json
 // @Param        id path string true "Schedule ID"
 // @Success      200 {object} models.GetSchedule
+// @Header       200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure      400 {object} models.Response
 // @Failure      404 {object} models.Response
 // @Failure      500 {object} models.Response
@@ -152,13 +256,15 @@ func (h Handler) GetByIDSchedule(c *gin.Context) {
 
 	schedule, err := h.Service.Schedule().GetByID(ctx, id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting schedule by id", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting schedule by id", serviceErrorStatus(err), err)
 		return
 	}
+	setETag(c, schedule.Version)
 	handleResponseLog(c, h.Log, "", http.StatusOK, schedule)
 }
 
 // DeleteSchedule godoc
+// @Security ApiKeyAuth
 // @Router          /schedule/{id} [DELETE]
 // @Summary         delete a schedule by ID
 // @Description     Deletes a schedule by its ID
@@ -166,9 +272,12 @@ func (h Handler) GetByIDSchedule(c *gin.Context) {
 // @Accept          json
 // @Produce         json
 // @Param           id path string true "Schedule ID"
+// @Param           If-Match header string true "ETag of the row"
 // @Success         200 {string} models.Response
 // @Failure         400 {object} models.Response
 // @Failure         404 {object} models.Response
+// @Failure         412 {object} models.Response
+// @Failure         428 {object} models.Response
 // @Failure         500 {object} models.Response
 func (h Handler) DeleteSchedule(c *gin.Context) {
 
@@ -177,17 +286,59 @@ func (h Handler) DeleteSchedule(c *gin.Context) {
 
 	err := uuid.Validate(id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
-	err = h.Service.Schedule().Delete(ctx, id)
+	err = h.Service.Schedule().Delete(ctx, id, version)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while deleting schedule", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while deleting schedule", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "schedule deleted", http.StatusOK, id)
 }
+
+// RestoreSchedule godoc
+// @Security ApiKeyAuth
+// @Router          /schedule/{id}/restore [POST]
+// @Summary         restore a deleted schedule
+// @Description     Takes a deleted schedule out of the trash
+// @Tags            schedule
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Schedule ID"
+// @Success         200 {object} models.Schedule
+// @Header          200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure         400 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) RestoreSchedule(c *gin.Context) {
+
+	id := c.Param("id")
+
+	err := uuid.Validate(id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	schedule, err := h.Service.Schedule().Restore(ctx, id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while restoring schedule", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, schedule.Version)
+	handleResponseLog(c, h.Log, "restored schedule", http.StatusOK, schedule)
+}
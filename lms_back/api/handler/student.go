@@ -14,39 +14,51 @@ import (
 
 // CreateStudent godoc
 // @Security ApiKeyAuth
-// @Router 		/student/login [POST]
-// @Summary     login
-// @Description This api is to login
-// @Tags 		auth
+// @Router 		/student [POST]
+// @Summary     create a student
+// @Description This api creates a new student and returns it
+// @Tags 		student
 // @Accept		json
 // @Produce		json
 // @Param		student  body  models.CreateStudent true "student"
-// @Success		200  {object}  models.Student
+// @Param		Idempotency-Key header string false "retries with the same key return the first response"
+// @Success		200  {object}  models.GetStudent
 // @Failure		400  {object}  models.Response
 // @Failure		404  {object}  models.Response
+// @Failure		422  {object}  models.Response
 // @Failure		500  {object}  models.Response
 func (h Handler) CreateStudent(c *gin.Context) {
-	student := models.Student{}
+	createStudent := models.CreateStudent{}
 
-	if err := c.ShouldBindJSON(&student); err != nil {
+	if err := bindJSON(c, &createStudent); err != nil {
 
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
 
+	student := models.Student{
+		Full_Name: createStudent.Full_Name,
+		Email:     createStudent.Email,
+		Age:       createStudent.Age,
+		Status:    createStudent.Status,
+		Login:     createStudent.Login,
+		Password:  createStudent.Password,
+		GroupID:   createStudent.GroupID,
+	}
+
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	hashedPass, err := password.HashPassword(student.Password)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while generating customer password", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while generating customer password", http.StatusInternalServerError, err)
 		return
 	}
 	student.Password = string(hashedPass)
 
 	id, err := h.Service.Student().Create(ctx, student)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while creating student", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while creating student", serviceErrorStatus(err), err)
 		return
 	}
 
@@ -62,47 +74,130 @@ func (h Handler) CreateStudent(c *gin.Context) {
 // @Accept 			      json
 // @Produce 		      json
 // @Param 			      id path string true "Student ID"
+// @Param 			      If-Match header string true "ETag of the row"
 // @Param       		  car body models.UpdateStudent true "student"
-// @Success 		      200 {object} models.Student
+// @Success 		      200 {object} models.GetStudent
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure 		      400 {object} models.Response
 // @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
 // @Failure 		      500 {object} models.Response
 func (h Handler) UpdateStudent(c *gin.Context) {
 
-	student := models.Student{}
-	if err := c.ShouldBindJSON(&student); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	updateStudent := models.UpdateStudent{}
+	if err := bindJSON(c, &updateStudent); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
 
-	student.ID = c.Query("id")
+	student := models.Student{
+		ID:        c.Param("id"),
+		Full_Name: updateStudent.Full_Name,
+		Email:     updateStudent.Email,
+		Age:       updateStudent.Age,
+		Status:    updateStudent.Status,
+		Login:     updateStudent.Login,
+		GroupID:   updateStudent.GroupID,
+	}
 
 	err := uuid.Validate(student.ID)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
+	student.Version = version
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
+	id, err := h.Service.Student().Update(ctx, student)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while updating student", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, id.Version)
+	handleResponseLog(c, h.Log, "updated successfully", http.StatusOK, id)
+}
 
-	hashedPass, err := password.HashPassword(student.Password)
+// PatchStudent godoc
+// @Security ApiKeyAuth
+// @Router                /student/{id} [PATCH]
+// @Summary 			  patch a student
+// @Description           this api changes only the student fields in the body, a JSON merge patch
+// @Tags 			      student
+// @Accept 			      json
+// @Produce 		      json
+// @Param 			      id path string true "Student ID"
+// @Param 			      If-Match header string true "ETag of the row"
+// @Param       		  patch body object true "fields to change by their json names"
+// @Success 		      200 {object} models.GetStudent
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure 		      400 {object} models.Response
+// @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
+// @Failure 		      500 {object} models.Response
+func (h Handler) PatchStudent(c *gin.Context) {
+
+	patch := models.Patch{}
+	if err := bindPatch(c, &patch, models.UpdateStudent{}); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while generating customer password", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
-	
-	student.Password = string(hashedPass)
-	id, err := h.Service.Student().Update(ctx, student)
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	student, err := h.Service.Student().Patch(ctx, id, version, patch)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while updating student", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while patching student", serviceErrorStatus(err), err)
 		return
 	}
-	handleResponseLog(c, h.Log, "updated successfully", http.StatusOK, id)
+	setETag(c, student.Version)
+	handleResponseLog(c, h.Log, "patched successfully", http.StatusOK, student)
+}
+
+// ChangeStudentPassword godoc
+// @Security ApiKeyAuth
+// @Router                /student/{id}/password [PUT]
+// @Summary 			  change student password
+// @Description           Students change their own password by confirming the old one, admins may set a new one directly
+// @Tags 			      student
+// @Accept 			      json
+// @Produce 		      json
+// @Param 			      id path string true "Student ID"
+// @Param       		  password body models.ChangePasswordRequest true "password"
+// @Success 		      200 {object} models.Response
+// @Failure 		      400 {object} models.Response
+// @Failure               403 {object} models.Response
+// @Failure 		      500 {object} models.Response
+func (h Handler) ChangeStudentPassword(c *gin.Context) {
+	h.changePassword(c, config.STUDENT_ROLE, h.Service.Student().ChangePassword)
 }
 
 // GetAllStudents godoc
+// @Security ApiKeyAuth
 // @Router 			/student [GET]
 // @Summary 		get all students
 // @Description 	This API returns student list
@@ -111,7 +206,15 @@ func (h Handler) UpdateStudent(c *gin.Context) {
 // @Produce 		json
 // @Param 			page query int false "page number"
 // @Param 			limit query int false "limit per page"
-// @Param 			search query string false "search keyword"
+// @Param 			sort query string false "comma separated sort fields, - for descending, e.g. -created_at"
+// @Param 			cursor query string false "next_cursor of the previous page, empty for the first page of cursor pagination"
+// @Param 			deleted query string false "only to list the deleted ones"
+// @Param 			search query string false "search by full name, email or login"
+// @Param 			status query string false "status"
+// @Param 			group_id query string false "group id"
+// @Param 			branch_id query string false "branch id of the group"
+// @Param 			created_from query string false "created on or after, 2006-01-02"
+// @Param 			created_to query string false "created on or before, 2006-01-02"
 // @Success 		200 {object} models.GetAllStudentsResponse
 // @Failure 		400 {object} models.Response
 // @Failure         404 {object} models.Response
@@ -119,18 +222,30 @@ func (h Handler) UpdateStudent(c *gin.Context) {
 func (h Handler) GetAllStudent(c *gin.Context) {
 	var (
 		request = models.GetAllStudentsRequest{}
+		err     error
 	)
 
 	request.Search = c.Query("search")
+	request.Status = c.Query("status")
+	request.GroupID = c.Query("group_id")
+	request.BranchID = c.Query("branch_id")
+	if request.CreatedFrom, err = parseDateQueryParam(c, "created_from"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing created_from", http.StatusBadRequest, err)
+		return
+	}
+	if request.CreatedTo, err = parseDateQueryParam(c, "created_to"); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing created_to", http.StatusBadRequest, err)
+		return
+	}
 
 	page, err := ParsePageQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err)
 		return
 	}
 	limit, err := ParseLimitQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err)
 		return
 	}
 	fmt.Println("page: ", page)
@@ -138,19 +253,24 @@ func (h Handler) GetAllStudent(c *gin.Context) {
 
 	request.Page = page
 	request.Limit = limit
+	if request.ListParams, err = parseListParams(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing list params", http.StatusBadRequest, err)
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	student, err := h.Service.Student().GetAll(ctx, request)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting student", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting student", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "", http.StatusOK, student)
 }
 
 // GetByIDStudent godoc
+// @Security ApiKeyAuth
 // @Router       /student/{id} [GET]
 // @Summary      return a student by ID
 // @Description  Retrieves a student by its ID
@@ -159,6 +279,7 @@ func (h Handler) GetAllStudent(c *gin.Context) {
 // @Produce      json
 // @Param        id path string true "Student ID"
 // @Success      200 {object} models.GetStudent
+// @Header       200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure      400 {object} models.Response
 // @Failure      404 {object} models.Response
 // @Failure      500 {object} models.Response
@@ -167,18 +288,30 @@ func (h Handler) GetByIDStudent(c *gin.Context) {
 	id := c.Param("id")
 	fmt.Println("id: ", id)
 
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err)
+		return
+	}
+	if authInfo.UserRole == config.STUDENT_ROLE && authInfo.UserID != id {
+		handleResponseLog(c, h.Log, "student can only get own profile", http.StatusForbidden, "forbidden")
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	student, err := h.Service.Student().GetByID(ctx, id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting student by id", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting student by id", serviceErrorStatus(err), err)
 		return
 	}
+	setETag(c, student.Version)
 	handleResponseLog(c, h.Log, "", http.StatusOK, student)
 }
 
 // DeleteStudent godoc
+// @Security ApiKeyAuth
 // @Router          /student/{id} [DELETE]
 // @Summary         delete a student by ID
 // @Description     Deletes a student by its ID
@@ -186,9 +319,12 @@ func (h Handler) GetByIDStudent(c *gin.Context) {
 // @Accept          json
 // @Produce         json
 // @Param           id path string true "Student ID"
+// @Param           If-Match header string true "ETag of the row"
 // @Success         200 {string} models.Response
 // @Failure         400 {object} models.Response
 // @Failure         404 {object} models.Response
+// @Failure         412 {object} models.Response
+// @Failure         428 {object} models.Response
 // @Failure         500 {object} models.Response
 func (h Handler) DeleteStudent(c *gin.Context) {
 
@@ -197,17 +333,59 @@ func (h Handler) DeleteStudent(c *gin.Context) {
 
 	err := uuid.Validate(id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
-	err = h.Service.Student().Delete(ctx, id)
+	err = h.Service.Student().Delete(ctx, id, version)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while deleting student", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while deleting student", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "deleted student", http.StatusOK, id)
 }
+
+// RestoreStudent godoc
+// @Security ApiKeyAuth
+// @Router          /student/{id}/restore [POST]
+// @Summary         restore a deleted student
+// @Description     Takes a deleted student out of the trash
+// @Tags            student
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Student ID"
+// @Success         200 {object} models.GetStudent
+// @Header          200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure         400 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) RestoreStudent(c *gin.Context) {
+
+	id := c.Param("id")
+
+	err := uuid.Validate(id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	student, err := h.Service.Student().Restore(ctx, id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while restoring student", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, student.Version)
+	handleResponseLog(c, h.Log, "restored student", http.StatusOK, student)
+}
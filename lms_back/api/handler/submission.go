@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SubmitTask godoc
+// @Security ApiKeyAuth
+// @Router          /task/{id}/submission [POST]
+// @Summary         submit an answer to a task
+// @Description     Hands in the answer of the student of the token to a task of its group. Resubmitting replaces the answer until it is graded, answers after the deadline are flagged late
+// @Tags            submission
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Task ID"
+// @Param           submission body models.SubmitTask true "answer"
+// @Success         200 {object} models.Submission
+// @Failure         400 {object} models.Response
+// @Failure         403 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         409 {object} models.Response
+// @Failure         422 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) SubmitTask(c *gin.Context) {
+	req := models.SubmitTask{}
+	if err := bindJSON(c, &req); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	submission, err := h.Service.Submission().Submit(ctx, id, req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while submitting task", serviceErrorStatus(err), err)
+		return
+	}
+	handleResponseLog(c, h.Log, "submitted task", http.StatusOK, submission)
+}
+
+// GetTaskSubmissions godoc
+// @Security ApiKeyAuth
+// @Router          /task/{id}/submissions [GET]
+// @Summary         get the submissions to a task
+// @Description     Returns the submissions to the task, latest first. Students only get their own
+// @Tags            submission
+// @Produce         json
+// @Param           id path string true "Task ID"
+// @Param           page query int false "page number"
+// @Param           limit query int false "limit per page"
+// @Param           student_id query string false "student id"
+// @Success         200 {object} models.GetAllSubmissionsResponse
+// @Failure         400 {object} models.Response
+// @Failure         403 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) GetTaskSubmissions(c *gin.Context) {
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	var (
+		request = models.GetAllSubmissionsRequest{
+			TaskID:    id,
+			StudentID: c.Query("student_id"),
+		}
+		err error
+	)
+	if request.StudentID != "" {
+		if err = uuid.Validate(request.StudentID); err != nil {
+			handleResponseLog(c, h.Log, "error while validating student_id", http.StatusBadRequest, err)
+			return
+		}
+	}
+	if request.Page, err = ParsePageQueryParam(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusBadRequest, err)
+		return
+	}
+	if request.Limit, err = ParseLimitQueryParam(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	submissions, err := h.Service.Submission().GetAll(ctx, request)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting submissions of task", serviceErrorStatus(err), err)
+		return
+	}
+	handleResponseLog(c, h.Log, "", http.StatusOK, submissions)
+}
+
+// GradeSubmission godoc
+// @Security ApiKeyAuth
+// @Router          /submission/{id}/grade [PUT]
+// @Summary         grade a submission
+// @Description     Scores the submission out of the score of its task with feedback, grading again replaces the grade. Teachers may only grade the tasks of their own groups
+// @Tags            submission
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Submission ID"
+// @Param           grade body models.GradeSubmission true "grade"
+// @Success         200 {object} models.Submission
+// @Failure         400 {object} models.Response
+// @Failure         403 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         422 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) GradeSubmission(c *gin.Context) {
+	req := models.GradeSubmission{}
+	if err := bindJSON(c, &req); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	submission, err := h.Service.Submission().Grade(ctx, id, req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while grading submission", serviceErrorStatus(err), err)
+		return
+	}
+	handleResponseLog(c, h.Log, "graded submission", http.StatusOK, submission)
+}
@@ -13,6 +13,7 @@ import (
 )
 
 // CreateTask godoc
+// @Security ApiKeyAuth
 // @Router 		/task [POST]
 // @Summary 	create a task
 // @Description This api is creates a new task and returns its id
@@ -20,25 +21,28 @@ import (
 // @Accept		json
 // @Produce		json
 // @Param		car body models.CreateTask true "task"
+// @Param		Idempotency-Key header string false "retries with the same key return the first response"
 // @Success		200  {object}  models.CreateTask
 // @Failure		400  {object}  models.Response
 // @Failure		404  {object}  models.Response
+// @Failure		422  {object}  models.Response
 // @Failure		500  {object}  models.Response
 func (h Handler) CreateTask(c *gin.Context) {
-	task := models.Task{}
+	createTask := models.CreateTask{}
 
-	if err := c.ShouldBindJSON(&task); err != nil {
+	if err := bindJSON(c, &createTask); err != nil {
 
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
+	task := models.Task{LessonId: createTask.LessonId, GroupId: createTask.GroupId, Task: createTask.Task, Score: createTask.Score, Deadline: createTask.Deadline}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	id, err := h.Service.Task().Create(ctx, task)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while creating task", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while creating task", http.StatusInternalServerError, err)
 		return
 	}
 
@@ -46,6 +50,7 @@ func (h Handler) CreateTask(c *gin.Context) {
 }
 
 // UpdateTask godoc
+// @Security ApiKeyAuth
 // @Router                /task/{id} [PUT]
 // @Summary 			  update a task
 // @Description:          this api updates task information
@@ -53,38 +58,103 @@ func (h Handler) CreateTask(c *gin.Context) {
 // @Accept 			      json
 // @Produce 		      json
 // @Param 			      id path string true "Task ID"
+// @Param 			      If-Match header string true "ETag of the row"
 // @Param       		  task body models.UpdateTask true "task"
 // @Success 		      200 {object} models.Task
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure 		      400 {object} models.Response
 // @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
 // @Failure 		      500 {object} models.Response
 func (h Handler) UpdateTask(c *gin.Context) {
 
-	task := models.Task{}
-	if err := c.ShouldBindJSON(&task); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	updateTask := models.UpdateTask{}
+	if err := bindJSON(c, &updateTask); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
 
-	task.Id = c.Query("id")
+	task := models.Task{Id: c.Param("id"), LessonId: updateTask.LessonId, GroupId: updateTask.GroupId, Task: updateTask.Task, Score: updateTask.Score, Deadline: updateTask.Deadline}
 	err := uuid.Validate(task.Id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
 		return
 	}
 
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
+		return
+	}
+	task.Version = version
+
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	id, err := h.Service.Task().Update(ctx, task)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while updating task", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while updating task", serviceErrorStatus(err), err)
 		return
 	}
+	setETag(c, id.Version)
 	handleResponseLog(c, h.Log, "updated successfully", http.StatusOK, id)
 }
 
+// PatchTask godoc
+// @Security ApiKeyAuth
+// @Router                /task/{id} [PATCH]
+// @Summary 			  patch a task
+// @Description           this api changes only the task fields in the body, a JSON merge patch
+// @Tags 			      task
+// @Accept 			      json
+// @Produce 		      json
+// @Param 			      id path string true "Task ID"
+// @Param 			      If-Match header string true "ETag of the row"
+// @Param       		  patch body object true "fields to change by their json names"
+// @Success 		      200 {object} models.Task
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure 		      400 {object} models.Response
+// @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
+// @Failure 		      500 {object} models.Response
+func (h Handler) PatchTask(c *gin.Context) {
+
+	patch := models.Patch{}
+	if err := bindPatch(c, &patch, models.UpdateTask{}); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	task, err := h.Service.Task().Patch(ctx, id, version, patch)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while patching task", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, task.Version)
+	handleResponseLog(c, h.Log, "patched successfully", http.StatusOK, task)
+}
+
 // GetAlltasks godoc
+// @Security ApiKeyAuth
 // @Router 			/task [GET]
 // @Summary 		get all tasks
 // @Description 	This API returns task list
@@ -93,7 +163,12 @@ func (h Handler) UpdateTask(c *gin.Context) {
 // @Produce 		json
 // @Param 			page query int false "page number"
 // @Param 			limit query int false "limit per page"
-// @Param 			search query string false "search keyword"
+// @Param 			sort query string false "comma separated sort fields, - for descending, e.g. -created_at"
+// @Param 			cursor query string false "next_cursor of the previous page, empty for the first page of cursor pagination"
+// @Param 			deleted query string false "only to list the deleted ones"
+// @Param 			search query string false "search by task text"
+// @Param 			lesson_id query string false "lesson id"
+// @Param 			group_id query string false "group id"
 // @Success 		200 {object} models.GetAllTasksResponse
 // @Failure 		400 {object} models.Response
 // @Failure         404 {object} models.Response
@@ -104,15 +179,17 @@ func (h Handler) GetAllTask(c *gin.Context) {
 	)
 
 	request.Search = c.Query("search")
+	request.LessonID = c.Query("lesson_id")
+	request.GroupID = c.Query("group_id")
 
 	page, err := ParsePageQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err)
 		return
 	}
 	limit, err := ParseLimitQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err)
 		return
 	}
 	fmt.Println("page: ", page)
@@ -120,19 +197,24 @@ func (h Handler) GetAllTask(c *gin.Context) {
 
 	request.Page = page
 	request.Limit = limit
+	if request.ListParams, err = parseListParams(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing list params", http.StatusBadRequest, err)
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	task, err := h.Service.Task().GetAll(ctx, request)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting task", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting task", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "", http.StatusOK, task)
 }
 
 // GetByIDTask godoc
+// @Security ApiKeyAuth
 // @Router       /task/{id} [GET]
 // @Summary      return a task by ID
 // @Description  Retrieves a task by its ID
@@ -141,6 +223,7 @@ func (h Handler) GetAllTask(c *gin.Context) {
 // @Produce      json
 // @Param        id path string true "Task ID"
 // @Success      200 {object} models.GetTask
+// @Header       200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure      400 {object} models.Response
 // @Failure      404 {object} models.Response
 // @Failure      500 {object} models.Response
@@ -154,13 +237,15 @@ func (h Handler) GetByIDtask(c *gin.Context) {
 
 	task, err := h.Service.Task().GetByID(ctx, id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting task by id", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting task by id", http.StatusInternalServerError, err)
 		return
 	}
+	setETag(c, task.Version)
 	handleResponseLog(c, h.Log, "", http.StatusOK, task)
 }
 
 // DeleteTask godoc
+// @Security ApiKeyAuth
 // @Router          /task/{id} [DELETE]
 // @Summary         delete a task by ID
 // @Description     Deletes a task by its ID
@@ -168,9 +253,12 @@ func (h Handler) GetByIDtask(c *gin.Context) {
 // @Accept          json
 // @Produce         json
 // @Param           id path string true "Task ID"
+// @Param           If-Match header string true "ETag of the row"
 // @Success         200 {string} models.Response
 // @Failure         400 {object} models.Response
 // @Failure         404 {object} models.Response
+// @Failure         412 {object} models.Response
+// @Failure         428 {object} models.Response
 // @Failure         500 {object} models.Response
 func (h Handler) DeleteTask(c *gin.Context) {
 
@@ -179,17 +267,59 @@ func (h Handler) DeleteTask(c *gin.Context) {
 
 	err := uuid.Validate(id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
-	err = h.Service.Task().Delete(ctx, id)
+	err = h.Service.Task().Delete(ctx, id, version)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while deleting task", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while deleting task", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "deleted task", http.StatusOK, id)
 }
+
+// RestoreTask godoc
+// @Security ApiKeyAuth
+// @Router          /task/{id}/restore [POST]
+// @Summary         restore a deleted task
+// @Description     Takes a deleted task out of the trash
+// @Tags            task
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Task ID"
+// @Success         200 {object} models.Task
+// @Header          200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure         400 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) RestoreTask(c *gin.Context) {
+
+	id := c.Param("id")
+
+	err := uuid.Validate(id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	task, err := h.Service.Task().Restore(ctx, id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while restoring task", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, task.Version)
+	handleResponseLog(c, h.Log, "restored task", http.StatusOK, task)
+}
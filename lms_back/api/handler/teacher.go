@@ -14,6 +14,7 @@ import (
 )
 
 // CreateTeacher godoc
+// @Security ApiKeyAuth
 // @Router 		/teacher [POST]
 // @Summary 	Create a teacher
 // @Description This api is creates a new teacher and returns its id
@@ -21,31 +22,42 @@ import (
 // @Accept		json
 // @Produce		json
 // @Param		car  body      models.CreateTeacher true "car"
-// @Success		200  {object}  models.Teacher
+// @Param		Idempotency-Key header string false "retries with the same key return the first response"
+// @Success		200  {object}  models.GetTeacher
 // @Failure		400  {object}  models.Response
 // @Failure		404  {object}  models.Response
+// @Failure		422  {object}  models.Response
 // @Failure		500  {object}  models.Response
 func (h Handler) CreateTeacher(c *gin.Context) {
-	teacher := models.Teacher{}
+	createTeacher := models.CreateTeacher{}
 
-	if err := c.ShouldBindJSON(&teacher); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	if err := bindJSON(c, &createTeacher); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
 
+	teacher := models.Teacher{
+		Full_name: createTeacher.Full_name,
+		Email:     createTeacher.Email,
+		Age:       createTeacher.Age,
+		Status:    createTeacher.Status,
+		Login:     createTeacher.Login,
+		Password:  createTeacher.Password,
+	}
+
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	hashedPass, err := password.HashPassword(teacher.Password)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while generating customer password", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while generating customer password", http.StatusInternalServerError, err)
 		return
 	}
 	teacher.Password = string(hashedPass)
 
 	id, err := h.Service.Teacher().Create(ctx, teacher)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while creating teacher", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while creating teacher", http.StatusInternalServerError, err)
 		return
 	}
 
@@ -53,6 +65,7 @@ func (h Handler) CreateTeacher(c *gin.Context) {
 }
 
 // UpdateTeacher godoc
+// @Security ApiKeyAuth
 // @Router                /teacher/{id} [PUT]
 // @Summary 			  update a teacher
 // @Description:          this api updates teacher information
@@ -60,43 +73,126 @@ func (h Handler) CreateTeacher(c *gin.Context) {
 // @Accept 			      json
 // @Produce 		      json
 // @Param 			      id path string true "Teacher ID"
+// @Param 			      If-Match header string true "ETag of the row"
 // @Param       		  car body models.UpdateTeacher true "teacher"
-// @Success 		      200 {object} models.Teacher
+// @Success 		      200 {object} models.GetTeacher
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure 		      400 {object} models.Response
 // @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
 // @Failure 		      500 {object} models.Response
 func (h Handler) UpdateTeacher(c *gin.Context) {
-	teacher := models.Teacher{}
-	if err := c.ShouldBindJSON(&teacher); err != nil {
-		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err.Error())
+	updateTeacher := models.UpdateTeacher{}
+	if err := bindJSON(c, &updateTeacher); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
 		return
 	}
-	teacher.Id = c.Query("id")
+	teacher := models.Teacher{
+		Id:        c.Param("id"),
+		Full_name: updateTeacher.Full_name,
+		Email:     updateTeacher.Email,
+		Age:       updateTeacher.Age,
+		Status:    updateTeacher.Status,
+		Login:     updateTeacher.Login,
+	}
 	err := uuid.Validate(teacher.Id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
+	teacher.Version = version
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
-	hashedPass, err := password.HashPassword(teacher.Password)
+	id, err := h.Service.Teacher().Update(ctx, teacher)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while generating customer password", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while updating teacher", serviceErrorStatus(err), err)
 		return
 	}
-	teacher.Password = string(hashedPass)
+	setETag(c, id.Version)
+	handleResponseLog(c, h.Log, "updated teacher", http.StatusOK, id)
+}
 
-	id, err := h.Service.Teacher().Update(ctx, teacher)
+// PatchTeacher godoc
+// @Security ApiKeyAuth
+// @Router                /teacher/{id} [PATCH]
+// @Summary 			  patch a teacher
+// @Description           this api changes only the teacher fields in the body, a JSON merge patch
+// @Tags 			      teacher
+// @Accept 			      json
+// @Produce 		      json
+// @Param 			      id path string true "Teacher ID"
+// @Param 			      If-Match header string true "ETag of the row"
+// @Param       		  patch body object true "fields to change by their json names"
+// @Success 		      200 {object} models.GetTeacher
+// @Header 		      200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure 		      400 {object} models.Response
+// @Failure               404 {object} models.Response
+// @Failure               422 {object} models.Response
+// @Failure 		      412 {object} models.Response
+// @Failure 		      428 {object} models.Response
+// @Failure 		      500 {object} models.Response
+func (h Handler) PatchTeacher(c *gin.Context) {
+
+	patch := models.Patch{}
+	if err := bindPatch(c, &patch, models.UpdateTeacher{}); err != nil {
+		handleResponseLog(c, h.Log, "error while decoding request body", http.StatusBadRequest, err)
+		return
+	}
+
+	id := c.Param("id")
+	if err := uuid.Validate(id); err != nil {
+		handleResponseLog(c, h.Log, "error while validating", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while updating teacher", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
-	handleResponseLog(c, h.Log, "updated teacher", http.StatusOK, id)
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	teacher, err := h.Service.Teacher().Patch(ctx, id, version, patch)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while patching teacher", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, teacher.Version)
+	handleResponseLog(c, h.Log, "patched successfully", http.StatusOK, teacher)
+}
+
+// ChangeTeacherPassword godoc
+// @Security ApiKeyAuth
+// @Router                /teacher/{id}/password [PUT]
+// @Summary 			  change teacher password
+// @Description           Teachers change their own password by confirming the old one, admins may set a new one directly
+// @Tags 			      teacher
+// @Accept 			      json
+// @Produce 		      json
+// @Param 			      id path string true "Teacher ID"
+// @Param       		  password body models.ChangePasswordRequest true "password"
+// @Success 		      200 {object} models.Response
+// @Failure 		      400 {object} models.Response
+// @Failure               403 {object} models.Response
+// @Failure 		      500 {object} models.Response
+func (h Handler) ChangeTeacherPassword(c *gin.Context) {
+	h.changePassword(c, config.TEACHER_ROLE, h.Service.Teacher().ChangePassword)
 }
 
 // GetAllTeachers godoc
+// @Security ApiKeyAuth
 // @Router 			/teacher [GET]
 // @Summary 		get all teachers
 // @Description 	This API returns teacher list
@@ -105,7 +201,11 @@ func (h Handler) UpdateTeacher(c *gin.Context) {
 // @Produce 		json
 // @Param 			page query int false "page number"
 // @Param 			limit query int false "limit per page"
-// @Param 			search query string false "search keyword"
+// @Param 			sort query string false "comma separated sort fields, - for descending, e.g. -created_at"
+// @Param 			cursor query string false "next_cursor of the previous page, empty for the first page of cursor pagination"
+// @Param 			deleted query string false "only to list the deleted ones"
+// @Param 			search query string false "search by full name, email or login"
+// @Param 			status query string false "active or inactive"
 // @Success 		200 {object} models.GetAllTeachersResponse
 // @Failure 		400 {object} models.Response
 // @Failure         404 {object} models.Response
@@ -116,15 +216,16 @@ func (h Handler) GetAllTeacher(c *gin.Context) {
 	)
 
 	request.Search = c.Query("search")
+	request.Status = c.Query("status")
 
 	page, err := ParsePageQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing page", http.StatusInternalServerError, err)
 		return
 	}
 	limit, err := ParseLimitQueryParam(c)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while parsing limit", http.StatusInternalServerError, err)
 		return
 	}
 	fmt.Println("page: ", page)
@@ -132,19 +233,24 @@ func (h Handler) GetAllTeacher(c *gin.Context) {
 
 	request.Page = page
 	request.Limit = limit
+	if request.ListParams, err = parseListParams(c); err != nil {
+		handleResponseLog(c, h.Log, "error while parsing list params", http.StatusBadRequest, err)
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
 	teachers, err := h.Service.Teacher().GetAll(ctx, request)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting teachers", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting teachers", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "", http.StatusOK, teachers)
 }
 
 // GetByIDTEacher godoc
+// @Security ApiKeyAuth
 // @Router       /teacher/{id} [GET]
 // @Summary      return a teacher by ID
 // @Description  Retrieves a teacher by its ID
@@ -153,6 +259,7 @@ func (h Handler) GetAllTeacher(c *gin.Context) {
 // @Produce      json
 // @Param        id path string true "Teacher ID"
 // @Success      200 {object} models.GetTeacher
+// @Header       200 {string} ETag "version of the row, send it in If-Match to change the row"
 // @Failure      400 {object} models.Response
 // @Failure      404 {object} models.Response
 // @Failure      500 {object} models.Response
@@ -166,13 +273,15 @@ func (h Handler) GetByIDTeacher(c *gin.Context) {
 
 	teacher, err := h.Service.Teacher().GetByID(ctx, id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while getting teacher by id", http.StatusInternalServerError, err.Error())
+		handleResponseLog(c, h.Log, "error while getting teacher by id", http.StatusInternalServerError, err)
 		return
 	}
+	setETag(c, teacher.Version)
 	handleResponseLog(c, h.Log, "", http.StatusOK, teacher)
 }
 
 // DeleteTeacher godoc
+// @Security ApiKeyAuth
 // @Router          /teacher/{id} [DELETE]
 // @Summary         delete a teacher by ID
 // @Description     Deletes a teacher by its ID
@@ -180,9 +289,12 @@ func (h Handler) GetByIDTeacher(c *gin.Context) {
 // @Accept          json
 // @Produce         json
 // @Param           id path string true "Teacher ID"
+// @Param           If-Match header string true "ETag of the row"
 // @Success         200 {string} models.Response
 // @Failure         400 {object} models.Response
 // @Failure         404 {object} models.Response
+// @Failure         412 {object} models.Response
+// @Failure         428 {object} models.Response
 // @Failure         500 {object} models.Response
 func (h Handler) DeleteTeacher(c *gin.Context) {
 
@@ -191,18 +303,60 @@ func (h Handler) DeleteTeacher(c *gin.Context) {
 
 	err := uuid.Validate(id)
 	if err != nil {
-		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err.Error())
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	version, err := parseIfMatch(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while checking If-Match", serviceErrorStatus(err), err)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(c, config.Timeout)
 	defer cancel()
 
-	err = h.Service.Teacher().Delete(ctx, id)
+	err = h.Service.Teacher().Delete(ctx, id, version)
 	if err != nil {
 		fmt.Println("error while deleting teacher, err:", err)
-		handleResponseLog(c, h.Log, "error while deleting teacher", http.StatusInternalServerError, err)
+		handleResponseLog(c, h.Log, "error while deleting teacher", serviceErrorStatus(err), err)
 		return
 	}
 	handleResponseLog(c, h.Log, "teacher deleted", http.StatusOK, id)
 }
+
+// RestoreTeacher godoc
+// @Security ApiKeyAuth
+// @Router          /teacher/{id}/restore [POST]
+// @Summary         restore a deleted teacher
+// @Description     Takes a deleted teacher out of the trash
+// @Tags            teacher
+// @Accept          json
+// @Produce         json
+// @Param           id path string true "Teacher ID"
+// @Success         200 {object} models.GetTeacher
+// @Header          200 {string} ETag "version of the row, send it in If-Match to change the row"
+// @Failure         400 {object} models.Response
+// @Failure         404 {object} models.Response
+// @Failure         500 {object} models.Response
+func (h Handler) RestoreTeacher(c *gin.Context) {
+
+	id := c.Param("id")
+
+	err := uuid.Validate(id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while validating id", http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	teacher, err := h.Service.Teacher().Restore(ctx, id)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while restoring teacher", serviceErrorStatus(err), err)
+		return
+	}
+	setETag(c, teacher.Version)
+	handleResponseLog(c, h.Log, "restored teacher", http.StatusOK, teacher)
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"lms_back/api/models"
+	"lms_back/config"
+	"lms_back/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupTwoFactor godoc
+// @Security ApiKeyAuth
+// @Router       /auth/2fa/setup [POST]
+// @Summary      Set up two-factor authentication
+// @Description  Generates a TOTP secret and otpauth uri for an authenticator app, it is enabled after confirmation
+// @Tags         auth
+// @Produce      json
+// @Success      200  {object}  models.TwoFactorSetupResponse
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h *Handler) SetupTwoFactor(c *gin.Context) {
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	resp, err := h.Service.Auth().SetupTwoFactor(ctx, authInfo)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while setting up two-factor authentication", twoFactorErrorStatus(err), err)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "two-factor authentication is set up", http.StatusOK, resp)
+}
+
+// ConfirmTwoFactor godoc
+// @Security ApiKeyAuth
+// @Router       /auth/2fa/confirm [POST]
+// @Summary      Confirm two-factor authentication
+// @Description  Enables two-factor authentication with a code from the authenticator app and returns one-time recovery codes
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body     models.TwoFactorConfirmRequest true "code from the authenticator app"
+// @Success      200  {object}  models.TwoFactorConfirmResponse
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h *Handler) ConfirmTwoFactor(c *gin.Context) {
+	req := models.TwoFactorConfirmRequest{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err)
+		return
+	}
+
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	resp, err := h.Service.Auth().ConfirmTwoFactor(ctx, authInfo, req)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while confirming two-factor authentication", twoFactorErrorStatus(err), err)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "two-factor authentication is enabled", http.StatusOK, resp)
+}
+
+// DisableTwoFactor godoc
+// @Security ApiKeyAuth
+// @Router       /auth/2fa/disable [POST]
+// @Summary      Disable two-factor authentication
+// @Description  Disables two-factor authentication, needs the password and a one-time or recovery code
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body     models.TwoFactorDisableRequest true "password and code"
+// @Success      200  {object}  models.Response
+// @Failure      400  {object}  models.Response
+// @Failure      401  {object}  models.Response
+// @Failure      500  {object}  models.Response
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	req := models.TwoFactorDisableRequest{}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		handleResponseLog(c, h.Log, "error while binding body", http.StatusBadRequest, err)
+		return
+	}
+
+	authInfo, err := getAuthInfo(c)
+	if err != nil {
+		handleResponseLog(c, h.Log, "error while getting auth info", http.StatusUnauthorized, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c, config.Timeout)
+	defer cancel()
+
+	if err = h.Service.Auth().DisableTwoFactor(ctx, authInfo, req); err != nil {
+		handleResponseLog(c, h.Log, "error while disabling two-factor authentication", twoFactorErrorStatus(err), err)
+		return
+	}
+
+	handleResponseLog(c, h.Log, "two-factor authentication is disabled", http.StatusOK, "")
+}
+
+func twoFactorErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials),
+		errors.Is(err, service.ErrInvalidTwoFactorCode),
+		errors.Is(err, service.ErrTwoFactorEnabled),
+		errors.Is(err, service.ErrTwoFactorNotSetUp),
+		errors.Is(err, service.ErrTwoFactorNotAllowed):
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lms_back/api/models"
+	"lms_back/pkg/errs"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// registerValidators names the invalid fields by their json names and adds
+// the rules of the domain to the binding tags of the models.
+func registerValidators() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	v.RegisterValidation("group_type", oneOf(models.GroupTypes))
+	v.RegisterValidation("status", oneOf(models.Statuses))
+	v.RegisterValidation("attendance_status", oneOf(models.AttendanceStatuses))
+	v.RegisterValidation("clock", func(fl validator.FieldLevel) bool {
+		return isClock(fl.Field().String())
+	})
+}
+
+func oneOf(values []string) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		for _, value := range values {
+			if fl.Field().String() == value {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// isClock reports whether s is a time of day like 15:04 or 15:04:05.
+func isClock(s string) bool {
+	if _, err := time.Parse("15:04", s); err == nil {
+		return true
+	}
+	_, err := time.Parse(time.TimeOnly, s)
+	return err == nil
+}
+
+// bindJSON decodes the body into obj and checks its binding rules. A value of
+// another type and every broken rule are returned as one validation error of
+// the fields, a body that isn't JSON as it is.
+func bindJSON(c *gin.Context, obj interface{}) error {
+	err := c.ShouldBindJSON(obj)
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return errs.Validation("", errs.FieldError{Field: typeErr.Field, Message: "must be " + jsonType(typeErr.Type)}).Wrap(err)
+	}
+
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return err
+	}
+
+	fields := make([]errs.FieldError, 0, len(invalid))
+	for _, field := range invalid {
+		fields = append(fields, errs.FieldError{Field: fieldPath(field), Message: ruleMessage(field)})
+	}
+	return errs.Validation("", fields...).Wrap(err)
+}
+
+// bindPatch decodes a JSON merge patch into patch and checks every patched
+// value by the binding rules of the field with the same json name in model,
+// the update request of the entity, so a patch can't set what an update
+// rejects. Null and values of another type are left to the storage, which
+// rejects them as well.
+func bindPatch(c *gin.Context, patch *models.Patch, model interface{}) error {
+	if err := c.ShouldBindJSON(patch); err != nil {
+		return err
+	}
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil
+	}
+
+	var fields []errs.FieldError
+	t := reflect.TypeOf(model)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		raw, ok := (*patch)[name]
+		rules := field.Tag.Get("binding")
+		if !ok || rules == "" || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			continue
+		}
+
+		value := reflect.New(field.Type)
+		if err := json.Unmarshal(raw, value.Interface()); err != nil {
+			continue
+		}
+		var invalid validator.ValidationErrors
+		if err := v.Var(value.Elem().Interface(), rules); errors.As(err, &invalid) {
+			for _, rule := range invalid {
+				fields = append(fields, errs.FieldError{Field: name, Message: ruleMessage(rule)})
+			}
+		}
+	}
+
+	if len(fields) > 0 {
+		return errs.Validation("", fields...)
+	}
+	return nil
+}
+
+// fieldPath names the field by its path from the body, so a field of a list
+// item reads like students[0].status.
+func fieldPath(field validator.FieldError) string {
+	_, path, ok := strings.Cut(field.Namespace(), ".")
+	if !ok {
+		return field.Field()
+	}
+	return path
+}
+
+// jsonType names the JSON type a value of t is decoded from.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return "a string"
+}
+
+// ruleMessage explains the broken rule of a field.
+func ruleMessage(field validator.FieldError) string {
+	switch field.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be an email"
+	case "uuid":
+		return "must be a uuid"
+	case "number":
+		return "must be a non-negative integer"
+	case "gt":
+		return "must be greater than " + field.Param()
+	case "gte":
+		return "must be at least " + field.Param()
+	case "min":
+		if isList(field) {
+			return fmt.Sprintf("must have at least %s items", field.Param())
+		}
+		return fmt.Sprintf("must be at least %s characters long", field.Param())
+	case "max":
+		if isList(field) {
+			return fmt.Sprintf("must have at most %s items", field.Param())
+		}
+		return fmt.Sprintf("must be at most %s characters long", field.Param())
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(field.Param(), " ", ", ")
+	case "group_type":
+		return "must be one of " + strings.Join(models.GroupTypes, ", ")
+	case "status":
+		return "must be one of " + strings.Join(models.Statuses, ", ")
+	case "attendance_status":
+		return "must be one of " + strings.Join(models.AttendanceStatuses, ", ")
+	case "datetime":
+		return "must be a date like " + field.Param()
+	case "clock":
+		return "must be a time like 15:04"
+	}
+	return "is invalid"
+}
+
+// isList reports whether the length of the field counts items rather than
+// characters.
+func isList(field validator.FieldError) bool {
+	kind := field.Kind()
+	return kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map
+}
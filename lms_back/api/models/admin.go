@@ -7,27 +7,36 @@ type Admin struct {
 	Age        uint   `json:"age"`
 	Status     string `json:"status"`
 	Login      string `json:"login"`
-	Password   string `json:"password"`
+	Password   string `json:"-"`
+	Role       string   `json:"role"`
+	BranchIDs  []string `json:"branch_ids"`
+	Version    int    `json:"version"`
 	Created_at string `json:"create_at"`
 	Updated_at string `json:"updated_at"`
 }
 
 type CreateAdmin struct {
-	Full_Name string `json:"full_name"`
-	Email     string `json:"email"`
-	Age       uint   `json:"age"`
-	Status    string `json:"status"`
-	Login     string `json:"login"`
-	Password  string `json:"password"`
+	Full_Name string `json:"full_name" binding:"required,max=255"`
+	Email     string `json:"email" binding:"required,email"`
+	Age       uint   `json:"age" binding:"required,gt=0"`
+	Status    string `json:"status" binding:"required,status"`
+	Login     string `json:"login" binding:"required,max=255"`
+	Password  string `json:"password" binding:"required,min=6"`
+	// Role is admin or superadmin, admin by default
+	Role string `json:"role" binding:"omitempty,oneof=admin superadmin"`
 }
 
 type UpdateAdmin struct {
-	Full_Name string `json:"full_name"`
-	Email     string `json:"email"`
-	Age       uint   `json:"age"`
-	Status    string `json:"status"`
-	Login     string `json:"login"`
-	Password  string `json:"password"`
+	Full_Name string `json:"full_name" binding:"required,max=255"`
+	Email     string `json:"email" binding:"required,email"`
+	Age       uint   `json:"age" binding:"required,gt=0"`
+	Status    string `json:"status" binding:"required,status"`
+	Login     string `json:"login" binding:"required,max=255"`
+	Role      string `json:"role" binding:"omitempty,oneof=admin superadmin"`
+}
+
+type UpdateAdminBranches struct {
+	BranchIDs []string `json:"branch_ids"`
 }
 
 type GetAdmin struct {
@@ -37,19 +46,25 @@ type GetAdmin struct {
 	Age        uint   `json:"age"`
 	Status     string `json:"status"`
 	Login      string `json:"login"`
-	Password   string `json:"password"`
+	Role       string   `json:"role"`
+	BranchIDs  []string `json:"branch_ids"`
+	Version    int    `json:"version"`
 	Created_at string `json:"create_at"`
 	Updated_at string `json:"updated_at"`
 }
 
 type GetAllAdminsResponse struct {
-	Admins []Admin `json:"admins"`
+	Admins []GetAdmin `json:"admins"`
 	Count  int16   `json:"count"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 type GetAllAdminsRequest struct {
 	Search string `json:"search"`
+	Status string `json:"status"`
+	Role   string `json:"role"`
 	Page   uint64 `json:"page"`
 	Limit  uint64 `json:"limit"`
+	ListParams
 }
 
 
@@ -72,6 +87,6 @@ type PaymentReport struct {
 }
 
 type AdminPayment struct {
-	Admin   Admin   `json:"admin"`
+	Admin   GetAdmin `json:"admin"`
 	Payment Payment `json:"payment"`
 }
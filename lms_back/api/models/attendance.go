@@ -0,0 +1,75 @@
+package models
+
+const (
+	AttendancePresent = "present"
+	AttendanceAbsent  = "absent"
+	AttendanceLate    = "late"
+	AttendanceExcused = "excused"
+)
+
+type Attendance struct {
+	LessonID  string `json:"lesson_id"`
+	StudentID string `json:"student_id"`
+	Status    string `json:"status"`
+	Note      string `json:"note"`
+	// MarkedBy is the id of the teacher or admin who marked the student last
+	MarkedBy string `json:"marked_by"`
+	// GroupID and LessonDate are the group and the from date of the lesson
+	GroupID    string `json:"group_id"`
+	LessonDate string `json:"lesson_date"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+type MarkAttendance struct {
+	StudentID string `json:"student_id" binding:"required,uuid"`
+	Status    string `json:"status" binding:"required,attendance_status"`
+	Note      string `json:"note" binding:"max=255"`
+}
+
+// MarkAttendanceRequest marks the students of a lesson, the marks of students
+// marked before are replaced.
+type MarkAttendanceRequest struct {
+	Students []MarkAttendance `json:"students" binding:"required,min=1,dive"`
+}
+
+// AttendanceRate counts the marks of a student.
+type AttendanceRate struct {
+	StudentID string `json:"student_id"`
+	Present   int    `json:"present"`
+	Late      int    `json:"late"`
+	Absent    int    `json:"absent"`
+	Excused   int    `json:"excused"`
+	// Rate is the share of the lessons the student attended, late or not, out
+	// of the ones not excused, rounded to two decimals. It is 0 without such
+	// lessons.
+	Rate float64 `json:"rate"`
+}
+
+type GetAllAttendanceRequest struct {
+	LessonID  string `json:"lesson_id"`
+	StudentID string `json:"student_id"`
+	GroupID   string `json:"group_id"`
+	// From and To are inclusive "2006-01-02" bounds of the lesson date
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Page  uint64 `json:"page"`
+	Limit uint64 `json:"limit"`
+}
+
+type GetAllAttendanceResponse struct {
+	Attendance []Attendance `json:"attendance"`
+	Count      int64        `json:"count"`
+}
+
+// StudentAttendanceResponse is a page of the marks of a student and the rate
+// of all of them.
+type StudentAttendanceResponse struct {
+	Attendance []Attendance   `json:"attendance"`
+	Count      int64          `json:"count"`
+	Rate       AttendanceRate `json:"rate"`
+}
+
+type GroupAttendanceResponse struct {
+	Students []AttendanceRate `json:"students"`
+}
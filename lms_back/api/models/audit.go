@@ -0,0 +1,38 @@
+package models
+
+import "encoding/json"
+
+type AuditLog struct {
+	Id         string          `json:"id"`
+	ActorID    string          `json:"actor_id"`
+	ActorRole  string          `json:"actor_role"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	BranchID   string          `json:"branch_id"`
+	Action     string          `json:"action"`
+	Before     json.RawMessage `json:"before" swaggertype:"object"`
+	After      json.RawMessage `json:"after" swaggertype:"object"`
+	// Changes holds the changed fields as {"field": {"before": ..., "after": ...}}
+	Changes   json.RawMessage `json:"changes" swaggertype:"object"`
+	IP        string          `json:"ip"`
+	CreatedAt string          `json:"created_at"`
+}
+
+type GetAllAuditLogsResponse struct {
+	AuditLogs []AuditLog `json:"audit_logs"`
+	Count     int64      `json:"count"`
+}
+
+type GetAllAuditLogsRequest struct {
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	ActorID    string `json:"actor_id"`
+	Action     string `json:"action"`
+	// From and To are "2006-01-02 15:04:05" timestamps, From is inclusive and To exclusive
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Page  uint64 `json:"page"`
+	Limit uint64 `json:"limit"`
+	// BranchIDs limits the result to the branches, nil means all branches
+	BranchIDs []string `json:"-"`
+}
@@ -1,11 +1,14 @@
 package models
 
-type StudentLoginRequest struct {
+type LoginRequest struct {
 	Login    string `json:"login"`
 	Password string `json:"password"`
+	// OTPCode or RecoveryCode is required when two-factor authentication is enabled
+	OTPCode      string `json:"otp_code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
 }
 
-type StudentLoginResponse struct {
+type LoginResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 }
@@ -13,8 +16,53 @@ type StudentLoginResponse struct {
 type AuthInfo struct {
 	UserID   string `json:"user_id"`
 	UserRole string `json:"user_role"`
+	// BranchIDs are the branches an admin manages, superadmins manage all of them
+	BranchIDs []string `json:"branch_ids"`
+	TokenID   string   `json:"-"`
+	ExpiresAt int64    `json:"-"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
 }
 
 type StudentRegisterRequest struct {
 	Mail string `json:"mail"`
-}
\ No newline at end of file
+}
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RevokedToken struct {
+	JTI       string `json:"jti"`
+	UserID    string `json:"user_id"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+type ForgotPasswordRequest struct {
+	Role  string `json:"role"`
+	Login string `json:"login"`
+}
+
+type ResetPasswordRequest struct {
+	Role        string `json:"role"`
+	Login       string `json:"login"`
+	Code        string `json:"code"`
+	NewPassword string `json:"new_password"`
+}
+
+type PasswordReset struct {
+	Id        string `json:"id"`
+	UserID    string `json:"user_id"`
+	UserRole  string `json:"user_role"`
+	CodeHash  string `json:"-"`
+	Attempts  int    `json:"attempts"`
+	ExpiresAt string `json:"expires_at"`
+	UsedAt    string `json:"used_at"`
+	CreatedAt string `json:"created_at"`
+}
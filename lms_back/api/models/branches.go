@@ -4,19 +4,20 @@ type Branch struct {
 	Id        string `json:"id"`
 	Name      string `json:"name"`
 	Address   string `json:"address"`
+	Version   int    `json:"version"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 	DeletedAt string `json:"deleted_at"`
 }
 
 type CreateBranch struct {
-	Name      string `json:"name"`
-	Address   string `json:"address"`
+	Name      string `json:"name" binding:"required,max=255"`
+	Address   string `json:"address" binding:"required,max=255"`
 }
 
 type UpdateBranch struct {
-	Name      string `json:"name"`
-	Address   string `json:"address"`
+	Name      string `json:"name" binding:"required,max=255"`
+	Address   string `json:"address" binding:"required,max=255"`
 }
 
 type GetBranch struct {
@@ -31,10 +32,12 @@ type GetBranch struct {
 type GetAllBranchesResponse struct {
 	Branches []Branch `json:"branches"`
 	Count    int16    `json:"count"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type GetAllBranchesRequest struct {
 	Search string `json:"search"`
 	Page   uint64 `json:"page"`
 	Limit  uint64 `json:"limit"`
+	ListParams
 }
@@ -0,0 +1,48 @@
+package models
+
+// The statuses of enrollments. An enrollment is active until the student is
+// transferred to another group or withdrawn.
+const (
+	EnrollmentActive      = "active"
+	EnrollmentTransferred = "transferred"
+	EnrollmentWithdrawn   = "withdrawn"
+)
+
+type Enrollment struct {
+	Id         string `json:"id"`
+	StudentID  string `json:"student_id"`
+	GroupID    string `json:"group_id"`
+	EnrolledAt string `json:"enrolled_at"`
+	// LeftAt is empty while the enrollment is active
+	LeftAt string `json:"left_at"`
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+type EnrollRequest struct {
+	GroupID string `json:"group_id" binding:"required,uuid"`
+}
+
+type TransferRequest struct {
+	FromGroupID string `json:"from_group_id" binding:"required,uuid"`
+	ToGroupID   string `json:"to_group_id" binding:"required,uuid"`
+	Reason      string `json:"reason" binding:"max=255"`
+}
+
+type WithdrawRequest struct {
+	GroupID string `json:"group_id" binding:"required,uuid"`
+	Reason  string `json:"reason" binding:"max=255"`
+}
+
+type GetAllEnrollmentsResponse struct {
+	Enrollments []Enrollment `json:"enrollments"`
+	Count       int64        `json:"count"`
+}
+
+type GetAllEnrollmentsRequest struct {
+	StudentID string `json:"student_id"`
+	GroupID   string `json:"group_id"`
+	Status    string `json:"status"`
+	Page      uint64 `json:"page"`
+	Limit     uint64 `json:"limit"`
+}
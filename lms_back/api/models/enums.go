@@ -0,0 +1,13 @@
+package models
+
+// GroupTypes are the types of groups, the ones the type check of the group
+// table allows.
+var GroupTypes = []string{"backend", "frontend", "mobile", "devops", "qa", "pm", "designer"}
+
+// Statuses are the statuses of admins, teachers and students. Inactive users
+// can't log in.
+var Statuses = []string{"active", "inactive"}
+
+// AttendanceStatuses are the marks of a student for a lesson. Late students
+// attended, excused ones don't count against the attendance rate.
+var AttendanceStatuses = []string{AttendancePresent, AttendanceAbsent, AttendanceLate, AttendanceExcused}
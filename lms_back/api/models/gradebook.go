@@ -0,0 +1,112 @@
+package models
+
+// The trends of the scores of a student.
+const (
+	TrendUp     = "up"
+	TrendDown   = "down"
+	TrendSteady = "steady"
+)
+
+// GradebookRequest selects the lessons of a group, and the tasks of the
+// lessons, that a gradebook or progress report covers.
+type GradebookRequest struct {
+	GroupID string `json:"group_id"`
+	// From and To are inclusive "2006-01-02" bounds of the lesson date
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// GradebookStudent is a student actively enrolled in the group.
+type GradebookStudent struct {
+	StudentID string `json:"student_id"`
+	FullName  string `json:"full_name"`
+}
+
+type GradebookLesson struct {
+	LessonID string `json:"lesson_id"`
+	Theme    string `json:"theme"`
+	Date     string `json:"date"`
+}
+
+type GradebookTask struct {
+	TaskID   string `json:"task_id"`
+	LessonID string `json:"lesson_id"`
+	Task     string `json:"task"`
+	MaxScore int    `json:"max_score"`
+	Deadline string `json:"deadline"`
+}
+
+// GradebookScore is a submission to a task, Score is nil until it is graded.
+type GradebookScore struct {
+	TaskID    string `json:"task_id"`
+	StudentID string `json:"student_id"`
+	Score     *int   `json:"score"`
+	Late      bool   `json:"late"`
+}
+
+// Gradebook is a matrix of the students of a group and the tasks of its
+// lessons, Students[i].Scores[j] is the score of the student for Tasks[j].
+type Gradebook struct {
+	GroupID  string                `json:"group_id"`
+	From     string                `json:"from"`
+	To       string                `json:"to"`
+	Tasks    []GradebookColumn     `json:"tasks"`
+	Students []GradebookStudentRow `json:"students"`
+}
+
+// GradebookColumn is a task of the gradebook with the lesson it was given in.
+// Average is the mean share of the max score of its graded submissions.
+type GradebookColumn struct {
+	GradebookTask
+	LessonTheme string  `json:"lesson_theme"`
+	LessonDate  string  `json:"lesson_date"`
+	Average     float64 `json:"average"`
+	Graded      int     `json:"graded"`
+}
+
+// GradebookStudentRow is a student of the gradebook. Average is the mean
+// share of the max score of its graded tasks and Attendance its attendance
+// rate over the lessons of the gradebook, both are 0 without any.
+type GradebookStudentRow struct {
+	GradebookStudent
+	Scores     []GradebookCell `json:"scores"`
+	Submitted  int             `json:"submitted"`
+	Graded     int             `json:"graded"`
+	Average    float64         `json:"average"`
+	Attendance float64         `json:"attendance"`
+}
+
+type GradebookCell struct {
+	Submitted bool `json:"submitted"`
+	Late      bool `json:"late"`
+	// Score is nil until the submission is graded
+	Score *int `json:"score"`
+}
+
+// LessonProgress is how far a student got with a lesson. A lesson is
+// completed once every one of its tasks is submitted, a lesson without tasks
+// once the student attended it.
+type LessonProgress struct {
+	GradebookLesson
+	// Attendance is the mark of the student, empty if it wasn't marked
+	Attendance string `json:"attendance"`
+	Tasks      int    `json:"tasks"`
+	Submitted  int    `json:"submitted"`
+	Completed  bool   `json:"completed"`
+}
+
+// StudentProgress is the progress of a student in a group. Trend compares the
+// later half of its graded tasks with the earlier half, Rank is its place by
+// Average among the students of the group, students with the same average
+// share a place.
+type StudentProgress struct {
+	StudentID  string           `json:"student_id"`
+	GroupID    string           `json:"group_id"`
+	Lessons    []LessonProgress `json:"lessons"`
+	Completed  int              `json:"completed"`
+	Average    float64          `json:"average"`
+	Attendance float64          `json:"attendance"`
+	Trend      string           `json:"trend"`
+	Rank       int              `json:"rank"`
+	GroupSize  int              `json:"group_size"`
+}
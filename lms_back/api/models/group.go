@@ -6,22 +6,21 @@ type Group struct {
 	Branch_id  string `json:"branch_id"`
 	Teacher_id string `json:"teacher_id"`
 	Type       string `json:"type"`
+	Version    int    `json:"version"`
 	Created_at string `json:"created_at"`
 	Updated_at string `json:"updated_at"`
 }
 
 type CreateGroup struct {
 	Group_id   string `json:"group_id"`
-	Branch_id  string `json:"branch_id"`
-	Teacher_id string `json:"teacher_id"`
-	Type       string `json:"type"`
+	Branch_id  string `json:"branch_id" binding:"required,uuid"`
+	Teacher_id string `json:"teacher_id" binding:"required,uuid"`
+	Type       string `json:"type" binding:"required,group_type"`
 }
 
 type UpdateGroup struct {
-	Group_id   string `json:"group_id"`
-	Branch_id  string `json:"branch_id"`
-	Teacher_id string `json:"teacher_id"`
-	Type       string `json:"type"`
+	Group_id string `json:"group_id"`
+	Type     string `json:"type" binding:"required,group_type"`
 }
 
 type GetGroup struct {
@@ -35,12 +34,19 @@ type GetGroup struct {
 }
 
 type GetAllGroupsResponse struct {
-	Groups []Group `json:"groups"`
-	Count  int16   `json:"count"`
+	Groups     []Group `json:"groups"`
+	Count      int16   `json:"count"`
+	NextCursor string  `json:"next_cursor,omitempty"`
 }
 
 type GetAllGroupsRequest struct {
-	Search string `json:"search"`
-	Page   uint64 `json:"page"`
-	Limit  uint64 `json:"limit"`
+	Search    string `json:"search"`
+	BranchID  string `json:"branch_id"`
+	TeacherID string `json:"teacher_id"`
+	Type      string `json:"type"`
+	Page      uint64 `json:"page"`
+	Limit     uint64 `json:"limit"`
+	ListParams
+	// BranchIDs limits the result to the branches, nil means all branches
+	BranchIDs []string `json:"-"`
 }
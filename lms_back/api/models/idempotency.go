@@ -0,0 +1,17 @@
+package models
+
+// IdempotencyKey is a request made with an Idempotency-Key header and the
+// response stored for its replays.
+type IdempotencyKey struct {
+	Key         string `json:"key"`
+	UserID      string `json:"user_id"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	RequestHash string `json:"request_hash"`
+	// StatusCode is 0 while the first request is still running
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type"`
+	ETag        string `json:"etag"`
+	Response    []byte `json:"-"`
+	CreatedAt   string `json:"created_at"`
+}
@@ -7,24 +7,25 @@ type Lesson struct {
 	From       string `json:"from"`
 	To         string `json:"to"`
 	Theme      string `json:"theme"`
+	Version    int    `json:"version"`
 	Created_at string `json:"created_at"`
 	Updated_at string `json:"updated_at"`
 }
 
 type CreateLesson struct {
-	ScheduleId string `json:"schedule_id"`
-	GroupId    string `json:"group_id"`
-	From       string `json:"from"`
-	To         string `json:"to"`
-	Theme      string `json:"theme"`
+	ScheduleId string `json:"schedule_id" binding:"required,uuid"`
+	GroupId    string `json:"group_id" binding:"required,uuid"`
+	From       string `json:"from" binding:"required,datetime=2006-01-02"`
+	To         string `json:"to" binding:"required,datetime=2006-01-02"`
+	Theme      string `json:"theme" binding:"required,max=255"`
 }
 
 type UpdateLesson struct {
-	ScheduleId string `json:"schedule_id"`
-	GroupId    string `json:"group_id"`
-	From       string `json:"from"`
-	To         string `json:"to"`
-	Theme      string `json:"theme"`
+	ScheduleId string `json:"schedule_id" binding:"required,uuid"`
+	GroupId    string `json:"group_id" binding:"required,uuid"`
+	From       string `json:"from" binding:"required,datetime=2006-01-02"`
+	To         string `json:"to" binding:"required,datetime=2006-01-02"`
+	Theme      string `json:"theme" binding:"required,max=255"`
 }
 
 type GetLesson struct {
@@ -42,10 +43,17 @@ type GetLesson struct {
 type GetAllLessonsResponse struct {
 	Lessons []Lesson `json:"lessons"`
 	Count   int16    `json:"count"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type GetAllLessonsRequest struct {
-	Search string `json:"search"`
-	Page   uint64 `json:"page"`
-	Limit  uint64 `json:"limit"`
+	Search     string `json:"search"`
+	GroupID    string `json:"group_id"`
+	ScheduleID string `json:"schedule_id"`
+	// FromDate and ToDate are inclusive "2006-01-02" bounds of the lesson start
+	FromDate string `json:"from_date"`
+	ToDate   string `json:"to_date"`
+	Page     uint64 `json:"page"`
+	Limit    uint64 `json:"limit"`
+	ListParams
 }
@@ -0,0 +1,17 @@
+package models
+
+// DeletedOnly is the value of the deleted list param that lists the trash,
+// the soft deleted rows.
+const DeletedOnly = "only"
+
+// ListParams are the params shared by list requests.
+type ListParams struct {
+	// Sort is a comma separated list of fields, a leading "-" sorts descending, e.g. "-created_at,full_name"
+	Sort string `json:"sort"`
+	// Cursor is the next_cursor of the previous page in cursor pagination
+	Cursor string `json:"cursor"`
+	// UseCursor pages by cursor instead of page, it is set by passing the cursor param, empty for the first page
+	UseCursor bool `json:"-"`
+	// Deleted is DeletedOnly to list soft deleted rows instead of the others
+	Deleted string `json:"deleted"`
+}
@@ -0,0 +1,43 @@
+package models
+
+type LoginAttempt struct {
+	Id        string `json:"id"`
+	Login     string `json:"login"`
+	UserRole  string `json:"user_role"`
+	UserID    string `json:"user_id"`
+	BranchID  string `json:"branch_id"`
+	IP        string `json:"ip"`
+	Success   bool   `json:"success"`
+	CreatedAt string `json:"created_at"`
+}
+
+type GetAllLoginAttemptsResponse struct {
+	LoginAttempts []LoginAttempt `json:"login_attempts"`
+	Count         int64          `json:"count"`
+}
+
+type GetAllLoginAttemptsRequest struct {
+	Login    string `json:"login"`
+	UserRole string `json:"user_role"`
+	BranchID string `json:"branch_id"`
+	IP       string `json:"ip"`
+	// Success is "true", "false" or empty for all attempts
+	Success string `json:"success"`
+	Page    uint64 `json:"page"`
+	Limit   uint64 `json:"limit"`
+	// BranchIDs limits the result to the branches, nil means all branches
+	BranchIDs []string `json:"-"`
+}
+
+// LoginThrottle counts the recent failed logins of a login or an ip address.
+type LoginThrottle struct {
+	Key      string `json:"key"`
+	Failures int    `json:"failures"`
+	// RetryAfter is the number of seconds left until the next attempt is allowed
+	RetryAfter int `json:"retry_after"`
+}
+
+type UnlockLoginRequest struct {
+	Role  string `json:"role"`
+	Login string `json:"login"`
+}
@@ -0,0 +1,7 @@
+package models
+
+import "encoding/json"
+
+// Patch is a JSON merge patch (RFC 7396) of an entity, the new values of the
+// fields by their json names. The fields that are not in it are kept.
+type Patch map[string]json.RawMessage
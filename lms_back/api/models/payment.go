@@ -6,22 +6,23 @@ type Payment struct {
 	Student_id string  `json:"student_id"`
 	Branch_id  string  `json:"branch_id"`
 	Admin_id   string  `json:"admin_id"`
+	Version    int     `json:"version"`
 	CreatedAt  string  `json:"created_at"`
 	UpdatedAt  string  `json:"updated_at"`
 }
 
 type CreatePayment struct {
-	Price      float64 `json:"price"`
-	Student_id string  `json:"student_id"`
-	Branch_id  string  `json:"branch_id"`
-	Admin_id   string  `json:"admin_id"`
+	Price      float64 `json:"price" binding:"required,gt=0"`
+	Student_id string  `json:"student_id" binding:"required,uuid"`
+	Branch_id  string  `json:"branch_id" binding:"required,uuid"`
+	Admin_id   string  `json:"admin_id" binding:"required,uuid"`
 }
 
 type UpdatePayment struct {
-	Price      float64 `json:"price"`
-	Student_id string  `json:"student_id"`
-	Branch_id  string  `json:"branch_id"`
-	Admin_id   string  `json:"admin_id"`
+	Price      float64 `json:"price" binding:"required,gt=0"`
+	Student_id string  `json:"student_id" binding:"required,uuid"`
+	Branch_id  string  `json:"branch_id" binding:"required,uuid"`
+	Admin_id   string  `json:"admin_id" binding:"required,uuid"`
 }
 
 type GetPayment struct {
@@ -35,12 +36,24 @@ type GetPayment struct {
 }
 
 type GetAllPaymentsResponse struct {
-	Payments []Payment `json:"payments"`
-	Count    int16     `json:"count"`
+	Payments   []Payment `json:"payments"`
+	Count      int16     `json:"count"`
+	NextCursor string    `json:"next_cursor,omitempty"`
 }
 
 type GetAllPaymentsRequest struct {
-	Search string `json:"search"`
-	Page   uint64 `json:"page"`
-	Limit  uint64 `json:"limit"`
+	Search    string  `json:"search"`
+	StudentID string  `json:"student_id"`
+	BranchID  string  `json:"branch_id"`
+	AdminID   string  `json:"admin_id"`
+	MinPrice  float64 `json:"min_price"`
+	MaxPrice  float64 `json:"max_price"`
+	// CreatedFrom and CreatedTo are inclusive "2006-01-02" dates
+	CreatedFrom string `json:"created_from"`
+	CreatedTo   string `json:"created_to"`
+	Page        uint64 `json:"page"`
+	Limit       uint64 `json:"limit"`
+	ListParams
+	// BranchIDs limits the result to the branches, nil means all branches
+	BranchIDs []string `json:"-"`
 }
@@ -1,7 +1,13 @@
 package models
 
+import "lms_back/pkg/errs"
+
 type Response struct {
 	StatusCode  int
 	Description string
 	Data        interface{}
+	// Code is the machine-readable name of a domain error, like "not_found"
+	Code string `json:"code,omitempty"`
+	// Errors are the invalid fields of a validation error
+	Errors []errs.FieldError `json:"errors,omitempty"`
 }
@@ -9,28 +9,29 @@ type Schedule struct {
 	Date       string `json:"date"`
 	Branch_id  string `json:"branch_id"`
 	Teacher_id string `json:"teacher_id"`
+	Version    int    `json:"version"`
 	Created_at string `json:"created_id"`
 	Updated_at string `json:"updated_id"`
 }
 
 type CreateSchedule struct {
-	Group_id   string `json:"group_id"`
-	Group_type string `json:"group_type"`
-	Start_time string `json:"start_time"`
-	End_time   string `json:"end_time"`
-	Date       string `json:"date"`
-	Branch_id  string `json:"branch_id"`
-	Teacher_id string `json:"teacher_id"`
+	Group_id   string `json:"group_id" binding:"required,uuid"`
+	Group_type string `json:"group_type" binding:"omitempty,group_type"`
+	Start_time string `json:"start_time" binding:"required,clock"`
+	End_time   string `json:"end_time" binding:"required,clock"`
+	Date       string `json:"date" binding:"required,max=255"`
+	Branch_id  string `json:"branch_id" binding:"required,uuid"`
+	Teacher_id string `json:"teacher_id" binding:"required,uuid"`
 }
 
 type UpdateSchedule struct {
-	Group_id   string `json:"group_id"`
-	Group_type string `json:"group_type"`
-	Start_time string `json:"start_time"`
-	End_time   string `json:"end_time"`
-	Date       string `json:"date"`
-	Branch_id  string `json:"branch_id"`
-	Teacher_id string `json:"teacher_id"`
+	Group_id   string `json:"group_id" binding:"required,uuid"`
+	Group_type string `json:"group_type" binding:"omitempty,group_type"`
+	Start_time string `json:"start_time" binding:"required,clock"`
+	End_time   string `json:"end_time" binding:"required,clock"`
+	Date       string `json:"date" binding:"required,max=255"`
+	Branch_id  string `json:"branch_id" binding:"required,uuid"`
+	Teacher_id string `json:"teacher_id" binding:"required,uuid"`
 }
 
 
@@ -51,10 +52,18 @@ type GetSchedule struct {
 type GetAllSchedulesResponse struct {
 	Schedules []Schedule `json:"students"`
 	Count    int16     `json:"count"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type GetAllSchedulesRequest struct {
-	Search string `json:"search"`
-	Page   uint64 `json:"page"`
-	Limit  uint64 `json:"limit"`
+	Search    string `json:"search"`
+	GroupID   string `json:"group_id"`
+	BranchID  string `json:"branch_id"`
+	TeacherID string `json:"teacher_id"`
+	Date      string `json:"date"`
+	Page      uint64 `json:"page"`
+	Limit     uint64 `json:"limit"`
+	ListParams
+	// BranchIDs limits the result to the branches, nil means all branches
+	BranchIDs []string `json:"-"`
 }
@@ -8,34 +8,31 @@ type Student struct {
 	PaidSum    float64 `json:"paid_sum"`
 	Status     string  `json:"status"`
 	Login      string  `json:"login"`
-	Password   string  `json:"password"`
+	Password   string  `json:"-"`
 	GroupID    string  `json:"group_id"`
+	Version    int     `json:"version"`
 	Created_At string  `json:"created_at"`
 	Updated_At string  `json:"updated_at"`
 	Deleted_At int     `json:"deleted_at"`
 }
 
 type CreateStudent struct {
-	Full_Name string  `json:"full_name"`
-	Email     string  `json:"email"`
-	Age       int     `json:"age"`
-	PaidSum   float64 `json:"paid_sum"`
-	Status    string  `json:"status"`
-	Login     string  `json:"login"`
-	Password  string  `json:"password"`
-	GroupID   string  `json:"group_id"`
+	Full_Name string `json:"full_name" binding:"required,max=255"`
+	Email     string `json:"email" binding:"required,email"`
+	Age       int    `json:"age" binding:"required,gt=0"`
+	Status    string `json:"status" binding:"required,status"`
+	Login     string `json:"login" binding:"required,max=255"`
+	Password  string `json:"password" binding:"required,min=6"`
+	GroupID   string `json:"group_id" binding:"omitempty,uuid"`
 }
 
 type UpdateStudent struct {
-	ID        string  `json:"id"`
-	Full_Name string  `json:"full_name"`
-	Email     string  `json:"email"`
-	Age       int     `json:"age"`
-	PaidSum   float64 `json:"paid_sum"`
-	Status    string  `json:"status"`
-	Login     string  `json:"login"`
-	Password  string  `json:"password"`
-	GroupID   string  `json:"group_id"`
+	Full_Name string  `json:"full_name" binding:"required,max=255"`
+	Email     string  `json:"email" binding:"required,email"`
+	Age       int     `json:"age" binding:"required,gt=0"`
+	Status    string  `json:"status" binding:"required,status"`
+	Login     string  `json:"login" binding:"required,max=255"`
+	GroupID   string  `json:"group_id" binding:"omitempty,uuid"`
 }
 
 type GetStudent struct {
@@ -46,21 +43,35 @@ type GetStudent struct {
 	PaidSum    float64 `json:"paid_sum"`
 	Status     string  `json:"status"`
 	Login      string  `json:"login"`
-	Password   string  `json:"password"`
 	GroupID    string  `json:"group_id"`
+	Version    int     `json:"version"`
 	Created_At string  `json:"created_at"`
 	Updated_At string  `json:"updated_at"`
 	Deleted_At int     `json:"deleted_at"`
 }
 
 type GetAllStudentsResponse struct {
-	Students []Student `json:"students"`
+	Students []GetStudent `json:"students"`
 	Count    int16     `json:"count"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type GetAllStudentsRequest struct {
-	Search string `json:"search"`
-	Page   uint64 `json:"page"`
-	Limit  uint64 `json:"limit"`
+	Search  string `json:"search"`
+	Status  string `json:"status"`
+	GroupID string `json:"group_id"`
+	// EnrolledGroupID filters by an active enrollment in the group, unlike
+	// GroupID it also finds the students the group isn't the primary group of
+	EnrolledGroupID string `json:"-"`
+	// BranchID filters by the branch of the student's group
+	BranchID string `json:"branch_id"`
+	// CreatedFrom and CreatedTo are inclusive "2006-01-02" dates
+	CreatedFrom string `json:"created_from"`
+	CreatedTo   string `json:"created_to"`
+	Page        uint64 `json:"page"`
+	Limit       uint64 `json:"limit"`
+	ListParams
+	// BranchIDs limits the result to the branches, nil means all branches
+	BranchIDs []string `json:"-"`
 }
 
@@ -0,0 +1,45 @@
+package models
+
+// Submission is the answer of a student to a task.
+type Submission struct {
+	Id         string `json:"id"`
+	TaskID     string `json:"task_id"`
+	StudentID  string `json:"student_id"`
+	Answer     string `json:"answer"`
+	Attachment string `json:"attachment"`
+	// SubmittedAt is the time of the last submission, Late tells if it was
+	// after the deadline of the task
+	SubmittedAt string `json:"submitted_at"`
+	Late        bool   `json:"late"`
+	// Score is nil until the submission is graded
+	Score     *int   `json:"score"`
+	Feedback  string `json:"feedback"`
+	GradedBy  string `json:"graded_by"`
+	GradedAt  string `json:"graded_at"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// SubmitTask is the answer of the student, Attachment references a file
+// uploaded elsewhere, like a link.
+type SubmitTask struct {
+	Answer     string `json:"answer" binding:"required,max=10000"`
+	Attachment string `json:"attachment" binding:"max=255"`
+}
+
+type GradeSubmission struct {
+	Score    *int   `json:"score" binding:"required,gte=0"`
+	Feedback string `json:"feedback" binding:"max=1000"`
+}
+
+type GetAllSubmissionsRequest struct {
+	TaskID    string `json:"task_id"`
+	StudentID string `json:"student_id"`
+	Page      uint64 `json:"page"`
+	Limit     uint64 `json:"limit"`
+}
+
+type GetAllSubmissionsResponse struct {
+	Submissions []Submission `json:"submissions"`
+	Count       int64        `json:"count"`
+}
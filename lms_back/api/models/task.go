@@ -3,29 +3,41 @@ package models
 type Task struct {
 	Id        string `json:"id"`
 	LessonId  string `json:"lesson_id"`
-	GroupId   string `json:"task"`
+	GroupId   string `json:"group_id"`
+	Task      string `json:"task"`
+	// Score is the most a submission of the task can be graded
 	Score     string `json:"score"`
+	// Deadline is the RFC 3339 time in UTC submissions are late after, empty
+	// for a task without one
+	Deadline  string `json:"deadline"`
+	Version   int    `json:"version"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 }
 
 type CreateTask struct {
-	LessonId  string `json:"lesson_id"`
-	GroupId   string `json:"task"`
-	Score     string `json:"score"`
+	LessonId  string `json:"lesson_id" binding:"required,uuid"`
+	GroupId   string `json:"group_id" binding:"required,uuid"`
+	Task      string `json:"task" binding:"required,max=255"`
+	Score     string `json:"score" binding:"required,number"`
+	Deadline  string `json:"deadline" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
 }
 
 type UpdateTask struct {
-	LessonId  string `json:"lesson_id"`
-	GroupId   string `json:"task"`
-	Score     string `json:"score"`
+	LessonId  string `json:"lesson_id" binding:"required,uuid"`
+	GroupId   string `json:"group_id" binding:"required,uuid"`
+	Task      string `json:"task" binding:"required,max=255"`
+	Score     string `json:"score" binding:"required,number"`
+	Deadline  string `json:"deadline" binding:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
 }
 
 type GetTask struct {
 	Id        string `json:"id"`
 	LessonId  string `json:"lesson_id"`
-	GroupId   string `json:"task"`
+	GroupId   string `json:"group_id"`
+	Task      string `json:"task"`
 	Score     string `json:"score"`
+	Deadline  string `json:"deadline"`
 	CreatedAt string `json:"created_at"`
 	UpdatedAt string `json:"updated_at"`
 }
@@ -33,11 +45,15 @@ type GetTask struct {
 type GetAllTasksResponse struct {
 	Tasks []Task `json:"tasks"`
 	Count int16  `json:"count"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type GetAllTasksRequest struct {
-	Search string `json:"search"`
-	Page   uint64 `json:"page"`
-	Limit  uint64 `json:"limit"`
+	Search   string `json:"search"`
+	LessonID string `json:"lesson_id"`
+	GroupID  string `json:"group_id"`
+	Page     uint64 `json:"page"`
+	Limit    uint64 `json:"limit"`
+	ListParams
 }
 
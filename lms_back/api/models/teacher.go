@@ -7,28 +7,28 @@ type Teacher struct {
 	Age        int    `json:"age"`
 	Status     string `json:"status"`
 	Login      string `json:"login"`
-	Password   string `json:"password"`
+	Password   string `json:"-"`
+	Version    int    `json:"version"`
 	Created_at string `json:"created_at"`
 	Updated_at string `json:"updated_at"`
 	Deleted_at string `json:"deleted_at"`
 }
 
 type CreateTeacher struct {
-	Full_name  string `json:"full_name"`
-	Email      string `json:"email"`
-	Age        int    `json:"age"`
-	Status     string `json:"status"`
-	Login      string `json:"login"`
-	Password   string `json:"password"`
+	Full_name  string `json:"full_name" binding:"required,max=255"`
+	Email      string `json:"email" binding:"required,email"`
+	Age        int    `json:"age" binding:"required,gt=0"`
+	Status     string `json:"status" binding:"required,status"`
+	Login      string `json:"login" binding:"required,max=255"`
+	Password   string `json:"password" binding:"required,min=6"`
 }
 
 type UpdateTeacher struct {
-	Full_name  string `json:"full_name"`
-	Email      string `json:"email"`
-	Age        int    `json:"age"`
-	Status     string `json:"status"`
-	Login      string `json:"login"`
-	Password   string `json:"password"`
+	Full_name  string `json:"full_name" binding:"required,max=255"`
+	Email      string `json:"email" binding:"required,email"`
+	Age        int    `json:"age" binding:"required,gt=0"`
+	Status     string `json:"status" binding:"required,status"`
+	Login      string `json:"login" binding:"required,max=255"`
 }
 
 type GetTeacher struct {
@@ -38,19 +38,22 @@ type GetTeacher struct {
 	Age        int    `json:"age"`
 	Status     string `json:"status"`
 	Login      string `json:"login"`
-	Password   string `json:"password"`
+	Version    int    `json:"version"`
 	Created_at string `json:"created_at"`
 	Updated_at string `json:"updated_at"`
 	Deleted_at string `json:"deleted_at"`
 }
 
 type GetAllTeachersResponse struct {
-	Teachers []Teacher `json:"teachers"`
+	Teachers []GetTeacher `json:"teachers"`
 	Count    int16     `json:"count"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type GetAllTeachersRequest struct {
 	Search string `json:"search"`
+	Status string `json:"status"`
 	Page   uint64 `json:"page"`
 	Limit  uint64 `json:"limit"`
+	ListParams
 }
@@ -0,0 +1,37 @@
+package models
+
+type TwoFactor struct {
+	UserID       string `json:"user_id"`
+	UserRole     string `json:"user_role"`
+	Secret       string `json:"-"`
+	Enabled      bool   `json:"enabled"`
+	LastUsedStep int64  `json:"-"`
+	CreatedAt    string `json:"created_at"`
+	EnabledAt    string `json:"enabled_at"`
+}
+
+type RecoveryCode struct {
+	Id       string `json:"id"`
+	UserID   string `json:"user_id"`
+	UserRole string `json:"user_role"`
+	CodeHash string `json:"-"`
+}
+
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type TwoFactorConfirmRequest struct {
+	Code string `json:"code"`
+}
+
+type TwoFactorConfirmResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password"`
+	// Code is a one-time password or a recovery code
+	Code string `json:"code"`
+}
@@ -1,11 +1,10 @@
 package api
 
 import (
-	"errors"
 	"lms_back/api/handler"
+	"lms_back/config"
 	"lms_back/pkg/logger"
 	"lms_back/service"
-	"net/http"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -16,79 +15,130 @@ import (
 // @title           Swagger Example API
 // @version         1.0
 // @description     This is a sample server celler server.
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
 // @name Authorization
 func New(service service.IServiceManager, log logger.ILogger) *gin.Engine {
 	h := handler.NewStrg(service, log)
 
 	r := gin.Default()
+	// handlers pass the gin context to services, which read the caller from the request context
+	r.ContextWithFallback = true
+	r.Use(h.ClientIPMiddleware())
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	r.Use(authMiddleware)
-
-	r.GET("/admin", h.GetAllAdmins)
-	r.GET("/admin/:id", h.GetByIDAdmin)
-	r.POST("/admin", h.CreateAdmin)
-	r.PUT("/admin/:id", h.UpdateAdmin)
-	r.DELETE("/admin/:id", h.DeleteAdmin)
-	r.GET("adminPay/:id", h.GetByIdAdminReport)
-
-	r.GET("/branch", h.GetAllBranches)
-	r.GET("/branch/:id", h.GetByIDBranch)
-	r.POST("/branch", h.CreateBranch)
-	r.PUT("/branch/:id", h.UpdateBranch)
-	r.DELETE("/branch/:id", h.DeleteBranch)
-
-	r.GET("/group", h.GetAllGroups)
-	r.GET("/group/:id", h.GetByIDGroup)
-	r.POST("/group", h.CreateGroup)
-	r.PUT("/group/:id", h.UpdateGroup)
-	r.DELETE("/group/:id", h.DeleteGroup)
-
-	r.GET("/lesson", h.GetAllLessons)
-	r.GET("/lesson/:id", h.GetByIDLesson)
-	r.POST("/lesson", h.CreateLesson)
-	r.PUT("/lesson/:id", h.UpdateLesson)
-	r.DELETE("/lesson/:id", h.DeleteLessson)
-
-	r.GET("/payment", h.GetAllPayment)
-	r.GET("/payment/:id", h.GetByIDPayment)
-	r.POST("/payment", h.CreatePayment)
-	r.PUT("/payment/:id", h.UpdatePayment)
-	r.DELETE("/payment/:id", h.DeletePayment)
-
-	r.GET("/schedule", h.GetAllSchedule)
-	r.GET("/schedule/:id", h.GetByIDSchedule)
-	r.POST("/schedule", h.CreateSchedule)
-	r.PUT("/schedule/:id", h.UpdateSchedule)
-	r.DELETE("/schedule/:id", h.DeleteSchedule)
-
+	r.POST("/admin/login", h.AdminLogin)
+	r.POST("/teacher/login", h.TeacherLogin)
 	r.POST("/student/login", h.StudentLogin)
-
-	r.GET("/student", h.GetAllStudent)
-	r.GET("/student/:id", h.GetByIDStudent)
-	r.POST("/student", h.CreateStudent)
-	r.PUT("/student/:id", h.UpdateStudent)
-	r.DELETE("/student/:id", h.DeleteStudent)
-
-	r.GET("/task", h.GetAllTask)
-	r.GET("/task/:id", h.GetByIDtask)
-	r.POST("/task", h.CreateTask)
-	r.PUT("/task/:id", h.UpdateTask)
-	r.DELETE("/task/:id", h.DeleteTask)
-
-	r.GET("/teacher", h.GetAllTeacher)
-	r.GET("/teacher/:id", h.GetByIDTeacher)
-	r.POST("/teacher", h.CreateTeacher)
-	r.PUT("/teacher/:id", h.UpdateTeacher)
-	r.DELETE("/teacher/:id", h.DeleteTeacher)
+	r.POST("/auth/refresh", h.RefreshToken)
+	r.POST("/auth/forgot-password", h.ForgotPassword)
+	r.POST("/auth/reset-password", h.ResetPassword)
+
+	superadmin := r.Group("/", h.AuthMiddleware(config.SUPERADMIN_ROLE))
+	admin := r.Group("/", h.AuthMiddleware(config.ADMIN_ROLE))
+	staff := r.Group("/", h.AuthMiddleware(config.ADMIN_ROLE, config.TEACHER_ROLE))
+	everyone := r.Group("/", h.AuthMiddleware(config.ADMIN_ROLE, config.TEACHER_ROLE, config.STUDENT_ROLE))
+	students := r.Group("/", h.AuthMiddleware(config.STUDENT_ROLE))
+	idempotent := h.IdempotencyMiddleware()
+
+	everyone.POST("/auth/logout", h.Logout)
+	admin.POST("/auth/unlock", h.UnlockLogin)
+	staff.POST("/auth/2fa/setup", h.SetupTwoFactor)
+	staff.POST("/auth/2fa/confirm", h.ConfirmTwoFactor)
+	staff.POST("/auth/2fa/disable", h.DisableTwoFactor)
+	admin.GET("/login-attempt", h.GetAllLoginAttempts)
+	admin.GET("/audit", h.GetAllAuditLogs)
+
+	superadmin.GET("/admin", h.GetAllAdmins)
+	superadmin.GET("/admin/:id", h.GetByIDAdmin)
+	superadmin.POST("/admin", idempotent, h.CreateAdmin)
+	superadmin.PUT("/admin/:id", h.UpdateAdmin)
+	superadmin.PATCH("/admin/:id", h.PatchAdmin)
+	superadmin.PUT("/admin/:id/branch", h.UpdateAdminBranches)
+	admin.PUT("/admin/:id/password", h.ChangeAdminPassword)
+	superadmin.DELETE("/admin/:id", h.DeleteAdmin)
+	superadmin.POST("/admin/:id/restore", h.RestoreAdmin)
+	admin.GET("adminPay/:id", h.GetByIdAdminReport)
+
+	everyone.GET("/branch", h.GetAllBranches)
+	everyone.GET("/branch/:id", h.GetByIDBranch)
+	admin.POST("/branch", idempotent, h.CreateBranch)
+	admin.PUT("/branch/:id", h.UpdateBranch)
+	admin.PATCH("/branch/:id", h.PatchBranch)
+	admin.DELETE("/branch/:id", h.DeleteBranch)
+	admin.POST("/branch/:id/restore", h.RestoreBranch)
+
+	everyone.GET("/group", h.GetAllGroups)
+	everyone.GET("/group/:id", h.GetByIDGroup)
+	admin.POST("/group", idempotent, h.CreateGroup)
+	admin.PUT("/group/:id", h.UpdateGroup)
+	admin.PATCH("/group/:id", h.PatchGroup)
+	admin.DELETE("/group/:id", h.DeleteGroup)
+	admin.POST("/group/:id/restore", h.RestoreGroup)
+
+	everyone.GET("/lesson", h.GetAllLessons)
+	everyone.GET("/lesson/:id", h.GetByIDLesson)
+	staff.POST("/lesson", idempotent, h.CreateLesson)
+	staff.PUT("/lesson/:id", h.UpdateLesson)
+	staff.PATCH("/lesson/:id", h.PatchLesson)
+	staff.DELETE("/lesson/:id", h.DeleteLessson)
+	staff.POST("/lesson/:id/restore", h.RestoreLesson)
+	staff.PUT("/lesson/:id/attendance", h.MarkAttendance)
+
+	admin.GET("/payment", h.GetAllPayment)
+	admin.GET("/payment/:id", h.GetByIDPayment)
+	admin.POST("/payment", idempotent, h.CreatePayment)
+	admin.PUT("/payment/:id", h.UpdatePayment)
+	admin.PATCH("/payment/:id", h.PatchPayment)
+	admin.DELETE("/payment/:id", h.DeletePayment)
+	admin.POST("/payment/:id/restore", h.RestorePayment)
+
+	everyone.GET("/schedule", h.GetAllSchedule)
+	everyone.GET("/schedule/:id", h.GetByIDSchedule)
+	admin.POST("/schedule", idempotent, h.CreateSchedule)
+	admin.PUT("/schedule/:id", h.UpdateSchedule)
+	admin.PATCH("/schedule/:id", h.PatchSchedule)
+	admin.DELETE("/schedule/:id", h.DeleteSchedule)
+	admin.POST("/schedule/:id/restore", h.RestoreSchedule)
+
+	staff.GET("/student", h.GetAllStudent)
+	everyone.GET("/student/:id", h.GetByIDStudent)
+	admin.POST("/student", idempotent, h.CreateStudent)
+	admin.PUT("/student/:id", h.UpdateStudent)
+	admin.PATCH("/student/:id", h.PatchStudent)
+	everyone.PUT("/student/:id/password", h.ChangeStudentPassword)
+	admin.DELETE("/student/:id", h.DeleteStudent)
+	admin.POST("/student/:id/restore", h.RestoreStudent)
+	admin.POST("/student/:id/enroll", h.EnrollStudent)
+	admin.POST("/student/:id/transfer", h.TransferStudent)
+	admin.POST("/student/:id/withdraw", h.WithdrawStudent)
+	everyone.GET("/student/:id/enrollments", h.GetStudentEnrollments)
+	staff.GET("/group/:id/students", h.GetGroupStudents)
+	everyone.GET("/student/:id/attendance", h.GetStudentAttendance)
+	staff.GET("/group/:id/attendance", h.GetGroupAttendance)
+
+	everyone.GET("/task", h.GetAllTask)
+	everyone.GET("/task/:id", h.GetByIDtask)
+	staff.POST("/task", idempotent, h.CreateTask)
+	staff.PUT("/task/:id", h.UpdateTask)
+	staff.PATCH("/task/:id", h.PatchTask)
+	staff.DELETE("/task/:id", h.DeleteTask)
+	staff.POST("/task/:id/restore", h.RestoreTask)
+	students.POST("/task/:id/submission", h.SubmitTask)
+	everyone.GET("/task/:id/submissions", h.GetTaskSubmissions)
+	staff.PUT("/submission/:id/grade", h.GradeSubmission)
+
+	staff.GET("/group/:id/gradebook", h.GetGroupGradebook)
+	everyone.GET("/student/:id/progress", h.GetStudentProgress)
+
+	everyone.GET("/teacher", h.GetAllTeacher)
+	everyone.GET("/teacher/:id", h.GetByIDTeacher)
+	admin.POST("/teacher", idempotent, h.CreateTeacher)
+	admin.PUT("/teacher/:id", h.UpdateTeacher)
+	admin.PATCH("/teacher/:id", h.PatchTeacher)
+	staff.PUT("/teacher/:id/password", h.ChangeTeacherPassword)
+	admin.DELETE("/teacher/:id", h.DeleteTeacher)
+	admin.POST("/teacher/:id/restore", h.RestoreTeacher)
 
 	return r
 }
-
-func authMiddleware(c *gin.Context) {
-	auth := c.GetHeader("Authorization")
-	if auth == "" {
-		c.AbortWithError(http.StatusUnauthorized, errors.New("unauthorized"))
-	}
-	c.Next()
-}
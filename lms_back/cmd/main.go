@@ -5,14 +5,42 @@ import (
 	"fmt"
 	"lms_back/api"
 	"lms_back/config"
+	"lms_back/pkg/jwt"
 	"lms_back/pkg/logger"
+	"lms_back/pkg/notify"
 	"lms_back/service"
 	"lms_back/storage/postgres"
+	"os"
 )
 
 func main() {
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Println("error while loading config, err: ", err)
+		os.Exit(1)
+	}
 	log := logger.New(cfg.ServiceName)
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			fmt.Println("error while migrating, err: ", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.MigrateOnStart {
+		if err := migrateUp(context.Background(), cfg); err != nil {
+			fmt.Println("error while migrating, err: ", err)
+			return
+		}
+	}
+
+	if err := jwt.Init(cfg); err != nil {
+		fmt.Println("error while loading jwt keys, err: ", err)
+		return
+	}
+
 	store, err := postgres.New(context.Background(), cfg)
 	if err != nil {
 		fmt.Println("error while connecting db, err: ", err)
@@ -20,7 +48,16 @@ func main() {
 	}
 	defer store.CloseDB()
 
-	services := service.New(store, log)
+	var notifier notify.Notifier
+	switch cfg.Notifier {
+	case "file":
+		notifier = notify.NewFileNotifier(cfg.NotifierFile)
+	default:
+		notifier = notify.NewLogNotifier(log)
+	}
+
+	services := service.New(cfg, store, notifier, log)
+	go services.Trash().RunPurge(context.Background())
 	server := api.New(services, log)
 
 
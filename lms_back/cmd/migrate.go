@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lms_back/config"
+	"lms_back/migrations"
+	"lms_back/pkg/migrate"
+	"lms_back/storage/postgres"
+	"strconv"
+)
+
+// migrationsSourceDir is where migrate create writes new migrations, relative
+// to the module root. They are embedded into the binary on the next build.
+const migrationsSourceDir = "migrations/postgres"
+
+const migrateUsage = "usage: lms_back migrate up [n] | down [n] | status | create <name>"
+
+// runMigrate runs the migrate subcommand with its arguments.
+func runMigrate(cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	command, args := args[0], args[1:]
+
+	if command == "create" {
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+		files, err := migrate.Create(migrationsSourceDir, args[0])
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			fmt.Println("created", file)
+		}
+		return nil
+	}
+
+	ctx := context.Background()
+	migrator, closeDB, err := newMigrator(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	switch command {
+	case "up":
+		n, err := parseMigrateCount(args, 0)
+		if err != nil {
+			return err
+		}
+		applied, err := migrator.Up(ctx, n)
+		for _, migration := range applied {
+			fmt.Printf("applied %02d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("no pending migrations")
+		}
+		return err
+
+	case "down":
+		n, err := parseMigrateCount(args, 1)
+		if err != nil {
+			return err
+		}
+		reverted, err := migrator.Down(ctx, n)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %02d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range status {
+			appliedAt := "pending"
+			if migration.AppliedAt != nil {
+				appliedAt = migration.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%02d_%-30s %s\n", migration.Version, migration.Name, appliedAt)
+		}
+		return nil
+	}
+
+	return errors.New(migrateUsage)
+}
+
+// migrateUp applies the pending migrations, it is run on startup with MIGRATE_ON_START.
+func migrateUp(ctx context.Context, cfg config.Config) error {
+	migrator, closeDB, err := newMigrator(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+
+	applied, err := migrator.Up(ctx, 0)
+	for _, migration := range applied {
+		fmt.Printf("applied migration %02d_%s\n", migration.Version, migration.Name)
+	}
+	return err
+}
+
+func newMigrator(ctx context.Context, cfg config.Config) (*migrate.Migrator, func(), error) {
+	pool, err := postgres.Connect(ctx, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	migrator, err := migrate.New(pool, migrations.FS, migrations.Dir)
+	if err != nil {
+		pool.Close()
+		return nil, nil, err
+	}
+
+	return migrator, pool.Close, nil
+}
+
+// parseMigrateCount returns the number of migrations to apply or revert.
+func parseMigrateCount(args []string, defaultCount int) (int, error) {
+	if len(args) == 0 {
+		return defaultCount, nil
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("%q is not a number of migrations", args[0])
+	}
+	return n, nil
+}
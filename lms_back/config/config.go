@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cast"
@@ -14,11 +16,56 @@ type Config struct {
 	PostgresPassword string
 	PostgresUser     string
 	PostgresDatabase string
-	
+
 	ServiceName string
+
+	// JWTAlgorithm is the signing algorithm of issued tokens: HS256, RS256 or EdDSA.
+	JWTAlgorithm string
+	// JWTActiveKeyID is the kid new tokens are signed with.
+	JWTActiveKeyID string
+	// JWTSecrets holds HMAC secrets by kid, every one of them is accepted for verification.
+	JWTSecrets map[string]string
+	// JWTPrivateKeyFile is the PEM file of the active RS256/EdDSA private key.
+	JWTPrivateKeyFile string
+	// JWTPublicKeyFiles holds PEM public key files by kid, used to verify RS256/EdDSA tokens.
+	JWTPublicKeyFiles map[string]string
+
+	// Notifier selects how messages to users are delivered: log or file.
+	Notifier     string
+	NotifierFile string
+
+	PasswordResetCodeTTL     time.Duration
+	PasswordResetMaxAttempts int
+
+	// LoginMaxFailures is the number of failed logins after which the login is locked for LoginLockoutDuration.
+	LoginMaxFailures int
+	// LoginIPMaxFailures is the same limit for all logins from one ip address.
+	LoginIPMaxFailures   int
+	LoginLockoutDuration time.Duration
+	// LoginDelayStep is the delay after the first failure, it doubles with every next one up to LoginMaxDelay.
+	LoginDelayStep time.Duration
+	LoginMaxDelay  time.Duration
+
+	// TOTPIssuer is the account issuer shown in authenticator apps.
+	TOTPIssuer string
+
+	// IdempotencyKeyTTL is how long responses are replayed for a reused Idempotency-Key.
+	IdempotencyKeyTTL time.Duration
+	// IdempotencyKeyLease is how long a key is held for a request that is still
+	// running, a retry after it takes the key over from a request that died.
+	IdempotencyKeyLease time.Duration
+
+	// TrashRetention is how long soft deleted entities are kept before they are purged.
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	// MigrateOnStart applies the pending migrations before the server starts.
+	MigrateOnStart bool
 }
 
-func Load() Config {
+// Load reads the config from the environment and the .env file. It fails if a
+// file the config points to can't be read.
+func Load() (Config, error) {
 
 	if err := godotenv.Load(); err != nil {
 		fmt.Println("error!!!", err)
@@ -32,7 +79,46 @@ func Load() Config {
 	cfg.PostgresPassword = cast.ToString(getOrReturnDefault("POSTGRES_PASSWORD", "1"))
 	cfg.ServiceName = cast.ToString(getOrReturnDefault("SERVICE_NAME", "rent_car_api_gateway"))
 
-	return cfg
+	cfg.JWTAlgorithm = cast.ToString(getOrReturnDefault("JWT_ALGORITHM", "HS256"))
+	cfg.JWTActiveKeyID = cast.ToString(getOrReturnDefault("JWT_ACTIVE_KEY_ID", ""))
+	cfg.JWTSecrets = parseKeyValues(cast.ToString(getOrReturnDefault("JWT_SECRETS", "")), ",")
+	cfg.JWTPrivateKeyFile = cast.ToString(getOrReturnDefault("JWT_PRIVATE_KEY_FILE", ""))
+	cfg.JWTPublicKeyFiles = parseKeyValues(cast.ToString(getOrReturnDefault("JWT_PUBLIC_KEY_FILES", "")), ",")
+
+	cfg.Notifier = cast.ToString(getOrReturnDefault("NOTIFIER", "log"))
+	cfg.NotifierFile = cast.ToString(getOrReturnDefault("NOTIFIER_FILE", "notifications.log"))
+
+	cfg.PasswordResetCodeTTL = cast.ToDuration(getOrReturnDefault("PASSWORD_RESET_CODE_TTL", "15m"))
+	cfg.PasswordResetMaxAttempts = cast.ToInt(getOrReturnDefault("PASSWORD_RESET_MAX_ATTEMPTS", 5))
+
+	cfg.LoginMaxFailures = cast.ToInt(getOrReturnDefault("LOGIN_MAX_FAILURES", 5))
+	cfg.LoginIPMaxFailures = cast.ToInt(getOrReturnDefault("LOGIN_IP_MAX_FAILURES", 20))
+	cfg.LoginLockoutDuration = cast.ToDuration(getOrReturnDefault("LOGIN_LOCKOUT_DURATION", "15m"))
+	cfg.LoginDelayStep = cast.ToDuration(getOrReturnDefault("LOGIN_DELAY_STEP", "1s"))
+	cfg.LoginMaxDelay = cast.ToDuration(getOrReturnDefault("LOGIN_MAX_DELAY", "30s"))
+
+	cfg.TOTPIssuer = cast.ToString(getOrReturnDefault("TOTP_ISSUER", "LMS"))
+
+	cfg.IdempotencyKeyTTL = cast.ToDuration(getOrReturnDefault("IDEMPOTENCY_KEY_TTL", "24h"))
+	cfg.IdempotencyKeyLease = cast.ToDuration(getOrReturnDefault("IDEMPOTENCY_KEY_LEASE", "1m"))
+
+	cfg.TrashRetention = cast.ToDuration(getOrReturnDefault("TRASH_RETENTION", "720h"))
+	cfg.TrashPurgeInterval = cast.ToDuration(getOrReturnDefault("TRASH_PURGE_INTERVAL", "1h"))
+
+	cfg.MigrateOnStart = cast.ToBool(getOrReturnDefault("MIGRATE_ON_START", false))
+
+	// JWT_SECRETS_FILE keeps one "kid:secret" pair per line, so secrets stay out of the environment
+	if secretsFile := cast.ToString(getOrReturnDefault("JWT_SECRETS_FILE", "")); secretsFile != "" {
+		content, err := os.ReadFile(secretsFile)
+		if err != nil {
+			return Config{}, fmt.Errorf("reading jwt secrets file: %w", err)
+		}
+		for kid, secret := range parseKeyValues(string(content), "\n") {
+			cfg.JWTSecrets[kid] = secret
+		}
+	}
+
+	return cfg, nil
 }
 
 func getOrReturnDefault(key string, defaultValue interface{}) interface{} {
@@ -42,3 +128,23 @@ func getOrReturnDefault(key string, defaultValue interface{}) interface{} {
 	}
 	return os.Getenv(key)
 }
+
+// parseKeyValues parses "kid:value" pairs separated by sep.
+func parseKeyValues(s, sep string) map[string]string {
+	values := make(map[string]string)
+
+	for _, pair := range strings.Split(s, sep) {
+		pair = strings.TrimSpace(pair)
+		if pair == "" || strings.HasPrefix(pair, "#") {
+			continue
+		}
+
+		kid, value, found := strings.Cut(pair, ":")
+		if !found {
+			continue
+		}
+		values[strings.TrimSpace(kid)] = strings.TrimSpace(value)
+	}
+
+	return values
+}
@@ -8,9 +8,16 @@ const (
 	ERR_REDIRECTION     = "You have been redirected and the completion of the request requires further action"
 	ERR_BADREQUEST      = "Bad request"
 	ERR_INTERNAL_SERVER = "While the request appears to be valid, the server could not complete the request"
+	ERR_UNAUTHORIZED    = "Authentication is required to access this resource"
+	ERR_FORBIDDEN       = "You do not have permission to access this resource"
+	ERR_NOT_FOUND       = "The requested resource was not found"
+	ERR_CONFLICT        = "The request conflicts with the current state of the resource"
+	ERR_VALIDATION      = "The request has invalid fields"
+	SUPERADMIN_ROLE     = "superadmin"
 	ADMIN_ROLE          = "admin"
+	TEACHER_ROLE        = "teacher"
 	STUDENT_ROLE        = "student"
+	AUTH_INFO_KEY       = "auth_info"
 )
-var SignedKey = []byte("MGJd@Ro]yKoCc)mVY1^c:upz~4rn9Pt!hYd]>c8dt#+%")
 
 const  Timeout = time.Second * 2
\ No newline at end of file
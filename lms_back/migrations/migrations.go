@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL migrations into the binary.
+package migrations
+
+import "embed"
+
+// Dir is the directory of the postgres migrations in FS.
+const Dir = "postgres"
+
+//go:embed postgres/*.sql
+var FS embed.FS
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS "tasks";
+DROP TABLE IF EXISTS "lesson";
+DROP TABLE IF EXISTS "schedule";
+DROP TABLE IF EXISTS "payment";
+DROP TABLE IF EXISTS "student";
+DROP TABLE IF EXISTS "group";
+DROP TABLE IF EXISTS "admin";
+DROP TABLE IF EXISTS "teacher";
+DROP TABLE IF EXISTS "branches";
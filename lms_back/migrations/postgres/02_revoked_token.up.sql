@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS "revoked_token" (
+  "jti" uuid PRIMARY KEY,
+  "user_id" uuid NOT NULL,
+  "expires_at" timestamp NOT NULL,
+  "revoked_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "revoked_token_expires_at_idx" ON "revoked_token"("expires_at");
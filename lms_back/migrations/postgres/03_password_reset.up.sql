@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS "password_reset" (
+  "id" uuid PRIMARY KEY,
+  "user_id" uuid NOT NULL,
+  "user_role" varchar(60) NOT NULL CHECK ("user_role" IN ('admin', 'teacher', 'student')),
+  "code_hash" varchar(255) NOT NULL,
+  "attempts" int NOT NULL DEFAULT 0,
+  "expires_at" timestamp NOT NULL,
+  "used_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "password_reset_user_idx" ON "password_reset"("user_id", "user_role");
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS "login_attempt" (
+  "id" uuid PRIMARY KEY,
+  "login" varchar(255) NOT NULL,
+  "user_role" varchar(60) NOT NULL,
+  "user_id" uuid,
+  "branch_id" uuid REFERENCES "branches"("id"),
+  "ip" varchar(64) NOT NULL,
+  "success" boolean NOT NULL,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "login_attempt_created_at_idx" ON "login_attempt"("created_at");
+CREATE INDEX IF NOT EXISTS "login_attempt_branch_idx" ON "login_attempt"("branch_id", "created_at");
+
+-- key is "login:<role>:<login>" or "ip:<ip>"
+CREATE TABLE IF NOT EXISTS "login_throttle" (
+  "key" varchar(320) PRIMARY KEY,
+  "failures" int NOT NULL DEFAULT 0,
+  "locked_until" timestamp,
+  "updated_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
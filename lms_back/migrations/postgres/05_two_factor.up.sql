@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS "two_factor" (
+  "user_id" uuid NOT NULL,
+  "user_role" varchar(60) NOT NULL CHECK ("user_role" IN ('admin', 'teacher')),
+  "secret" varchar(255) NOT NULL,
+  "enabled" boolean NOT NULL DEFAULT false,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "enabled_at" timestamp,
+  PRIMARY KEY ("user_id", "user_role")
+);
+
+CREATE TABLE IF NOT EXISTS "recovery_code" (
+  "id" uuid PRIMARY KEY,
+  "user_id" uuid NOT NULL,
+  "user_role" varchar(60) NOT NULL,
+  "code_hash" varchar(255) NOT NULL,
+  "used_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "recovery_code_user_idx" ON "recovery_code"("user_id", "user_role");
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "admin_branch";
+
+ALTER TABLE "admin" DROP COLUMN IF EXISTS "role";
@@ -0,0 +1,11 @@
+ALTER TABLE "admin" ADD COLUMN IF NOT EXISTS "role" varchar(60) NOT NULL DEFAULT 'admin' CHECK ("role" IN ('admin', 'superadmin'));
+
+-- existing admins keep access to every branch until they are assigned to their branches
+UPDATE "admin" SET "role" = 'superadmin';
+
+CREATE TABLE IF NOT EXISTS "admin_branch" (
+  "admin_id" uuid NOT NULL REFERENCES "admin"("id") ON DELETE CASCADE,
+  "branch_id" uuid NOT NULL REFERENCES "branches"("id") ON DELETE CASCADE,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("admin_id", "branch_id")
+);
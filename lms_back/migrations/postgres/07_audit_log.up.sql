@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS "audit_log" (
+  "id" uuid PRIMARY KEY,
+  "actor_id" uuid,
+  "actor_role" varchar(60) NOT NULL DEFAULT '',
+  "entity_type" varchar(60) NOT NULL,
+  "entity_id" varchar(255) NOT NULL,
+  "branch_id" uuid,
+  "action" varchar(60) NOT NULL,
+  "before" jsonb,
+  "after" jsonb,
+  "changes" jsonb,
+  "ip" varchar(64) NOT NULL DEFAULT '',
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS "audit_log_entity_idx" ON "audit_log"("entity_type", "entity_id");
+CREATE INDEX IF NOT EXISTS "audit_log_actor_idx" ON "audit_log"("actor_id");
+CREATE INDEX IF NOT EXISTS "audit_log_created_at_idx" ON "audit_log"("created_at");
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_no_update_delete BEFORE UPDATE OR DELETE ON "audit_log"
+  FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON "audit_log"
+  FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,15 @@
+-- status_code is NULL while the first request with the key is still running
+CREATE TABLE IF NOT EXISTS "idempotency_key" (
+  "key" varchar(255) NOT NULL,
+  "user_id" varchar(64) NOT NULL,
+  "method" varchar(10) NOT NULL,
+  "path" varchar(255) NOT NULL,
+  "request_hash" varchar(64) NOT NULL,
+  "status_code" int,
+  "content_type" varchar(255) NOT NULL DEFAULT '',
+  "response" bytea,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("user_id", "key")
+);
+
+CREATE INDEX IF NOT EXISTS "idempotency_key_created_at_idx" ON "idempotency_key"("created_at");
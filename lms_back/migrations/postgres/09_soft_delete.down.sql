@@ -0,0 +1,9 @@
+ALTER TABLE "teacher" ALTER COLUMN "deleted_at" DROP NOT NULL;
+
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "lesson" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "schedule" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "payment" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "student" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "group" DROP COLUMN IF EXISTS "deleted_at";
+ALTER TABLE "admin" DROP COLUMN IF EXISTS "deleted_at";
@@ -0,0 +1,11 @@
+-- deleted_at is the unix time of the deletion, 0 for rows that are not deleted
+ALTER TABLE "admin" ADD COLUMN IF NOT EXISTS "deleted_at" integer NOT NULL DEFAULT 0;
+ALTER TABLE "group" ADD COLUMN IF NOT EXISTS "deleted_at" integer NOT NULL DEFAULT 0;
+ALTER TABLE "student" ADD COLUMN IF NOT EXISTS "deleted_at" integer NOT NULL DEFAULT 0;
+ALTER TABLE "payment" ADD COLUMN IF NOT EXISTS "deleted_at" integer NOT NULL DEFAULT 0;
+ALTER TABLE "schedule" ADD COLUMN IF NOT EXISTS "deleted_at" integer NOT NULL DEFAULT 0;
+ALTER TABLE "lesson" ADD COLUMN IF NOT EXISTS "deleted_at" integer NOT NULL DEFAULT 0;
+ALTER TABLE "tasks" ADD COLUMN IF NOT EXISTS "deleted_at" integer NOT NULL DEFAULT 0;
+
+UPDATE "teacher" SET "deleted_at" = 0 WHERE "deleted_at" IS NULL;
+ALTER TABLE "teacher" ALTER COLUMN "deleted_at" SET NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE "task" RENAME TO "tasks";
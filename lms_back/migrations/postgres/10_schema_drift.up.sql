@@ -0,0 +1,20 @@
+-- 01 creates the group id without a primary key
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conrelid = '"group"'::regclass AND contype = 'p') THEN
+    ALTER TABLE "group" ALTER COLUMN "id" SET NOT NULL;
+    ALTER TABLE "group" ADD PRIMARY KEY ("id");
+  END IF;
+END;
+$$;
+
+-- the code has always queried "task", some databases have it created by hand
+DO $$
+BEGIN
+  IF to_regclass('"task"') IS NULL THEN
+    ALTER TABLE "tasks" RENAME TO "task";
+  END IF;
+END;
+$$;
+
+ALTER TABLE "task" ADD COLUMN IF NOT EXISTS "deleted_at" integer NOT NULL DEFAULT 0;
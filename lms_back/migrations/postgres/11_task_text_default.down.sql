@@ -0,0 +1 @@
+ALTER TABLE "task" ALTER COLUMN "task" DROP DEFAULT;
@@ -0,0 +1,2 @@
+-- the task text isn't written by the api yet, without a default no task can be created
+ALTER TABLE "task" ALTER COLUMN "task" SET DEFAULT '';
@@ -0,0 +1,9 @@
+ALTER TABLE "task" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "lesson" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "schedule" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "payment" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "student" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "group" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "teacher" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "branches" DROP COLUMN IF EXISTS "version";
+ALTER TABLE "admin" DROP COLUMN IF EXISTS "version";
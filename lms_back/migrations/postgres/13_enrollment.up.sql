@@ -0,0 +1,17 @@
+-- a student can be in several groups, student.group_id stays the primary one
+CREATE TABLE IF NOT EXISTS "enrollment" (
+  "id" uuid PRIMARY KEY,
+  "student_id" uuid NOT NULL REFERENCES "student"("id") ON DELETE CASCADE,
+  "group_id" uuid NOT NULL REFERENCES "group"("id"),
+  "enrolled_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "left_at" timestamp,
+  "status" varchar(60) NOT NULL DEFAULT 'active' CHECK ("status" IN ('active', 'transferred', 'withdrawn')),
+  "reason" varchar(255) NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "enrollment_active_idx" ON "enrollment"("student_id", "group_id") WHERE "status" = 'active';
+CREATE INDEX IF NOT EXISTS "enrollment_group_idx" ON "enrollment"("group_id");
+
+-- the students are enrolled in their current group since they were created
+INSERT INTO "enrollment" ("id", "student_id", "group_id", "enrolled_at")
+  SELECT gen_random_uuid(), "id", "group_id", "created_at" FROM "student" WHERE "group_id" IS NOT NULL;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS "attendance" (
+  "lesson_id" uuid NOT NULL REFERENCES "lesson"("id") ON DELETE CASCADE,
+  "student_id" uuid NOT NULL REFERENCES "student"("id") ON DELETE CASCADE,
+  "status" varchar(60) NOT NULL CHECK ("status" IN ('present', 'absent', 'late', 'excused')),
+  "note" varchar(255) NOT NULL DEFAULT '',
+  -- the teacher or admin who marked the student last
+  "marked_by" uuid,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updated_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("lesson_id", "student_id")
+);
+
+CREATE INDEX IF NOT EXISTS "attendance_student_idx" ON "attendance"("student_id");
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "task_submission";
+ALTER TABLE "task" DROP COLUMN IF EXISTS "deadline";
@@ -0,0 +1,21 @@
+ALTER TABLE "task" ADD COLUMN IF NOT EXISTS "deadline" timestamp;
+
+-- a student hands in a task once, resubmitting replaces the answer until it is graded
+CREATE TABLE IF NOT EXISTS "task_submission" (
+  "id" uuid PRIMARY KEY,
+  "task_id" uuid NOT NULL REFERENCES "task"("id") ON DELETE CASCADE,
+  "student_id" uuid NOT NULL REFERENCES "student"("id") ON DELETE CASCADE,
+  "answer" text NOT NULL DEFAULT '',
+  "attachment" varchar(255) NOT NULL DEFAULT '',
+  "submitted_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "late" boolean NOT NULL DEFAULT false,
+  "score" integer CHECK ("score" >= 0),
+  "feedback" text NOT NULL DEFAULT '',
+  "graded_by" uuid,
+  "graded_at" timestamp,
+  "created_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updated_at" timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE ("task_id", "student_id")
+);
+
+CREATE INDEX IF NOT EXISTS "task_submission_student_idx" ON "task_submission"("student_id");
@@ -0,0 +1 @@
+ALTER TABLE "idempotency_key" ADD COLUMN IF NOT EXISTS "etag" varchar(64) NOT NULL DEFAULT '';
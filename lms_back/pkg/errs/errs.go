@@ -0,0 +1,106 @@
+// Package errs has the domain errors of the api. Storage and services return
+// them and handlers answer them with the status of their kind, their code and
+// the invalid fields.
+package errs
+
+import (
+	"errors"
+	"strings"
+)
+
+// Kind is the class of a domain error.
+type Kind string
+
+const (
+	KindNotFound     Kind = "not_found"
+	KindConflict     Kind = "conflict"
+	KindValidation   Kind = "validation"
+	KindForbidden    Kind = "forbidden"
+	KindUnauthorized Kind = "unauthorized"
+)
+
+// FieldError is an invalid field of a request, by its json name.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error. Code is its machine-readable name, like
+// "already_exists", clients tell errors of the same kind apart by it.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+	// Err is the error it was translated from, if any
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a domain error of the same kind and code, so
+// errors.Is matches the copies Wrap returns.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+// Wrap returns a copy of e that was translated from err.
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+func NotFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func Conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func Forbidden(code, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+func Unauthorized(code, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+// Validation returns an error of invalid fields with the code
+// "validation_failed". An empty message lists the fields.
+func Validation(message string, fields ...FieldError) *Error {
+	if message == "" {
+		invalid := make([]string, 0, len(fields))
+		for _, field := range fields {
+			invalid = append(invalid, field.Field+" "+field.Message)
+		}
+		message = strings.Join(invalid, ", ")
+	}
+	return &Error{Kind: KindValidation, Code: "validation_failed", Message: message, Fields: fields}
+}
+
+// As returns the domain error in the chain of err.
+func As(err error) (*Error, bool) {
+	var e *Error
+	if errors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// KindOf returns the kind of the domain error in the chain of err, or "" if
+// there is none.
+func KindOf(err error) Kind {
+	if e, ok := As(err); ok {
+		return e.Kind
+	}
+	return ""
+}
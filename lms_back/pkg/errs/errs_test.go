@@ -0,0 +1,36 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	cause := errors.New("no rows in result set")
+	notFound := NotFound("not_found", "not found")
+
+	err := fmt.Errorf("getting student: %w", notFound.Wrap(cause))
+	if !errors.Is(err, notFound) || !errors.Is(err, cause) {
+		t.Errorf("errors.Is() of the wrapped error = false, want it to match the domain error and its cause")
+	}
+	if errors.Is(err, NotFound("student_not_found", "not found")) {
+		t.Errorf("errors.Is() matched a domain error with another code")
+	}
+	if KindOf(err) != KindNotFound {
+		t.Errorf("KindOf() = %q, want %q", KindOf(err), KindNotFound)
+	}
+	if KindOf(cause) != "" {
+		t.Errorf("KindOf() of a plain error = %q, want none", KindOf(cause))
+	}
+}
+
+func TestValidation(t *testing.T) {
+	err := Validation("", FieldError{Field: "age", Message: "must be positive"}, FieldError{Field: "email", Message: "is invalid"})
+	if err.Error() != "age must be positive, email is invalid" {
+		t.Errorf("Error() = %q", err.Error())
+	}
+	if err.Code != "validation_failed" || len(err.Fields) != 2 {
+		t.Errorf("Validation() = %+v", err)
+	}
+}
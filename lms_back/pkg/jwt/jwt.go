@@ -3,8 +3,14 @@ package jwt
 import (
 	"fmt"
 	"time"
-	"lms_back/config"
+
 	"github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+)
+
+const (
+	AccessTokenType  = "access"
+	RefreshTokenType = "refresh"
 )
 
 func GenJWT(m map[interface{}]interface{}) (string, string, error) {
@@ -13,8 +19,16 @@ func GenJWT(m map[interface{}]interface{}) (string, string, error) {
 		claims                    jwt.MapClaims
 	)
 
-	accessToken = jwt.New(jwt.SigningMethodHS256)
-	refreshToken = jwt.New(jwt.SigningMethodHS256)
+	kid, key, err := getActiveKey()
+	if err != nil {
+		return "", "", err
+	}
+
+	accessToken = jwt.New(key.method)
+	refreshToken = jwt.New(key.method)
+
+	accessToken.Header["kid"] = kid
+	refreshToken.Header["kid"] = kid
 
 	claims = accessToken.Claims.(jwt.MapClaims)
 	rClaims := refreshToken.Claims.(jwt.MapClaims)
@@ -25,20 +39,27 @@ func GenJWT(m map[interface{}]interface{}) (string, string, error) {
 	}
 
 	claims["iss"] = "user"
+	claims["jti"] = uuid.NewString()
+	claims["token_type"] = AccessTokenType
 	claims["iat"] = time.Now().Unix()
 	claims["exp"] = time.Now().AddDate(0, 0, 1).Unix()
 
 	rClaims["iss"] = "user"
+	rClaims["jti"] = uuid.NewString()
+	rClaims["token_type"] = RefreshTokenType
 	rClaims["iat"] = time.Now().Unix()
 	rClaims["exp"] = time.Now().AddDate(0, 0, 10).Unix()
+	// the access token issued with the refresh token is revoked when it is rotated
+	rClaims["access_jti"] = claims["jti"]
+	rClaims["access_exp"] = claims["exp"]
 
-	accessTokenString, err := accessToken.SignedString(config.SignedKey)
+	accessTokenString, err := accessToken.SignedString(key.signKey)
 	if err != nil {
 		err = fmt.Errorf("access_token generating error: %s", err)
 		return "", "", err
 	}
 
-	refreshTokenString, err := refreshToken.SignedString(config.SignedKey)
+	refreshTokenString, err := refreshToken.SignedString(key.signKey)
 	if err != nil {
 		err = fmt.Errorf("refresh_token generating error: %s", err)
 		return "", "", err
@@ -48,22 +69,23 @@ func GenJWT(m map[interface{}]interface{}) (string, string, error) {
 }
 
 func ExtractClaims(tokenStr string) (jwt.MapClaims, error) {
-	var (
-		token *jwt.Token
-		err   error
-	)
-	token, err = jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		// check token signing method etc
-		return config.SignedKey, nil
-	})
-	if err != nil {
-		token, err = jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			// check token signing method etc
-			return config.SignedKey, nil
-		})
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+
+		key, err := getVerifyKey(kid)
 		if err != nil {
 			return nil, err
 		}
+
+		// the alg header is chosen by the client, so it must match the key it is verified with
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		return key.verifyKey, nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
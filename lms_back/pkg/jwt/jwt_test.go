@@ -0,0 +1,160 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"lms_back/config"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func genTokens(t *testing.T) (string, string) {
+	t.Helper()
+
+	accessToken, refreshToken, err := GenJWT(map[interface{}]interface{}{
+		"user_id":   "user",
+		"user_role": "admin",
+	})
+	if err != nil {
+		t.Fatalf("GenJWT() error = %v", err)
+	}
+
+	return accessToken, refreshToken
+}
+
+func TestExtractClaims_KeyRotation(t *testing.T) {
+	cfg := config.Config{
+		JWTAlgorithm:   "HS256",
+		JWTActiveKeyID: "old",
+		JWTSecrets:     map[string]string{"old": "old-secret"},
+	}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	oldToken, _ := genTokens(t)
+
+	cfg.JWTActiveKeyID = "new"
+	cfg.JWTSecrets = map[string]string{"old": "old-secret", "new": "new-secret"}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	newToken, _ := genTokens(t)
+
+	for _, token := range []string{oldToken, newToken} {
+		claims, err := ExtractClaims(token)
+		if err != nil {
+			t.Fatalf("ExtractClaims() error = %v", err)
+		}
+		if claims["user_id"] != "user" {
+			t.Errorf("ExtractClaims() user_id = %v, want user", claims["user_id"])
+		}
+	}
+
+	cfg.JWTSecrets = map[string]string{"new": "new-secret"}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if _, err := ExtractClaims(oldToken); err == nil {
+		t.Errorf("ExtractClaims() accepted a token signed with a retired key")
+	}
+}
+
+func TestExtractClaims_RejectsTamperedTokens(t *testing.T) {
+	cfg := config.Config{
+		JWTAlgorithm:   "HS256",
+		JWTActiveKeyID: "k1",
+		JWTSecrets:     map[string]string{"k1": "secret"},
+	}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	unsigned := jwt.New(jwt.SigningMethodNone)
+	unsigned.Header["kid"] = "k1"
+	noneToken, err := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forged := jwt.New(jwt.SigningMethodHS256)
+	forged.Header["kid"] = "k1"
+	forgedToken, err := forged.SignedString([]byte("another secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	accessToken, _ := genTokens(t)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "none algorithm", token: noneToken},
+		{name: "wrong secret", token: forgedToken},
+		{name: "modified signature", token: accessToken[:len(accessToken)-2] + "xx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ExtractClaims(tt.token); err == nil {
+				t.Errorf("ExtractClaims() accepted %s token", tt.name)
+			}
+		})
+	}
+}
+
+func TestExtractClaims_EdDSA(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateFile := filepath.Join(dir, "private.pem")
+	publicFile := filepath.Join(dir, "public.pem")
+	if err = os.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = Init(config.Config{
+		JWTAlgorithm:      "EdDSA",
+		JWTActiveKeyID:    "ed1",
+		JWTPrivateKeyFile: privateFile,
+	}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	accessToken, _ := genTokens(t)
+
+	// after rotating to ed2 the ed1 tokens are verified with its public key only
+	if err = Init(config.Config{
+		JWTAlgorithm:      "EdDSA",
+		JWTActiveKeyID:    "ed2",
+		JWTPrivateKeyFile: privateFile,
+		JWTPublicKeyFiles: map[string]string{"ed1": publicFile},
+	}); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	claims, err := ExtractClaims(accessToken)
+	if err != nil {
+		t.Fatalf("ExtractClaims() error = %v", err)
+	}
+	if claims["token_type"] != AccessTokenType {
+		t.Errorf("ExtractClaims() token_type = %v, want %v", claims["token_type"], AccessTokenType)
+	}
+}
@@ -0,0 +1,202 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"lms_back/config"
+	"os"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+type signingKey struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+var (
+	keysMu      sync.RWMutex
+	keys        = map[string]signingKey{}
+	activeKeyID string
+)
+
+// Init loads the signing and verification keys from the config. It has to be
+// called once on startup, before any token is issued or verified.
+func Init(cfg config.Config) error {
+	loaded := map[string]signingKey{}
+
+	switch cfg.JWTAlgorithm {
+	case "", jwt.SigningMethodHS256.Alg():
+		for kid, secret := range cfg.JWTSecrets {
+			if secret == "" {
+				return fmt.Errorf("jwt secret %q is empty", kid)
+			}
+			loaded[kid] = signingKey{
+				method:    jwt.SigningMethodHS256,
+				signKey:   []byte(secret),
+				verifyKey: []byte(secret),
+			}
+		}
+
+	case jwt.SigningMethodRS256.Alg(), SigningMethodEdDSA.Alg():
+		method := jwt.GetSigningMethod(cfg.JWTAlgorithm)
+
+		for kid, file := range cfg.JWTPublicKeyFiles {
+			publicKey, err := readPublicKey(file)
+			if err != nil {
+				return fmt.Errorf("error while reading public key %q: %w", kid, err)
+			}
+			if !keyMatchesMethod(method, publicKey) {
+				return fmt.Errorf("public key %q can not be used with %s", kid, method.Alg())
+			}
+			loaded[kid] = signingKey{
+				method:    method,
+				verifyKey: publicKey,
+			}
+		}
+
+		if cfg.JWTPrivateKeyFile != "" {
+			privateKey, publicKey, err := readPrivateKey(cfg.JWTPrivateKeyFile)
+			if err != nil {
+				return fmt.Errorf("error while reading private key: %w", err)
+			}
+			if !keyMatchesMethod(method, publicKey) {
+				return fmt.Errorf("private key can not be used with %s", method.Alg())
+			}
+			loaded[cfg.JWTActiveKeyID] = signingKey{
+				method:    method,
+				signKey:   privateKey,
+				verifyKey: publicKey,
+			}
+		}
+
+	default:
+		return fmt.Errorf("unsupported jwt algorithm %q", cfg.JWTAlgorithm)
+	}
+
+	active, ok := loaded[cfg.JWTActiveKeyID]
+	if !ok {
+		return fmt.Errorf("active jwt key %q is not configured", cfg.JWTActiveKeyID)
+	}
+	if active.signKey == nil {
+		return fmt.Errorf("active jwt key %q has no private key", cfg.JWTActiveKeyID)
+	}
+
+	keysMu.Lock()
+	defer keysMu.Unlock()
+
+	keys = loaded
+	activeKeyID = cfg.JWTActiveKeyID
+
+	return nil
+}
+
+func getActiveKey() (string, signingKey, error) {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+
+	key, ok := keys[activeKeyID]
+	if !ok {
+		return "", signingKey{}, errors.New("jwt keys are not initialized")
+	}
+
+	return activeKeyID, key, nil
+}
+
+func getVerifyKey(kid string) (signingKey, error) {
+	keysMu.RLock()
+	defer keysMu.RUnlock()
+
+	if kid == "" {
+		kid = activeKeyID
+	}
+
+	key, ok := keys[kid]
+	if !ok {
+		return signingKey{}, fmt.Errorf("unknown jwt key id %q", kid)
+	}
+
+	return key, nil
+}
+
+func keyMatchesMethod(method jwt.SigningMethod, publicKey interface{}) bool {
+	switch publicKey.(type) {
+	case *rsa.PublicKey:
+		return method.Alg() == jwt.SigningMethodRS256.Alg()
+	case ed25519.PublicKey:
+		return method.Alg() == SigningMethodEdDSA.Alg()
+	}
+	return false
+}
+
+func readPrivateKey(file string) (interface{}, interface{}, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, &k.PublicKey, nil
+	case ed25519.PrivateKey:
+		return k, k.Public(), nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported private key type %T", key)
+}
+
+func readPublicKey(file string) (interface{}, error) {
+	block, err := readPEM(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unsupported public key type %T", key)
+}
+
+func readPEM(file string) (*pem.Block, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", file)
+	}
+
+	return block, nil
+}
@@ -0,0 +1,70 @@
+package migrate
+
+import (
+	"lms_back/migrations"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"sql/02_second.up.sql":  {Data: []byte("CREATE TABLE b ();")},
+		"sql/01_first.up.sql":   {Data: []byte("CREATE TABLE a ();")},
+		"sql/01_first.down.sql": {Data: []byte("DROP TABLE a;")},
+		"sql/README.md":         {Data: []byte("not a migration")},
+	}
+
+	got, err := Load(fsys, "sql")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(got) != 2 || got[0].Version != 1 || got[1].Version != 2 {
+		t.Fatalf("Load() = %+v, want versions 1 and 2", got)
+	}
+	if got[0].Name != "first" || got[0].Down != "DROP TABLE a;" || got[1].Down != "" {
+		t.Errorf("Load() = %+v", got)
+	}
+
+	fsys["sql/03_third.down.sql"] = &fstest.MapFile{Data: []byte("DROP TABLE c;")}
+	if _, err = Load(fsys, "sql"); err == nil {
+		t.Errorf("Load() accepted a migration without an up file")
+	}
+}
+
+func TestLoad_Embedded(t *testing.T) {
+	got, err := Load(migrations.FS, migrations.Dir)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	for i, migration := range got {
+		if migration.Version != i+1 {
+			t.Errorf("migration %s has version %d, want %d", migration.Name, migration.Version, i+1)
+		}
+		if migration.Down == "" {
+			t.Errorf("migration %02d_%s has no down file", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "09_existing.up.sql"), []byte("SELECT 1;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Create(dir, "add_column")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	want := []string{filepath.Join(dir, "10_add_column.up.sql"), filepath.Join(dir, "10_add_column.down.sql")}
+	if len(files) != 2 || files[0] != want[0] || files[1] != want[1] {
+		t.Errorf("Create() = %v, want %v", files, want)
+	}
+
+	if _, err = Create(dir, "bad name"); err == nil {
+		t.Errorf("Create() accepted a name with a space")
+	}
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"lms_back/pkg/logger"
+	"os"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notifier delivers messages to users. Mail or SMS gateways can be plugged in
+// by implementing it, the log and file notifiers work offline.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+type logNotifier struct {
+	log logger.ILogger
+}
+
+func NewLogNotifier(log logger.ILogger) Notifier {
+	return logNotifier{
+		log: log,
+	}
+}
+
+func (n logNotifier) Send(ctx context.Context, msg Message) error {
+	n.log.Info("NOTIFICATION", logger.String("to", msg.To), logger.String("subject", msg.Subject), logger.String("body", msg.Body))
+	return nil
+}
+
+type fileNotifier struct {
+	mu   *sync.Mutex
+	path string
+}
+
+// NewFileNotifier appends every message as a JSON line to the file at path.
+func NewFileNotifier(path string) Notifier {
+	return fileNotifier{
+		mu:   &sync.Mutex{},
+		path: path,
+	}
+}
+
+func (n fileNotifier) Send(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(struct {
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{
+		Message: msg,
+		SentAt:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	file, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
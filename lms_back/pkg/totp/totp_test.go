@@ -0,0 +1,67 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the RFC 6238 test vectors.
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+		{unix: 20000000000, want: "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	code, err := Code(secret, Step(now.Add(-Period)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step, ok := Validate(secret, code, now, 1)
+	if !ok || step != Step(now)-1 {
+		t.Errorf("Validate() = %d, %v, want %d, true", step, ok, Step(now)-1)
+	}
+
+	if _, ok = Validate(secret, code, now.Add(2*Period), 1); ok {
+		t.Errorf("Validate() accepted a code outside of the skew window")
+	}
+	if _, ok = Validate(secret, "12345", now, 1); ok {
+		t.Errorf("Validate() accepted a short code")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("LMS", "admin", "SECRET")
+	if !strings.HasPrefix(uri, "otpauth://totp/LMS:admin?") || !strings.Contains(uri, "secret=SECRET") {
+		t.Errorf("URI() = %s", uri)
+	}
+}
@@ -3,51 +3,106 @@ package service
 import (
 	"context"
 	"lms_back/api/models"
+	"lms_back/config"
 	"lms_back/pkg/logger"
+	"lms_back/pkg/password"
 	"lms_back/storage"
 )
 
 type adminService struct {
 	storage storage.IStorage
+	audit   auditService
 	logger  logger.ILogger
 }
 
-func NewAdminService(storage storage.IStorage, logger logger.ILogger) adminService {
+func NewAdminService(storage storage.IStorage, audit auditService, logger logger.ILogger) adminService {
 	return adminService{
 		storage: storage,
+		audit:   audit,
 		logger:  logger,
 	}
 }
 
-func (u adminService) Create(ctx context.Context, admin models.Admin) (models.Admin, error) {
+func (u adminService) Create(ctx context.Context, admin models.Admin) (models.GetAdmin, error) {
 
-	pKey, err := u.storage.Admin().Create(ctx, admin)
+	if admin.Role == "" {
+		admin.Role = config.ADMIN_ROLE
+	}
+
+	var pKey models.GetAdmin
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Admin().Create(ctx, admin); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityAdmin, EntityID: pKey.Id, Action: AuditActionCreate, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("failed to create admin", logger.Error(err))
-		return models.Admin{}, err
+		return models.GetAdmin{}, err
 	}
 
 	return pKey, nil
 }
 
-func (u adminService) Update(ctx context.Context, admin models.Admin) (models.Admin, error) {
+func (u adminService) Update(ctx context.Context, admin models.Admin) (models.GetAdmin, error) {
+
+	before, err := u.storage.Admin().GetByID(ctx, admin.Id)
+	if err != nil {
+		u.logger.Error("failed to get admin by ID", logger.Error(err))
+		return models.GetAdmin{}, err
+	}
+
+	if admin.Role == "" {
+		admin.Role = before.Role
+	}
 
-	pKey, err := u.storage.Admin().Update(ctx, admin)
+	var pKey models.GetAdmin
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Admin().Update(ctx, admin); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityAdmin, EntityID: admin.Id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("failed to update admin", logger.Error(err))
+		return models.GetAdmin{}, err
+	}
+
+	return pKey, nil
+}
+
+func (u adminService) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.GetAdmin, error) {
+
+	before, err := u.storage.Admin().GetByID(ctx, id)
+	if err != nil {
+		u.logger.Error("failed to get admin by ID", logger.Error(err))
+		return models.GetAdmin{}, err
+	}
 
-		return models.Admin{}, err
+	var pKey models.GetAdmin
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Admin().Patch(ctx, id, version, patch); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityAdmin, EntityID: id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
+	if err != nil {
+		u.logger.Error("failed to patch admin", logger.Error(err))
+		return models.GetAdmin{}, err
 	}
 
 	return pKey, nil
 }
 
-func (u adminService) GetByID(ctx context.Context, id string) (models.Admin, error) {
+func (u adminService) GetByID(ctx context.Context, id string) (models.GetAdmin, error) {
 
 	pKey, err := u.storage.Admin().GetByID(ctx, id)
 	if err != nil {
 		u.logger.Error("failed to get admin by ID", logger.Error(err))
-		return models.Admin{}, err
+		return models.GetAdmin{}, err
 	}
 
 	return pKey, nil
@@ -55,6 +110,10 @@ func (u adminService) GetByID(ctx context.Context, id string) (models.Admin, err
 
 func (u adminService) GetAll(ctx context.Context, req models.GetAllAdminsRequest) (models.GetAllAdminsResponse, error) {
 
+	if err := checkTrashAccess(ctx, req.Deleted); err != nil {
+		return models.GetAllAdminsResponse{}, err
+	}
+
 	pKey, err := u.storage.Admin().GetAll(ctx, req)
 	if err != nil {
 		u.logger.Error("failed to get all cars", logger.Error(err))
@@ -64,9 +123,20 @@ func (u adminService) GetAll(ctx context.Context, req models.GetAllAdminsRequest
 	return pKey, nil
 }
 
-func (u adminService) Delete(ctx context.Context, id string) error {
+func (u adminService) Delete(ctx context.Context, id string, version int) error {
 
-	err := u.storage.Admin().Delete(ctx, id)
+	before, err := u.storage.Admin().GetByID(ctx, id)
+	if err != nil {
+		u.logger.Error("failed to get admin by ID", logger.Error(err))
+		return err
+	}
+
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Admin().Delete(ctx, id, version); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityAdmin, EntityID: id, Action: AuditActionDelete, Before: before})
+	})
 	if err != nil {
 		u.logger.Error("failed to delete car", logger.Error(err))
 		return err
@@ -75,6 +145,28 @@ func (u adminService) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+func (u adminService) Restore(ctx context.Context, id string) (models.GetAdmin, error) {
+
+	var after models.GetAdmin
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Admin().Restore(ctx, id); err != nil {
+			return err
+		}
+
+		var err error
+		if after, err = tx.Admin().GetByID(ctx, id); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityAdmin, EntityID: id, Action: AuditActionRestore, After: after})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while restoring admin", logger.Error(err))
+		return models.GetAdmin{}, err
+	}
+
+	return after, nil
+}
+
 func (u adminService) GetByIdAdminReport(ctx context.Context, id models.AdminKey) ([]models.AdminPayment, error) {
 
 	pKey, err := u.storage.AdminReport().GetByIDAdminPayment(ctx, id)
@@ -85,3 +177,77 @@ func (u adminService) GetByIdAdminReport(ctx context.Context, id models.AdminKey
 
 	return pKey, nil
 }
+
+func (u adminService) ChangePassword(ctx context.Context, id string, req models.ChangePasswordRequest, checkOldPassword bool) error {
+
+	if len(req.NewPassword) < 6 {
+		return ErrWeakPassword
+	}
+
+	if checkOldPassword {
+		admin, err := u.storage.Admin().GetByID(ctx, id)
+		if err != nil {
+			u.logger.Error("failed to get admin for password change", logger.Error(err))
+			return err
+		}
+
+		credentials, err := u.storage.Admin().GetByLogin(ctx, admin.Login)
+		if err != nil {
+			u.logger.Error("failed to get admin credentials", logger.Error(err))
+			return err
+		}
+
+		if credentials.Id != id || password.CompareHashAndPassword(credentials.Password, req.OldPassword) != nil {
+			return ErrWrongOldPassword
+		}
+	}
+
+	hashedPass, err := password.HashPassword(req.NewPassword)
+	if err != nil {
+		u.logger.Error("failed to hash admin password", logger.Error(err))
+		return err
+	}
+
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Admin().UpdatePassword(ctx, id, hashedPass); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityAdmin, EntityID: id, Action: AuditActionChangePassword})
+	})
+	if err != nil {
+		u.logger.Error("failed to update admin password", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// SetBranches replaces the branches the admin manages. The change is applied
+// to the tokens of the admin on their next login or token refresh.
+func (u adminService) SetBranches(ctx context.Context, id string, branchIDs []string) (models.GetAdmin, error) {
+
+	before, err := u.storage.Admin().GetByID(ctx, id)
+	if err != nil {
+		u.logger.Error("failed to get admin by ID", logger.Error(err))
+		return models.GetAdmin{}, err
+	}
+
+	var admin models.GetAdmin
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Admin().SetBranches(ctx, id, branchIDs); err != nil {
+			return err
+		}
+
+		var err error
+		if admin, err = tx.Admin().GetByID(ctx, id); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityAdmin, EntityID: id, Action: AuditActionSetBranches, Before: before, After: admin})
+	})
+	if err != nil {
+		u.logger.Error("failed to set admin branches", logger.Error(err))
+		return models.GetAdmin{}, err
+	}
+
+	return admin, nil
+}
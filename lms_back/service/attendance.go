@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"lms_back/api/models"
+	"lms_back/pkg/errs"
+	"lms_back/pkg/logger"
+	"lms_back/storage"
+)
+
+// attendanceService marks the students of lessons and sums the marks up into
+// attendance rates.
+type attendanceService struct {
+	storage storage.IStorage
+	group   groupService
+	student studentService
+	audit   auditService
+	logger  logger.ILogger
+}
+
+func NewAttendanceService(storage storage.IStorage, group groupService, student studentService, audit auditService, logger logger.ILogger) attendanceService {
+	return attendanceService{
+		storage: storage,
+		group:   group,
+		student: student,
+		audit:   audit,
+		logger:  logger,
+	}
+}
+
+// Mark marks the students of the lesson. Teachers may only mark the lessons
+// of their own groups, and only students actively enrolled in the group of
+// the lesson can be marked.
+func (a attendanceService) Mark(ctx context.Context, lessonID string, req models.MarkAttendanceRequest) ([]models.Attendance, error) {
+
+	lesson, err := a.storage.Lesson().GetByID(ctx, lessonID)
+	if err != nil {
+		a.logger.Error("ERROR in service layer while getting lesson for attendance", logger.Error(err))
+		return nil, err
+	}
+	group, err := a.group.checkAccess(ctx, lesson.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkGroupTeacher(ctx, group); err != nil {
+		return nil, err
+	}
+	if err = a.checkStudents(ctx, group.Id, req.Students); err != nil {
+		return nil, err
+	}
+
+	authInfo, _ := AuthInfoFromContext(ctx)
+
+	var marks []models.Attendance
+	err = a.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if marks, err = tx.Attendance().Mark(ctx, lessonID, authInfo.UserID, req.Students); err != nil {
+			return err
+		}
+		return a.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityAttendance, EntityID: lessonID, BranchID: group.Branch_id, Action: AuditActionMarkAttendance, After: marks})
+	})
+	if err != nil {
+		a.logger.Error("ERROR in service layer while marking attendance", logger.Error(err))
+		return nil, err
+	}
+
+	return marks, nil
+}
+
+// checkStudents returns a validation error naming every mark of a student
+// listed twice or not actively enrolled in the group.
+func (a attendanceService) checkStudents(ctx context.Context, groupID string, marks []models.MarkAttendance) error {
+	var (
+		fields []errs.FieldError
+		seen   = map[string]bool{}
+	)
+	for i, mark := range marks {
+		field := fmt.Sprintf("students[%d].student_id", i)
+		if seen[mark.StudentID] {
+			fields = append(fields, errs.FieldError{Field: field, Message: "is listed twice"})
+			continue
+		}
+		seen[mark.StudentID] = true
+
+		active, err := enrolled(ctx, a.storage, mark.StudentID, groupID)
+		if err != nil {
+			a.logger.Error("ERROR in service layer while getting enrollment for attendance", logger.Error(err))
+			return err
+		}
+		if !active {
+			fields = append(fields, errs.FieldError{Field: field, Message: "is not enrolled in the group of the lesson"})
+		}
+	}
+
+	if len(fields) > 0 {
+		return errs.Validation("", fields...)
+	}
+	return nil
+}
+
+// GetStudentAttendance returns a page of the marks of the student and the
+// rate of all the marks matching req.
+func (a attendanceService) GetStudentAttendance(ctx context.Context, req models.GetAllAttendanceRequest) (models.StudentAttendanceResponse, error) {
+
+	if _, _, err := a.student.checkAccess(ctx, req.StudentID); err != nil {
+		return models.StudentAttendanceResponse{}, err
+	}
+
+	marks, err := a.storage.Attendance().GetAll(ctx, req)
+	if err != nil {
+		a.logger.Error("ERROR in service layer while getting attendance of student", logger.Error(err))
+		return models.StudentAttendanceResponse{}, err
+	}
+
+	rates, err := a.storage.Attendance().Rates(ctx, req)
+	if err != nil {
+		a.logger.Error("ERROR in service layer while getting attendance rate of student", logger.Error(err))
+		return models.StudentAttendanceResponse{}, err
+	}
+
+	resp := models.StudentAttendanceResponse{
+		Attendance: marks.Attendance,
+		Count:      marks.Count,
+		Rate:       models.AttendanceRate{StudentID: req.StudentID},
+	}
+	if len(rates) > 0 {
+		resp.Rate = withRate(rates[0])
+	}
+
+	return resp, nil
+}
+
+// GetGroupAttendance returns the rates of the students enrolled in the group
+// or marked in its lessons between req.From and req.To.
+func (a attendanceService) GetGroupAttendance(ctx context.Context, req models.GetAllAttendanceRequest) (models.GroupAttendanceResponse, error) {
+
+	if _, err := a.group.checkAccess(ctx, req.GroupID); err != nil {
+		return models.GroupAttendanceResponse{}, err
+	}
+
+	rates, err := a.storage.Attendance().Rates(ctx, req)
+	if err != nil {
+		a.logger.Error("ERROR in service layer while getting attendance rates of group", logger.Error(err))
+		return models.GroupAttendanceResponse{}, err
+	}
+
+	resp := models.GroupAttendanceResponse{Students: make([]models.AttendanceRate, 0, len(rates))}
+	for _, rate := range rates {
+		resp.Students = append(resp.Students, withRate(rate))
+	}
+
+	return resp, nil
+}
+
+// withRate fills in the rate of the counted marks, rounded to two decimals.
+func withRate(rate models.AttendanceRate) models.AttendanceRate {
+	rate.Rate = 0
+	if counted := rate.Present + rate.Late + rate.Absent; counted > 0 {
+		rate.Rate = round2(float64(rate.Present+rate.Late) / float64(counted))
+	}
+	return rate
+}
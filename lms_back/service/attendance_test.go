@@ -0,0 +1,64 @@
+package service
+
+import (
+	"errors"
+	"lms_back/api/models"
+	"lms_back/config"
+	"lms_back/pkg/errs"
+	"testing"
+)
+
+func TestAttendance(t *testing.T) {
+	f := newFixture(t)
+	attendance := NewAttendanceService(f.store, f.groups, f.students, f.audit, f.log)
+
+	student := f.newStudent("ali", f.group.Id)
+	outsider := f.newStudent("soli", f.otherGroup.Id)
+
+	teacher, other := f.as(f.teachers[0], config.TEACHER_ROLE), f.as(f.teachers[1], config.TEACHER_ROLE)
+	marks := models.MarkAttendanceRequest{Students: []models.MarkAttendance{{StudentID: student.ID, Status: models.AttendanceLate}}}
+
+	if _, err := attendance.Mark(other, f.lesson.Id, marks); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Mark() by the teacher of another group error = %v, want ErrForbidden", err)
+	}
+
+	invalid := models.MarkAttendanceRequest{Students: []models.MarkAttendance{
+		{StudentID: student.ID, Status: models.AttendancePresent},
+		{StudentID: outsider.ID, Status: models.AttendancePresent},
+		{StudentID: student.ID, Status: models.AttendanceAbsent},
+	}}
+	_, err := attendance.Mark(teacher, f.lesson.Id, invalid)
+	if e, ok := errs.As(err); !ok || e.Kind != errs.KindValidation || len(e.Fields) != 2 ||
+		e.Fields[0].Field != "students[1].student_id" || e.Fields[1].Field != "students[2].student_id" {
+		t.Errorf("Mark() of a student not in the group and a student listed twice error = %v", err)
+	}
+
+	marked, err := attendance.Mark(teacher, f.lesson.Id, marks)
+	f.check(err)
+	if len(marked) != 1 || marked[0].MarkedBy != f.teachers[0] {
+		t.Errorf("Mark() = %+v, want the teacher as the marker", marked)
+	}
+
+	own, err := attendance.GetStudentAttendance(f.ctx, models.GetAllAttendanceRequest{StudentID: student.ID, Page: 1, Limit: 10})
+	f.check(err)
+	if own.Count != 1 || own.Rate.Late != 1 || own.Rate.Rate != 1 {
+		t.Errorf("GetStudentAttendance() = %+v", own)
+	}
+}
+
+func TestWithRate(t *testing.T) {
+	tests := []struct {
+		rate models.AttendanceRate
+		want float64
+	}{
+		{models.AttendanceRate{}, 0},
+		{models.AttendanceRate{Excused: 3}, 0},
+		{models.AttendanceRate{Present: 1, Late: 1, Absent: 1, Excused: 5}, 0.67},
+		{models.AttendanceRate{Present: 4, Absent: 1}, 0.8},
+	}
+	for _, tt := range tests {
+		if got := withRate(tt.rate).Rate; got != tt.want {
+			t.Errorf("withRate(%+v).Rate = %v, want %v", tt.rate, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"lms_back/api/models"
+	"lms_back/pkg/logger"
+	"lms_back/storage"
+	"reflect"
+)
+
+const (
+	AuditEntityAdmin    = "admin"
+	AuditEntityBranch   = "branch"
+	AuditEntityGroup    = "group"
+	AuditEntityLesson   = "lesson"
+	AuditEntityPayment  = "payment"
+	AuditEntitySchedule = "schedule"
+	AuditEntityStudent  = "student"
+	AuditEntityTask     = "task"
+	AuditEntityTeacher  = "teacher"
+	// AuditEntityEnrollment entries are about a student joining or leaving a group.
+	AuditEntityEnrollment = "enrollment"
+	// AuditEntityAttendance entries are about the marks of a lesson, their
+	// entity id is the id of the lesson.
+	AuditEntityAttendance = "attendance"
+	AuditEntitySubmission = "submission"
+	// AuditEntityLogin entries are about a login throttle key, not a user.
+	AuditEntityLogin = "login"
+)
+
+const (
+	AuditActionCreate         = "create"
+	AuditActionUpdate         = "update"
+	AuditActionDelete         = "delete"
+	AuditActionRestore        = "restore"
+	AuditActionChangePassword = "change_password"
+	AuditActionResetPassword  = "reset_password"
+	AuditActionSetBranches    = "set_branches"
+	AuditActionUnlockLogin    = "unlock_login"
+	AuditActionEnable2FA      = "enable_2fa"
+	AuditActionDisable2FA     = "disable_2fa"
+	AuditActionEnroll         = "enroll"
+	AuditActionTransfer       = "transfer"
+	AuditActionWithdraw       = "withdraw"
+	AuditActionMarkAttendance = "mark_attendance"
+	AuditActionSubmit         = "submit"
+	AuditActionGrade          = "grade"
+)
+
+type auditService struct {
+	storage storage.IStorage
+	logger  logger.ILogger
+}
+
+func NewAuditService(storage storage.IStorage, logger logger.ILogger) auditService {
+	return auditService{
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// auditEntry is a change of an entity. Before is nil for created and After for
+// deleted entities.
+type auditEntry struct {
+	EntityType string
+	EntityID   string
+	BranchID   string
+	Action     string
+	Before     interface{}
+	After      interface{}
+}
+
+// write writes the entry with the caller and ip of the request to the audit
+// log of st. Changes write their entries with their transaction, so a change
+// is rolled back if its entry can't be written.
+func (a auditService) write(ctx context.Context, st storage.IStorage, entry auditEntry) error {
+	log := models.AuditLog{
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		BranchID:   entry.BranchID,
+		Action:     entry.Action,
+		IP:         clientIPFromContext(ctx),
+	}
+
+	if authInfo, ok := AuthInfoFromContext(ctx); ok {
+		log.ActorID = authInfo.UserID
+		log.ActorRole = authInfo.UserRole
+	}
+
+	var err error
+	if log.Before, log.Changes, err = marshalAudit(entry.Before, entry.After); err != nil {
+		return err
+	}
+	if entry.After != nil {
+		if log.After, err = json.Marshal(entry.After); err != nil {
+			return err
+		}
+	}
+
+	return st.Audit().Create(ctx, log)
+}
+
+func (a auditService) GetAll(ctx context.Context, req models.GetAllAuditLogsRequest) (models.GetAllAuditLogsResponse, error) {
+
+	req.BranchIDs = allowedBranches(ctx)
+
+	logs, err := a.storage.Audit().GetAll(ctx, req)
+	if err != nil {
+		a.logger.Error("ERROR in service layer while GetAll audit log", logger.Error(err))
+		return models.GetAllAuditLogsResponse{}, err
+	}
+
+	return logs, nil
+}
+
+// marshalAudit encodes before and the fields that differ between before and after.
+func marshalAudit(before, after interface{}) (json.RawMessage, json.RawMessage, error) {
+	if before == nil {
+		return nil, nil, nil
+	}
+
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	if after == nil {
+		return beforeJSON, nil, nil
+	}
+
+	changes, err := diffJSON(before, after)
+	if err != nil {
+		return nil, nil, err
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return beforeJSON, changesJSON, nil
+}
+
+type fieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// diffJSON compares the json fields of before and after. Fields missing on
+// one side are reported with a null value.
+func diffJSON(before, after interface{}) (map[string]fieldChange, error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]fieldChange{}
+	for key, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[key]) {
+			changes[key] = fieldChange{Before: value, After: afterFields[key]}
+		}
+	}
+	for key, value := range afterFields {
+		if _, ok := beforeFields[key]; !ok {
+			changes[key] = fieldChange{After: value}
+		}
+	}
+
+	return changes, nil
+}
+
+func jsonFields(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"encoding/json"
+	"lms_back/api/models"
+	"reflect"
+	"testing"
+)
+
+func TestDiffJSON(t *testing.T) {
+	before := models.Payment{Id: "p1", Price: 100, Student_id: "s1", Branch_id: "b1"}
+	after := models.Payment{Id: "p1", Price: 150, Student_id: "s1", Branch_id: "b2"}
+
+	changes, err := diffJSON(before, after)
+	if err != nil {
+		t.Fatalf("diffJSON() error = %v", err)
+	}
+
+	want := map[string]fieldChange{
+		"price":     {Before: float64(100), After: float64(150)},
+		"branch_id": {Before: "b1", After: "b2"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("diffJSON() = %v, want %v", changes, want)
+	}
+}
+
+func TestMarshalAudit_Created(t *testing.T) {
+	before, changes, err := marshalAudit(nil, models.Payment{Id: "p1"})
+	if err != nil {
+		t.Fatalf("marshalAudit() error = %v", err)
+	}
+	if before != nil || changes != nil {
+		t.Errorf("marshalAudit() = %s, %s, want no before and changes for created entities", before, changes)
+	}
+}
+
+func TestAuditGroupUpdate(t *testing.T) {
+	f := newFixture(t)
+
+	updated, err := f.groups.Update(f.ctx, models.Group{Id: f.group.Id, Type: "frontend", Version: f.group.Version})
+	f.check(err)
+	if updated.Branch_id != f.branch.Id || updated.Teacher_id != f.teachers[0] {
+		t.Errorf("Update() = %+v, want the branch and teacher of the group", updated)
+	}
+
+	logs, err := f.store.Audit().GetAll(f.ctx, models.GetAllAuditLogsRequest{EntityID: f.group.Id, Action: AuditActionUpdate, Page: 1, Limit: 10})
+	f.check(err)
+	if len(logs.AuditLogs) != 1 || logs.AuditLogs[0].BranchID != f.branch.Id {
+		t.Fatalf("audit logs of the update = %+v, want one in the branch of the group", logs)
+	}
+	changes := map[string]fieldChange{}
+	f.check(json.Unmarshal(logs.AuditLogs[0].Changes, &changes))
+	for _, field := range []string{"group_id", "branch_id", "teacher_id", "created_at"} {
+		if _, ok := changes[field]; ok {
+			t.Errorf("changes of the update = %+v, want %s unchanged", changes, field)
+		}
+	}
+}
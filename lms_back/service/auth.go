@@ -2,53 +2,417 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"lms_back/api/models"
+	"lms_back/config"
+	"lms_back/pkg/errs"
 	"lms_back/pkg/jwt"
 	"lms_back/pkg/logger"
+	"lms_back/pkg/notify"
 	"lms_back/pkg/password"
-	"lms_back/config"
 	"lms_back/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/spf13/cast"
 )
 
 type authService struct {
-	storage storage.IStorage
-	log     logger.ILogger
+	cfg      config.Config
+	storage  storage.IStorage
+	audit    auditService
+	notifier notify.Notifier
+	log      logger.ILogger
 }
 
-func NewAuthService(storage storage.IStorage, log logger.ILogger) authService {
+func NewAuthService(cfg config.Config, storage storage.IStorage, audit auditService, notifier notify.Notifier, log logger.ILogger) authService {
 	return authService{
-		storage: storage,
-		log:     log,
+		cfg:      cfg,
+		storage:  storage,
+		audit:    audit,
+		notifier: notifier,
+		log:      log,
+	}
+}
+
+var ErrInvalidCredentials = errs.Unauthorized("invalid_credentials", "login or password is incorrect")
+
+// LoginLockedError is returned while the login or the ip address is locked after failed attempts.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %s", e.RetryAfter)
+}
+
+// credentials is the part of a user needed to authenticate them, whatever their role is.
+type credentials struct {
+	ID       string
+	Login    string
+	Email    string
+	Password string
+	Status   string
+	// Role is the role put into tokens, it differs from the account role for superadmins
+	Role      string
+	BranchIDs []string
+}
+
+func (a authService) getCredentials(ctx context.Context, role, login string) (credentials, error) {
+	switch AccountRole(role) {
+	case config.ADMIN_ROLE:
+		admin, err := a.storage.Admin().GetByLogin(ctx, login)
+		if err != nil {
+			return credentials{}, err
+		}
+		if admin.Role == "" {
+			admin.Role = config.ADMIN_ROLE
+		}
+		return credentials{ID: admin.Id, Login: admin.Login, Email: admin.Email, Password: admin.Password, Status: admin.Status, Role: admin.Role, BranchIDs: admin.BranchIDs}, nil
+
+	case config.TEACHER_ROLE:
+		teacher, err := a.storage.Teacher().GetByLogin(ctx, login)
+		if err != nil {
+			return credentials{}, err
+		}
+		return credentials{ID: teacher.Id, Login: teacher.Login, Email: teacher.Email, Password: teacher.Password, Status: teacher.Status, Role: config.TEACHER_ROLE}, nil
+
+	case config.STUDENT_ROLE:
+		student, err := a.storage.Student().GetByLogin(ctx, login)
+		if err != nil {
+			return credentials{}, err
+		}
+		return credentials{ID: student.ID, Login: student.Login, Email: student.Email, Password: student.Password, Status: student.Status, Role: config.STUDENT_ROLE}, nil
+	}
+
+	return credentials{}, fmt.Errorf("unknown role %q", role)
+}
+
+func (a authService) getCredentialsByID(ctx context.Context, role, id string) (credentials, error) {
+	var login string
+
+	switch AccountRole(role) {
+	case config.ADMIN_ROLE:
+		admin, err := a.storage.Admin().GetByID(ctx, id)
+		if err != nil {
+			return credentials{}, err
+		}
+		login = admin.Login
+
+	case config.TEACHER_ROLE:
+		teacher, err := a.storage.Teacher().GetByID(ctx, id)
+		if err != nil {
+			return credentials{}, err
+		}
+		login = teacher.Login
+
+	case config.STUDENT_ROLE:
+		student, err := a.storage.Student().GetByID(ctx, id)
+		if err != nil {
+			return credentials{}, err
+		}
+		login = student.Login
+
+	default:
+		return credentials{}, fmt.Errorf("unknown role %q", role)
+	}
+
+	user, err := a.getCredentials(ctx, role, login)
+	if err != nil {
+		return credentials{}, err
+	}
+	if user.ID != id {
+		return credentials{}, errors.New("login belongs to another user")
+	}
+
+	return user, nil
+}
+
+// Login checks the credentials of an admin, teacher or student. Failed attempts
+// are counted per login and per ip, every failure delays the next attempt a bit
+// more and after too many of them the login or ip is locked for a while.
+func (a authService) Login(ctx context.Context, role string, loginRequest models.LoginRequest, ip string) (models.LoginResponse, error) {
+	loginKey, ipKey := loginThrottleKey(role, loginRequest.Login), "ip:"+ip
+
+	for _, key := range []string{loginKey, ipKey} {
+		throttle, err := a.storage.LoginAttempt().GetThrottle(ctx, key)
+		if err != nil {
+			a.log.Error("error while getting login throttle", logger.Error(err))
+			return models.LoginResponse{}, err
+		}
+		if throttle.RetryAfter > 0 {
+			return models.LoginResponse{}, LoginLockedError{RetryAfter: time.Duration(throttle.RetryAfter) * time.Second}
+		}
+	}
+
+	attempt := models.LoginAttempt{
+		Login:    loginRequest.Login,
+		UserRole: role,
+		IP:       ip,
+	}
+
+	user, err := a.getCredentials(ctx, role, loginRequest.Login)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		a.log.Error("error while getting "+role+" credentials by login", logger.Error(err))
+		return models.LoginResponse{}, err
+	}
+
+	attempt.UserID = user.ID
+
+	if err != nil || password.CompareHashAndPassword(user.Password, loginRequest.Password) != nil {
+		return models.LoginResponse{}, a.loginFailed(ctx, attempt, ErrInvalidCredentials)
+	}
+
+	if err = a.verifySecondFactor(ctx, user.ID, role, loginRequest); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			return models.LoginResponse{}, a.loginFailed(ctx, attempt, err)
+		}
+		return models.LoginResponse{}, err
+	}
+
+	if user.Status == "inactive" {
+		return models.LoginResponse{}, errs.Forbidden("inactive", role+" is inactive")
+	}
+
+	attempt.Success = true
+	a.recordAttempt(ctx, attempt)
+
+	if err = a.storage.LoginAttempt().ResetThrottle(ctx, loginKey); err != nil {
+		a.log.Error("error while resetting login throttle", logger.Error(err))
+		return models.LoginResponse{}, err
+	}
+
+	return a.generateTokens(user)
+}
+
+// loginFailed records the failed attempt and counts it for both the login and
+// the ip address. It returns reason, or the error of the counting.
+func (a authService) loginFailed(ctx context.Context, attempt models.LoginAttempt, reason error) error {
+	a.recordAttempt(ctx, attempt)
+
+	if err := a.registerFailure(ctx, loginThrottleKey(attempt.UserRole, attempt.Login), a.cfg.LoginMaxFailures); err != nil {
+		return err
+	}
+	if err := a.registerFailure(ctx, "ip:"+attempt.IP, a.cfg.LoginIPMaxFailures); err != nil {
+		return err
+	}
+
+	return reason
+}
+
+// registerFailure counts a failed attempt of the key and locks it for the
+// progressive delay, or for the lockout duration once maxFailures is reached.
+func (a authService) registerFailure(ctx context.Context, key string, maxFailures int) error {
+	failures, err := a.storage.LoginAttempt().RegisterFailure(ctx, key, a.cfg.LoginLockoutDuration)
+	if err != nil {
+		a.log.Error("error while registering failed login", logger.Error(err))
+		return err
+	}
+
+	lockFor := loginDelay(failures, a.cfg.LoginDelayStep, a.cfg.LoginMaxDelay)
+	if failures >= maxFailures {
+		lockFor = a.cfg.LoginLockoutDuration
+		a.log.Warning("login is locked after failed attempts", logger.String("key", key), logger.Int("failures", failures))
+	}
+
+	if lockFor <= 0 {
+		return nil
+	}
+
+	if err = a.storage.LoginAttempt().Lock(ctx, key, lockFor); err != nil {
+		a.log.Error("error while locking login", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (a authService) recordAttempt(ctx context.Context, attempt models.LoginAttempt) {
+	// the attempt log is for analysis only, failing to write it must not block logins
+	if err := a.storage.LoginAttempt().Create(ctx, attempt); err != nil {
+		a.log.Error("error while recording login attempt", logger.Error(err))
+	}
+}
+
+// loginDelay doubles step with every failure after the first one, up to max.
+func loginDelay(failures int, step, max time.Duration) time.Duration {
+	if failures <= 0 || step <= 0 {
+		return 0
 	}
+
+	delay := step
+	for i := 1; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+
+	if delay > max {
+		return max
+	}
+
+	return delay
+}
+
+func loginThrottleKey(role, login string) string {
+	return "login:" + role + ":" + login
 }
 
-func (a authService) StudentLogin(ctx context.Context, loginRequest models.StudentLoginRequest) (models.StudentLoginResponse, error) {
-	fmt.Println(" loginRequest.Login: ", loginRequest.Login)
-	student, err := a.storage.Student().GetByLogin(ctx, loginRequest.Login)
+// UnlockLogin clears the failed attempts of the login, so it can log in again right away.
+func (a authService) UnlockLogin(ctx context.Context, req models.UnlockLoginRequest) error {
+	key := loginThrottleKey(req.Role, req.Login)
+	err := a.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.LoginAttempt().ResetThrottle(ctx, key); err != nil {
+			return err
+		}
+		return a.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityLogin, EntityID: key, Action: AuditActionUnlockLogin})
+	})
 	if err != nil {
-		a.log.Error("error while getting student credentials by login", logger.Error(err))
-		return models.StudentLoginResponse{}, err
+		a.log.Error("error while unlocking login", logger.Error(err))
+		return err
 	}
 
-	if err = password.CompareHashAndPassword(student.Password, loginRequest.Password); err != nil {
-		a.log.Error("error while comparing password", logger.Error(err))
-		return models.StudentLoginResponse{}, err
+	return nil
+}
+
+func (a authService) GetAllLoginAttempts(ctx context.Context, req models.GetAllLoginAttemptsRequest) (models.GetAllLoginAttemptsResponse, error) {
+	req.BranchIDs = allowedBranches(ctx)
+
+	attempts, err := a.storage.LoginAttempt().GetAll(ctx, req)
+	if err != nil {
+		a.log.Error("error while getting login attempts", logger.Error(err))
+		return models.GetAllLoginAttemptsResponse{}, err
 	}
 
+	return attempts, nil
+}
+
+func (a authService) generateTokens(user credentials) (models.LoginResponse, error) {
 	m := make(map[interface{}]interface{})
 
-	m["user_id"] = student.ID
-	m["user_role"] = config.STUDENT_ROLE
+	m["user_id"] = user.ID
+	m["user_role"] = user.Role
+	if user.Role == config.ADMIN_ROLE {
+		branchIDs := user.BranchIDs
+		if branchIDs == nil {
+			branchIDs = []string{}
+		}
+		m["branch_ids"] = branchIDs
+	}
 
 	accessToken, refreshToken, err := jwt.GenJWT(m)
 	if err != nil {
-		a.log.Error("error while generating tokens for student login", logger.Error(err))
-		return models.StudentLoginResponse{}, err
+		a.log.Error("error while generating tokens for "+user.Role+" login", logger.Error(err))
+		return models.LoginResponse{}, err
 	}
 
-	return models.StudentLoginResponse{
+	return models.LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 	}, nil
 }
+
+func (a authService) RefreshToken(ctx context.Context, req models.RefreshTokenRequest) (models.LoginResponse, error) {
+	claims, err := jwt.ExtractClaims(req.RefreshToken)
+	if err != nil {
+		a.log.Error("error while extracting refresh token claims", logger.Error(err))
+		return models.LoginResponse{}, err
+	}
+
+	if cast.ToString(claims["token_type"]) != jwt.RefreshTokenType {
+		return models.LoginResponse{}, errs.Unauthorized("invalid_refresh_token", "token is not a refresh token")
+	}
+
+	token := models.RevokedToken{
+		JTI:       cast.ToString(claims["jti"]),
+		UserID:    cast.ToString(claims["user_id"]),
+		ExpiresAt: cast.ToInt64(claims["exp"]),
+	}
+	userRole := cast.ToString(claims["user_role"])
+	if token.JTI == "" || token.UserID == "" || userRole == "" {
+		return models.LoginResponse{}, errs.Unauthorized("invalid_refresh_token", "invalid refresh token")
+	}
+
+	// refresh tokens are single use, the old one is revoked before the new pair is issued
+	revoked, err := a.storage.Token().Revoke(ctx, token)
+	if err != nil {
+		a.log.Error("error while revoking refresh token", logger.Error(err))
+		return models.LoginResponse{}, err
+	}
+	if !revoked {
+		return models.LoginResponse{}, errs.Unauthorized("invalid_refresh_token", "refresh token is revoked")
+	}
+
+	// the access token issued together with it is replaced as well
+	if accessJTI := cast.ToString(claims["access_jti"]); accessJTI != "" {
+		_, err = a.storage.Token().Revoke(ctx, models.RevokedToken{
+			JTI:       accessJTI,
+			UserID:    token.UserID,
+			ExpiresAt: cast.ToInt64(claims["access_exp"]),
+		})
+		if err != nil {
+			a.log.Error("error while revoking access token of refresh token", logger.Error(err))
+			return models.LoginResponse{}, err
+		}
+	}
+
+	// the account may have been deactivated and the role and branches of admins
+	// may have changed since the login, so they are loaded again
+	user, err := a.getCredentialsByID(ctx, userRole, token.UserID)
+	if err != nil {
+		a.log.Error("error while getting "+userRole+" for refreshing tokens", logger.Error(err))
+		return models.LoginResponse{}, err
+	}
+	if user.Status == "inactive" {
+		return models.LoginResponse{}, errs.Forbidden("inactive", AccountRole(userRole)+" is inactive")
+	}
+
+	return a.generateTokens(user)
+}
+
+func (a authService) Logout(ctx context.Context, authInfo models.AuthInfo, req models.LogoutRequest) error {
+	_, err := a.storage.Token().Revoke(ctx, models.RevokedToken{
+		JTI:       authInfo.TokenID,
+		UserID:    authInfo.UserID,
+		ExpiresAt: authInfo.ExpiresAt,
+	})
+	if err != nil {
+		a.log.Error("error while revoking access token", logger.Error(err))
+		return err
+	}
+
+	if req.RefreshToken == "" {
+		return nil
+	}
+
+	claims, err := jwt.ExtractClaims(req.RefreshToken)
+	if err != nil {
+		// an expired or broken refresh token can not be used anyway
+		return nil
+	}
+
+	if cast.ToString(claims["user_id"]) != authInfo.UserID {
+		return errs.Forbidden("forbidden", "refresh token belongs to another user")
+	}
+
+	_, err = a.storage.Token().Revoke(ctx, models.RevokedToken{
+		JTI:       cast.ToString(claims["jti"]),
+		UserID:    authInfo.UserID,
+		ExpiresAt: cast.ToInt64(claims["exp"]),
+	})
+	if err != nil {
+		a.log.Error("error while revoking refresh token", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (a authService) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	revoked, err := a.storage.Token().IsRevoked(ctx, jti)
+	if err != nil {
+		a.log.Error("error while checking token revocation", logger.Error(err))
+		return false, err
+	}
+
+	return revoked, nil
+}
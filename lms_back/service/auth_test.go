@@ -0,0 +1,66 @@
+package service
+
+import (
+	"lms_back/api/models"
+	"lms_back/config"
+	"lms_back/pkg/errs"
+	"lms_back/pkg/jwt"
+	"testing"
+	"time"
+
+	"github.com/spf13/cast"
+)
+
+func TestLoginDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 0, want: 0},
+		{failures: 1, want: time.Second},
+		{failures: 2, want: 2 * time.Second},
+		{failures: 3, want: 4 * time.Second},
+		{failures: 5, want: 10 * time.Second},
+		{failures: 100, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := loginDelay(tt.failures, time.Second, 10*time.Second); got != tt.want {
+			t.Errorf("loginDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	f := newFixture(t)
+	f.check(jwt.Init(config.Config{JWTAlgorithm: "HS256", JWTActiveKeyID: "key", JWTSecrets: map[string]string{"key": "secret"}}))
+	auth := NewAuthService(config.Config{}, f.store, f.audit, nil, f.log)
+
+	revoked := func(token string) bool {
+		t.Helper()
+		claims, err := jwt.ExtractClaims(token)
+		f.check(err)
+		revoked, err := auth.IsTokenRevoked(f.ctx, cast.ToString(claims["jti"]))
+		f.check(err)
+		return revoked
+	}
+
+	tokens, err := auth.generateTokens(credentials{ID: f.teachers[0], Role: config.TEACHER_ROLE})
+	f.check(err)
+	refreshed, err := auth.RefreshToken(f.ctx, models.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+	f.check(err)
+	if !revoked(tokens.AccessToken) || revoked(refreshed.AccessToken) {
+		t.Errorf("RefreshToken() kept the old access token or revoked the new one")
+	}
+	if _, err = auth.RefreshToken(f.ctx, models.RefreshTokenRequest{RefreshToken: tokens.RefreshToken}); errs.KindOf(err) != errs.KindUnauthorized {
+		t.Errorf("RefreshToken() with a used token error = %v, want unauthorized", err)
+	}
+
+	inactive, err := f.store.Teacher().Create(f.ctx, models.Teacher{Full_name: "soli", Status: "inactive", Login: "soli"})
+	f.check(err)
+	tokens, err = auth.generateTokens(credentials{ID: inactive.Id, Role: config.TEACHER_ROLE})
+	f.check(err)
+	if _, err = auth.RefreshToken(f.ctx, models.RefreshTokenRequest{RefreshToken: tokens.RefreshToken}); errs.KindOf(err) != errs.KindForbidden {
+		t.Errorf("RefreshToken() of an inactive teacher error = %v, want forbidden", err)
+	}
+}
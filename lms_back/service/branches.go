@@ -9,19 +9,28 @@ import (
 
 type branchService struct {
 	storage storage.IStorage
+	audit   auditService
 	logger  logger.ILogger
 }
 
-func NewBranchService(storage storage.IStorage, logger logger.ILogger) branchService {
+func NewBranchService(storage storage.IStorage, audit auditService, logger logger.ILogger) branchService {
 	return branchService{
 		storage: storage,
+		audit:   audit,
 		logger:  logger,
 	}
 }
 
 func (u branchService) Create(ctx context.Context, branch models.Branch) (models.Branch, error) {
 
-	pKey, err := u.storage.Branch().Create(ctx, branch)
+	var pKey models.Branch
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Branch().Create(ctx, branch); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityBranch, EntityID: pKey.Id, BranchID: pKey.Id, Action: AuditActionCreate, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("error while creating branch in service layer", logger.Error(err))
 		return models.Branch{}, err
@@ -32,7 +41,20 @@ func (u branchService) Create(ctx context.Context, branch models.Branch) (models
 
 func (u branchService) Update(ctx context.Context, branch models.Branch) (models.Branch, error) {
 
-	pKey, err := u.storage.Branch().Update(ctx, branch)
+	before, err := u.storage.Branch().GetByID(ctx, branch.Id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting branch before update", logger.Error(err))
+		return models.Branch{}, err
+	}
+
+	var pKey models.Branch
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Branch().Update(ctx, branch); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityBranch, EntityID: branch.Id, BranchID: branch.Id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while updating branch", logger.Error(err))
 		return models.Branch{}, err
@@ -41,6 +63,30 @@ func (u branchService) Update(ctx context.Context, branch models.Branch) (models
 	return pKey, nil
 }
 
+func (u branchService) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Branch, error) {
+
+	before, err := u.storage.Branch().GetByID(ctx, id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting branch before patch", logger.Error(err))
+		return models.Branch{}, err
+	}
+
+	var pKey models.Branch
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Branch().Patch(ctx, id, version, patch); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityBranch, EntityID: id, BranchID: id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while patching branch", logger.Error(err))
+		return models.Branch{}, err
+	}
+
+	return pKey, nil
+}
+
 func (u branchService) GetByID(ctx context.Context, id string) (models.Branch, error) {
 
 	pKey, err := u.storage.Branch().GetByID(ctx, id)
@@ -54,6 +100,10 @@ func (u branchService) GetByID(ctx context.Context, id string) (models.Branch, e
 
 func (u branchService) GetAll(ctx context.Context, req models.GetAllBranchesRequest) (models.GetAllBranchesResponse, error) {
 
+	if err := checkTrashAccess(ctx, req.Deleted); err != nil {
+		return models.GetAllBranchesResponse{}, err
+	}
+
 	pKey, err := u.storage.Branch().GetAll(ctx, req)
 	if err != nil {
 		u.logger.Error("ERROR in service layer while GetAll branch", logger.Error(err))
@@ -63,9 +113,20 @@ func (u branchService) GetAll(ctx context.Context, req models.GetAllBranchesRequ
 	return pKey, nil
 }
 
-func (u branchService) Delete(ctx context.Context, id string) error {
+func (u branchService) Delete(ctx context.Context, id string, version int) error {
+
+	before, err := u.storage.Branch().GetByID(ctx, id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting branch before delete", logger.Error(err))
+		return err
+	}
 
-	err := u.storage.Branch().Delete(ctx, id)
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Branch().Delete(ctx, id, version); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityBranch, EntityID: id, BranchID: id, Action: AuditActionDelete, Before: before})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while deleting branch", logger.Error(err))
 		return err
@@ -73,3 +134,25 @@ func (u branchService) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+func (u branchService) Restore(ctx context.Context, id string) (models.Branch, error) {
+
+	var after models.Branch
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Branch().Restore(ctx, id); err != nil {
+			return err
+		}
+
+		var err error
+		if after, err = tx.Branch().GetByID(ctx, id); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityBranch, EntityID: id, BranchID: id, Action: AuditActionRestore, After: after})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while restoring branch", logger.Error(err))
+		return models.Branch{}, err
+	}
+
+	return after, nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/config"
+	"lms_back/pkg/errs"
+)
+
+// ErrForbidden is returned when the caller is not allowed to access the entity.
+var ErrForbidden = errs.Forbidden("forbidden", "not allowed to access the entity")
+
+type contextKey int
+
+const (
+	authInfoKey contextKey = iota
+	clientIPKey
+)
+
+// ContextWithAuthInfo returns a copy of ctx carrying the authenticated user.
+func ContextWithAuthInfo(ctx context.Context, authInfo models.AuthInfo) context.Context {
+	return context.WithValue(ctx, authInfoKey, authInfo)
+}
+
+// AuthInfoFromContext returns the authenticated user of the request, if there is one.
+func AuthInfoFromContext(ctx context.Context) (models.AuthInfo, bool) {
+	authInfo, ok := ctx.Value(authInfoKey).(models.AuthInfo)
+	return authInfo, ok
+}
+
+// ContextWithClientIP returns a copy of ctx carrying the ip address of the request.
+func ContextWithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+func clientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+// AccountRole returns the role whose table keeps the account, superadmins are stored as admins.
+func AccountRole(role string) string {
+	if role == config.SUPERADMIN_ROLE {
+		return config.ADMIN_ROLE
+	}
+	return role
+}
+
+// allowedBranches returns the branches the caller may access. It is nil when
+// access is not limited: for superadmins, teachers, students and calls without
+// an authenticated user.
+func allowedBranches(ctx context.Context) []string {
+	authInfo, ok := AuthInfoFromContext(ctx)
+	if !ok || authInfo.UserRole != config.ADMIN_ROLE {
+		return nil
+	}
+
+	if authInfo.BranchIDs == nil {
+		return []string{}
+	}
+
+	return authInfo.BranchIDs
+}
+
+// checkBranch returns ErrForbidden unless the caller may access all of the branches.
+func checkBranch(ctx context.Context, branchIDs ...string) error {
+	allowed := allowedBranches(ctx)
+	if allowed == nil {
+		return nil
+	}
+
+	for _, branchID := range branchIDs {
+		found := false
+		for _, id := range allowed {
+			if id == branchID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrForbidden
+		}
+	}
+
+	return nil
+}
+
+// checkGroupTeacher returns ErrForbidden if the caller is a teacher and the
+// group is taught by another one.
+func checkGroupTeacher(ctx context.Context, group models.Group) error {
+	authInfo, ok := AuthInfoFromContext(ctx)
+	if ok && authInfo.UserRole == config.TEACHER_ROLE && authInfo.UserID != group.Teacher_id {
+		return ErrForbidden
+	}
+	return nil
+}
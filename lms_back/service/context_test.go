@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"lms_back/api/models"
+	"lms_back/config"
+	"testing"
+)
+
+func TestCheckBranch(t *testing.T) {
+	admin := ContextWithAuthInfo(context.Background(), models.AuthInfo{
+		UserRole:  config.ADMIN_ROLE,
+		BranchIDs: []string{"b1", "b2"},
+	})
+	superadmin := ContextWithAuthInfo(context.Background(), models.AuthInfo{UserRole: config.SUPERADMIN_ROLE})
+	unassigned := ContextWithAuthInfo(context.Background(), models.AuthInfo{UserRole: config.ADMIN_ROLE})
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		branches []string
+		wantErr  bool
+	}{
+		{name: "own branch", ctx: admin, branches: []string{"b1"}},
+		{name: "move between own branches", ctx: admin, branches: []string{"b1", "b2"}},
+		{name: "other branch", ctx: admin, branches: []string{"b3"}, wantErr: true},
+		{name: "move to other branch", ctx: admin, branches: []string{"b1", "b3"}, wantErr: true},
+		{name: "no branch", ctx: admin, branches: []string{""}, wantErr: true},
+		{name: "admin without branches", ctx: unassigned, branches: []string{"b1"}, wantErr: true},
+		{name: "superadmin", ctx: superadmin, branches: []string{"b3"}},
+		{name: "no auth info", ctx: context.Background(), branches: []string{"b3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBranch(tt.ctx, tt.branches...)
+			if tt.wantErr != errors.Is(err, ErrForbidden) {
+				t.Errorf("checkBranch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if allowedBranches(unassigned) == nil {
+		t.Errorf("allowedBranches() of an admin without branches must not be nil")
+	}
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"lms_back/api/models"
+	"lms_back/pkg/errs"
+	"lms_back/pkg/logger"
+	"lms_back/storage"
+)
+
+var (
+	ErrAlreadyEnrolled = errs.Conflict("already_enrolled", "the student is already enrolled in the group")
+	ErrNotEnrolled     = errs.NotFound("not_enrolled", "the student is not enrolled in the group")
+)
+
+// enrollmentService moves students between groups. The group_id of a student
+// is its primary group, enrolling a student without one sets it and
+// transferring or withdrawing the student from it moves it along.
+type enrollmentService struct {
+	storage storage.IStorage
+	student studentService
+	audit   auditService
+	logger  logger.ILogger
+}
+
+func NewEnrollmentService(storage storage.IStorage, student studentService, audit auditService, logger logger.ILogger) enrollmentService {
+	return enrollmentService{
+		storage: storage,
+		student: student,
+		audit:   audit,
+		logger:  logger,
+	}
+}
+
+func (e enrollmentService) Enroll(ctx context.Context, studentID string, req models.EnrollRequest) (models.Enrollment, error) {
+
+	var (
+		enrollment models.Enrollment
+		branchID   string
+	)
+	err := e.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		scoped := e.student
+		scoped.storage = tx
+
+		student, _, err := scoped.checkAccess(ctx, studentID)
+		if err != nil {
+			return err
+		}
+		if branchID, err = scoped.checkGroupBranch(ctx, req.GroupID); err != nil {
+			return err
+		}
+
+		if enrollment, err = enroll(ctx, tx, studentID, req.GroupID, ""); err != nil {
+			return err
+		}
+		if student.GroupID == "" {
+			if err = tx.Student().SetGroup(ctx, studentID, req.GroupID); err != nil {
+				return err
+			}
+		}
+		return e.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityEnrollment, EntityID: enrollment.Id, BranchID: branchID, Action: AuditActionEnroll, After: enrollment})
+	})
+	if err != nil {
+		e.logger.Error("ERROR in service layer while enrolling student", logger.Error(err))
+		return models.Enrollment{}, err
+	}
+
+	return enrollment, nil
+}
+
+// Transfer closes the enrollment of the student in one group and opens one in
+// the other, it returns the new enrollment.
+func (e enrollmentService) Transfer(ctx context.Context, studentID string, req models.TransferRequest) (models.Enrollment, error) {
+
+	if req.FromGroupID == req.ToGroupID {
+		return models.Enrollment{}, errs.Validation("", errs.FieldError{Field: "to_group_id", Message: "must differ from from_group_id"})
+	}
+
+	var (
+		closed, opened models.Enrollment
+		branchID       string
+	)
+	err := e.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		scoped := e.student
+		scoped.storage = tx
+
+		student, _, err := scoped.checkAccess(ctx, studentID)
+		if err != nil {
+			return err
+		}
+		if _, err = scoped.checkGroupBranch(ctx, req.FromGroupID); err != nil {
+			return err
+		}
+		if branchID, err = scoped.checkGroupBranch(ctx, req.ToGroupID); err != nil {
+			return err
+		}
+
+		if closed, err = leave(ctx, tx, studentID, req.FromGroupID, models.EnrollmentTransferred, req.Reason); err != nil {
+			return err
+		}
+		if opened, err = enroll(ctx, tx, studentID, req.ToGroupID, req.Reason); err != nil {
+			return err
+		}
+		if student.GroupID == req.FromGroupID {
+			if err = tx.Student().SetGroup(ctx, studentID, req.ToGroupID); err != nil {
+				return err
+			}
+		}
+		return e.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityEnrollment, EntityID: opened.Id, BranchID: branchID, Action: AuditActionTransfer, Before: closed, After: opened})
+	})
+	if err != nil {
+		e.logger.Error("ERROR in service layer while transferring student", logger.Error(err))
+		return models.Enrollment{}, err
+	}
+
+	return opened, nil
+}
+
+// Withdraw closes the enrollment of the student in the group. If it was the
+// primary group another active enrollment becomes primary, a student without
+// one keeps the group so it stays in its branch.
+func (e enrollmentService) Withdraw(ctx context.Context, studentID string, req models.WithdrawRequest) (models.Enrollment, error) {
+
+	var (
+		closed   models.Enrollment
+		branchID string
+	)
+	err := e.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		scoped := e.student
+		scoped.storage = tx
+
+		student, _, err := scoped.checkAccess(ctx, studentID)
+		if err != nil {
+			return err
+		}
+		if branchID, err = scoped.checkGroupBranch(ctx, req.GroupID); err != nil {
+			return err
+		}
+
+		if closed, err = leave(ctx, tx, studentID, req.GroupID, models.EnrollmentWithdrawn, req.Reason); err != nil {
+			return err
+		}
+		if student.GroupID == req.GroupID {
+			others, err := tx.Enrollment().GetAll(ctx, models.GetAllEnrollmentsRequest{StudentID: studentID, Status: models.EnrollmentActive, Page: 1, Limit: 1})
+			if err != nil {
+				return err
+			}
+			if len(others.Enrollments) > 0 {
+				if err = tx.Student().SetGroup(ctx, studentID, others.Enrollments[0].GroupID); err != nil {
+					return err
+				}
+			}
+		}
+		return e.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityEnrollment, EntityID: closed.Id, BranchID: branchID, Action: AuditActionWithdraw, After: closed})
+	})
+	if err != nil {
+		e.logger.Error("ERROR in service layer while withdrawing student", logger.Error(err))
+		return models.Enrollment{}, err
+	}
+
+	return closed, nil
+}
+
+// GetAll lists the enrollments of req.StudentID, the caller has to be allowed
+// to access the student.
+func (e enrollmentService) GetAll(ctx context.Context, req models.GetAllEnrollmentsRequest) (models.GetAllEnrollmentsResponse, error) {
+
+	if _, _, err := e.student.checkAccess(ctx, req.StudentID); err != nil {
+		return models.GetAllEnrollmentsResponse{}, err
+	}
+
+	enrollments, err := e.storage.Enrollment().GetAll(ctx, req)
+	if err != nil {
+		e.logger.Error("ERROR in service layer while GetAll enrollment", logger.Error(err))
+		return models.GetAllEnrollmentsResponse{}, err
+	}
+
+	return enrollments, nil
+}
+
+// GetGroupStudents lists the students actively enrolled in req.EnrolledGroupID,
+// from any branch, if the caller may access the group.
+func (e enrollmentService) GetGroupStudents(ctx context.Context, req models.GetAllStudentsRequest) (models.GetAllStudentsResponse, error) {
+
+	if err := checkTrashAccess(ctx, req.Deleted); err != nil {
+		return models.GetAllStudentsResponse{}, err
+	}
+	if _, err := e.student.checkGroupBranch(ctx, req.EnrolledGroupID); err != nil {
+		return models.GetAllStudentsResponse{}, err
+	}
+
+	students, err := e.storage.Student().GetAll(ctx, req)
+	if err != nil {
+		e.logger.Error("ERROR in service layer while getting students of group", logger.Error(err))
+		return models.GetAllStudentsResponse{}, err
+	}
+
+	return students, nil
+}
+
+// enroll opens an enrollment of the student in the group. It checks for an
+// active one first, a unique violation would abort the transaction.
+func enroll(ctx context.Context, tx storage.IStorage, studentID, groupID, reason string) (models.Enrollment, error) {
+	active, err := enrolled(ctx, tx, studentID, groupID)
+	if err != nil {
+		return models.Enrollment{}, err
+	}
+	if active {
+		return models.Enrollment{}, ErrAlreadyEnrolled
+	}
+
+	return tx.Enrollment().Create(ctx, models.Enrollment{StudentID: studentID, GroupID: groupID, Reason: reason})
+}
+
+// enrolled reports whether the student is actively enrolled in the group.
+func enrolled(ctx context.Context, st storage.IStorage, studentID, groupID string) (bool, error) {
+	active, err := st.Enrollment().GetAll(ctx, models.GetAllEnrollmentsRequest{StudentID: studentID, GroupID: groupID, Status: models.EnrollmentActive, Page: 1, Limit: 1})
+	if err != nil {
+		return false, err
+	}
+	return active.Count > 0, nil
+}
+
+// leave closes the active enrollment of the student in the group.
+func leave(ctx context.Context, tx storage.IStorage, studentID, groupID, status, reason string) (models.Enrollment, error) {
+	closed, err := tx.Enrollment().Close(ctx, studentID, groupID, status, reason)
+	if errors.Is(err, storage.ErrNotFound) {
+		return models.Enrollment{}, ErrNotEnrolled
+	}
+	return closed, err
+}
+
+// moveEnrollment keeps the enrollments in step with the primary group when a
+// student is created or written with another group_id.
+func moveEnrollment(ctx context.Context, tx storage.IStorage, studentID, from, to string) error {
+	if from == to {
+		return nil
+	}
+	if from != "" {
+		if _, err := leave(ctx, tx, studentID, from, models.EnrollmentTransferred, ""); err != nil && !errors.Is(err, ErrNotEnrolled) {
+			return err
+		}
+	}
+	if to != "" {
+		if _, err := enroll(ctx, tx, studentID, to, ""); err != nil && !errors.Is(err, ErrAlreadyEnrolled) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"errors"
+	"lms_back/api/models"
+	"testing"
+)
+
+func TestEnrollment(t *testing.T) {
+	f := newFixture(t)
+	ctx := f.ctx
+	enrollments := NewEnrollmentService(f.store, f.students, f.audit, f.log)
+
+	primary := func(id string) string {
+		t.Helper()
+		student, err := f.store.Student().GetByID(ctx, id)
+		f.check(err)
+		return student.GroupID
+	}
+
+	backend, frontend, qa := f.group.Id, f.otherGroup.Id, f.newGroup(f.teachers[0], "qa").Id
+	student := f.newStudent("ali", backend)
+
+	if _, err := enrollments.Enroll(ctx, student.ID, models.EnrollRequest{GroupID: backend}); !errors.Is(err, ErrAlreadyEnrolled) {
+		t.Errorf("Enroll() in the group of Create() error = %v, want ErrAlreadyEnrolled", err)
+	}
+
+	moved, err := enrollments.Transfer(ctx, student.ID, models.TransferRequest{FromGroupID: backend, ToGroupID: frontend, Reason: "schedule"})
+	f.check(err)
+	if moved.GroupID != frontend || moved.Status != models.EnrollmentActive || primary(student.ID) != frontend {
+		t.Errorf("Transfer() = %+v, primary group %s, want the student moved to %s", moved, primary(student.ID), frontend)
+	}
+	if _, err = enrollments.Transfer(ctx, student.ID, models.TransferRequest{FromGroupID: backend, ToGroupID: qa}); !errors.Is(err, ErrNotEnrolled) {
+		t.Errorf("Transfer() from a group the student left error = %v, want ErrNotEnrolled", err)
+	}
+
+	_, err = enrollments.Enroll(ctx, student.ID, models.EnrollRequest{GroupID: qa})
+	f.check(err)
+	if primary(student.ID) != frontend {
+		t.Errorf("Enroll() changed the primary group to %s", primary(student.ID))
+	}
+
+	withdrawn, err := enrollments.Withdraw(ctx, student.ID, models.WithdrawRequest{GroupID: frontend, Reason: "quit"})
+	f.check(err)
+	if withdrawn.Status != models.EnrollmentWithdrawn || primary(student.ID) != qa {
+		t.Errorf("Withdraw() = %+v, primary group %s, want the other enrollment %s primary", withdrawn, primary(student.ID), qa)
+	}
+
+	history, err := enrollments.GetAll(ctx, models.GetAllEnrollmentsRequest{StudentID: student.ID, Page: 1, Limit: 10})
+	f.check(err)
+	statuses := map[string]string{}
+	for _, enrollment := range history.Enrollments {
+		statuses[enrollment.GroupID] = enrollment.Status
+	}
+	want := map[string]string{backend: models.EnrollmentTransferred, frontend: models.EnrollmentWithdrawn, qa: models.EnrollmentActive}
+	if history.Count != 3 || len(statuses) != 3 || statuses[backend] != want[backend] || statuses[frontend] != want[frontend] || statuses[qa] != want[qa] {
+		t.Errorf("GetAll() = %+v, want the statuses %v", history, want)
+	}
+
+	inQA, err := enrollments.GetGroupStudents(ctx, models.GetAllStudentsRequest{EnrolledGroupID: qa, Page: 1, Limit: 10})
+	f.check(err)
+	inBackend, err := enrollments.GetGroupStudents(ctx, models.GetAllStudentsRequest{EnrolledGroupID: backend, Page: 1, Limit: 10})
+	f.check(err)
+	if inQA.Count != 1 || inBackend.Count != 0 {
+		t.Errorf("GetGroupStudents() = %d in qa and %d in backend, want 1 and 0", inQA.Count, inBackend.Count)
+	}
+}
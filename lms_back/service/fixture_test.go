@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/pkg/logger"
+	"lms_back/storage/memory"
+	"testing"
+)
+
+// fixture is a memory storage with a branch, where the teachers vali and gani
+// teach a backend and a frontend group, and the services most tests build on.
+// The backend group has a lesson on 2024-03-04.
+type fixture struct {
+	t   *testing.T
+	ctx context.Context
+	log logger.ILogger
+
+	store    memory.Store
+	audit    auditService
+	groups   groupService
+	students studentService
+
+	branch     models.Branch
+	teachers   []string
+	group      models.Group
+	otherGroup models.Group
+	schedule   models.Schedule
+	lesson     models.Lesson
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	f := &fixture{t: t, ctx: context.Background(), log: logger.New("test"), store: memory.New()}
+	f.audit = NewAuditService(f.store, f.log)
+	f.groups = NewGroupService(f.store, f.audit, f.log)
+	f.students = NewStudentService(f.store, f.audit, f.log)
+
+	var err error
+	f.branch, err = f.store.Branch().Create(f.ctx, models.Branch{Name: "Chilonzor"})
+	f.check(err)
+	for _, login := range []string{"vali", "gani"} {
+		teacher, err := f.store.Teacher().Create(f.ctx, models.Teacher{Full_name: login, Status: "active", Login: login})
+		f.check(err)
+		f.teachers = append(f.teachers, teacher.Id)
+	}
+	f.group = f.newGroup(f.teachers[0], "backend")
+	f.otherGroup = f.newGroup(f.teachers[1], "frontend")
+	f.schedule, err = f.store.Schedule().Create(f.ctx, models.Schedule{Group_id: f.group.Id, Group_type: "backend", Start_time: "09:00", End_time: "11:00", Date: "monday", Branch_id: f.branch.Id, Teacher_id: f.teachers[0]})
+	f.check(err)
+	f.lesson = f.newLesson("2024-03-04")
+
+	return f
+}
+
+func (f *fixture) check(err error) {
+	f.t.Helper()
+	if err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// as returns a context of the user with the role.
+func (f *fixture) as(id, role string) context.Context {
+	return ContextWithAuthInfo(f.ctx, models.AuthInfo{UserID: id, UserRole: role})
+}
+
+func (f *fixture) newGroup(teacherID, groupType string) models.Group {
+	f.t.Helper()
+	group, err := f.store.Group().Create(f.ctx, models.Group{Branch_id: f.branch.Id, Teacher_id: teacherID, Type: groupType})
+	f.check(err)
+	return group
+}
+
+// newLesson adds a lesson of the backend group on the date.
+func (f *fixture) newLesson(date string) models.Lesson {
+	f.t.Helper()
+	lesson, err := f.store.Lesson().Create(f.ctx, models.Lesson{ScheduleId: f.schedule.Id, GroupId: f.group.Id, From: date, To: date, Theme: "Lesson " + date})
+	f.check(err)
+	return lesson
+}
+
+// newStudent creates a student enrolled in the group through the student
+// service.
+func (f *fixture) newStudent(login, groupID string) models.GetStudent {
+	f.t.Helper()
+	student, err := f.students.Create(f.ctx, models.Student{Full_Name: login, Status: "active", Login: login, GroupID: groupID})
+	f.check(err)
+	return student
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/pkg/logger"
+	"lms_back/storage"
+	"math"
+)
+
+// trendThreshold is how much the later half of the graded tasks of a student
+// has to differ from the earlier half, as a share of the max score, to count
+// as a trend.
+const trendThreshold = 0.05
+
+// gradebookService puts the scores and attendance of the students of a group
+// together for its teachers.
+type gradebookService struct {
+	storage storage.IStorage
+	group   groupService
+	student studentService
+	logger  logger.ILogger
+}
+
+func NewGradebookService(storage storage.IStorage, group groupService, student studentService, logger logger.ILogger) gradebookService {
+	return gradebookService{
+		storage: storage,
+		group:   group,
+		student: student,
+		logger:  logger,
+	}
+}
+
+// Gradebook returns the scores of the students actively enrolled in the group
+// for the tasks of its lessons between req.From and req.To.
+func (g gradebookService) Gradebook(ctx context.Context, req models.GradebookRequest) (models.Gradebook, error) {
+
+	if _, err := g.group.checkAccess(ctx, req.GroupID); err != nil {
+		return models.Gradebook{}, err
+	}
+
+	book, _, err := g.build(ctx, req)
+	if err != nil {
+		g.logger.Error("ERROR in service layer while building gradebook", logger.Error(err))
+		return models.Gradebook{}, err
+	}
+
+	return book, nil
+}
+
+// Progress returns the progress of the student in req.GroupID, or in its
+// primary group if it is empty. The student has to be actively enrolled in
+// the group.
+func (g gradebookService) Progress(ctx context.Context, studentID string, req models.GradebookRequest) (models.StudentProgress, error) {
+
+	student, _, err := g.student.checkAccess(ctx, studentID)
+	if err != nil {
+		return models.StudentProgress{}, err
+	}
+	if req.GroupID == "" {
+		req.GroupID = student.GroupID
+	}
+	if req.GroupID == "" {
+		return models.StudentProgress{}, ErrNotEnrolled
+	}
+	if _, err = g.group.checkAccess(ctx, req.GroupID); err != nil {
+		return models.StudentProgress{}, err
+	}
+
+	book, lessons, err := g.build(ctx, req)
+	if err != nil {
+		g.logger.Error("ERROR in service layer while building gradebook for progress", logger.Error(err))
+		return models.StudentProgress{}, err
+	}
+
+	var row *models.GradebookStudentRow
+	for i := range book.Students {
+		if book.Students[i].StudentID == studentID {
+			row = &book.Students[i]
+		}
+	}
+	if row == nil {
+		return models.StudentProgress{}, ErrNotEnrolled
+	}
+
+	marks := map[string]string{}
+	if len(lessons) > 0 {
+		attendance, err := g.storage.Attendance().GetAll(ctx, models.GetAllAttendanceRequest{StudentID: studentID, GroupID: req.GroupID, From: req.From, To: req.To, Page: 1, Limit: uint64(len(lessons))})
+		if err != nil {
+			g.logger.Error("ERROR in service layer while getting attendance for progress", logger.Error(err))
+			return models.StudentProgress{}, err
+		}
+		for _, mark := range attendance.Attendance {
+			marks[mark.LessonID] = mark.Status
+		}
+	}
+
+	progress := models.StudentProgress{
+		StudentID:  studentID,
+		GroupID:    req.GroupID,
+		Lessons:    make([]models.LessonProgress, 0, len(lessons)),
+		Average:    row.Average,
+		Attendance: row.Attendance,
+		Rank:       1,
+		GroupSize:  len(book.Students),
+	}
+
+	var shares []float64
+	for _, lesson := range lessons {
+		lessonProgress := models.LessonProgress{GradebookLesson: lesson, Attendance: marks[lesson.LessonID]}
+		for j, task := range book.Tasks {
+			if task.LessonID != lesson.LessonID {
+				continue
+			}
+			lessonProgress.Tasks++
+			cell := row.Scores[j]
+			if cell.Submitted {
+				lessonProgress.Submitted++
+			}
+			if share, ok := scoreShare(cell.Score, task.MaxScore); ok {
+				shares = append(shares, share)
+			}
+		}
+
+		if lessonProgress.Tasks > 0 {
+			lessonProgress.Completed = lessonProgress.Submitted == lessonProgress.Tasks
+		} else {
+			lessonProgress.Completed = lessonProgress.Attendance == models.AttendancePresent || lessonProgress.Attendance == models.AttendanceLate
+		}
+		if lessonProgress.Completed {
+			progress.Completed++
+		}
+		progress.Lessons = append(progress.Lessons, lessonProgress)
+	}
+	progress.Trend = trend(shares)
+
+	for _, other := range book.Students {
+		if other.Average > row.Average {
+			progress.Rank++
+		}
+	}
+
+	return progress, nil
+}
+
+// build returns the gradebook of req and its lessons.
+func (g gradebookService) build(ctx context.Context, req models.GradebookRequest) (models.Gradebook, []models.GradebookLesson, error) {
+	students, err := g.storage.Gradebook().Students(ctx, req.GroupID)
+	if err != nil {
+		return models.Gradebook{}, nil, err
+	}
+	lessons, err := g.storage.Gradebook().Lessons(ctx, req)
+	if err != nil {
+		return models.Gradebook{}, nil, err
+	}
+	tasks, err := g.storage.Gradebook().Tasks(ctx, req)
+	if err != nil {
+		return models.Gradebook{}, nil, err
+	}
+	scores, err := g.storage.Gradebook().Scores(ctx, req)
+	if err != nil {
+		return models.Gradebook{}, nil, err
+	}
+	rates, err := g.storage.Attendance().Rates(ctx, models.GetAllAttendanceRequest{GroupID: req.GroupID, From: req.From, To: req.To})
+	if err != nil {
+		return models.Gradebook{}, nil, err
+	}
+
+	book := models.Gradebook{
+		GroupID:  req.GroupID,
+		From:     req.From,
+		To:       req.To,
+		Tasks:    make([]models.GradebookColumn, len(tasks)),
+		Students: make([]models.GradebookStudentRow, len(students)),
+	}
+
+	byLesson := map[string]models.GradebookLesson{}
+	for _, lesson := range lessons {
+		byLesson[lesson.LessonID] = lesson
+	}
+	columns := map[string]int{}
+	for j, task := range tasks {
+		lesson := byLesson[task.LessonID]
+		book.Tasks[j] = models.GradebookColumn{GradebookTask: task, LessonTheme: lesson.Theme, LessonDate: lesson.Date}
+		columns[task.TaskID] = j
+	}
+
+	attendance := map[string]float64{}
+	for _, rate := range rates {
+		attendance[rate.StudentID] = withRate(rate).Rate
+	}
+	rows := map[string]int{}
+	for i, student := range students {
+		book.Students[i] = models.GradebookStudentRow{
+			GradebookStudent: student,
+			Scores:           make([]models.GradebookCell, len(tasks)),
+			Attendance:       attendance[student.StudentID],
+		}
+		rows[student.StudentID] = i
+	}
+
+	var (
+		rowSums    = make([]float64, len(students))
+		columnSums = make([]float64, len(tasks))
+	)
+	for _, score := range scores {
+		i, ok := rows[score.StudentID]
+		j, found := columns[score.TaskID]
+		if !ok || !found {
+			continue
+		}
+
+		row, column := &book.Students[i], &book.Tasks[j]
+		row.Scores[j] = models.GradebookCell{Submitted: true, Late: score.Late, Score: score.Score}
+		row.Submitted++
+		if share, ok := scoreShare(score.Score, column.MaxScore); ok {
+			row.Graded++
+			rowSums[i] += share
+			column.Graded++
+			columnSums[j] += share
+		}
+	}
+	for i := range book.Students {
+		book.Students[i].Average = mean(rowSums[i], book.Students[i].Graded)
+	}
+	for j := range book.Tasks {
+		book.Tasks[j].Average = mean(columnSums[j], book.Tasks[j].Graded)
+	}
+
+	return book, lessons, nil
+}
+
+// scoreShare returns the share of the max score a graded submission got, a
+// task without a max score can't be counted.
+func scoreShare(score *int, maxScore int) (float64, bool) {
+	if score == nil || maxScore <= 0 {
+		return 0, false
+	}
+	return float64(*score) / float64(maxScore), true
+}
+
+// mean returns the mean of n values summing up to sum rounded to two
+// decimals, 0 without values.
+func mean(sum float64, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return round2(sum / float64(n))
+}
+
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
+
+// trend compares the mean of the later half of the shares with the mean of
+// the earlier half, the middle one of an odd number is left out.
+func trend(shares []float64) string {
+	half := len(shares) / 2
+	if half == 0 {
+		return models.TrendSteady
+	}
+
+	var earlier, later float64
+	for i := 0; i < half; i++ {
+		earlier += shares[i]
+		later += shares[len(shares)-half+i]
+	}
+
+	switch diff := (later - earlier) / float64(half); {
+	case diff >= trendThreshold:
+		return models.TrendUp
+	case diff <= -trendThreshold:
+		return models.TrendDown
+	}
+	return models.TrendSteady
+}
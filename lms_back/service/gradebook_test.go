@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"lms_back/api/models"
+	"lms_back/config"
+	"testing"
+)
+
+func TestGradebook(t *testing.T) {
+	f := newFixture(t)
+	submissions := NewSubmissionService(f.store, f.groups, f.audit, f.log)
+	attendance := NewAttendanceService(f.store, f.groups, f.students, f.audit, f.log)
+	gradebook := NewGradebookService(f.store, f.groups, f.students, f.log)
+
+	lessons := []models.Lesson{f.lesson, f.newLesson("2024-03-11"), f.newLesson("2024-03-18")}
+	var tasks []models.Task
+	for _, lesson := range lessons[:2] {
+		for _, score := range []string{"10", "20"} {
+			task, err := f.store.Task().Create(f.ctx, models.Task{LessonId: lesson.Id, GroupId: f.group.Id, Task: "Task of " + lesson.From, Score: score})
+			f.check(err)
+			tasks = append(tasks, task)
+		}
+	}
+
+	ids := []string{f.newStudent("ali", f.group.Id).ID, f.newStudent("soli", f.group.Id).ID}
+	ali, soli := f.as(ids[0], config.STUDENT_ROLE), f.as(ids[1], config.STUDENT_ROLE)
+	teacher := f.as(f.teachers[0], config.TEACHER_ROLE)
+
+	// ali gets worse, soli submits only the first task
+	for i, score := range []int{10, 20, 5, 10} {
+		submission, err := submissions.Submit(ali, tasks[i].Id, models.SubmitTask{Answer: "done"})
+		f.check(err)
+		_, err = submissions.Grade(teacher, submission.Id, models.GradeSubmission{Score: &score})
+		f.check(err)
+	}
+	submission, err := submissions.Submit(soli, tasks[0].Id, models.SubmitTask{Answer: "done"})
+	f.check(err)
+	score := 8
+	_, err = submissions.Grade(teacher, submission.Id, models.GradeSubmission{Score: &score})
+	f.check(err)
+	for _, lesson := range lessons {
+		_, err = attendance.Mark(teacher, lesson.Id, models.MarkAttendanceRequest{Students: []models.MarkAttendance{
+			{StudentID: ids[0], Status: models.AttendancePresent},
+			{StudentID: ids[1], Status: models.AttendanceAbsent},
+		}})
+		f.check(err)
+	}
+
+	book, err := gradebook.Gradebook(teacher, models.GradebookRequest{GroupID: f.group.Id})
+	f.check(err)
+	if len(book.Tasks) != 4 || len(book.Students) != 2 || book.Tasks[0].TaskID != tasks[0].Id || book.Tasks[0].LessonTheme != "Lesson 2024-03-04" {
+		t.Fatalf("Gradebook() = %+v", book)
+	}
+	first, second := book.Students[0], book.Students[1]
+	if first.StudentID != ids[0] || first.Average != 0.75 || first.Attendance != 1 || first.Graded != 4 || first.Scores[2].Score == nil || *first.Scores[2].Score != 5 {
+		t.Errorf("Gradebook() row of ali = %+v", first)
+	}
+	if second.Average != 0.8 || second.Attendance != 0 || second.Submitted != 1 || second.Scores[1].Submitted {
+		t.Errorf("Gradebook() row of soli = %+v", second)
+	}
+	if book.Tasks[0].Average != 0.9 || book.Tasks[0].Graded != 2 || book.Tasks[3].Average != 0.5 {
+		t.Errorf("Gradebook() tasks = %+v", book.Tasks)
+	}
+
+	if _, err = gradebook.Progress(ali, ids[0], models.GradebookRequest{GroupID: f.otherGroup.Id}); !errors.Is(err, ErrNotEnrolled) {
+		t.Errorf("Progress() in another group error = %v, want ErrNotEnrolled", err)
+	}
+	progress, err := gradebook.Progress(ali, ids[0], models.GradebookRequest{})
+	f.check(err)
+	if progress.GroupID != f.group.Id || len(progress.Lessons) != 3 || progress.Completed != 3 || progress.Trend != models.TrendDown || progress.Rank != 2 || progress.GroupSize != 2 {
+		t.Errorf("Progress() of ali = %+v", progress)
+	}
+	progress, err = gradebook.Progress(soli, ids[1], models.GradebookRequest{})
+	f.check(err)
+	if progress.Completed != 0 || progress.Lessons[0].Submitted != 1 || progress.Lessons[2].Attendance != models.AttendanceAbsent || progress.Trend != models.TrendSteady || progress.Rank != 1 {
+		t.Errorf("Progress() of soli = %+v", progress)
+	}
+}
+
+func TestTrend(t *testing.T) {
+	tests := []struct {
+		shares []float64
+		want   string
+	}{
+		{nil, models.TrendSteady},
+		{[]float64{0.2}, models.TrendSteady},
+		{[]float64{0.5, 0.6}, models.TrendUp},
+		{[]float64{0.9, 0.1, 0.7}, models.TrendDown},
+		{[]float64{0.5, 0.6, 0.52, 0.58}, models.TrendSteady},
+	}
+	for _, tt := range tests {
+		if got := trend(tt.shares); got != tt.want {
+			t.Errorf("trend(%v) = %s, want %s", tt.shares, got, tt.want)
+		}
+	}
+}
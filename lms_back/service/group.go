@@ -9,19 +9,32 @@ import (
 
 type groupService struct {
 	storage storage.IStorage
+	audit   auditService
 	logger  logger.ILogger
 }
 
-func NewGroupService(storage storage.IStorage, logger logger.ILogger) groupService {
+func NewGroupService(storage storage.IStorage, audit auditService, logger logger.ILogger) groupService {
 	return groupService{
 		storage: storage,
+		audit:   audit,
 		logger:  logger,
 	}
 }
 
 func (u groupService) Create(ctx context.Context, group models.Group) (models.Group, error) {
 
-	pKey, err := u.storage.Group().Create(ctx, group)
+	if err := checkBranch(ctx, group.Branch_id); err != nil {
+		return models.Group{}, err
+	}
+
+	var pKey models.Group
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Group().Create(ctx, group); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityGroup, EntityID: pKey.Id, BranchID: pKey.Branch_id, Action: AuditActionCreate, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while creating car", logger.Error(err))
 		return models.Group{}, err
@@ -32,7 +45,22 @@ func (u groupService) Create(ctx context.Context, group models.Group) (models.Gr
 
 func (u groupService) Update(ctx context.Context, group models.Group) (models.Group, error) {
 
-	pKey, err := u.storage.Group().Update(ctx, group)
+	before, err := u.checkAccess(ctx, group.Id)
+	if err != nil {
+		return models.Group{}, err
+	}
+
+	var pKey models.Group
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if _, err := tx.Group().Update(ctx, group); err != nil {
+			return err
+		}
+		// only the type is updated, the rest of the group is read back
+		if pKey, err = tx.Group().GetByID(ctx, group.Id); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityGroup, EntityID: group.Id, BranchID: before.Branch_id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while updating group", logger.Error(err))
 		return models.Group{}, err
@@ -41,6 +69,29 @@ func (u groupService) Update(ctx context.Context, group models.Group) (models.Gr
 	return pKey, nil
 }
 
+func (u groupService) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Group, error) {
+
+	before, err := u.checkAccess(ctx, id)
+	if err != nil {
+		return models.Group{}, err
+	}
+
+	var pKey models.Group
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Group().Patch(ctx, id, version, patch); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityGroup, EntityID: id, BranchID: pKey.Branch_id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while patching group", logger.Error(err))
+		return models.Group{}, err
+	}
+
+	return pKey, nil
+}
+
 func (u groupService) GetByID(ctx context.Context, id string) (models.Group, error) {
 
 	pKey, err := u.storage.Group().GetByID(ctx, id)
@@ -49,11 +100,21 @@ func (u groupService) GetByID(ctx context.Context, id string) (models.Group, err
 		return models.Group{}, err
 	}
 
+	if err = checkBranch(ctx, pKey.Branch_id); err != nil {
+		return models.Group{}, err
+	}
+
 	return pKey, nil
 }
 
 func (u groupService) GetAll(ctx context.Context, req models.GetAllGroupsRequest) (models.GetAllGroupsResponse, error) {
 
+	if err := checkTrashAccess(ctx, req.Deleted); err != nil {
+		return models.GetAllGroupsResponse{}, err
+	}
+
+	req.BranchIDs = allowedBranches(ctx)
+
 	pKey, err := u.storage.Group().GetAll(ctx, req)
 	if err != nil {
 		u.logger.Error("ERROR in service layer while GetAll group", logger.Error(err))
@@ -63,9 +124,19 @@ func (u groupService) GetAll(ctx context.Context, req models.GetAllGroupsRequest
 	return pKey, nil
 }
 
-func (u groupService) Delete(ctx context.Context, id string) error {
+func (u groupService) Delete(ctx context.Context, id string, version int) error {
 
-	err := u.storage.Group().Delete(ctx, id)
+	before, err := u.checkAccess(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Group().Delete(ctx, id, version); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityGroup, EntityID: id, BranchID: before.Branch_id, Action: AuditActionDelete, Before: before})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while deleting group", logger.Error(err))
 		return err
@@ -73,3 +144,46 @@ func (u groupService) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+func (u groupService) Restore(ctx context.Context, id string) (models.Group, error) {
+
+	var after models.Group
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Group().Restore(ctx, id); err != nil {
+			return err
+		}
+
+		// the restored group is checked in the transaction, so it stays in the
+		// trash if it is out of the caller's branches
+		scoped := u
+		scoped.storage = tx
+
+		var err error
+		if after, err = scoped.checkAccess(ctx, id); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityGroup, EntityID: id, BranchID: after.Branch_id, Action: AuditActionRestore, After: after})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while restoring group", logger.Error(err))
+		return models.Group{}, err
+	}
+
+	return after, nil
+}
+
+// checkAccess returns the group, or ErrForbidden unless the caller may access
+// its branch and every one of the other branches.
+func (u groupService) checkAccess(ctx context.Context, id string, branchIDs ...string) (models.Group, error) {
+	group, err := u.storage.Group().GetByID(ctx, id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting group branch", logger.Error(err))
+		return models.Group{}, err
+	}
+
+	if err = checkBranch(ctx, append(branchIDs, group.Branch_id)...); err != nil {
+		return models.Group{}, err
+	}
+
+	return group, nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"lms_back/api/models"
+	"lms_back/config"
+	"lms_back/pkg/logger"
+	"lms_back/storage"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used for another request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+type idempotencyService struct {
+	cfg     config.Config
+	storage storage.IStorage
+	logger  logger.ILogger
+}
+
+func NewIdempotencyService(cfg config.Config, storage storage.IStorage, logger logger.ILogger) idempotencyService {
+	return idempotencyService{
+		cfg:     cfg,
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// Start claims the key for the request of the caller. If the key was used
+// before for the same request, the stored response is returned with true and
+// should be replayed instead of running the request again.
+func (i idempotencyService) Start(ctx context.Context, key models.IdempotencyKey, body []byte) (models.IdempotencyKey, bool, error) {
+	key.UserID = idempotencyUserID(ctx)
+	key.RequestHash = hashRequest(key.Method, key.Path, body)
+
+	created, err := i.storage.Idempotency().Create(ctx, key, i.cfg.IdempotencyKeyTTL, i.cfg.IdempotencyKeyLease)
+	if err != nil {
+		i.logger.Error("ERROR in service layer while creating idempotency key", logger.Error(err))
+		return models.IdempotencyKey{}, false, err
+	}
+	if created {
+		return key, false, nil
+	}
+
+	stored, err := i.storage.Idempotency().Get(ctx, key.UserID, key.Key)
+	if err != nil {
+		// the first request failed and released the key in the meantime
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.IdempotencyKey{}, false, ErrIdempotencyKeyInProgress
+		}
+		i.logger.Error("ERROR in service layer while getting idempotency key", logger.Error(err))
+		return models.IdempotencyKey{}, false, err
+	}
+
+	if stored.RequestHash != key.RequestHash {
+		return models.IdempotencyKey{}, false, ErrIdempotencyKeyReused
+	}
+	if stored.StatusCode == 0 {
+		return models.IdempotencyKey{}, false, ErrIdempotencyKeyInProgress
+	}
+
+	return stored, true, nil
+}
+
+// Complete stores the response of the request, which is replayed for the key from now on.
+func (i idempotencyService) Complete(ctx context.Context, key models.IdempotencyKey) error {
+	if err := i.storage.Idempotency().Complete(ctx, key); err != nil {
+		i.logger.Error("ERROR in service layer while completing idempotency key", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// Release forgets the key of a request that failed, so it can be retried.
+func (i idempotencyService) Release(ctx context.Context, key models.IdempotencyKey) error {
+	if err := i.storage.Idempotency().Delete(ctx, key.UserID, key.Key); err != nil {
+		i.logger.Error("ERROR in service layer while releasing idempotency key", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// idempotencyUserID scopes keys to the caller, so users can't replay each other's responses.
+func idempotencyUserID(ctx context.Context) string {
+	authInfo, ok := AuthInfoFromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	return authInfo.UserID
+}
+
+func hashRequest(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
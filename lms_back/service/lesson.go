@@ -9,19 +9,28 @@ import (
 
 type lessonService struct {
 	storage storage.IStorage
+	audit   auditService
 	logger  logger.ILogger
 }
 
-func NewLessonService(storage storage.IStorage, logger logger.ILogger) lessonService {
+func NewLessonService(storage storage.IStorage, audit auditService, logger logger.ILogger) lessonService {
 	return lessonService{
 		storage: storage,
+		audit:   audit,
 		logger:  logger,
 	}
 }
 
 func (u lessonService) Create(ctx context.Context, lesson models.Lesson) (models.Lesson, error) {
 
-	pKey, err := u.storage.Lesson().Create(ctx, lesson)
+	var pKey models.Lesson
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Lesson().Create(ctx, lesson); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityLesson, EntityID: pKey.Id, Action: AuditActionCreate, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while creating lesson", logger.Error(err))
 		return models.Lesson{}, err
@@ -32,7 +41,20 @@ func (u lessonService) Create(ctx context.Context, lesson models.Lesson) (models
 
 func (u lessonService) Update(ctx context.Context, lesson models.Lesson) (models.Lesson, error) {
 
-	pKey, err := u.storage.Lesson().Update(ctx, lesson)
+	before, err := u.storage.Lesson().GetByID(ctx, lesson.Id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting lesson before update", logger.Error(err))
+		return models.Lesson{}, err
+	}
+
+	var pKey models.Lesson
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Lesson().Update(ctx, lesson); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityLesson, EntityID: lesson.Id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while updating lesson", logger.Error(err))
 		return models.Lesson{}, err
@@ -41,6 +63,30 @@ func (u lessonService) Update(ctx context.Context, lesson models.Lesson) (models
 	return pKey, nil
 }
 
+func (u lessonService) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Lesson, error) {
+
+	before, err := u.storage.Lesson().GetByID(ctx, id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting lesson before patch", logger.Error(err))
+		return models.Lesson{}, err
+	}
+
+	var pKey models.Lesson
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Lesson().Patch(ctx, id, version, patch); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityLesson, EntityID: id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while patching lesson", logger.Error(err))
+		return models.Lesson{}, err
+	}
+
+	return pKey, nil
+}
+
 func (u lessonService) GetByID(ctx context.Context, id string) (models.Lesson, error) {
 
 	pKey, err := u.storage.Lesson().GetByID(ctx, id)
@@ -54,6 +100,10 @@ func (u lessonService) GetByID(ctx context.Context, id string) (models.Lesson, e
 
 func (u lessonService) GetAll(ctx context.Context, req models.GetAllLessonsRequest) (models.GetAllLessonsResponse, error) {
 
+	if err := checkTrashAccess(ctx, req.Deleted); err != nil {
+		return models.GetAllLessonsResponse{}, err
+	}
+
 	pKey, err := u.storage.Lesson().GetAll(ctx, req)
 	if err != nil {
 		u.logger.Error("ERROR in service layer while GetAll lesson", logger.Error(err))
@@ -63,9 +113,20 @@ func (u lessonService) GetAll(ctx context.Context, req models.GetAllLessonsReque
 	return pKey, nil
 }
 
-func (u lessonService) Delete(ctx context.Context, id string) error {
+func (u lessonService) Delete(ctx context.Context, id string, version int) error {
+
+	before, err := u.storage.Lesson().GetByID(ctx, id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting lesson before delete", logger.Error(err))
+		return err
+	}
 
-	err := u.storage.Lesson().Delete(ctx, id)
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Lesson().Delete(ctx, id, version); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityLesson, EntityID: id, Action: AuditActionDelete, Before: before})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while deleting lesson", logger.Error(err))
 		return err
@@ -73,3 +134,25 @@ func (u lessonService) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+func (u lessonService) Restore(ctx context.Context, id string) (models.Lesson, error) {
+
+	var after models.Lesson
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Lesson().Restore(ctx, id); err != nil {
+			return err
+		}
+
+		var err error
+		if after, err = tx.Lesson().GetByID(ctx, id); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityLesson, EntityID: id, Action: AuditActionRestore, After: after})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while restoring lesson", logger.Error(err))
+		return models.Lesson{}, err
+	}
+
+	return after, nil
+}
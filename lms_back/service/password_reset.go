@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"lms_back/api/models"
+	"lms_back/config"
+	"lms_back/pkg/errs"
+	"lms_back/pkg/logger"
+	"lms_back/pkg/notify"
+	"lms_back/pkg/password"
+	"lms_back/storage"
+	"math/big"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidResetCode = errors.New("reset code is invalid or expired")
+	ErrWeakPassword     = errs.Validation("", errs.FieldError{Field: "new_password", Message: "must be at least 6 characters long"})
+	// ErrWrongOldPassword is returned by the password changes that check the old password.
+	ErrWrongOldPassword = errs.Validation("", errs.FieldError{Field: "old_password", Message: "is incorrect"})
+)
+
+func updatePassword(ctx context.Context, st storage.IStorage, role, id, hashedPassword string) error {
+	switch role {
+	case config.ADMIN_ROLE:
+		return st.Admin().UpdatePassword(ctx, id, hashedPassword)
+	case config.TEACHER_ROLE:
+		return st.Teacher().UpdatePassword(ctx, id, hashedPassword)
+	case config.STUDENT_ROLE:
+		return st.Student().UpdatePassword(ctx, id, hashedPassword)
+	}
+
+	return fmt.Errorf("unknown role %q", role)
+}
+
+// ForgotPassword sends a one-time reset code to the user's email. It does not
+// report whether the account exists, so the endpoint can't be used to probe logins.
+func (a authService) ForgotPassword(ctx context.Context, req models.ForgotPasswordRequest) error {
+	user, err := a.getCredentials(ctx, req.Role, req.Login)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		a.log.Error("error while getting credentials for password reset", logger.Error(err))
+		return err
+	}
+
+	if user.Email == "" {
+		a.log.Warning("password reset requested for a user without email", logger.String("user_id", user.ID))
+		return nil
+	}
+
+	code, err := generateResetCode()
+	if err != nil {
+		a.log.Error("error while generating password reset code", logger.Error(err))
+		return err
+	}
+
+	codeHash, err := password.HashPassword(code)
+	if err != nil {
+		a.log.Error("error while hashing password reset code", logger.Error(err))
+		return err
+	}
+
+	if _, err = a.storage.PasswordReset().Create(ctx, models.PasswordReset{
+		UserID:   user.ID,
+		UserRole: req.Role,
+		CodeHash: codeHash,
+	}, a.cfg.PasswordResetCodeTTL); err != nil {
+		a.log.Error("error while saving password reset code", logger.Error(err))
+		return err
+	}
+
+	if err = a.notifier.Send(ctx, notify.Message{
+		To:      user.Email,
+		Subject: "Password reset code",
+		Body:    fmt.Sprintf("Your password reset code is %s. It expires in %s.", code, a.cfg.PasswordResetCodeTTL),
+	}); err != nil {
+		a.log.Error("error while sending password reset code", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (a authService) ResetPassword(ctx context.Context, req models.ResetPasswordRequest) error {
+	if len(req.NewPassword) < 6 {
+		return ErrWeakPassword
+	}
+
+	user, err := a.getCredentials(ctx, req.Role, req.Login)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidResetCode
+		}
+		a.log.Error("error while getting credentials for password reset", logger.Error(err))
+		return err
+	}
+
+	reset, err := a.storage.PasswordReset().GetActive(ctx, user.ID, req.Role)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrInvalidResetCode
+		}
+		a.log.Error("error while getting password reset code", logger.Error(err))
+		return err
+	}
+
+	if reset.Attempts >= a.cfg.PasswordResetMaxAttempts {
+		return ErrInvalidResetCode
+	}
+
+	if err = password.CompareHashAndPassword(reset.CodeHash, req.Code); err != nil {
+		if err = a.storage.PasswordReset().IncrementAttempts(ctx, reset.Id); err != nil {
+			a.log.Error("error while counting password reset attempt", logger.Error(err))
+		}
+		return ErrInvalidResetCode
+	}
+
+	// the code is consumed first, so two concurrent requests can't both use it
+	used, err := a.storage.PasswordReset().MarkUsed(ctx, reset.Id)
+	if err != nil {
+		a.log.Error("error while marking password reset code as used", logger.Error(err))
+		return err
+	}
+	if !used {
+		return ErrInvalidResetCode
+	}
+
+	hashedPass, err := password.HashPassword(req.NewPassword)
+	if err != nil {
+		a.log.Error("error while hashing new password", logger.Error(err))
+		return err
+	}
+
+	err = a.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := updatePassword(ctx, tx, req.Role, user.ID, hashedPass); err != nil {
+			return err
+		}
+		return a.audit.write(ctx, tx, auditEntry{EntityType: req.Role, EntityID: user.ID, Action: AuditActionResetPassword})
+	})
+	if err != nil {
+		a.log.Error("error while updating password", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// generateResetCode returns a random 6 digit code.
+func generateResetCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"encoding/json"
+	"lms_back/api/models"
+)
+
+// patchedIDs returns the ids the patch sets the fields to, like the new branch
+// of a payment. Values that are not strings are left to the storage to reject.
+func patchedIDs(patch models.Patch, fields ...string) []string {
+	var ids []string
+	for _, field := range fields {
+		var id string
+		if err := json.Unmarshal(patch[field], &id); err == nil && id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -3,64 +3,85 @@ package service
 import (
 	"context"
 	"lms_back/api/models"
+	"lms_back/pkg/errs"
 	"lms_back/pkg/logger"
 	"lms_back/storage"
-
-	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/status"
 )
 
+var ErrStudentOfOtherBranch = errs.Validation("", errs.FieldError{Field: "student_id", Message: "must be a student of the branch of the payment"})
+
 type paymentService struct {
 	storage storage.IStorage
+	audit   auditService
 	logger  logger.ILogger
 }
 
-func NewPaymentService(storage storage.IStorage, logger logger.ILogger) paymentService {
+func NewPaymentService(storage storage.IStorage, audit auditService, logger logger.ILogger) paymentService {
 	return paymentService{
 		storage: storage,
+		audit:   audit,
 		logger:  logger,
 	}
 }
 
 func (u paymentService) Create(ctx context.Context, payment models.CreatePayment) (resp models.Payment, err error) {
 
-	pKey, err := u.storage.Payment().Create(ctx, payment)
-
-	if err != nil {
-
-		return models.Payment{}, status.Error(codes.InvalidArgument, err.Error())
+	if err := checkBranch(ctx, payment.Branch_id); err != nil {
+		return models.Payment{}, err
 	}
 
-	if payment.Student_id != "" && payment.Price > 0 {
-		student, err := u.storage.Student().GetByID(ctx, pKey.Student_id)
-		if err != nil {
-			return models.Payment{}, status.Error(codes.InvalidArgument, err.Error())
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := u.checkStudent(ctx, tx, payment.Student_id, payment.Branch_id); err != nil {
+			return err
 		}
 
-		student.PaidSum += payment.Price
-
-		_, err = u.storage.Student().Update(ctx, models.Student{
-			ID:      payment.Student_id,
-			PaidSum: student.PaidSum,
-			GroupID: student.GroupID,
-			Status:  student.Status,
-		})
-
+		pKey, err := tx.Payment().Create(ctx, payment)
 		if err != nil {
-			return models.Payment{}, status.Error(codes.InvalidArgument, err.Error())
+			return err
+		}
+
+		if err = u.updateBalances(ctx, tx, models.Payment{}, pKey); err != nil {
+			return err
 		}
-	}
 
-	resp, err = u.storage.Payment().GetByID(ctx, pKey.Id)
+		if resp, err = tx.Payment().GetByID(ctx, pKey.Id); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityPayment, EntityID: resp.Id, BranchID: resp.Branch_id, Action: AuditActionCreate, After: resp})
+	})
 	if err != nil {
-		return models.Payment{}, status.Error(codes.InvalidArgument, err.Error())
+		u.logger.Error("ERROR in service layer while creating payment", logger.Error(err))
+		return models.Payment{}, err
 	}
+
 	return
 }
 
 func (u paymentService) Update(ctx context.Context, payment models.Payment) (models.Payment, error) {
 
-	pKey, err := u.storage.Payment().Update(ctx, payment)
+	var before, pKey models.Payment
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if before, err = u.checkAccess(ctx, tx, payment.Id, payment.Branch_id); err != nil {
+			return err
+		}
+
+		if _, err = tx.Payment().Update(ctx, payment); err != nil {
+			return err
+		}
+		// the update returns the request, the row has the times of the payment
+		if pKey, err = tx.Payment().GetByID(ctx, payment.Id); err != nil {
+			return err
+		}
+		if err = u.checkStudent(ctx, tx, pKey.Student_id, pKey.Branch_id); err != nil {
+			return err
+		}
+
+		if err = u.updateBalances(ctx, tx, before, pKey); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityPayment, EntityID: payment.Id, BranchID: pKey.Branch_id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while updating payment", logger.Error(err))
 		return models.Payment{}, err
@@ -69,6 +90,35 @@ func (u paymentService) Update(ctx context.Context, payment models.Payment) (mod
 	return pKey, nil
 }
 
+func (u paymentService) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Payment, error) {
+
+	var before, pKey models.Payment
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if before, err = u.checkAccess(ctx, tx, id, patchedIDs(patch, "branch_id")...); err != nil {
+			return err
+		}
+
+		if pKey, err = tx.Payment().Patch(ctx, id, version, patch); err != nil {
+			return err
+		}
+		if err = u.checkStudent(ctx, tx, pKey.Student_id, pKey.Branch_id); err != nil {
+			return err
+		}
+
+		if err = u.updateBalances(ctx, tx, before, pKey); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityPayment, EntityID: id, BranchID: pKey.Branch_id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while patching payment", logger.Error(err))
+		return models.Payment{}, err
+	}
+
+	return pKey, nil
+}
+
 func (u paymentService) GetByID(ctx context.Context, id string) (models.Payment, error) {
 
 	pKey, err := u.storage.Payment().GetByID(ctx, id)
@@ -77,11 +127,21 @@ func (u paymentService) GetByID(ctx context.Context, id string) (models.Payment,
 		return models.Payment{}, err
 	}
 
+	if err = checkBranch(ctx, pKey.Branch_id); err != nil {
+		return models.Payment{}, err
+	}
+
 	return pKey, nil
 }
 
 func (u paymentService) GetAll(ctx context.Context, req models.GetAllPaymentsRequest) (models.GetAllPaymentsResponse, error) {
 
+	if err := checkTrashAccess(ctx, req.Deleted); err != nil {
+		return models.GetAllPaymentsResponse{}, err
+	}
+
+	req.BranchIDs = allowedBranches(ctx)
+
 	pKey, err := u.storage.Payment().GetAll(ctx, req)
 	if err != nil {
 		u.logger.Error("ERROR in service layer while GetAll payment", logger.Error(err))
@@ -91,9 +151,24 @@ func (u paymentService) GetAll(ctx context.Context, req models.GetAllPaymentsReq
 	return pKey, nil
 }
 
-func (u paymentService) Delete(ctx context.Context, id string) error {
+func (u paymentService) Delete(ctx context.Context, id string, version int) error {
 
-	err := u.storage.Payment().Delete(ctx, id)
+	var before models.Payment
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if before, err = u.checkAccess(ctx, tx, id); err != nil {
+			return err
+		}
+
+		if err = tx.Payment().Delete(ctx, id, version); err != nil {
+			return err
+		}
+
+		if err = u.updateBalances(ctx, tx, before, models.Payment{}); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityPayment, EntityID: id, BranchID: before.Branch_id, Action: AuditActionDelete, Before: before})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while deleting payment", logger.Error(err))
 		return err
@@ -101,3 +176,88 @@ func (u paymentService) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+// Restore takes the payment out of the trash and adds it to the paid sum of
+// the student again.
+func (u paymentService) Restore(ctx context.Context, id string) (models.Payment, error) {
+
+	var after models.Payment
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Payment().Restore(ctx, id); err != nil {
+			return err
+		}
+
+		var err error
+		if after, err = u.checkAccess(ctx, tx, id); err != nil {
+			return err
+		}
+
+		if err = u.updateBalances(ctx, tx, models.Payment{}, after); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityPayment, EntityID: id, BranchID: after.Branch_id, Action: AuditActionRestore, After: after})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while restoring payment", logger.Error(err))
+		return models.Payment{}, err
+	}
+
+	return after, nil
+}
+
+// updateBalances takes the price of the previous payment back from its student and
+// adds the price of the current one to theirs. An empty payment stands for a
+// created or deleted one.
+func (u paymentService) updateBalances(ctx context.Context, tx storage.IStorage, previous, current models.Payment) error {
+	if previous.Student_id != "" && previous.Price != 0 {
+		if err := tx.Student().AddPaidSum(ctx, previous.Student_id, -previous.Price); err != nil {
+			return err
+		}
+	}
+
+	if current.Student_id != "" && current.Price != 0 {
+		if err := tx.Student().AddPaidSum(ctx, current.Student_id, current.Price); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkAccess locks and returns the payment, or ErrForbidden unless the caller
+// may access its branch and every one of the other branches.
+func (u paymentService) checkAccess(ctx context.Context, tx storage.IStorage, id string, branchIDs ...string) (models.Payment, error) {
+	payment, err := tx.Payment().GetByIDForUpdate(ctx, id)
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	if err = checkBranch(ctx, append(branchIDs, payment.Branch_id)...); err != nil {
+		return models.Payment{}, err
+	}
+
+	return payment, nil
+}
+
+// checkStudent returns ErrStudentOfOtherBranch unless the primary group of the
+// student is in the branch, so a payment can't move the balance of a student
+// of another branch.
+func (u paymentService) checkStudent(ctx context.Context, tx storage.IStorage, studentID, branchID string) error {
+	student, err := tx.Student().GetByID(ctx, studentID)
+	if err != nil {
+		return err
+	}
+	if student.GroupID == "" {
+		return ErrStudentOfOtherBranch
+	}
+
+	group, err := tx.Group().GetByID(ctx, student.GroupID)
+	if err != nil {
+		return err
+	}
+	if group.Branch_id != branchID {
+		return ErrStudentOfOtherBranch
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"lms_back/api/models"
+	"lms_back/config"
+	"testing"
+)
+
+func TestPaymentStudentBranch(t *testing.T) {
+	f := newFixture(t)
+	payments := NewPaymentService(f.store, f.audit, f.log)
+
+	otherBranch, err := f.store.Branch().Create(f.ctx, models.Branch{Name: "Yunusobod"})
+	f.check(err)
+	otherGroup, err := f.store.Group().Create(f.ctx, models.Group{Branch_id: otherBranch.Id, Teacher_id: f.teachers[1], Type: "backend"})
+	f.check(err)
+
+	ali, soli := f.newStudent("ali", f.group.Id), f.newStudent("soli", otherGroup.Id)
+	branchAdmin, err := f.store.Admin().Create(f.ctx, models.Admin{Full_Name: "olim", Status: "active", Login: "olim", Role: config.ADMIN_ROLE, BranchIDs: []string{f.branch.Id}})
+	f.check(err)
+	admin := ContextWithAuthInfo(f.ctx, models.AuthInfo{UserID: branchAdmin.Id, UserRole: config.ADMIN_ROLE, BranchIDs: []string{f.branch.Id}})
+
+	if _, err = payments.Create(admin, models.CreatePayment{Price: 100, Student_id: soli.ID, Branch_id: f.branch.Id, Admin_id: branchAdmin.Id}); !errors.Is(err, ErrStudentOfOtherBranch) {
+		t.Errorf("Create() for a student of another branch error = %v, want ErrStudentOfOtherBranch", err)
+	}
+
+	payment, err := payments.Create(admin, models.CreatePayment{Price: 100, Student_id: ali.ID, Branch_id: f.branch.Id, Admin_id: branchAdmin.Id})
+	f.check(err)
+
+	studentID, _ := json.Marshal(soli.ID)
+	if _, err = payments.Patch(admin, payment.Id, payment.Version, models.Patch{"student_id": studentID}); !errors.Is(err, ErrStudentOfOtherBranch) {
+		t.Errorf("Patch() to a student of another branch error = %v, want ErrStudentOfOtherBranch", err)
+	}
+
+	updated, err := payments.Update(admin, models.Payment{Id: payment.Id, Price: 150, Student_id: ali.ID, Branch_id: f.branch.Id, Admin_id: branchAdmin.Id, Version: payment.Version})
+	f.check(err)
+	if updated.CreatedAt != payment.CreatedAt || updated.UpdatedAt == "" {
+		t.Errorf("Update() = %+v, want the times of the row", updated)
+	}
+
+	for _, student := range []models.GetStudent{ali, soli} {
+		got, err := f.store.Student().GetByID(f.ctx, student.ID)
+		f.check(err)
+		if want := map[string]float64{ali.ID: 150}[student.ID]; got.PaidSum != want {
+			t.Errorf("paid sum of %s = %v, want %v", student.Login, got.PaidSum, want)
+		}
+	}
+}
@@ -9,19 +9,32 @@ import (
 
 type scheduleService struct {
 	storage storage.IStorage
+	audit   auditService
 	logger  logger.ILogger
 }
 
-func NewScheduleService(storage storage.IStorage, logger logger.ILogger) scheduleService {
+func NewScheduleService(storage storage.IStorage, audit auditService, logger logger.ILogger) scheduleService {
 	return scheduleService{
 		storage: storage,
+		audit:   audit,
 		logger:  logger,
 	}
 }
 
 func (u scheduleService) Create(ctx context.Context, schedule models.Schedule) (models.Schedule, error) {
 
-	pKey, err := u.storage.Schedule().Create(ctx, schedule)
+	if err := checkBranch(ctx, schedule.Branch_id); err != nil {
+		return models.Schedule{}, err
+	}
+
+	var pKey models.Schedule
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Schedule().Create(ctx, schedule); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntitySchedule, EntityID: pKey.Id, BranchID: pKey.Branch_id, Action: AuditActionCreate, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while creating schedule", logger.Error(err))
 		return models.Schedule{}, err
@@ -32,11 +45,47 @@ func (u scheduleService) Create(ctx context.Context, schedule models.Schedule) (
 
 func (u scheduleService) Update(ctx context.Context, schedule models.Schedule) (models.Schedule, error) {
 
-	pKey, err := u.storage.Schedule().Update(ctx, schedule)
+	before, err := u.checkAccess(ctx, schedule.Id, schedule.Branch_id)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	var pKey models.Schedule
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Schedule().Update(ctx, schedule); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntitySchedule, EntityID: schedule.Id, BranchID: pKey.Branch_id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while updating schedule", logger.Error(err))
 		return models.Schedule{}, err
 	}
+
+	return pKey, nil
+}
+
+func (u scheduleService) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Schedule, error) {
+
+	before, err := u.checkAccess(ctx, id, patchedIDs(patch, "branch_id")...)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	var pKey models.Schedule
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Schedule().Patch(ctx, id, version, patch); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntitySchedule, EntityID: id, BranchID: pKey.Branch_id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while patching schedule", logger.Error(err))
+		return models.Schedule{}, err
+	}
+
 	return pKey, nil
 }
 
@@ -48,11 +97,21 @@ func (u scheduleService) GetByID(ctx context.Context, id string) (models.Schedul
 		return models.Schedule{}, err
 	}
 
+	if err = checkBranch(ctx, pKey.Branch_id); err != nil {
+		return models.Schedule{}, err
+	}
+
 	return pKey, nil
 }
 
 func (u scheduleService) GetAll(ctx context.Context, req models.GetAllSchedulesRequest) (models.GetAllSchedulesResponse, error) {
 
+	if err := checkTrashAccess(ctx, req.Deleted); err != nil {
+		return models.GetAllSchedulesResponse{}, err
+	}
+
+	req.BranchIDs = allowedBranches(ctx)
+
 	pKey, err := u.storage.Schedule().GetAll(ctx, req)
 	if err != nil {
 		u.logger.Error("ERROR in service layer while GetAll schedule", logger.Error(err))
@@ -62,9 +121,19 @@ func (u scheduleService) GetAll(ctx context.Context, req models.GetAllSchedulesR
 	return pKey, nil
 }
 
-func (u scheduleService) Delete(ctx context.Context, id string) error {
+func (u scheduleService) Delete(ctx context.Context, id string, version int) error {
+
+	before, err := u.checkAccess(ctx, id)
+	if err != nil {
+		return err
+	}
 
-	err := u.storage.Schedule().Delete(ctx, id)
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Schedule().Delete(ctx, id, version); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntitySchedule, EntityID: id, BranchID: before.Branch_id, Action: AuditActionDelete, Before: before})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while deleting schedule", logger.Error(err))
 		return err
@@ -72,3 +141,44 @@ func (u scheduleService) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+func (u scheduleService) Restore(ctx context.Context, id string) (models.Schedule, error) {
+
+	var after models.Schedule
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Schedule().Restore(ctx, id); err != nil {
+			return err
+		}
+
+		scoped := u
+		scoped.storage = tx
+
+		var err error
+		if after, err = scoped.checkAccess(ctx, id); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntitySchedule, EntityID: id, BranchID: after.Branch_id, Action: AuditActionRestore, After: after})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while restoring schedule", logger.Error(err))
+		return models.Schedule{}, err
+	}
+
+	return after, nil
+}
+
+// checkAccess returns the schedule, or ErrForbidden unless the caller may access
+// its branch and every one of the other branches.
+func (u scheduleService) checkAccess(ctx context.Context, id string, branchIDs ...string) (models.Schedule, error) {
+	schedule, err := u.storage.Schedule().GetByID(ctx, id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting schedule branch", logger.Error(err))
+		return models.Schedule{}, err
+	}
+
+	if err = checkBranch(ctx, append(branchIDs, schedule.Branch_id)...); err != nil {
+		return models.Schedule{}, err
+	}
+
+	return schedule, nil
+}
@@ -1,7 +1,9 @@
 package service
 
 import (
+	"lms_back/config"
 	"lms_back/pkg/logger"
+	"lms_back/pkg/notify"
 	"lms_back/storage"
 )
 
@@ -15,7 +17,14 @@ type IServiceManager interface {
 	Student() studentService
 	Task() taskService
 	Teacher() teacherService
+	Enrollment() enrollmentService
+	Attendance() attendanceService
+	Submission() submissionService
+	Gradebook() gradebookService
 	Auth() authService
+	Audit() auditService
+	Idempotency() idempotencyService
+	Trash() trashService
 }
 
 type Service struct {
@@ -29,24 +38,44 @@ type Service struct {
 	taskService     taskService
 	teacherService  teacherService
 	authService     authService
+	auditService    auditService
+
+	enrollmentService  enrollmentService
+	attendanceService  attendanceService
+	submissionService  submissionService
+	gradebookService   gradebookService
+	idempotencyService idempotencyService
+	trashService       trashService
 
 	logger logger.ILogger
 }
 
-func New(storage storage.IStorage, log logger.ILogger) Service {
+func New(cfg config.Config, storage storage.IStorage, notifier notify.Notifier, log logger.ILogger) Service {
+	audit := NewAuditService(storage, log)
+	group := NewGroupService(storage, audit, log)
+	student := NewStudentService(storage, audit, log)
+
 	return Service{
-		adminService:    NewAdminService(storage, log),
-		branchService:   NewBranchService(storage, log),
-		groupService:    NewGroupService(storage, log),
-		paymentService:  NewPaymentService(storage, log),
-		scheduleService: NewScheduleService(storage, log),
-		studentService:  NewStudentService(storage, log),
-		taskService:     NewTaskService(storage, log),
-		lessonService:   NewLessonService(storage, log),
-		teacherService:  NewTeacherService(storage, log),
-
-		authService:     NewAuthService(storage, log),
-		logger:          log,
+		adminService:    NewAdminService(storage, audit, log),
+		branchService:   NewBranchService(storage, audit, log),
+		groupService:    group,
+		paymentService:  NewPaymentService(storage, audit, log),
+		scheduleService: NewScheduleService(storage, audit, log),
+		studentService:  student,
+		taskService:     NewTaskService(storage, audit, log),
+		lessonService:   NewLessonService(storage, audit, log),
+		teacherService:  NewTeacherService(storage, audit, log),
+
+		authService:  NewAuthService(cfg, storage, audit, notifier, log),
+		auditService: audit,
+
+		enrollmentService:  NewEnrollmentService(storage, student, audit, log),
+		attendanceService:  NewAttendanceService(storage, group, student, audit, log),
+		submissionService:  NewSubmissionService(storage, group, audit, log),
+		gradebookService:   NewGradebookService(storage, group, student, log),
+		idempotencyService: NewIdempotencyService(cfg, storage, log),
+		trashService:       NewTrashService(cfg, storage, log),
+		logger:             log,
 	}
 }
 
@@ -86,6 +115,34 @@ func (s Service) Teacher() teacherService {
 	return s.teacherService
 }
 
+func (s Service) Enrollment() enrollmentService {
+	return s.enrollmentService
+}
+
+func (s Service) Attendance() attendanceService {
+	return s.attendanceService
+}
+
+func (s Service) Submission() submissionService {
+	return s.submissionService
+}
+
+func (s Service) Gradebook() gradebookService {
+	return s.gradebookService
+}
+
 func (s Service) Auth() authService {
 	return s.authService
 }
+
+func (s Service) Audit() auditService {
+	return s.auditService
+}
+
+func (s Service) Idempotency() idempotencyService {
+	return s.idempotencyService
+}
+
+func (s Service) Trash() trashService {
+	return s.trashService
+}
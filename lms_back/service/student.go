@@ -12,17 +12,19 @@ import (
 
 type studentService struct {
 	storage storage.IStorage
+	audit   auditService
 	logger  logger.ILogger
 }
 
-func NewStudentService(storage storage.IStorage, logger logger.ILogger) studentService {
+func NewStudentService(storage storage.IStorage, audit auditService, logger logger.ILogger) studentService {
 	return studentService{
 		storage: storage,
+		audit:   audit,
 		logger:  logger,
 	}
 }
 
-func (s studentService) Login(ctx context.Context, req models.StudentLoginRequest) (string, error) {
+func (s studentService) Login(ctx context.Context, req models.LoginRequest) (string, error) {
 
 	hashedPswd, err := s.storage.Student().GetPassword(ctx, req.Login)
 	if err != nil {
@@ -38,33 +40,110 @@ func (s studentService) Login(ctx context.Context, req models.StudentLoginReques
 	return "Login successfully", nil
 }
 
-func (u studentService) Create(ctx context.Context, student models.Student) (models.Student, error) {
+func (u studentService) Create(ctx context.Context, student models.Student) (models.GetStudent, error) {
 
-	pKey, err := u.storage.Student().Create(ctx, student)
+	branchID, err := u.checkGroupBranch(ctx, student.GroupID)
+	if err != nil {
+		return models.GetStudent{}, err
+	}
+	// a new student has paid nothing yet, payments add to the paid sum
+	student.PaidSum = 0
+
+	var pKey models.GetStudent
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Student().Create(ctx, student); err != nil {
+			return err
+		}
+		if err = moveEnrollment(ctx, tx, pKey.ID, "", pKey.GroupID); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityStudent, EntityID: pKey.ID, BranchID: branchID, Action: AuditActionCreate, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while creating student", logger.Error(err))
-		return models.Student{}, err
+		return models.GetStudent{}, err
 	}
 
 	return pKey, nil
 }
 
-func (u studentService) Update(ctx context.Context, student models.Student) (models.Student, error) {
+func (u studentService) Update(ctx context.Context, student models.Student) (models.GetStudent, error) {
+
+	before, _, err := u.checkAccess(ctx, student.ID)
+	if err != nil {
+		return models.GetStudent{}, err
+	}
+	branchID, err := u.checkGroupBranch(ctx, student.GroupID)
+	if err != nil {
+		return models.GetStudent{}, err
+	}
+	// only payments change the paid sum, the version check makes sure it is
+	// still the one read
+	student.PaidSum = before.PaidSum
 
-	pKey, err := u.storage.Student().Update(ctx, student)
+	var pKey models.GetStudent
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Student().Update(ctx, student); err != nil {
+			return err
+		}
+		if err = moveEnrollment(ctx, tx, student.ID, before.GroupID, pKey.GroupID); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityStudent, EntityID: student.ID, BranchID: branchID, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while updating student", logger.Error(err))
-		return models.Student{}, err
+		return models.GetStudent{}, err
+	}
+
+	return pKey, nil
+}
+
+// Patch checks the new group of the student as well if the patch moves it.
+// Like Create and Update it moves the enrollment of the primary group along.
+func (u studentService) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.GetStudent, error) {
+
+	before, branchID, err := u.checkAccess(ctx, id)
+	if err != nil {
+		return models.GetStudent{}, err
+	}
+	for _, groupID := range patchedIDs(patch, "group_id") {
+		if branchID, err = u.checkGroupBranch(ctx, groupID); err != nil {
+			return models.GetStudent{}, err
+		}
+	}
+
+	var pKey models.GetStudent
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Student().Patch(ctx, id, version, patch); err != nil {
+			return err
+		}
+		if err = moveEnrollment(ctx, tx, id, before.GroupID, pKey.GroupID); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityStudent, EntityID: id, BranchID: branchID, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while patching student", logger.Error(err))
+		return models.GetStudent{}, err
 	}
+
 	return pKey, nil
 }
 
-func (u studentService) GetByID(ctx context.Context, id string) (models.Student, error) {
+func (u studentService) GetByID(ctx context.Context, id string) (models.GetStudent, error) {
 
 	pKey, err := u.storage.Student().GetByID(ctx, id)
 	if err != nil {
 		u.logger.Error("ERROR in service layer while getbyid student", logger.Error(err))
-		return models.Student{}, err
+		return models.GetStudent{}, err
+	}
+
+	if _, err = u.checkGroupBranch(ctx, pKey.GroupID); err != nil {
+		return models.GetStudent{}, err
 	}
 
 	return pKey, nil
@@ -72,6 +151,12 @@ func (u studentService) GetByID(ctx context.Context, id string) (models.Student,
 
 func (u studentService) GetAll(ctx context.Context, req models.GetAllStudentsRequest) (models.GetAllStudentsResponse, error) {
 
+	if err := checkTrashAccess(ctx, req.Deleted); err != nil {
+		return models.GetAllStudentsResponse{}, err
+	}
+
+	req.BranchIDs = allowedBranches(ctx)
+
 	pKey, err := u.storage.Student().GetAll(ctx, req)
 	if err != nil {
 		u.logger.Error("ERROR in service layer while GetAll student", logger.Error(err))
@@ -81,9 +166,19 @@ func (u studentService) GetAll(ctx context.Context, req models.GetAllStudentsReq
 	return pKey, nil
 }
 
-func (u studentService) Delete(ctx context.Context, id string) error {
+func (u studentService) Delete(ctx context.Context, id string, version int) error {
 
-	err := u.storage.Student().Delete(ctx, id)
+	before, branchID, err := u.checkAccess(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Student().Delete(ctx, id, version); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityStudent, EntityID: id, BranchID: branchID, Action: AuditActionDelete, Before: before})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while deleting student", logger.Error(err))
 		return err
@@ -91,3 +186,117 @@ func (u studentService) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+func (u studentService) Restore(ctx context.Context, id string) (models.GetStudent, error) {
+
+	var (
+		after    models.GetStudent
+		branchID string
+	)
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Student().Restore(ctx, id); err != nil {
+			return err
+		}
+
+		scoped := u
+		scoped.storage = tx
+
+		var err error
+		if after, branchID, err = scoped.checkAccess(ctx, id); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityStudent, EntityID: id, BranchID: branchID, Action: AuditActionRestore, After: after})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while restoring student", logger.Error(err))
+		return models.GetStudent{}, err
+	}
+
+	return after, nil
+}
+
+func (u studentService) ChangePassword(ctx context.Context, id string, req models.ChangePasswordRequest, checkOldPassword bool) error {
+
+	_, branchID, err := u.checkAccess(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if len(req.NewPassword) < 6 {
+		return ErrWeakPassword
+	}
+
+	if checkOldPassword {
+		student, err := u.storage.Student().GetByID(ctx, id)
+		if err != nil {
+			u.logger.Error("ERROR in service layer while getting student for password change", logger.Error(err))
+			return err
+		}
+
+		credentials, err := u.storage.Student().GetByLogin(ctx, student.Login)
+		if err != nil {
+			u.logger.Error("ERROR in service layer while getting student credentials", logger.Error(err))
+			return err
+		}
+
+		if credentials.ID != id || password.CompareHashAndPassword(credentials.Password, req.OldPassword) != nil {
+			return ErrWrongOldPassword
+		}
+	}
+
+	hashedPass, err := password.HashPassword(req.NewPassword)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while hashing student password", logger.Error(err))
+		return err
+	}
+
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Student().UpdatePassword(ctx, id, hashedPass); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityStudent, EntityID: id, BranchID: branchID, Action: AuditActionChangePassword})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while updating student password", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// checkAccess returns the student and its branch, or ErrForbidden unless the
+// caller may access the branch of the student.
+func (u studentService) checkAccess(ctx context.Context, id string) (models.GetStudent, string, error) {
+	student, err := u.storage.Student().GetByID(ctx, id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting student branch", logger.Error(err))
+		return models.GetStudent{}, "", err
+	}
+
+	branchID, err := u.checkGroupBranch(ctx, student.GroupID)
+	if err != nil {
+		return models.GetStudent{}, "", err
+	}
+
+	return student, branchID, nil
+}
+
+// checkGroupBranch returns the branch of the group, or ErrForbidden unless the
+// caller may access it. Students without a group belong to no branch, so only
+// superadmins may access them.
+func (u studentService) checkGroupBranch(ctx context.Context, groupID string) (string, error) {
+	if groupID == "" {
+		if allowedBranches(ctx) != nil {
+			return "", ErrForbidden
+		}
+		return "", nil
+	}
+
+	group, err := u.storage.Group().GetByID(ctx, groupID)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting group of student", logger.Error(err))
+		return "", err
+	}
+
+	return group.Branch_id, checkBranch(ctx, group.Branch_id)
+}
@@ -0,0 +1,24 @@
+package service
+
+import (
+	"lms_back/api/models"
+	"testing"
+)
+
+func TestStudentPaidSum(t *testing.T) {
+	f := newFixture(t)
+
+	created, err := f.students.Create(f.ctx, models.Student{Full_Name: "ali", Status: "active", Login: "ali", GroupID: f.group.Id, PaidSum: 500})
+	f.check(err)
+	if created.PaidSum != 0 {
+		t.Errorf("Create() paid sum = %v, want 0", created.PaidSum)
+	}
+
+	// a payment adds to the paid sum and the version
+	f.check(f.store.Student().AddPaidSum(f.ctx, created.ID, 100))
+	updated, err := f.students.Update(f.ctx, models.Student{ID: created.ID, Full_Name: "ali", Status: "active", Login: "ali", GroupID: f.group.Id, PaidSum: 900, Version: created.Version + 1})
+	f.check(err)
+	if updated.PaidSum != 100 {
+		t.Errorf("Update() paid sum = %v, want the 100 of the payments", updated.PaidSum)
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"lms_back/api/models"
+	"lms_back/config"
+	"lms_back/pkg/errs"
+	"lms_back/pkg/logger"
+	"lms_back/storage"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrAlreadyGraded = errs.Conflict("already_graded", "the submission is already graded")
+
+// submissionService takes the answers of students to the tasks of their
+// groups and lets the teachers of the groups grade them.
+type submissionService struct {
+	storage storage.IStorage
+	group   groupService
+	audit   auditService
+	logger  logger.ILogger
+}
+
+func NewSubmissionService(storage storage.IStorage, group groupService, audit auditService, logger logger.ILogger) submissionService {
+	return submissionService{
+		storage: storage,
+		group:   group,
+		audit:   audit,
+		logger:  logger,
+	}
+}
+
+// Submit hands in the answer of the calling student to the task of one of its
+// groups. A student may resubmit until the submission is graded, a submission
+// after the deadline of the task is flagged late.
+func (s submissionService) Submit(ctx context.Context, taskID string, req models.SubmitTask) (models.Submission, error) {
+
+	authInfo, _ := AuthInfoFromContext(ctx)
+
+	task, group, err := s.checkTask(ctx, taskID)
+	if err != nil {
+		return models.Submission{}, err
+	}
+	active, err := enrolled(ctx, s.storage, authInfo.UserID, task.GroupId)
+	if err != nil {
+		s.logger.Error("ERROR in service layer while getting enrollment for submission", logger.Error(err))
+		return models.Submission{}, err
+	}
+	if !active {
+		return models.Submission{}, ErrNotEnrolled
+	}
+
+	var before, submitted models.Submission
+	err = s.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		earlier, err := tx.Submission().GetAll(ctx, models.GetAllSubmissionsRequest{TaskID: taskID, StudentID: authInfo.UserID, Page: 1, Limit: 1})
+		if err != nil {
+			return err
+		}
+		if len(earlier.Submissions) > 0 {
+			if before = earlier.Submissions[0]; before.Score != nil {
+				return ErrAlreadyGraded
+			}
+		}
+
+		submitted, err = tx.Submission().Submit(ctx, models.Submission{
+			TaskID:     taskID,
+			StudentID:  authInfo.UserID,
+			Answer:     req.Answer,
+			Attachment: req.Attachment,
+			Late:       pastDeadline(task.Deadline, time.Now()),
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			// graded since it was read
+			return ErrAlreadyGraded
+		}
+		if err != nil {
+			return err
+		}
+
+		entry := auditEntry{EntityType: AuditEntitySubmission, EntityID: submitted.Id, BranchID: group.Branch_id, Action: AuditActionSubmit, After: submitted}
+		if before.Id != "" {
+			entry.Before = before
+		}
+		return s.audit.write(ctx, tx, entry)
+	})
+	if err != nil {
+		s.logger.Error("ERROR in service layer while submitting task", logger.Error(err))
+		return models.Submission{}, err
+	}
+
+	return submitted, nil
+}
+
+// Grade scores the submission out of the score of its task. Teachers may
+// only grade the submissions to the tasks of their own groups.
+func (s submissionService) Grade(ctx context.Context, id string, req models.GradeSubmission) (models.Submission, error) {
+
+	before, err := s.storage.Submission().GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error("ERROR in service layer while getting submission before grading", logger.Error(err))
+		return models.Submission{}, err
+	}
+	task, group, err := s.checkTask(ctx, before.TaskID)
+	if err != nil {
+		return models.Submission{}, err
+	}
+	if err = checkGroupTeacher(ctx, group); err != nil {
+		return models.Submission{}, err
+	}
+	if max, err := strconv.Atoi(task.Score); err == nil && *req.Score > max {
+		return models.Submission{}, errs.Validation("", errs.FieldError{Field: "score", Message: "must be at most " + task.Score})
+	}
+
+	authInfo, _ := AuthInfoFromContext(ctx)
+
+	var graded models.Submission
+	err = s.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if graded, err = tx.Submission().Grade(ctx, id, *req.Score, req.Feedback, authInfo.UserID); err != nil {
+			return err
+		}
+		return s.audit.write(ctx, tx, auditEntry{EntityType: AuditEntitySubmission, EntityID: id, BranchID: group.Branch_id, Action: AuditActionGrade, Before: before, After: graded})
+	})
+	if err != nil {
+		s.logger.Error("ERROR in service layer while grading submission", logger.Error(err))
+		return models.Submission{}, err
+	}
+
+	return graded, nil
+}
+
+// GetAll lists the submissions to req.TaskID, a student only gets its own.
+func (s submissionService) GetAll(ctx context.Context, req models.GetAllSubmissionsRequest) (models.GetAllSubmissionsResponse, error) {
+
+	if _, _, err := s.checkTask(ctx, req.TaskID); err != nil {
+		return models.GetAllSubmissionsResponse{}, err
+	}
+	if authInfo, ok := AuthInfoFromContext(ctx); ok && authInfo.UserRole == config.STUDENT_ROLE {
+		req.StudentID = authInfo.UserID
+	}
+
+	submissions, err := s.storage.Submission().GetAll(ctx, req)
+	if err != nil {
+		s.logger.Error("ERROR in service layer while GetAll submission", logger.Error(err))
+		return models.GetAllSubmissionsResponse{}, err
+	}
+
+	return submissions, nil
+}
+
+// checkTask returns the task and its group, or ErrForbidden unless the caller
+// may access the branch of the group.
+func (s submissionService) checkTask(ctx context.Context, taskID string) (models.Task, models.Group, error) {
+	task, err := s.storage.Task().GetByID(ctx, taskID)
+	if err != nil {
+		s.logger.Error("ERROR in service layer while getting task of submission", logger.Error(err))
+		return models.Task{}, models.Group{}, err
+	}
+
+	group, err := s.group.checkAccess(ctx, task.GroupId)
+	if err != nil {
+		return models.Task{}, models.Group{}, err
+	}
+
+	return task, group, nil
+}
+
+// pastDeadline reports whether now is after the deadline, a task without one
+// is never late.
+func pastDeadline(deadline string, now time.Time) bool {
+	t, err := time.Parse(time.RFC3339, deadline)
+	return err == nil && now.After(t)
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"errors"
+	"lms_back/api/models"
+	"lms_back/config"
+	"lms_back/pkg/errs"
+	"testing"
+)
+
+func TestSubmission(t *testing.T) {
+	f := newFixture(t)
+	submissions := NewSubmissionService(f.store, f.groups, f.audit, f.log)
+
+	open, err := f.store.Task().Create(f.ctx, models.Task{LessonId: f.lesson.Id, GroupId: f.group.Id, Task: "Fan out", Score: "10"})
+	f.check(err)
+	overdue, err := f.store.Task().Create(f.ctx, models.Task{LessonId: f.lesson.Id, GroupId: f.group.Id, Task: "Fan in", Score: "10", Deadline: "2024-03-11T09:00:00Z"})
+	f.check(err)
+
+	ids := []string{f.newStudent("ali", f.group.Id).ID, f.newStudent("soli", f.otherGroup.Id).ID}
+	ali, soli := f.as(ids[0], config.STUDENT_ROLE), f.as(ids[1], config.STUDENT_ROLE)
+
+	if _, err = submissions.Submit(soli, open.Id, models.SubmitTask{Answer: "not my group"}); !errors.Is(err, ErrNotEnrolled) {
+		t.Errorf("Submit() to the task of another group error = %v, want ErrNotEnrolled", err)
+	}
+
+	submitted, err := submissions.Submit(ali, open.Id, models.SubmitTask{Answer: "done"})
+	f.check(err)
+	late, err := submissions.Submit(ali, overdue.Id, models.SubmitTask{Answer: "sorry"})
+	f.check(err)
+	if submitted.Late || !late.Late || submitted.StudentID != ids[0] {
+		t.Errorf("Submit() = %+v and %+v past the deadline, want only the second late", submitted, late)
+	}
+
+	score := 11
+	if _, err = submissions.Grade(f.as(f.teachers[1], config.TEACHER_ROLE), submitted.Id, models.GradeSubmission{Score: &score}); !errors.Is(err, ErrForbidden) {
+		t.Errorf("Grade() by the teacher of another group error = %v, want ErrForbidden", err)
+	}
+	teacher := f.as(f.teachers[0], config.TEACHER_ROLE)
+	if _, err = submissions.Grade(teacher, submitted.Id, models.GradeSubmission{Score: &score}); errs.KindOf(err) != errs.KindValidation {
+		t.Errorf("Grade() above the score of the task error = %v, want a validation error", err)
+	}
+	score = 9
+	graded, err := submissions.Grade(teacher, submitted.Id, models.GradeSubmission{Score: &score, Feedback: "close the channel"})
+	f.check(err)
+	if graded.Score == nil || *graded.Score != 9 || graded.GradedBy != f.teachers[0] {
+		t.Errorf("Grade() = %+v", graded)
+	}
+	logs, err := f.store.Audit().GetAll(f.ctx, models.GetAllAuditLogsRequest{EntityID: submitted.Id, Action: AuditActionGrade, Page: 1, Limit: 10})
+	f.check(err)
+	if len(logs.AuditLogs) != 1 || logs.AuditLogs[0].ActorID != f.teachers[0] {
+		t.Errorf("audit logs of the grade = %+v, want one by the teacher", logs)
+	}
+	if _, err = submissions.Submit(ali, open.Id, models.SubmitTask{Answer: "again"}); !errors.Is(err, ErrAlreadyGraded) {
+		t.Errorf("Submit() of a graded submission error = %v, want ErrAlreadyGraded", err)
+	}
+
+	// a student lists only its own submissions
+	_, err = f.store.Enrollment().Create(f.ctx, models.Enrollment{StudentID: ids[1], GroupID: f.group.Id})
+	f.check(err)
+	_, err = submissions.Submit(soli, open.Id, models.SubmitTask{Answer: "joined late"})
+	f.check(err)
+	all, err := submissions.GetAll(teacher, models.GetAllSubmissionsRequest{TaskID: open.Id, Page: 1, Limit: 10})
+	f.check(err)
+	own, err := submissions.GetAll(soli, models.GetAllSubmissionsRequest{TaskID: open.Id, Page: 1, Limit: 10})
+	f.check(err)
+	if all.Count != 2 || own.Count != 1 || own.Submissions[0].StudentID != ids[1] {
+		t.Errorf("GetAll() = %d for the teacher and %+v for the student", all.Count, own)
+	}
+}
@@ -9,19 +9,28 @@ import (
 
 type taskService struct {
 	storage storage.IStorage
+	audit   auditService
 	logger  logger.ILogger
 }
 
-func NewTaskService(storage storage.IStorage, logger logger.ILogger) taskService {
+func NewTaskService(storage storage.IStorage, audit auditService, logger logger.ILogger) taskService {
 	return taskService{
 		storage: storage,
+		audit:   audit,
 		logger:  logger,
 	}
 }
 
 func (u taskService) Create(ctx context.Context, task models.Task) (models.Task, error) {
 
-	pKey, err := u.storage.Task().Create(ctx, task)
+	var pKey models.Task
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Task().Create(ctx, task); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityTask, EntityID: pKey.Id, Action: AuditActionCreate, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while creating task", logger.Error(err))
 		return models.Task{}, err
@@ -32,11 +41,49 @@ func (u taskService) Create(ctx context.Context, task models.Task) (models.Task,
 
 func (u taskService) Update(ctx context.Context, task models.Task) (models.Task, error) {
 
-	pKey, err := u.storage.Task().Update(ctx, task)
+	before, err := u.storage.Task().GetByID(ctx, task.Id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting task before update", logger.Error(err))
+		return models.Task{}, err
+	}
+
+	var pKey models.Task
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Task().Update(ctx, task); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityTask, EntityID: task.Id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while updating task", logger.Error(err))
 		return models.Task{}, err
 	}
+
+	return pKey, nil
+}
+
+func (u taskService) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Task, error) {
+
+	before, err := u.storage.Task().GetByID(ctx, id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting task before patch", logger.Error(err))
+		return models.Task{}, err
+	}
+
+	var pKey models.Task
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Task().Patch(ctx, id, version, patch); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityTask, EntityID: id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while patching task", logger.Error(err))
+		return models.Task{}, err
+	}
+
 	return pKey, nil
 }
 
@@ -53,6 +100,10 @@ func (u taskService) GetByID(ctx context.Context, id string) (models.Task, error
 
 func (u taskService) GetAll(ctx context.Context, req models.GetAllTasksRequest) (models.GetAllTasksResponse, error) {
 
+	if err := checkTrashAccess(ctx, req.Deleted); err != nil {
+		return models.GetAllTasksResponse{}, err
+	}
+
 	pKey, err := u.storage.Task().GetAll(ctx, req)
 	if err != nil {
 		u.logger.Error("ERROR in service layer while GetAll task", logger.Error(err))
@@ -62,9 +113,20 @@ func (u taskService) GetAll(ctx context.Context, req models.GetAllTasksRequest)
 	return pKey, nil
 }
 
-func (u taskService) Delete(ctx context.Context, id string) error {
+func (u taskService) Delete(ctx context.Context, id string, version int) error {
+
+	before, err := u.storage.Task().GetByID(ctx, id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting task before delete", logger.Error(err))
+		return err
+	}
 
-	err := u.storage.Task().Delete(ctx, id)
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Task().Delete(ctx, id, version); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityTask, EntityID: id, Action: AuditActionDelete, Before: before})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while deleting task", logger.Error(err))
 		return err
@@ -72,3 +134,25 @@ func (u taskService) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+func (u taskService) Restore(ctx context.Context, id string) (models.Task, error) {
+
+	var after models.Task
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Task().Restore(ctx, id); err != nil {
+			return err
+		}
+
+		var err error
+		if after, err = tx.Task().GetByID(ctx, id); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityTask, EntityID: id, Action: AuditActionRestore, After: after})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while restoring task", logger.Error(err))
+		return models.Task{}, err
+	}
+
+	return after, nil
+}
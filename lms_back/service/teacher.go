@@ -4,47 +4,96 @@ import (
 	"context"
 	"lms_back/api/models"
 	"lms_back/pkg/logger"
+	"lms_back/pkg/password"
 	"lms_back/storage"
 )
 
 type teacherService struct {
 	storage storage.IStorage
+	audit   auditService
 	logger  logger.ILogger
 }
 
-func NewTeacherService(storage storage.IStorage, logger logger.ILogger) teacherService {
+func NewTeacherService(storage storage.IStorage, audit auditService, logger logger.ILogger) teacherService {
 	return teacherService{
 		storage: storage,
+		audit:   audit,
 		logger:  logger,
 	}
 }
 
-func (u teacherService) Create(ctx context.Context, teacher models.Teacher) (models.Teacher, error) {
+func (u teacherService) Create(ctx context.Context, teacher models.Teacher) (models.GetTeacher, error) {
 
-	pKey, err := u.storage.Teacher().Create(ctx, teacher)
+	var pKey models.GetTeacher
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Teacher().Create(ctx, teacher); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityTeacher, EntityID: pKey.Id, Action: AuditActionCreate, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while creating teacher", logger.Error(err))
-		return models.Teacher{}, err
+		return models.GetTeacher{}, err
 	}
+
 	return pKey, nil
 }
 
-func (u teacherService) Update(ctx context.Context, teacher models.Teacher) (models.Teacher, error) {
+func (u teacherService) Update(ctx context.Context, teacher models.Teacher) (models.GetTeacher, error) {
+
+	before, err := u.storage.Teacher().GetByID(ctx, teacher.Id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting teacher before update", logger.Error(err))
+		return models.GetTeacher{}, err
+	}
 
-	pKey, err := u.storage.Teacher().Update(ctx, teacher)
+	var pKey models.GetTeacher
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Teacher().Update(ctx, teacher); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityTeacher, EntityID: teacher.Id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while updating teacher", logger.Error(err))
-		return models.Teacher{}, err
+		return models.GetTeacher{}, err
 	}
+
 	return pKey, nil
 }
 
-func (u teacherService) GetByID(ctx context.Context, id string) (models.Teacher, error) {
+func (u teacherService) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.GetTeacher, error) {
+
+	before, err := u.storage.Teacher().GetByID(ctx, id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting teacher before patch", logger.Error(err))
+		return models.GetTeacher{}, err
+	}
+
+	var pKey models.GetTeacher
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		var err error
+		if pKey, err = tx.Teacher().Patch(ctx, id, version, patch); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityTeacher, EntityID: id, Action: AuditActionUpdate, Before: before, After: pKey})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while patching teacher", logger.Error(err))
+		return models.GetTeacher{}, err
+	}
+
+	return pKey, nil
+}
+
+func (u teacherService) GetByID(ctx context.Context, id string) (models.GetTeacher, error) {
 
 	pKey, err := u.storage.Teacher().GetByID(ctx, id)
 	if err != nil {
 		u.logger.Error("ERROR in service layer while getbyid teacher", logger.Error(err))
-		return models.Teacher{}, err
+		return models.GetTeacher{}, err
 	}
 
 	return pKey, nil
@@ -52,6 +101,10 @@ func (u teacherService) GetByID(ctx context.Context, id string) (models.Teacher,
 
 func (u teacherService) GetAll(ctx context.Context, req models.GetAllTeachersRequest) (models.GetAllTeachersResponse, error) {
 
+	if err := checkTrashAccess(ctx, req.Deleted); err != nil {
+		return models.GetAllTeachersResponse{}, err
+	}
+
 	pKey, err := u.storage.Teacher().GetAll(ctx, req)
 	if err != nil {
 		u.logger.Error("ERROR in service layer while GetAll teacher", logger.Error(err))
@@ -61,9 +114,20 @@ func (u teacherService) GetAll(ctx context.Context, req models.GetAllTeachersReq
 	return pKey, nil
 }
 
-func (u teacherService) Delete(ctx context.Context, id string) error {
+func (u teacherService) Delete(ctx context.Context, id string, version int) error {
 
-	err := u.storage.Teacher().Delete(ctx, id)
+	before, err := u.storage.Teacher().GetByID(ctx, id)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while getting teacher before delete", logger.Error(err))
+		return err
+	}
+
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Teacher().Delete(ctx, id, version); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityTeacher, EntityID: id, Action: AuditActionDelete, Before: before})
+	})
 	if err != nil {
 		u.logger.Error("ERROR in service layer while deleting teacher", logger.Error(err))
 		return err
@@ -71,3 +135,69 @@ func (u teacherService) Delete(ctx context.Context, id string) error {
 
 	return nil
 }
+
+func (u teacherService) Restore(ctx context.Context, id string) (models.GetTeacher, error) {
+
+	var after models.GetTeacher
+	err := u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Teacher().Restore(ctx, id); err != nil {
+			return err
+		}
+
+		var err error
+		if after, err = tx.Teacher().GetByID(ctx, id); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityTeacher, EntityID: id, Action: AuditActionRestore, After: after})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while restoring teacher", logger.Error(err))
+		return models.GetTeacher{}, err
+	}
+
+	return after, nil
+}
+
+func (u teacherService) ChangePassword(ctx context.Context, id string, req models.ChangePasswordRequest, checkOldPassword bool) error {
+
+	if len(req.NewPassword) < 6 {
+		return ErrWeakPassword
+	}
+
+	if checkOldPassword {
+		teacher, err := u.storage.Teacher().GetByID(ctx, id)
+		if err != nil {
+			u.logger.Error("ERROR in service layer while getting teacher for password change", logger.Error(err))
+			return err
+		}
+
+		credentials, err := u.storage.Teacher().GetByLogin(ctx, teacher.Login)
+		if err != nil {
+			u.logger.Error("ERROR in service layer while getting teacher credentials", logger.Error(err))
+			return err
+		}
+
+		if credentials.Id != id || password.CompareHashAndPassword(credentials.Password, req.OldPassword) != nil {
+			return ErrWrongOldPassword
+		}
+	}
+
+	hashedPass, err := password.HashPassword(req.NewPassword)
+	if err != nil {
+		u.logger.Error("ERROR in service layer while hashing teacher password", logger.Error(err))
+		return err
+	}
+
+	err = u.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.Teacher().UpdatePassword(ctx, id, hashedPass); err != nil {
+			return err
+		}
+		return u.audit.write(ctx, tx, auditEntry{EntityType: AuditEntityTeacher, EntityID: id, Action: AuditActionChangePassword})
+	})
+	if err != nil {
+		u.logger.Error("ERROR in service layer while updating teacher password", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"lms_back/config"
+	"lms_back/pkg/logger"
+	"lms_back/storage"
+	"time"
+)
+
+// checkTrashAccess returns ErrForbidden if the caller lists soft deleted rows
+// without being an admin.
+func checkTrashAccess(ctx context.Context, deleted string) error {
+	if deleted == "" {
+		return nil
+	}
+
+	authInfo, ok := AuthInfoFromContext(ctx)
+	if ok && AccountRole(authInfo.UserRole) != config.ADMIN_ROLE {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+type trashService struct {
+	cfg     config.Config
+	storage storage.IStorage
+	logger  logger.ILogger
+}
+
+func NewTrashService(cfg config.Config, storage storage.IStorage, logger logger.ILogger) trashService {
+	return trashService{
+		cfg:     cfg,
+		storage: storage,
+		logger:  logger,
+	}
+}
+
+// Purge hard-deletes the entities that were deleted more than the trash
+// retention ago. Entities are purged before the ones they reference, an entity
+// that is still referenced stays in the trash.
+func (t trashService) Purge(ctx context.Context) (int64, error) {
+	deletedBefore := time.Now().Add(-t.cfg.TrashRetention)
+
+	purgers := []struct {
+		entity string
+		purge  func(context.Context, time.Time) (int64, error)
+	}{
+		{AuditEntityPayment, t.storage.Payment().Purge},
+		{AuditEntityTask, t.storage.Task().Purge},
+		{AuditEntityLesson, t.storage.Lesson().Purge},
+		{AuditEntitySchedule, t.storage.Schedule().Purge},
+		{AuditEntityStudent, t.storage.Student().Purge},
+		{AuditEntityGroup, t.storage.Group().Purge},
+		{AuditEntityTeacher, t.storage.Teacher().Purge},
+		{AuditEntityAdmin, t.storage.Admin().Purge},
+		{AuditEntityBranch, t.storage.Branch().Purge},
+	}
+
+	var total int64
+	for _, p := range purgers {
+		count, err := p.purge(ctx, deletedBefore)
+		if err != nil {
+			t.logger.Error("ERROR in service layer while purging trash", logger.String("entity", p.entity), logger.Error(err))
+			return total, err
+		}
+		if count > 0 {
+			t.logger.Info("purged trash", logger.String("entity", p.entity), logger.Any("count", count))
+		}
+		total += count
+	}
+
+	return total, nil
+}
+
+// RunPurge purges the trash every TrashPurgeInterval until ctx is done.
+func (t trashService) RunPurge(ctx context.Context) {
+	if t.cfg.TrashRetention <= 0 || t.cfg.TrashPurgeInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(t.cfg.TrashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := t.Purge(ctx); err != nil {
+			t.logger.Error("error while purging trash", logger.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"lms_back/api/models"
+	"lms_back/config"
+	"lms_back/pkg/logger"
+	"lms_back/pkg/password"
+	"lms_back/pkg/totp"
+	"lms_back/storage"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const recoveryCodesCount = 10
+
+var (
+	ErrTwoFactorRequired    = errors.New("two-factor authentication code is required")
+	ErrInvalidTwoFactorCode = errors.New("two-factor authentication code is incorrect")
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotSetUp    = errors.New("two-factor authentication is not set up")
+	ErrTwoFactorNotAllowed  = errors.New("two-factor authentication is available for admins and teachers only")
+)
+
+// SetupTwoFactor generates a new secret for the user. It is not used on login
+// until the user confirms it with a code from the authenticator app.
+func (a authService) SetupTwoFactor(ctx context.Context, authInfo models.AuthInfo) (models.TwoFactorSetupResponse, error) {
+	if !supportsTwoFactor(AccountRole(authInfo.UserRole)) {
+		return models.TwoFactorSetupResponse{}, ErrTwoFactorNotAllowed
+	}
+
+	user, err := a.getCredentialsByID(ctx, AccountRole(authInfo.UserRole), authInfo.UserID)
+	if err != nil {
+		a.log.Error("error while getting user for two-factor setup", logger.Error(err))
+		return models.TwoFactorSetupResponse{}, err
+	}
+
+	twoFactor, err := a.storage.TwoFactor().Get(ctx, authInfo.UserID, AccountRole(authInfo.UserRole))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		a.log.Error("error while getting two-factor settings", logger.Error(err))
+		return models.TwoFactorSetupResponse{}, err
+	}
+	if twoFactor.Enabled {
+		return models.TwoFactorSetupResponse{}, ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		a.log.Error("error while generating two-factor secret", logger.Error(err))
+		return models.TwoFactorSetupResponse{}, err
+	}
+
+	if err = a.storage.TwoFactor().Setup(ctx, models.TwoFactor{
+		UserID:   authInfo.UserID,
+		UserRole: AccountRole(authInfo.UserRole),
+		Secret:   secret,
+	}); err != nil {
+		a.log.Error("error while saving two-factor secret", logger.Error(err))
+		return models.TwoFactorSetupResponse{}, err
+	}
+
+	return models.TwoFactorSetupResponse{
+		Secret: secret,
+		URI:    totp.URI(a.cfg.TOTPIssuer, user.Login, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables two-factor authentication once the user proves the
+// authenticator app works, and returns the recovery codes. They are shown only once.
+func (a authService) ConfirmTwoFactor(ctx context.Context, authInfo models.AuthInfo, req models.TwoFactorConfirmRequest) (models.TwoFactorConfirmResponse, error) {
+	twoFactor, err := a.storage.TwoFactor().Get(ctx, authInfo.UserID, AccountRole(authInfo.UserRole))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return models.TwoFactorConfirmResponse{}, ErrTwoFactorNotSetUp
+		}
+		a.log.Error("error while getting two-factor settings", logger.Error(err))
+		return models.TwoFactorConfirmResponse{}, err
+	}
+	if twoFactor.Enabled {
+		return models.TwoFactorConfirmResponse{}, ErrTwoFactorEnabled
+	}
+
+	if err = a.checkTOTP(ctx, twoFactor, req.Code); err != nil {
+		return models.TwoFactorConfirmResponse{}, err
+	}
+
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+	for i := 0; i < recoveryCodesCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			a.log.Error("error while generating recovery code", logger.Error(err))
+			return models.TwoFactorConfirmResponse{}, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+
+	err = a.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.TwoFactor().Enable(ctx, authInfo.UserID, AccountRole(authInfo.UserRole), hashes); err != nil {
+			return err
+		}
+		return a.audit.write(ctx, tx, auditEntry{EntityType: AccountRole(authInfo.UserRole), EntityID: authInfo.UserID, Action: AuditActionEnable2FA})
+	})
+	if err != nil {
+		a.log.Error("error while enabling two-factor authentication", logger.Error(err))
+		return models.TwoFactorConfirmResponse{}, err
+	}
+
+	return models.TwoFactorConfirmResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off, it needs both the
+// password and a one-time or recovery code of the user.
+func (a authService) DisableTwoFactor(ctx context.Context, authInfo models.AuthInfo, req models.TwoFactorDisableRequest) error {
+	user, err := a.getCredentialsByID(ctx, AccountRole(authInfo.UserRole), authInfo.UserID)
+	if err != nil {
+		a.log.Error("error while getting user for disabling two-factor", logger.Error(err))
+		return err
+	}
+
+	if err = password.CompareHashAndPassword(user.Password, req.Password); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	twoFactor, err := a.storage.TwoFactor().Get(ctx, authInfo.UserID, AccountRole(authInfo.UserRole))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrTwoFactorNotSetUp
+		}
+		a.log.Error("error while getting two-factor settings", logger.Error(err))
+		return err
+	}
+
+	if twoFactor.Enabled {
+		if err = a.checkSecondFactor(ctx, twoFactor, req.Code, req.Code); err != nil {
+			return err
+		}
+	}
+
+	err = a.storage.WithTx(ctx, func(tx storage.IStorage) error {
+		if err := tx.TwoFactor().Delete(ctx, authInfo.UserID, AccountRole(authInfo.UserRole)); err != nil {
+			return err
+		}
+		return a.audit.write(ctx, tx, auditEntry{EntityType: AccountRole(authInfo.UserRole), EntityID: authInfo.UserID, Action: AuditActionDisable2FA})
+	})
+	if err != nil {
+		a.log.Error("error while disabling two-factor authentication", logger.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// verifySecondFactor checks the one-time or recovery code of the login request
+// if the user has two-factor authentication enabled.
+func (a authService) verifySecondFactor(ctx context.Context, userID, userRole string, req models.LoginRequest) error {
+	if !supportsTwoFactor(userRole) {
+		return nil
+	}
+
+	twoFactor, err := a.storage.TwoFactor().Get(ctx, userID, userRole)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		a.log.Error("error while getting two-factor settings", logger.Error(err))
+		return err
+	}
+	if !twoFactor.Enabled {
+		return nil
+	}
+
+	if req.OTPCode == "" && req.RecoveryCode == "" {
+		return ErrTwoFactorRequired
+	}
+
+	return a.checkSecondFactor(ctx, twoFactor, req.OTPCode, req.RecoveryCode)
+}
+
+func (a authService) checkSecondFactor(ctx context.Context, twoFactor models.TwoFactor, otpCode, recoveryCode string) error {
+	if otpCode != "" && a.checkTOTP(ctx, twoFactor, otpCode) == nil {
+		return nil
+	}
+
+	if recoveryCode == "" {
+		return ErrInvalidTwoFactorCode
+	}
+
+	codes, err := a.storage.TwoFactor().GetRecoveryCodes(ctx, twoFactor.UserID, twoFactor.UserRole)
+	if err != nil {
+		a.log.Error("error while getting recovery codes", logger.Error(err))
+		return err
+	}
+
+	hash := hashRecoveryCode(recoveryCode)
+	for _, code := range codes {
+		if subtle.ConstantTimeCompare([]byte(code.CodeHash), []byte(hash)) != 1 {
+			continue
+		}
+
+		used, err := a.storage.TwoFactor().UseRecoveryCode(ctx, code.Id)
+		if err != nil {
+			a.log.Error("error while using recovery code", logger.Error(err))
+			return err
+		}
+		if used {
+			return nil
+		}
+	}
+
+	return ErrInvalidTwoFactorCode
+}
+
+// checkTOTP validates the code and marks its time step as used.
+func (a authService) checkTOTP(ctx context.Context, twoFactor models.TwoFactor, code string) error {
+	step, ok := totp.Validate(twoFactor.Secret, strings.TrimSpace(code), time.Now(), 1)
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	used, err := a.storage.TwoFactor().UseStep(ctx, twoFactor.UserID, twoFactor.UserRole, step)
+	if err != nil {
+		a.log.Error("error while saving two-factor code step", logger.Error(err))
+		return err
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+func supportsTwoFactor(role string) bool {
+	return role == config.ADMIN_ROLE || role == config.TEACHER_ROLE
+}
+
+// generateRecoveryCode returns a random code like "k3x9-pq2m-7vtr".
+func generateRecoveryCode() (string, error) {
+	raw := make([]byte, 8)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw))[:12]
+
+	return code[:4] + "-" + code[4:8] + "-" + code[8:], nil
+}
+
+// hashRecoveryCode hashes the code with sha256, recovery codes are random
+// enough that a slow password hash is not needed.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), " ", ""))
+	sum := sha256.Sum256([]byte(code))
+
+	return hex.EncodeToString(sum[:])
+}
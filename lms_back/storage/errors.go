@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"lms_back/pkg/errs"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	// ErrInvalidSort is returned for a sort field that the list can't be ordered by.
+	ErrInvalidSort = errors.New("invalid sort field")
+	// ErrInvalidCursor is returned for a cursor that wasn't issued for the same list and sort.
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// VersionConflictError is returned by an update or delete that expected
+// another version of the row, the row was changed since it was read.
+type VersionConflictError struct {
+	// Current is the version of the row in the storage
+	Current int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("the row was changed, its current version is %d", e.Current)
+}
+
+// ErrNotFound is returned for a row that doesn't exist or is deleted. It wraps
+// pgx.ErrNoRows, errors.Is matches both.
+var ErrNotFound = errs.NotFound("not_found", "not found").Wrap(pgx.ErrNoRows)
+
+// DBError translates an error of the database into the domain error that
+// wraps it: pgx.ErrNoRows is not found, unique violations and deletes of rows
+// still referenced are conflicts, and invalid values, check, not null and
+// foreign key violations of a written row are validation errors. Other errors
+// are returned as they are.
+func DBError(err error) error {
+	if err == nil || errs.KindOf(err) != "" {
+		return err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound.Wrap(err)
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	switch {
+	case pgErr.Code == "23505":
+		return errs.Conflict("already_exists", keyDetail(pgErr, "already exists")).Wrap(err)
+	case pgErr.Code == "23503" && strings.Contains(pgErr.Detail, "still referenced"):
+		return errs.Conflict("still_referenced", "the row is still referenced").Wrap(err)
+	case pgErr.Code == "23503":
+		return errs.Validation("", errs.FieldError{Field: detailColumn(pgErr), Message: "references a row that doesn't exist"}).Wrap(err)
+	case pgErr.Code == "23502":
+		return errs.Validation("", errs.FieldError{Field: pgErr.ColumnName, Message: "is required"}).Wrap(err)
+	case pgErr.Code == "23514":
+		column := strings.TrimSuffix(strings.TrimPrefix(pgErr.ConstraintName, pgErr.TableName+"_"), "_check")
+		return errs.Validation("", errs.FieldError{Field: column, Message: "is not one of the allowed values"}).Wrap(err)
+	case strings.HasPrefix(pgErr.Code, "22"):
+		return errs.Validation(pgErr.Message).Wrap(err)
+	}
+	return err
+}
+
+// detailColumn returns the column in a detail like "Key (login)=(x) already
+// exists.", or "" if the detail has none.
+func detailColumn(pgErr *pgconn.PgError) string {
+	detail := strings.TrimPrefix(pgErr.Detail, "Key (")
+	if end := strings.Index(detail, ")="); end >= 0 && detail != pgErr.Detail {
+		return detail[:end]
+	}
+	return ""
+}
+
+func keyDetail(pgErr *pgconn.PgError, reason string) string {
+	if column := detailColumn(pgErr); column != "" {
+		return column + " " + reason
+	}
+	return reason
+}
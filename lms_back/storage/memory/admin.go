@@ -0,0 +1,296 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/storage"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var adminSortColumns = map[string]column[adminRow]{
+	"full_name":  func(r adminRow) interface{} { return r.Full_Name },
+	"email":      func(r adminRow) interface{} { return r.Email },
+	"login":      func(r adminRow) interface{} { return r.Login },
+	"age":        func(r adminRow) interface{} { return float64(r.Age) },
+	"status":     func(r adminRow) interface{} { return r.Status },
+	"created_at": func(r adminRow) interface{} { return sortTime(r.createdAt) },
+	"updated_at": func(r adminRow) interface{} { return sortTime(r.updatedAt) },
+}
+
+type adminRepo struct {
+	db *db
+}
+
+func (r adminRow) get() models.GetAdmin {
+	return models.GetAdmin{
+		Id:         r.Id,
+		Full_Name:  r.Full_Name,
+		Email:      r.Email,
+		Age:        r.Age,
+		Status:     r.Status,
+		Login:      r.Login,
+		Role:       r.Role,
+		BranchIDs:  slices.Clone(r.branchIDs),
+		Version:    r.Version,
+		Created_at: formatTime(r.createdAt),
+		Updated_at: formatTime(r.updatedAt),
+	}
+}
+
+func checkAdmin(admin models.Admin) error {
+	if err := check("admin", "status", admin.Status, models.Statuses...); err != nil {
+		return err
+	}
+	return check("admin", "role", admin.Role, "admin", "superadmin")
+}
+
+func (c *adminRepo) Create(ctx context.Context, admin models.Admin) (models.GetAdmin, error) {
+	id := uuid.New().String()
+
+	err := c.db.write(func(t *tables) error {
+		if err := checkAdmin(admin); err != nil {
+			return err
+		}
+
+		now := c.db.now()
+		row := adminRow{Admin: admin, branchIDs: []string{}, createdAt: now, updatedAt: now}
+		row.Id, row.Version = id, 1
+		row.BranchIDs = nil
+		t.admins[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.GetAdmin{}, err
+	}
+
+	return models.GetAdmin{
+		Id:         id,
+		Full_Name:  admin.Full_Name,
+		Email:      admin.Email,
+		Age:        admin.Age,
+		Status:     admin.Status,
+		Login:      admin.Login,
+		Role:       admin.Role,
+		BranchIDs:  []string{},
+		Version:    1,
+		Created_at: admin.Created_at,
+		Updated_at: admin.Updated_at,
+	}, nil
+}
+
+func (c *adminRepo) Update(ctx context.Context, admin models.Admin) (models.GetAdmin, error) {
+	err := c.db.write(func(t *tables) error {
+		id, err := parseUUID(admin.Id)
+		if err != nil {
+			return err
+		}
+		if err = checkAdmin(admin); err != nil {
+			return err
+		}
+
+		row, ok := t.admins[id]
+		if err = checkVersion(ok && row.deletedAt == 0, row.Version, admin.Version); err != nil {
+			return err
+		}
+		row.Full_Name, row.Email, row.Age, row.Status, row.Login, row.Role = admin.Full_Name, admin.Email, admin.Age, admin.Status, admin.Login, admin.Role
+		row.Version++
+		row.updatedAt = c.db.now()
+		t.admins[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.GetAdmin{}, err
+	}
+
+	return models.GetAdmin{
+		Id:         admin.Id,
+		Full_Name:  admin.Full_Name,
+		Email:      admin.Email,
+		Age:        admin.Age,
+		Status:     admin.Status,
+		Login:      admin.Login,
+		Role:       admin.Role,
+		BranchIDs:  admin.BranchIDs,
+		Version:    admin.Version + 1,
+		Created_at: admin.Created_at,
+		Updated_at: admin.Updated_at,
+	}, nil
+}
+
+func (c *adminRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.GetAdmin, error) {
+	values, err := storage.AdminPatch.Decode(patch)
+	if err != nil {
+		return models.GetAdmin{}, err
+	}
+
+	current, err := c.GetByID(ctx, id)
+	if err != nil {
+		return models.GetAdmin{}, err
+	}
+
+	admin := models.Admin{}
+	if err = patchModel(current, values, &admin); err != nil {
+		return models.GetAdmin{}, err
+	}
+	admin.Version = version
+	if _, err = c.Update(ctx, admin); err != nil {
+		return models.GetAdmin{}, err
+	}
+	return c.GetByID(ctx, id)
+}
+
+func (c *adminRepo) UpdatePassword(ctx context.Context, id, password string) error {
+	return c.db.write(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.admins[id]
+		if !ok || row.deletedAt != 0 {
+			return nil
+		}
+		row.Password = password
+		row.Version++
+		row.updatedAt = c.db.now()
+		t.admins[id] = row
+		return nil
+	})
+}
+
+func (c *adminRepo) GetAll(ctx context.Context, req models.GetAllAdminsRequest) (models.GetAllAdminsResponse, error) {
+	resp := models.GetAllAdminsResponse{}
+
+	list, err := newList(req.ListParams, req.Page, req.Limit, adminSortColumns, func(r adminRow) interface{} { return r.Id }, "-created_at")
+	if err != nil {
+		return resp, err
+	}
+
+	err = c.db.read(func(t *tables) error {
+		var rows []adminRow
+		for _, row := range t.admins {
+			if !deleted(req.Deleted, row.deletedAt) ||
+				req.Search != "" && !contains(req.Search, row.Full_Name, row.Email, row.Login) ||
+				req.Status != "" && row.Status != req.Status ||
+				req.Role != "" && row.Role != req.Role {
+				continue
+			}
+			rows = append(rows, row)
+		}
+
+		rows, resp.Count, resp.NextCursor, err = list.apply(rows)
+		for _, row := range rows {
+			resp.Admins = append(resp.Admins, row.get())
+		}
+		return err
+	})
+
+	return resp, err
+}
+
+func (c *adminRepo) GetByID(ctx context.Context, id string) (models.GetAdmin, error) {
+	var admin models.GetAdmin
+
+	err := c.db.read(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.admins[id]
+		if !ok || row.deletedAt != 0 {
+			return pgx.ErrNoRows
+		}
+		admin = row.get()
+		return nil
+	})
+
+	return admin, err
+}
+
+func (c *adminRepo) GetByLogin(ctx context.Context, login string) (models.Admin, error) {
+	var admin models.Admin
+
+	err := c.db.read(func(t *tables) error {
+		for _, row := range t.admins {
+			if row.Login == login && row.deletedAt == 0 {
+				get := row.get()
+				admin = models.Admin{
+					Id:         get.Id,
+					Full_Name:  get.Full_Name,
+					Email:      get.Email,
+					Age:        get.Age,
+					Status:     get.Status,
+					Login:      get.Login,
+					Password:   row.Password,
+					Role:       get.Role,
+					BranchIDs:  get.BranchIDs,
+					Version:    get.Version,
+					Created_at: get.Created_at,
+					Updated_at: get.Updated_at,
+				}
+				return nil
+			}
+		}
+		return pgx.ErrNoRows
+	})
+
+	return admin, err
+}
+
+func (c *adminRepo) Delete(ctx context.Context, id string, version int) error {
+	return c.db.write(func(t *tables) error {
+		return softDelete(t.admins, id, version, c.db.now(), func(r *adminRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (c *adminRepo) Restore(ctx context.Context, id string) error {
+	return c.db.write(func(t *tables) error {
+		return restore(t.admins, id, func(r *adminRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (c *adminRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+
+	err := c.db.write(func(t *tables) error {
+		purged = purge(t.admins, deletedBefore, func(r adminRow) (int64, bool) {
+			return r.deletedAt, referenced(t.payments, func(p paymentRow) bool { return p.Admin_id == r.Id })
+		})
+		return nil
+	})
+
+	return purged, err
+}
+
+// SetBranches replaces the branches assigned to the admin.
+func (c *adminRepo) SetBranches(ctx context.Context, adminID string, branchIDs []string) error {
+	return c.db.write(func(t *tables) error {
+		adminID, err := parseUUID(adminID)
+		if err != nil {
+			return err
+		}
+
+		ids := []string{}
+		for _, branchID := range branchIDs {
+			branchID, err := parseUUID(branchID)
+			if err != nil {
+				return err
+			}
+			if !slices.Contains(ids, branchID) {
+				ids = append(ids, branchID)
+			}
+		}
+
+		if row, ok := t.admins[adminID]; ok {
+			row.branchIDs = ids
+			row.Version++
+			t.admins[adminID] = row
+		}
+		return nil
+	})
+}
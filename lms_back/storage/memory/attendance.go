@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type attendanceRepo struct {
+	db *db
+}
+
+func (r attendanceRow) get(lesson lessonRow) models.Attendance {
+	attendance := r.Attendance
+	attendance.GroupID = lesson.GroupId
+	attendance.LessonDate = formatTime(lesson.from)
+	attendance.CreatedAt = formatTime(r.createdAt)
+	attendance.UpdatedAt = formatTime(r.updatedAt)
+	return attendance
+}
+
+func (a *attendanceRepo) Mark(ctx context.Context, lessonID, markedBy string, marks []models.MarkAttendance) ([]models.Attendance, error) {
+	var attendance []models.Attendance
+
+	err := a.db.write(func(t *tables) error {
+		if err := parseUUIDs(&lessonID); err != nil {
+			return err
+		}
+		var err error
+		if markedBy, err = parseNullUUID(markedBy); err != nil {
+			return err
+		}
+
+		lesson, ok := t.lessons[lessonID]
+		if !ok || lesson.deletedAt != 0 {
+			return pgx.ErrNoRows
+		}
+
+		// every mark is checked before the first one is written
+		rows := make([]attendanceRow, 0, len(marks))
+		for _, mark := range marks {
+			row := attendanceRow{Attendance: models.Attendance{LessonID: lessonID, StudentID: mark.StudentID, Status: mark.Status, Note: mark.Note, MarkedBy: markedBy}}
+			if err := parseUUIDs(&row.StudentID); err != nil {
+				return err
+			}
+			if err := check("attendance", "status", row.Status, models.AttendanceStatuses...); err != nil {
+				return err
+			}
+			rows = append(rows, row)
+		}
+
+		now := a.db.now()
+		for _, row := range rows {
+			key := attendanceKey{lessonID: lessonID, studentID: row.StudentID}
+			row.createdAt, row.updatedAt = now, now
+			if existing, ok := t.attendance[key]; ok {
+				row.createdAt = existing.createdAt
+			}
+			t.attendance[key] = row
+			attendance = append(attendance, row.get(lesson))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return attendance, nil
+}
+
+// marks returns the marks matching req along with their lessons, the lesson
+// of rows[i] is lessons[i].
+func (a *attendanceRepo) marks(t *tables, req models.GetAllAttendanceRequest) ([]attendanceRow, []lessonRow, error) {
+	if err := parseFilterUUIDs(&req.LessonID, &req.StudentID, &req.GroupID); err != nil {
+		return nil, nil, err
+	}
+	date, err := dateRange(req.From, req.To)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		rows    []attendanceRow
+		lessons []lessonRow
+	)
+	for key, row := range t.attendance {
+		lesson, ok := t.lessons[key.lessonID]
+		if !ok || lesson.deletedAt != 0 ||
+			req.LessonID != "" && key.lessonID != req.LessonID ||
+			req.StudentID != "" && key.studentID != req.StudentID ||
+			req.GroupID != "" && lesson.GroupId != req.GroupID ||
+			(req.From != "" || req.To != "") && !date(lesson.from) {
+			continue
+		}
+		rows = append(rows, row)
+		lessons = append(lessons, lesson)
+	}
+	return rows, lessons, nil
+}
+
+func (a *attendanceRepo) GetAll(ctx context.Context, req models.GetAllAttendanceRequest) (models.GetAllAttendanceResponse, error) {
+	resp := models.GetAllAttendanceResponse{}
+
+	err := a.db.read(func(t *tables) error {
+		rows, lessons, err := a.marks(t, req)
+		if err != nil {
+			return err
+		}
+
+		marks := make([]models.Attendance, len(rows))
+		for i, row := range rows {
+			marks[i] = row.get(lessons[i])
+		}
+		sort.Slice(marks, func(i, j int) bool {
+			switch {
+			case marks[i].LessonDate != marks[j].LessonDate:
+				return marks[i].LessonDate > marks[j].LessonDate
+			case marks[i].LessonID != marks[j].LessonID:
+				return marks[i].LessonID < marks[j].LessonID
+			}
+			return marks[i].StudentID < marks[j].StudentID
+		})
+
+		resp.Attendance, resp.Count = offsetPage(marks, req.Page, req.Limit)
+		return nil
+	})
+
+	return resp, err
+}
+
+func (a *attendanceRepo) Rates(ctx context.Context, req models.GetAllAttendanceRequest) ([]models.AttendanceRate, error) {
+	var rates []models.AttendanceRate
+
+	err := a.db.read(func(t *tables) error {
+		rows, _, err := a.marks(t, req)
+		if err != nil {
+			return err
+		}
+
+		counts := map[string]*models.AttendanceRate{}
+		count := func(studentID string) *models.AttendanceRate {
+			if counts[studentID] == nil {
+				counts[studentID] = &models.AttendanceRate{StudentID: studentID}
+			}
+			return counts[studentID]
+		}
+		for _, row := range rows {
+			rate := count(row.StudentID)
+			switch row.Status {
+			case models.AttendancePresent:
+				rate.Present++
+			case models.AttendanceLate:
+				rate.Late++
+			case models.AttendanceAbsent:
+				rate.Absent++
+			case models.AttendanceExcused:
+				rate.Excused++
+			}
+		}
+		if req.GroupID != "" {
+			groupID, _ := parseUUID(req.GroupID)
+			for _, enrollment := range t.enrollments {
+				if enrollment.GroupID == groupID && enrollment.Status == models.EnrollmentActive {
+					count(enrollment.StudentID)
+				}
+			}
+		}
+
+		for studentID, rate := range counts {
+			if student, ok := t.students[studentID]; ok && student.deletedAt == 0 {
+				rates = append(rates, *rate)
+			}
+		}
+		sort.Slice(rates, func(i, j int) bool { return rates[i].StudentID < rates[j].StudentID })
+		return nil
+	})
+
+	return rates, err
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type auditRepo struct {
+	db *db
+}
+
+func (a *auditRepo) Create(ctx context.Context, log models.AuditLog) error {
+	return a.db.write(func(t *tables) error {
+		row := auditLogRow{AuditLog: log, createdAt: a.db.now()}
+		row.Id = uuid.New().String()
+
+		var err error
+		if row.ActorID, err = parseNullUUID(log.ActorID); err != nil {
+			return err
+		}
+		if row.BranchID, err = parseNullUUID(log.BranchID); err != nil {
+			return err
+		}
+		if row.Before, err = jsonb(log.Before); err != nil {
+			return err
+		}
+		if row.After, err = jsonb(log.After); err != nil {
+			return err
+		}
+		if row.Changes, err = jsonb(log.Changes); err != nil {
+			return err
+		}
+
+		t.auditLogs = append(t.auditLogs, row)
+		return nil
+	})
+}
+
+func (a *auditRepo) GetAll(ctx context.Context, req models.GetAllAuditLogsRequest) (models.GetAllAuditLogsResponse, error) {
+	resp := models.GetAllAuditLogsResponse{}
+
+	if err := parseFilterUUIDs(&req.ActorID); err != nil {
+		return resp, err
+	}
+	var from, to time.Time
+	for _, bound := range []struct {
+		value string
+		t     *time.Time
+	}{{req.From, &from}, {req.To, &to}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := parseTimestamp(bound.value)
+		if err != nil {
+			return resp, err
+		}
+		*bound.t = t
+	}
+
+	err := a.db.read(func(t *tables) error {
+		var rows []auditLogRow
+		for _, row := range t.auditLogs {
+			if req.EntityType != "" && row.EntityType != req.EntityType ||
+				req.EntityID != "" && row.EntityID != req.EntityID ||
+				req.ActorID != "" && row.ActorID != req.ActorID ||
+				req.Action != "" && row.Action != req.Action ||
+				!anyOf(row.BranchID, req.BranchIDs) ||
+				!from.IsZero() && row.createdAt.Before(from) ||
+				!to.IsZero() && !row.createdAt.Before(to) {
+				continue
+			}
+			rows = append(rows, row)
+		}
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].createdAt.After(rows[j].createdAt) })
+
+		rows, resp.Count = offsetPage(rows, req.Page, req.Limit)
+		for _, row := range rows {
+			log := row.AuditLog
+			log.CreatedAt = formatTime(row.createdAt)
+			resp.AuditLogs = append(resp.AuditLogs, log)
+		}
+		return nil
+	})
+
+	return resp, err
+}
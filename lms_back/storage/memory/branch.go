@@ -0,0 +1,201 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/storage"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var branchSortColumns = map[string]column[branchRow]{
+	"name":       func(r branchRow) interface{} { return r.Name },
+	"address":    func(r branchRow) interface{} { return r.Address },
+	"created_at": func(r branchRow) interface{} { return sortTime(r.createdAt) },
+}
+
+type branchRepo struct {
+	db *db
+}
+
+func (r branchRow) get() models.Branch {
+	return models.Branch{
+		Id:        r.Id,
+		Name:      r.Name,
+		Address:   r.Address,
+		Version:   r.Version,
+		CreatedAt: formatTime(r.createdAt),
+		UpdatedAt: formatTime(r.updatedAt),
+		DeletedAt: strconv.FormatInt(r.deletedAt, 10),
+	}
+}
+
+func (c *branchRepo) Create(ctx context.Context, branch models.Branch) (models.Branch, error) {
+	id := uuid.New().String()
+
+	err := c.db.write(func(t *tables) error {
+		row := branchRow{Branch: branch, createdAt: c.db.now()}
+		row.Id, row.Version = id, 1
+		t.branches[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.Branch{}, err
+	}
+
+	return models.Branch{
+		Id:        id,
+		Name:      branch.Name,
+		Address:   branch.Address,
+		Version:   1,
+		CreatedAt: branch.CreatedAt,
+	}, nil
+}
+
+func (c *branchRepo) Update(ctx context.Context, branch models.Branch) (models.Branch, error) {
+	err := c.db.write(func(t *tables) error {
+		id, err := parseUUID(branch.Id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.branches[id]
+		if err = checkVersion(ok && row.deletedAt == 0, row.Version, branch.Version); err != nil {
+			return err
+		}
+		row.Name, row.Address = branch.Name, branch.Address
+		row.Version++
+		row.updatedAt = c.db.now()
+		t.branches[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.Branch{}, err
+	}
+
+	return models.Branch{
+		Id:        branch.Id,
+		Name:      branch.Name,
+		Address:   branch.Address,
+		Version:   branch.Version + 1,
+		CreatedAt: branch.CreatedAt,
+		UpdatedAt: branch.UpdatedAt,
+	}, nil
+}
+
+func (c *branchRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Branch, error) {
+	values, err := storage.BranchPatch.Decode(patch)
+	if err != nil {
+		return models.Branch{}, err
+	}
+
+	current, err := c.GetByID(ctx, id)
+	if err != nil {
+		return models.Branch{}, err
+	}
+
+	branch := models.Branch{}
+	if err = patchModel(current, values, &branch); err != nil {
+		return models.Branch{}, err
+	}
+	branch.Version = version
+	if _, err = c.Update(ctx, branch); err != nil {
+		return models.Branch{}, err
+	}
+	return c.GetByID(ctx, id)
+}
+
+func (c *branchRepo) GetAll(ctx context.Context, req models.GetAllBranchesRequest) (models.GetAllBranchesResponse, error) {
+	resp := models.GetAllBranchesResponse{}
+
+	list, err := newList(req.ListParams, req.Page, req.Limit, branchSortColumns, func(r branchRow) interface{} { return r.Id }, "-created_at")
+	if err != nil {
+		return resp, err
+	}
+
+	err = c.db.read(func(t *tables) error {
+		var rows []branchRow
+		for _, row := range t.branches {
+			if !deleted(req.Deleted, row.deletedAt) ||
+				req.Search != "" && !contains(req.Search, row.Name, row.Address) {
+				continue
+			}
+			rows = append(rows, row)
+		}
+
+		rows, resp.Count, resp.NextCursor, err = list.apply(rows)
+		for _, row := range rows {
+			resp.Branches = append(resp.Branches, row.get())
+		}
+		return err
+	})
+
+	return resp, err
+}
+
+func (c *branchRepo) GetByID(ctx context.Context, id string) (models.Branch, error) {
+	var branch models.Branch
+
+	err := c.db.read(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.branches[id]
+		if !ok || row.deletedAt != 0 {
+			return pgx.ErrNoRows
+		}
+		branch = row.get()
+		return nil
+	})
+
+	return branch, err
+}
+
+func (c *branchRepo) Delete(ctx context.Context, id string, version int) error {
+	return c.db.write(func(t *tables) error {
+		return softDelete(t.branches, id, version, c.db.now(), func(r *branchRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (c *branchRepo) Restore(ctx context.Context, id string) error {
+	return c.db.write(func(t *tables) error {
+		return restore(t.branches, id, func(r *branchRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (c *branchRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+
+	err := c.db.write(func(t *tables) error {
+		ids := maps.Clone(t.branches)
+		purged = purge(t.branches, deletedBefore, func(r branchRow) (int64, bool) {
+			return r.deletedAt, referenced(t.groups, func(g groupRow) bool { return g.Branch_id == r.Id }) ||
+				referenced(t.payments, func(p paymentRow) bool { return p.Branch_id == r.Id }) ||
+				referenced(t.schedules, func(s scheduleRow) bool { return s.Branch_id == r.Id }) ||
+				slices.ContainsFunc(t.loginAttempts, func(a loginAttemptRow) bool { return a.BranchID == r.Id })
+		})
+
+		// the assignments of admins to purged branches are deleted with them
+		for id, admin := range t.admins {
+			branchIDs := slices.DeleteFunc(slices.Clone(admin.branchIDs), func(branchID string) bool {
+				_, existed := ids[branchID]
+				_, exists := t.branches[branchID]
+				return existed && !exists
+			})
+			if len(branchIDs) != len(admin.branchIDs) {
+				admin.branchIDs = branchIDs
+				t.admins[id] = admin
+			}
+		}
+		return nil
+	})
+
+	return purged, err
+}
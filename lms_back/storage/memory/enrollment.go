@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type enrollmentRepo struct {
+	db *db
+}
+
+func (r enrollmentRow) get() models.Enrollment {
+	enrollment := r.Enrollment
+	enrollment.EnrolledAt = formatTime(r.enrolledAt)
+	enrollment.LeftAt = formatTime(r.leftAt)
+	return enrollment
+}
+
+func (e *enrollmentRepo) Create(ctx context.Context, enrollment models.Enrollment) (models.Enrollment, error) {
+	var created enrollmentRow
+
+	err := e.db.write(func(t *tables) error {
+		row := enrollmentRow{Enrollment: enrollment, enrolledAt: e.db.now()}
+		if err := parseUUIDs(&row.StudentID, &row.GroupID); err != nil {
+			return err
+		}
+		if _, ok := e.active(t, row.StudentID, row.GroupID); ok {
+			return unique("enrollment", "enrollment_active_idx", []string{"student_id", "group_id"}, []string{row.StudentID, row.GroupID})
+		}
+
+		row.Id, row.Status = uuid.New().String(), models.EnrollmentActive
+		t.enrollments[row.Id] = row
+		created = row
+		return nil
+	})
+	if err != nil {
+		return models.Enrollment{}, err
+	}
+
+	return created.get(), nil
+}
+
+func (e *enrollmentRepo) Close(ctx context.Context, studentID, groupID, status, reason string) (models.Enrollment, error) {
+	var closed enrollmentRow
+
+	err := e.db.write(func(t *tables) error {
+		if err := parseUUIDs(&studentID, &groupID); err != nil {
+			return err
+		}
+		if err := check("enrollment", "status", status, models.EnrollmentActive, models.EnrollmentTransferred, models.EnrollmentWithdrawn); err != nil {
+			return err
+		}
+
+		row, ok := e.active(t, studentID, groupID)
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		row.Status, row.Reason, row.leftAt = status, reason, e.db.now()
+		t.enrollments[row.Id] = row
+		closed = row
+		return nil
+	})
+	if err != nil {
+		return models.Enrollment{}, err
+	}
+
+	return closed.get(), nil
+}
+
+// active returns the active enrollment of the student in the group.
+func (e *enrollmentRepo) active(t *tables, studentID, groupID string) (enrollmentRow, bool) {
+	for _, row := range t.enrollments {
+		if row.StudentID == studentID && row.GroupID == groupID && row.Status == models.EnrollmentActive {
+			return row, true
+		}
+	}
+	return enrollmentRow{}, false
+}
+
+func (e *enrollmentRepo) GetAll(ctx context.Context, req models.GetAllEnrollmentsRequest) (models.GetAllEnrollmentsResponse, error) {
+	resp := models.GetAllEnrollmentsResponse{}
+
+	if err := parseFilterUUIDs(&req.StudentID, &req.GroupID); err != nil {
+		return resp, err
+	}
+
+	err := e.db.read(func(t *tables) error {
+		var rows []enrollmentRow
+		for _, row := range t.enrollments {
+			if req.StudentID != "" && row.StudentID != req.StudentID ||
+				req.GroupID != "" && row.GroupID != req.GroupID ||
+				req.Status != "" && row.Status != req.Status {
+				continue
+			}
+			rows = append(rows, row)
+		}
+		sort.Slice(rows, func(i, j int) bool {
+			if !rows[i].enrolledAt.Equal(rows[j].enrolledAt) {
+				return rows[i].enrolledAt.After(rows[j].enrolledAt)
+			}
+			return rows[i].Id < rows[j].Id
+		})
+
+		rows, resp.Count = offsetPage(rows, req.Page, req.Limit)
+		for _, row := range rows {
+			resp.Enrollments = append(resp.Enrollments, row.get())
+		}
+		return nil
+	})
+
+	return resp, err
+}
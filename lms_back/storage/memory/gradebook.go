@@ -0,0 +1,172 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"sort"
+)
+
+type gradebookRepo struct {
+	db *db
+}
+
+func (g *gradebookRepo) Students(ctx context.Context, groupID string) ([]models.GradebookStudent, error) {
+	var students []models.GradebookStudent
+
+	err := g.db.read(func(t *tables) error {
+		groupID, err := parseUUID(groupID)
+		if err != nil {
+			return err
+		}
+
+		for _, enrollment := range t.enrollments {
+			if enrollment.GroupID != groupID || enrollment.Status != models.EnrollmentActive {
+				continue
+			}
+			if student, ok := t.students[enrollment.StudentID]; ok && student.deletedAt == 0 {
+				students = append(students, models.GradebookStudent{StudentID: student.ID, FullName: student.Full_Name})
+			}
+		}
+		sort.Slice(students, func(i, j int) bool {
+			if students[i].FullName != students[j].FullName {
+				return students[i].FullName < students[j].FullName
+			}
+			return students[i].StudentID < students[j].StudentID
+		})
+		return nil
+	})
+
+	return students, err
+}
+
+// lessons returns the lessons of req by date.
+func (g *gradebookRepo) lessons(t *tables, req models.GradebookRequest) ([]lessonRow, error) {
+	if err := parseFilterUUIDs(&req.GroupID); err != nil {
+		return nil, err
+	}
+	date, err := dateRange(req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+
+	var lessons []lessonRow
+	for _, row := range t.lessons {
+		if row.deletedAt != 0 ||
+			req.GroupID != "" && row.GroupId != req.GroupID ||
+			(req.From != "" || req.To != "") && !date(row.from) {
+			continue
+		}
+		lessons = append(lessons, row)
+	}
+	sort.Slice(lessons, func(i, j int) bool {
+		if !lessons[i].from.Equal(lessons[j].from) {
+			return lessons[i].from.Before(lessons[j].from)
+		}
+		return lessons[i].Id < lessons[j].Id
+	})
+	return lessons, nil
+}
+
+// tasks returns the tasks of the group of req given in the lessons of req, in
+// the order of the lessons.
+func (g *gradebookRepo) tasks(t *tables, req models.GradebookRequest) ([]taskRow, error) {
+	lessons, err := g.lessons(t, models.GradebookRequest{From: req.From, To: req.To})
+	if err != nil {
+		return nil, err
+	}
+	groupID, err := parseUUID(req.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	byLesson := map[string][]taskRow{}
+	for _, row := range t.tasks {
+		if row.deletedAt == 0 && row.GroupId == groupID {
+			byLesson[row.LessonId] = append(byLesson[row.LessonId], row)
+		}
+	}
+
+	var tasks []taskRow
+	for _, lesson := range lessons {
+		rows := byLesson[lesson.Id]
+		sort.Slice(rows, func(i, j int) bool {
+			if !rows[i].createdAt.Equal(rows[j].createdAt) {
+				return rows[i].createdAt.Before(rows[j].createdAt)
+			}
+			return rows[i].Id < rows[j].Id
+		})
+		tasks = append(tasks, rows...)
+	}
+	return tasks, nil
+}
+
+func (g *gradebookRepo) Lessons(ctx context.Context, req models.GradebookRequest) ([]models.GradebookLesson, error) {
+	var lessons []models.GradebookLesson
+
+	err := g.db.read(func(t *tables) error {
+		rows, err := g.lessons(t, req)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			lessons = append(lessons, models.GradebookLesson{LessonID: row.Id, Theme: row.Theme, Date: formatTime(row.from)})
+		}
+		return nil
+	})
+
+	return lessons, err
+}
+
+func (g *gradebookRepo) Tasks(ctx context.Context, req models.GradebookRequest) ([]models.GradebookTask, error) {
+	var tasks []models.GradebookTask
+
+	err := g.db.read(func(t *tables) error {
+		rows, err := g.tasks(t, req)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			tasks = append(tasks, models.GradebookTask{
+				TaskID:   row.Id,
+				LessonID: row.LessonId,
+				Task:     row.Task.Task,
+				MaxScore: row.score,
+				Deadline: formatTime(row.deadline),
+			})
+		}
+		return nil
+	})
+
+	return tasks, err
+}
+
+func (g *gradebookRepo) Scores(ctx context.Context, req models.GradebookRequest) ([]models.GradebookScore, error) {
+	var scores []models.GradebookScore
+
+	err := g.db.read(func(t *tables) error {
+		rows, err := g.tasks(t, req)
+		if err != nil {
+			return err
+		}
+		tasks := map[string]bool{}
+		for _, row := range rows {
+			tasks[row.Id] = true
+		}
+
+		for _, row := range t.submissions {
+			if tasks[row.TaskID] {
+				submission := row.get()
+				scores = append(scores, models.GradebookScore{TaskID: submission.TaskID, StudentID: submission.StudentID, Score: submission.Score, Late: submission.Late})
+			}
+		}
+		sort.Slice(scores, func(i, j int) bool {
+			if scores[i].TaskID != scores[j].TaskID {
+				return scores[i].TaskID < scores[j].TaskID
+			}
+			return scores[i].StudentID < scores[j].StudentID
+		})
+		return nil
+	})
+
+	return scores, err
+}
@@ -0,0 +1,226 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/pkg"
+	"lms_back/storage"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var groupSortColumns = map[string]column[groupRow]{
+	"group_id":   func(r groupRow) interface{} { return r.Group_id },
+	"type":       func(r groupRow) interface{} { return r.Type },
+	"created_at": func(r groupRow) interface{} { return sortTime(r.createdAt) },
+}
+
+type groupRepo struct {
+	db *db
+}
+
+func (r groupRow) get() models.Group {
+	return models.Group{
+		Id:         r.Id,
+		Group_id:   r.Group_id,
+		Branch_id:  r.Branch_id,
+		Teacher_id: r.Teacher_id,
+		Type:       r.Type,
+		Version:    r.Version,
+		Created_at: formatTime(r.createdAt),
+		Updated_at: formatTime(r.updatedAt),
+	}
+}
+
+func (g *groupRepo) Create(ctx context.Context, group models.Group) (models.Group, error) {
+	id := uuid.New().String()
+	var groupID string
+
+	err := g.db.write(func(t *tables) error {
+		row := groupRow{Group: group}
+		if err := parseUUIDs(&row.Branch_id, &row.Teacher_id); err != nil {
+			return err
+		}
+		if err := check("group", "type", group.Type, models.GroupTypes...); err != nil {
+			return err
+		}
+
+		// the next number after the greatest group_id, of deleted groups too
+		maxID := "GR-0000000"
+		for _, other := range t.groups {
+			if other.Group_id > maxID {
+				maxID = other.Group_id
+			}
+		}
+		digit, err := strconv.Atoi(maxID[3:])
+		if err != nil {
+			return err
+		}
+		groupID = "Gr-" + pkg.GetSerialId(digit)
+
+		now := g.db.now()
+		row.Id, row.Group_id, row.Version = id, groupID, 1
+		row.createdAt, row.updatedAt = now, now
+		t.groups[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.Group{}, err
+	}
+
+	return models.Group{
+		Id:         id,
+		Group_id:   groupID,
+		Branch_id:  group.Branch_id,
+		Teacher_id: group.Teacher_id,
+		Type:       group.Type,
+		Version:    1,
+		Created_at: group.Created_at,
+	}, nil
+}
+
+func (g *groupRepo) Update(ctx context.Context, group models.Group) (models.Group, error) {
+	err := g.db.write(func(t *tables) error {
+		id, err := parseUUID(group.Id)
+		if err != nil {
+			return err
+		}
+		if err = check("group", "type", group.Type, models.GroupTypes...); err != nil {
+			return err
+		}
+
+		row, ok := t.groups[id]
+		if err = checkVersion(ok && row.deletedAt == 0, row.Version, group.Version); err != nil {
+			return err
+		}
+		row.Type = group.Type
+		row.Version++
+		row.updatedAt = g.db.now()
+		t.groups[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.Group{}, err
+	}
+
+	return models.Group{
+		Id:         group.Id,
+		Group_id:   group.Group_id,
+		Branch_id:  group.Branch_id,
+		Teacher_id: group.Teacher_id,
+		Type:       group.Type,
+		Version:    group.Version + 1,
+		Created_at: group.Created_at,
+		Updated_at: group.Updated_at,
+	}, nil
+}
+
+func (g *groupRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Group, error) {
+	values, err := storage.GroupPatch.Decode(patch)
+	if err != nil {
+		return models.Group{}, err
+	}
+
+	current, err := g.GetByID(ctx, id)
+	if err != nil {
+		return models.Group{}, err
+	}
+
+	group := models.Group{}
+	if err = patchModel(current, values, &group); err != nil {
+		return models.Group{}, err
+	}
+	group.Version = version
+	if _, err = g.Update(ctx, group); err != nil {
+		return models.Group{}, err
+	}
+	return g.GetByID(ctx, id)
+}
+
+func (g *groupRepo) GetAll(ctx context.Context, req models.GetAllGroupsRequest) (models.GetAllGroupsResponse, error) {
+	resp := models.GetAllGroupsResponse{}
+
+	if err := parseFilterUUIDs(&req.BranchID, &req.TeacherID); err != nil {
+		return resp, err
+	}
+
+	list, err := newList(req.ListParams, req.Page, req.Limit, groupSortColumns, func(r groupRow) interface{} { return r.Id }, "-created_at")
+	if err != nil {
+		return resp, err
+	}
+
+	err = g.db.read(func(t *tables) error {
+		var rows []groupRow
+		for _, row := range t.groups {
+			if !deleted(req.Deleted, row.deletedAt) ||
+				req.Search != "" && !contains(req.Search, row.Group_id) ||
+				req.BranchID != "" && row.Branch_id != req.BranchID ||
+				req.TeacherID != "" && row.Teacher_id != req.TeacherID ||
+				req.Type != "" && row.Type != req.Type ||
+				!anyOf(row.Branch_id, req.BranchIDs) {
+				continue
+			}
+			rows = append(rows, row)
+		}
+
+		rows, resp.Count, resp.NextCursor, err = list.apply(rows)
+		for _, row := range rows {
+			resp.Groups = append(resp.Groups, row.get())
+		}
+		return err
+	})
+
+	return resp, err
+}
+
+func (g *groupRepo) GetByID(ctx context.Context, id string) (models.Group, error) {
+	var group models.Group
+
+	err := g.db.read(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.groups[id]
+		if !ok || row.deletedAt != 0 {
+			return pgx.ErrNoRows
+		}
+		group = row.get()
+		return nil
+	})
+
+	return group, err
+}
+
+func (g *groupRepo) Delete(ctx context.Context, id string, version int) error {
+	return g.db.write(func(t *tables) error {
+		return softDelete(t.groups, id, version, g.db.now(), func(r *groupRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (g *groupRepo) Restore(ctx context.Context, id string) error {
+	return g.db.write(func(t *tables) error {
+		return restore(t.groups, id, func(r *groupRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (g *groupRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+
+	err := g.db.write(func(t *tables) error {
+		purged = purge(t.groups, deletedBefore, func(r groupRow) (int64, bool) {
+			return r.deletedAt, referenced(t.students, func(s studentRow) bool { return s.GroupID == r.Id }) ||
+				referenced(t.schedules, func(s scheduleRow) bool { return s.Group_id == r.Id }) ||
+				referenced(t.lessons, func(l lessonRow) bool { return l.GroupId == r.Id }) ||
+				referenced(t.tasks, func(task taskRow) bool { return task.GroupId == r.Id }) ||
+				referenced(t.enrollments, func(e enrollmentRow) bool { return e.GroupID == r.Id })
+		})
+		return nil
+	})
+
+	return purged, err
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type idempotencyRepo struct {
+	db *db
+}
+
+func (i *idempotencyRepo) Create(ctx context.Context, key models.IdempotencyKey, ttl, lease time.Duration) (bool, error) {
+	var created bool
+
+	err := i.db.write(func(t *tables) error {
+		id := idempotencyID{userID: key.UserID, key: key.Key}
+		now := i.db.now()
+
+		if row, ok := t.idempotencyKeys[id]; ok && !row.createdAt.Before(now.Add(-ttl)) {
+			if row.StatusCode != 0 || !row.createdAt.Before(now.Add(-lease)) {
+				return nil
+			}
+		}
+		t.idempotencyKeys[id] = idempotencyKeyRow{
+			IdempotencyKey: models.IdempotencyKey{
+				Key:         key.Key,
+				UserID:      key.UserID,
+				Method:      key.Method,
+				Path:        key.Path,
+				RequestHash: key.RequestHash,
+			},
+			createdAt: now,
+		}
+		created = true
+		return nil
+	})
+
+	return created, err
+}
+
+func (i *idempotencyRepo) Get(ctx context.Context, userID, key string) (models.IdempotencyKey, error) {
+	var idempotencyKey models.IdempotencyKey
+
+	err := i.db.read(func(t *tables) error {
+		row, ok := t.idempotencyKeys[idempotencyID{userID: userID, key: key}]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		idempotencyKey = row.IdempotencyKey
+		idempotencyKey.CreatedAt = formatTime(row.createdAt)
+		return nil
+	})
+
+	return idempotencyKey, err
+}
+
+func (i *idempotencyRepo) Complete(ctx context.Context, key models.IdempotencyKey) error {
+	return i.db.write(func(t *tables) error {
+		id := idempotencyID{userID: key.UserID, key: key.Key}
+
+		row, ok := t.idempotencyKeys[id]
+		if !ok || row.StatusCode != 0 {
+			return nil
+		}
+		row.StatusCode, row.ContentType, row.ETag, row.Response = key.StatusCode, key.ContentType, key.ETag, key.Response
+		t.idempotencyKeys[id] = row
+		return nil
+	})
+}
+
+func (i *idempotencyRepo) Delete(ctx context.Context, userID, key string) error {
+	return i.db.write(func(t *tables) error {
+		delete(t.idempotencyKeys, idempotencyID{userID: userID, key: key})
+		return nil
+	})
+}
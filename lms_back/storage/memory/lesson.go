@@ -0,0 +1,216 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var lessonSortColumns = map[string]column[lessonRow]{
+	"theme":      func(r lessonRow) interface{} { return r.Theme },
+	"from":       func(r lessonRow) interface{} { return sortTime(r.from) },
+	"created_at": func(r lessonRow) interface{} { return sortTime(r.createdAt) },
+}
+
+type lessonRepo struct {
+	db *db
+}
+
+func (r lessonRow) get() models.Lesson {
+	return models.Lesson{
+		Id:         r.Id,
+		ScheduleId: r.ScheduleId,
+		GroupId:    r.GroupId,
+		From:       formatTime(r.from),
+		To:         formatTime(r.to),
+		Theme:      r.Theme,
+		Version:    r.Version,
+		Created_at: formatTime(r.createdAt),
+		Updated_at: formatTime(r.updatedAt),
+	}
+}
+
+// parseLesson returns the row of the lesson without its timestamps.
+func parseLesson(lesson models.Lesson) (lessonRow, error) {
+	row := lessonRow{Lesson: lesson}
+	if err := parseUUIDs(&row.ScheduleId, &row.GroupId); err != nil {
+		return lessonRow{}, err
+	}
+
+	var err error
+	if row.from, err = parseDate(lesson.From); err != nil {
+		return lessonRow{}, err
+	}
+	if row.to, err = parseDate(lesson.To); err != nil {
+		return lessonRow{}, err
+	}
+
+	return row, nil
+}
+
+func (c *lessonRepo) Create(ctx context.Context, lesson models.Lesson) (models.Lesson, error) {
+	id := uuid.New().String()
+
+	err := c.db.write(func(t *tables) error {
+		row, err := parseLesson(lesson)
+		if err != nil {
+			return err
+		}
+
+		now := c.db.now()
+		row.Id, row.Version = id, 1
+		row.createdAt, row.updatedAt = now, now
+		t.lessons[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.Lesson{}, err
+	}
+
+	lesson.Id, lesson.Version = id, 1
+	return lesson, nil
+}
+
+func (c *lessonRepo) Update(ctx context.Context, lesson models.Lesson) (models.Lesson, error) {
+	err := c.db.write(func(t *tables) error {
+		id, err := parseUUID(lesson.Id)
+		if err != nil {
+			return err
+		}
+		updated, err := parseLesson(lesson)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.lessons[id]
+		if err = checkVersion(ok && row.deletedAt == 0, row.Version, lesson.Version); err != nil {
+			return err
+		}
+		updated.Id, updated.Version = id, row.Version+1
+		updated.createdAt, updated.updatedAt, updated.deletedAt = row.createdAt, c.db.now(), row.deletedAt
+		t.lessons[id] = updated
+		return nil
+	})
+	if err != nil {
+		return models.Lesson{}, err
+	}
+
+	lesson.Version++
+	return lesson, nil
+}
+
+func (c *lessonRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Lesson, error) {
+	values, err := storage.LessonPatch.Decode(patch)
+	if err != nil {
+		return models.Lesson{}, err
+	}
+
+	current, err := c.GetByID(ctx, id)
+	if err != nil {
+		return models.Lesson{}, err
+	}
+
+	lesson := models.Lesson{}
+	if err = patchModel(current, values, &lesson); err != nil {
+		return models.Lesson{}, err
+	}
+	lesson.Version = version
+	if _, err = c.Update(ctx, lesson); err != nil {
+		return models.Lesson{}, err
+	}
+	return c.GetByID(ctx, id)
+}
+
+func (c *lessonRepo) GetAll(ctx context.Context, req models.GetAllLessonsRequest) (models.GetAllLessonsResponse, error) {
+	resp := models.GetAllLessonsResponse{}
+
+	if err := parseFilterUUIDs(&req.GroupID, &req.ScheduleID); err != nil {
+		return resp, err
+	}
+	from, err := dateRange(req.FromDate, req.ToDate)
+	if err != nil {
+		return resp, err
+	}
+
+	list, err := newList(req.ListParams, req.Page, req.Limit, lessonSortColumns, func(r lessonRow) interface{} { return r.Id }, "-created_at")
+	if err != nil {
+		return resp, err
+	}
+
+	err = c.db.read(func(t *tables) error {
+		var rows []lessonRow
+		for _, row := range t.lessons {
+			if !deleted(req.Deleted, row.deletedAt) ||
+				req.Search != "" && !contains(req.Search, row.Theme) ||
+				req.GroupID != "" && row.GroupId != req.GroupID ||
+				req.ScheduleID != "" && row.ScheduleId != req.ScheduleID ||
+				!from(row.from) {
+				continue
+			}
+			rows = append(rows, row)
+		}
+
+		rows, resp.Count, resp.NextCursor, err = list.apply(rows)
+		for _, row := range rows {
+			resp.Lessons = append(resp.Lessons, row.get())
+		}
+		return err
+	})
+
+	return resp, err
+}
+
+func (c *lessonRepo) GetByID(ctx context.Context, id string) (models.Lesson, error) {
+	var lesson models.Lesson
+
+	err := c.db.read(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.lessons[id]
+		if !ok || row.deletedAt != 0 {
+			return pgx.ErrNoRows
+		}
+		lesson = row.get()
+		return nil
+	})
+
+	return lesson, err
+}
+
+func (c *lessonRepo) Delete(ctx context.Context, id string, version int) error {
+	return c.db.write(func(t *tables) error {
+		return softDelete(t.lessons, id, version, c.db.now(), func(r *lessonRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (c *lessonRepo) Restore(ctx context.Context, id string) error {
+	return c.db.write(func(t *tables) error {
+		return restore(t.lessons, id, func(r *lessonRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (c *lessonRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+
+	err := c.db.write(func(t *tables) error {
+		purged = purge(t.lessons, deletedBefore, func(r lessonRow) (int64, bool) {
+			return r.deletedAt, referenced(t.tasks, func(task taskRow) bool { return task.LessonId == r.Id })
+		})
+		// the marks of the purged lessons are deleted with them
+		for key := range t.attendance {
+			if _, ok := t.lessons[key.lessonID]; !ok {
+				delete(t.attendance, key)
+			}
+		}
+		return nil
+	})
+
+	return purged, err
+}
@@ -0,0 +1,205 @@
+package memory
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"lms_back/api/models"
+	"lms_back/storage"
+	"sort"
+	"strings"
+)
+
+// column returns the sort value of a row, a string or a float64. Timestamps
+// are formatted with sortTime.
+type column[T any] func(row T) interface{}
+
+type order[T any] struct {
+	value column[T]
+	desc  bool
+}
+
+// list orders and paginates rows like filter.List does in postgres, by page
+// or by a cursor holding the sort values of the last row of the page.
+type list[T any] struct {
+	params      models.ListParams
+	page, limit uint64
+	orders      []order[T]
+	after       []interface{}
+}
+
+// newList parses the sort of params against columns. id is always the last
+// order, so rows with equal sort values keep a stable order.
+func newList[T any](params models.ListParams, page, limit uint64, columns map[string]column[T], id column[T], defaultSort string) (*list[T], error) {
+	if params.Sort == "" {
+		params.Sort = defaultSort
+	}
+
+	l := &list[T]{params: params, page: page, limit: limit}
+	for _, field := range strings.Split(params.Sort, ",") {
+		field = strings.TrimSpace(field)
+		value, ok := columns[strings.TrimPrefix(field, "-")]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", storage.ErrInvalidSort, field)
+		}
+		l.orders = append(l.orders, order[T]{value: value, desc: strings.HasPrefix(field, "-")})
+	}
+	l.orders = append(l.orders, order[T]{value: id})
+
+	if params.UseCursor && params.Cursor != "" {
+		after, err := decodeCursor(params.Cursor, params.Sort)
+		if err != nil || len(after) != len(l.orders) {
+			return nil, storage.ErrInvalidCursor
+		}
+		l.after = after
+	}
+
+	return l, nil
+}
+
+// apply sorts the matching rows and returns the page, the total count, which
+// is 0 in cursor mode, and the cursor of the next page.
+func (l *list[T]) apply(rows []T) ([]T, int16, string, error) {
+	if l.after != nil {
+		matched := rows[:0:0]
+		for _, row := range rows {
+			c, err := l.compare(l.values(row), l.after)
+			if err != nil {
+				return nil, 0, "", err
+			}
+			if c > 0 {
+				matched = append(matched, row)
+			}
+		}
+		rows = matched
+	}
+
+	var err error
+	sort.SliceStable(rows, func(i, j int) bool {
+		c, cmpErr := l.compare(l.values(rows[i]), l.values(rows[j]))
+		if cmpErr != nil {
+			err = cmpErr
+		}
+		return c < 0
+	})
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	if !l.params.UseCursor {
+		count := int16(len(rows))
+		offset := (l.page - 1) * l.limit
+		if offset >= uint64(len(rows)) {
+			return nil, count, "", nil
+		}
+		rows = rows[offset:]
+		if uint64(len(rows)) > l.limit {
+			rows = rows[:l.limit]
+		}
+		return rows, count, "", nil
+	}
+
+	if uint64(len(rows)) <= l.limit {
+		return rows, 0, "", nil
+	}
+	rows = rows[:l.limit]
+	if len(rows) == 0 {
+		return nil, 0, "", nil
+	}
+	return rows, 0, encodeCursor(l.values(rows[len(rows)-1]), l.params.Sort), nil
+}
+
+func (l *list[T]) values(row T) []interface{} {
+	values := make([]interface{}, len(l.orders))
+	for i, order := range l.orders {
+		values[i] = order.value(row)
+	}
+	return values
+}
+
+// compare compares the sort values of two rows in the order of the list. A
+// value of another type than the column, only found in a forged cursor, is an
+// invalid cursor.
+func (l *list[T]) compare(a, b []interface{}) (int, error) {
+	for i, order := range l.orders {
+		var c int
+		switch x := a[i].(type) {
+		case string:
+			y, ok := b[i].(string)
+			if !ok {
+				return 0, storage.ErrInvalidCursor
+			}
+			c = strings.Compare(x, y)
+		case float64:
+			y, ok := b[i].(float64)
+			if !ok {
+				return 0, storage.ErrInvalidCursor
+			}
+			switch {
+			case x < y:
+				c = -1
+			case x > y:
+				c = 1
+			}
+		}
+
+		if order.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c, nil
+		}
+	}
+	return 0, nil
+}
+
+type cursor struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+}
+
+func encodeCursor(values []interface{}, sort string) string {
+	data, _ := json.Marshal(cursor{Sort: sort, Values: values})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the sort values of the cursor. A cursor is only valid
+// with the sort it was issued for.
+func decodeCursor(value, sort string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var c cursor
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if c.Sort != sort {
+		return nil, fmt.Errorf("cursor was issued for sort %q", c.Sort)
+	}
+	for _, v := range c.Values {
+		switch v.(type) {
+		case string, float64:
+		default:
+			return nil, fmt.Errorf("cursor has a %T value", v)
+		}
+	}
+
+	return c.Values, nil
+}
+
+// offsetPage returns the rows of a query ending in OFFSET and LIMIT and the
+// value of its count(id) OVER() column, which is 0 when the page is empty.
+func offsetPage[T any](rows []T, page, limit uint64) ([]T, int64) {
+	count := int64(len(rows))
+	offset := (page - 1) * limit
+	if page == 0 || offset >= uint64(len(rows)) || limit == 0 {
+		return nil, 0
+	}
+	rows = rows[offset:]
+	if uint64(len(rows)) > limit {
+		rows = rows[:limit]
+	}
+	return rows, count
+}
@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type loginAttemptRepo struct {
+	db *db
+}
+
+// branchOf returns the branch recorded with a login of the user, the branch
+// of a teacher is the one of their first group.
+func (t *tables) branchOf(userRole, userID string) string {
+	switch userRole {
+	case "student":
+		if student, ok := t.students[userID]; ok {
+			return t.groups[student.GroupID].Branch_id
+		}
+	case "teacher":
+		var first *groupRow
+		for _, group := range t.groups {
+			if group.Teacher_id == userID && (first == nil || group.createdAt.Before(first.createdAt)) {
+				group := group
+				first = &group
+			}
+		}
+		if first != nil {
+			return first.Branch_id
+		}
+	}
+	return ""
+}
+
+func (l *loginAttemptRepo) Create(ctx context.Context, attempt models.LoginAttempt) error {
+	return l.db.write(func(t *tables) error {
+		userID, err := parseNullUUID(attempt.UserID)
+		if err != nil {
+			return err
+		}
+
+		t.loginAttempts = append(t.loginAttempts, loginAttemptRow{
+			LoginAttempt: models.LoginAttempt{
+				Id:       uuid.New().String(),
+				Login:    attempt.Login,
+				UserRole: attempt.UserRole,
+				UserID:   userID,
+				BranchID: t.branchOf(attempt.UserRole, userID),
+				IP:       attempt.IP,
+				Success:  attempt.Success,
+			},
+			createdAt: l.db.now(),
+		})
+		return nil
+	})
+}
+
+func (l *loginAttemptRepo) GetAll(ctx context.Context, req models.GetAllLoginAttemptsRequest) (models.GetAllLoginAttemptsResponse, error) {
+	resp := models.GetAllLoginAttemptsResponse{}
+
+	if err := parseFilterUUIDs(&req.BranchID); err != nil {
+		return resp, err
+	}
+
+	err := l.db.read(func(t *tables) error {
+		var rows []loginAttemptRow
+		for _, row := range t.loginAttempts {
+			if req.Login != "" && row.Login != req.Login ||
+				req.UserRole != "" && row.UserRole != req.UserRole ||
+				req.BranchID != "" && row.BranchID != req.BranchID ||
+				req.IP != "" && row.IP != req.IP ||
+				req.Success != "" && row.Success != (req.Success == "true") ||
+				!anyOf(row.BranchID, req.BranchIDs) {
+				continue
+			}
+			rows = append(rows, row)
+		}
+		sort.SliceStable(rows, func(i, j int) bool { return rows[i].createdAt.After(rows[j].createdAt) })
+
+		rows, resp.Count = offsetPage(rows, req.Page, req.Limit)
+		for _, row := range rows {
+			attempt := row.LoginAttempt
+			attempt.CreatedAt = formatTime(row.createdAt)
+			resp.LoginAttempts = append(resp.LoginAttempts, attempt)
+		}
+		return nil
+	})
+
+	return resp, err
+}
+
+func (l *loginAttemptRepo) GetThrottle(ctx context.Context, key string) (models.LoginThrottle, error) {
+	throttle := models.LoginThrottle{Key: key}
+
+	err := l.db.read(func(t *tables) error {
+		row, ok := t.loginThrottles[key]
+		if !ok {
+			return nil
+		}
+
+		throttle.Failures = row.failures
+		if left := row.lockedUntil.Sub(l.db.now()); !row.lockedUntil.IsZero() && left > 0 {
+			throttle.RetryAfter = int(math.Ceil(left.Seconds()))
+		}
+		return nil
+	})
+
+	return throttle, err
+}
+
+func (l *loginAttemptRepo) RegisterFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	var failures int
+
+	err := l.db.write(func(t *tables) error {
+		now := l.db.now()
+
+		row, ok := t.loginThrottles[key]
+		if !ok || row.updatedAt.Before(now.Add(-window)) {
+			row.failures = 0
+		}
+		row.failures++
+		row.updatedAt = now
+		t.loginThrottles[key] = row
+
+		failures = row.failures
+		return nil
+	})
+
+	return failures, err
+}
+
+func (l *loginAttemptRepo) Lock(ctx context.Context, key string, duration time.Duration) error {
+	return l.db.write(func(t *tables) error {
+		if row, ok := t.loginThrottles[key]; ok {
+			row.lockedUntil = l.db.now().Add(duration)
+			t.loginThrottles[key] = row
+		}
+		return nil
+	})
+}
+
+func (l *loginAttemptRepo) ResetThrottle(ctx context.Context, key string) error {
+	return l.db.write(func(t *tables) error {
+		delete(t.loginThrottles, key)
+		return nil
+	})
+}
@@ -0,0 +1,181 @@
+// Package memory implements storage.IStorage in memory, for tests that
+// shouldn't need a running postgres. It behaves like the postgres storage:
+// soft deletes, filters, sorting and cursors work the same, CURRENT_TIMESTAMP
+// is fixed for a transaction, and invalid uuids and check constraints fail
+// with the same *pgconn.PgError codes, translated to the same domain errors.
+// Foreign keys are not
+// enforced and text is sorted by bytes instead of by the database collation.
+package memory
+
+import (
+	"context"
+	"lms_back/storage"
+	"sync"
+	"time"
+)
+
+// db holds the tables of a store, or the copy of them a transaction works on.
+type db struct {
+	mu sync.Mutex
+	// version is incremented by every write, a transaction only commits if no
+	// other write happened since it began
+	version uint64
+	tables  *tables
+	// txTime is the start of the transaction, postgres returns it from
+	// CURRENT_TIMESTAMP for every statement of the transaction
+	txTime time.Time
+}
+
+// read runs fn, its errors are translated with storage.DBError like the ones
+// of the postgres storage.
+func (d *db) read(fn func(t *tables) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return storage.DBError(fn(d.tables))
+}
+
+// write runs fn, which has to check everything before it changes a table, so
+// a failed write changes nothing.
+func (d *db) write(fn func(t *tables) error) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := fn(d.tables); err != nil {
+		return storage.DBError(err)
+	}
+	d.version++
+	return nil
+}
+
+func (d *db) now() time.Time {
+	if !d.txTime.IsZero() {
+		return d.txTime
+	}
+	return time.Now().UTC().Round(time.Microsecond)
+}
+
+type Store struct {
+	db   *db
+	inTx bool
+}
+
+func New() Store {
+	return Store{
+		db: &db{tables: newTables()},
+	}
+}
+
+func (s Store) CloseDB() {}
+
+// WithTx runs fn on a copy of the tables, which replaces them if fn succeeds.
+// Instead of the row locks of postgres, the commit fails with a serialization
+// error if anything else was written meanwhile, so no write is lost.
+func (s Store) WithTx(ctx context.Context, fn func(storage.IStorage) error) error {
+	if s.inTx {
+		return fn(s)
+	}
+
+	s.db.mu.Lock()
+	tx := &db{tables: s.db.tables.clone(), txTime: s.db.now()}
+	version := s.db.version
+	s.db.mu.Unlock()
+
+	if err := fn(Store{db: tx, inTx: true}); err != nil {
+		return err
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if tx.version == 0 {
+		return nil
+	}
+	if s.db.version != version {
+		return errSerialization
+	}
+	s.db.tables = tx.tables
+	s.db.version++
+
+	return nil
+}
+
+func (s Store) Admin() storage.IAdminStorage {
+	return &adminRepo{db: s.db}
+}
+
+func (s Store) Branch() storage.IBranchStorage {
+	return &branchRepo{db: s.db}
+}
+
+func (s Store) Group() storage.IGroupStorage {
+	return &groupRepo{db: s.db}
+}
+
+func (s Store) Payment() storage.IPaymentStorage {
+	return &paymentRepo{db: s.db}
+}
+
+func (s Store) Student() storage.IStudentStorage {
+	return &studentRepo{db: s.db}
+}
+
+func (s Store) Teacher() storage.ITeacherStorage {
+	return &teacherRepo{db: s.db}
+}
+
+func (s Store) Schedule() storage.IScheduleStorage {
+	return &scheduleRepo{db: s.db}
+}
+
+func (s Store) Task() storage.ITaskStorage {
+	return &taskRepo{db: s.db}
+}
+
+func (s Store) Lesson() storage.ILessonStorage {
+	return &lessonRepo{db: s.db}
+}
+
+func (s Store) Enrollment() storage.IEnrollmentStorage {
+	return &enrollmentRepo{db: s.db}
+}
+
+func (s Store) Attendance() storage.IAttendanceStorage {
+	return &attendanceRepo{db: s.db}
+}
+
+func (s Store) Submission() storage.ISubmissionStorage {
+	return &submissionRepo{db: s.db}
+}
+
+func (s Store) Gradebook() storage.IGradebookStorage {
+	return &gradebookRepo{db: s.db}
+}
+
+func (s Store) AdminReport() storage.IAdminReportStorage {
+	return &adminReportRepo{db: s.db}
+}
+
+func (s Store) Token() storage.ITokenStorage {
+	return &tokenRepo{db: s.db}
+}
+
+func (s Store) PasswordReset() storage.IPasswordResetStorage {
+	return &passwordResetRepo{db: s.db}
+}
+
+func (s Store) LoginAttempt() storage.ILoginAttemptStorage {
+	return &loginAttemptRepo{db: s.db}
+}
+
+func (s Store) TwoFactor() storage.ITwoFactorStorage {
+	return &twoFactorRepo{db: s.db}
+}
+
+func (s Store) Audit() storage.IAuditStorage {
+	return &auditRepo{db: s.db}
+}
+
+func (s Store) Idempotency() storage.IIdempotencyStorage {
+	return &idempotencyRepo{db: s.db}
+}
@@ -0,0 +1,13 @@
+package memory
+
+import (
+	"lms_back/storage"
+	"lms_back/storage/storagetest"
+	"testing"
+)
+
+func TestStorage(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.IStorage {
+		return New()
+	})
+}
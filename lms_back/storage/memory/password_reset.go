@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type passwordResetRepo struct {
+	db *db
+}
+
+func (p *passwordResetRepo) Create(ctx context.Context, reset models.PasswordReset, ttl time.Duration) (models.PasswordReset, error) {
+	id := uuid.New().String()
+
+	err := p.db.write(func(t *tables) error {
+		userID, err := parseUUID(reset.UserID)
+		if err != nil {
+			return err
+		}
+		if err = check("password_reset", "user_role", reset.UserRole, "admin", "teacher", "student"); err != nil {
+			return err
+		}
+
+		now := p.db.now()
+		for resetID, row := range t.passwordResets {
+			if row.UserID == userID && row.UserRole == reset.UserRole && row.usedAt.IsZero() {
+				row.usedAt = now
+				t.passwordResets[resetID] = row
+			}
+		}
+
+		t.passwordResets[id] = passwordResetRow{
+			PasswordReset: models.PasswordReset{
+				Id:       id,
+				UserID:   userID,
+				UserRole: reset.UserRole,
+				CodeHash: reset.CodeHash,
+			},
+			expiresAt: now.Add(ttl),
+			createdAt: now,
+		}
+		return nil
+	})
+	if err != nil {
+		return models.PasswordReset{}, err
+	}
+
+	return models.PasswordReset{
+		Id:       id,
+		UserID:   reset.UserID,
+		UserRole: reset.UserRole,
+		CodeHash: reset.CodeHash,
+	}, nil
+}
+
+func (p *passwordResetRepo) GetActive(ctx context.Context, userID, userRole string) (models.PasswordReset, error) {
+	var reset models.PasswordReset
+
+	err := p.db.read(func(t *tables) error {
+		userID, err := parseUUID(userID)
+		if err != nil {
+			return err
+		}
+
+		now := p.db.now()
+		var latest *passwordResetRow
+		for _, row := range t.passwordResets {
+			if row.UserID != userID || row.UserRole != userRole || !row.usedAt.IsZero() || !row.expiresAt.After(now) {
+				continue
+			}
+			if latest == nil || row.createdAt.After(latest.createdAt) {
+				row := row
+				latest = &row
+			}
+		}
+		if latest == nil {
+			return pgx.ErrNoRows
+		}
+
+		reset = latest.PasswordReset
+		reset.ExpiresAt = formatTime(latest.expiresAt)
+		reset.CreatedAt = formatTime(latest.createdAt)
+		return nil
+	})
+
+	return reset, err
+}
+
+func (p *passwordResetRepo) IncrementAttempts(ctx context.Context, id string) error {
+	return p.db.write(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		if row, ok := t.passwordResets[id]; ok {
+			row.Attempts++
+			t.passwordResets[id] = row
+		}
+		return nil
+	})
+}
+
+func (p *passwordResetRepo) MarkUsed(ctx context.Context, id string) (bool, error) {
+	var used bool
+
+	err := p.db.write(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.passwordResets[id]
+		if !ok || !row.usedAt.IsZero() {
+			return nil
+		}
+		row.usedAt = p.db.now()
+		t.passwordResets[id] = row
+		used = true
+		return nil
+	})
+
+	return used, err
+}
@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"lms_back/storage"
+)
+
+// patchModel sets the values on a copy of current and stores it in patched,
+// both are models with the json names of the patched fields. A value of a
+// field the model keeps as a string, like the score of a task, is formatted.
+func patchModel(current interface{}, values []storage.PatchValue, patched interface{}) error {
+	data, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]interface{}{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for _, value := range values {
+		if _, ok := fields[value.Field].(string); ok {
+			fields[value.Field] = fmt.Sprint(value.Value)
+			continue
+		}
+		fields[value.Field] = value.Value
+	}
+
+	if data, err = json.Marshal(fields); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, patched)
+}
@@ -0,0 +1,223 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var paymentSortColumns = map[string]column[paymentRow]{
+	"price":      func(r paymentRow) interface{} { return r.Price },
+	"created_at": func(r paymentRow) interface{} { return sortTime(r.createdAt) },
+}
+
+type paymentRepo struct {
+	db *db
+}
+
+func (r paymentRow) get() models.Payment {
+	return models.Payment{
+		Id:         r.Id,
+		Price:      r.Price,
+		Student_id: r.Student_id,
+		Branch_id:  r.Branch_id,
+		Admin_id:   r.Admin_id,
+		Version:    r.Version,
+		CreatedAt:  formatTime(r.createdAt),
+		UpdatedAt:  formatTime(r.updatedAt),
+	}
+}
+
+func (p *paymentRepo) Create(ctx context.Context, payment models.CreatePayment) (models.Payment, error) {
+	id := uuid.New().String()
+
+	err := p.db.write(func(t *tables) error {
+		row := paymentRow{Payment: models.Payment{
+			Id:         id,
+			Price:      numeric(payment.Price),
+			Student_id: payment.Student_id,
+			Branch_id:  payment.Branch_id,
+			Admin_id:   payment.Admin_id,
+			Version:    1,
+		}}
+		if err := parseUUIDs(&row.Student_id, &row.Branch_id, &row.Admin_id); err != nil {
+			return err
+		}
+
+		now := p.db.now()
+		row.createdAt, row.updatedAt = now, now
+		t.payments[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	return models.Payment{
+		Id:         id,
+		Price:      payment.Price,
+		Student_id: payment.Student_id,
+		Branch_id:  payment.Branch_id,
+		Admin_id:   payment.Admin_id,
+		Version:    1,
+	}, nil
+}
+
+func (p *paymentRepo) GetAll(ctx context.Context, req models.GetAllPaymentsRequest) (models.GetAllPaymentsResponse, error) {
+	resp := models.GetAllPaymentsResponse{}
+
+	if err := parseFilterUUIDs(&req.StudentID, &req.BranchID, &req.AdminID); err != nil {
+		return resp, err
+	}
+	created, err := dateRange(req.CreatedFrom, req.CreatedTo)
+	if err != nil {
+		return resp, err
+	}
+
+	list, err := newList(req.ListParams, req.Page, req.Limit, paymentSortColumns, func(r paymentRow) interface{} { return r.Id }, "-created_at")
+	if err != nil {
+		return resp, err
+	}
+
+	err = p.db.read(func(t *tables) error {
+		// search matches the name and login of the student, deleted or not
+		studentMatches := func(id string) bool {
+			student, ok := t.students[id]
+			return ok && contains(req.Search, student.Full_Name, student.Login)
+		}
+
+		var rows []paymentRow
+		for _, row := range t.payments {
+			if !deleted(req.Deleted, row.deletedAt) ||
+				req.StudentID != "" && row.Student_id != req.StudentID ||
+				req.BranchID != "" && row.Branch_id != req.BranchID ||
+				req.AdminID != "" && row.Admin_id != req.AdminID ||
+				req.MinPrice != 0 && row.Price < req.MinPrice ||
+				req.MaxPrice != 0 && row.Price > req.MaxPrice ||
+				!created(row.createdAt) ||
+				!anyOf(row.Branch_id, req.BranchIDs) ||
+				req.Search != "" && !studentMatches(row.Student_id) {
+				continue
+			}
+			rows = append(rows, row)
+		}
+
+		rows, resp.Count, resp.NextCursor, err = list.apply(rows)
+		for _, row := range rows {
+			resp.Payments = append(resp.Payments, row.get())
+		}
+		return err
+	})
+
+	return resp, err
+}
+
+func (p *paymentRepo) GetByID(ctx context.Context, id string) (models.Payment, error) {
+	var payment models.Payment
+
+	err := p.db.read(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.payments[id]
+		if !ok || row.deletedAt != 0 {
+			return pgx.ErrNoRows
+		}
+		payment = row.get()
+		return nil
+	})
+
+	return payment, err
+}
+
+// GetByIDForUpdate is GetByID, instead of waiting for the row lock a
+// concurrent change of the payment fails the commit of the transaction.
+func (p *paymentRepo) GetByIDForUpdate(ctx context.Context, id string) (models.Payment, error) {
+	return p.GetByID(ctx, id)
+}
+
+func (p *paymentRepo) Update(ctx context.Context, payment models.Payment) (models.Payment, error) {
+	err := p.db.write(func(t *tables) error {
+		id, studentID, branchID, adminID := payment.Id, payment.Student_id, payment.Branch_id, payment.Admin_id
+		if err := parseUUIDs(&id, &studentID, &branchID, &adminID); err != nil {
+			return err
+		}
+
+		row, ok := t.payments[id]
+		if err := checkVersion(ok && row.deletedAt == 0, row.Version, payment.Version); err != nil {
+			return err
+		}
+		row.Price, row.Student_id, row.Branch_id, row.Admin_id = numeric(payment.Price), studentID, branchID, adminID
+		row.Version++
+		row.updatedAt = p.db.now()
+		t.payments[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	return models.Payment{
+		Id:         payment.Id,
+		Price:      payment.Price,
+		Student_id: payment.Student_id,
+		Branch_id:  payment.Branch_id,
+		Admin_id:   payment.Admin_id,
+		Version:    payment.Version + 1,
+		CreatedAt:  payment.CreatedAt,
+		UpdatedAt:  payment.UpdatedAt,
+	}, nil
+}
+
+func (p *paymentRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Payment, error) {
+	values, err := storage.PaymentPatch.Decode(patch)
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	current, err := p.GetByID(ctx, id)
+	if err != nil {
+		return models.Payment{}, err
+	}
+
+	payment := models.Payment{}
+	if err = patchModel(current, values, &payment); err != nil {
+		return models.Payment{}, err
+	}
+	payment.Version = version
+	if _, err = p.Update(ctx, payment); err != nil {
+		return models.Payment{}, err
+	}
+	return p.GetByID(ctx, id)
+}
+
+func (p *paymentRepo) Delete(ctx context.Context, id string, version int) error {
+	return p.db.write(func(t *tables) error {
+		return softDelete(t.payments, id, version, p.db.now(), func(r *paymentRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (p *paymentRepo) Restore(ctx context.Context, id string) error {
+	return p.db.write(func(t *tables) error {
+		return restore(t.payments, id, func(r *paymentRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (p *paymentRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+
+	err := p.db.write(func(t *tables) error {
+		purged = purge(t.payments, deletedBefore, func(r paymentRow) (int64, bool) {
+			return r.deletedAt, false
+		})
+		return nil
+	})
+
+	return purged, err
+}
@@ -0,0 +1,58 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"sort"
+)
+
+type adminReportRepo struct {
+	db *db
+}
+
+// GetByIDAdminPayment joins the admin with its payments, like the postgres
+// query it ignores deleted_at on both sides.
+func (c *adminReportRepo) GetByIDAdminPayment(ctx context.Context, req models.AdminKey) ([]models.AdminPayment, error) {
+	var adminPayments []models.AdminPayment
+
+	err := c.db.read(func(t *tables) error {
+		id, err := parseUUID(req.Id)
+		if err != nil {
+			return err
+		}
+
+		admin, ok := t.admins[id]
+		if !ok {
+			return nil
+		}
+
+		var payments []paymentRow
+		for _, payment := range t.payments {
+			if payment.Admin_id == id {
+				payments = append(payments, payment)
+			}
+		}
+		sort.SliceStable(payments, func(i, j int) bool { return payments[i].createdAt.Before(payments[j].createdAt) })
+
+		for _, payment := range payments {
+			adminPayments = append(adminPayments, models.AdminPayment{
+				Admin: models.GetAdmin{
+					Id:        admin.Id,
+					Full_Name: admin.Full_Name,
+				},
+				Payment: models.Payment{
+					Id:         payment.Id,
+					Price:      payment.Price,
+					Student_id: payment.Student_id,
+					Branch_id:  payment.Branch_id,
+				},
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return adminPayments, nil
+}
@@ -0,0 +1,200 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var scheduleSortColumns = map[string]column[scheduleRow]{
+	"date":       func(r scheduleRow) interface{} { return r.Date },
+	"created_at": func(r scheduleRow) interface{} { return sortTime(r.createdAt) },
+}
+
+type scheduleRepo struct {
+	db *db
+}
+
+func (r scheduleRow) get() models.Schedule {
+	schedule := r.Schedule
+	schedule.Created_at = formatTime(r.createdAt)
+	schedule.Updated_at = formatTime(r.updatedAt)
+	return schedule
+}
+
+// parseSchedule checks the columns of the schedule and returns it the way it is stored.
+func parseSchedule(schedule models.Schedule) (models.Schedule, error) {
+	if err := parseUUIDs(&schedule.Group_id, &schedule.Branch_id, &schedule.Teacher_id); err != nil {
+		return models.Schedule{}, err
+	}
+
+	var err error
+	if schedule.Start_time, err = parseTime(schedule.Start_time); err != nil {
+		return models.Schedule{}, err
+	}
+	if schedule.End_time, err = parseTime(schedule.End_time); err != nil {
+		return models.Schedule{}, err
+	}
+
+	return schedule, nil
+}
+
+func (c *scheduleRepo) Create(ctx context.Context, schedule models.Schedule) (models.Schedule, error) {
+	id := uuid.New().String()
+
+	err := c.db.write(func(t *tables) error {
+		stored, err := parseSchedule(schedule)
+		if err != nil {
+			return err
+		}
+
+		now := c.db.now()
+		row := scheduleRow{Schedule: stored, createdAt: now, updatedAt: now}
+		row.Id, row.Version = id, 1
+		t.schedules[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	schedule.Id, schedule.Version = id, 1
+	return schedule, nil
+}
+
+func (c *scheduleRepo) Update(ctx context.Context, schedule models.Schedule) (models.Schedule, error) {
+	err := c.db.write(func(t *tables) error {
+		id, err := parseUUID(schedule.Id)
+		if err != nil {
+			return err
+		}
+		stored, err := parseSchedule(schedule)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.schedules[id]
+		if err = checkVersion(ok && row.deletedAt == 0, row.Version, schedule.Version); err != nil {
+			return err
+		}
+		stored.Id, stored.Version = id, row.Version+1
+		row.Schedule = stored
+		row.updatedAt = c.db.now()
+		t.schedules[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	schedule.Version++
+	return schedule, nil
+}
+
+func (c *scheduleRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Schedule, error) {
+	values, err := storage.SchedulePatch.Decode(patch)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	current, err := c.GetByID(ctx, id)
+	if err != nil {
+		return models.Schedule{}, err
+	}
+
+	schedule := models.Schedule{}
+	if err = patchModel(current, values, &schedule); err != nil {
+		return models.Schedule{}, err
+	}
+	schedule.Version = version
+	if _, err = c.Update(ctx, schedule); err != nil {
+		return models.Schedule{}, err
+	}
+	return c.GetByID(ctx, id)
+}
+
+func (c *scheduleRepo) GetAll(ctx context.Context, req models.GetAllSchedulesRequest) (models.GetAllSchedulesResponse, error) {
+	resp := models.GetAllSchedulesResponse{}
+
+	if err := parseFilterUUIDs(&req.GroupID, &req.BranchID, &req.TeacherID); err != nil {
+		return resp, err
+	}
+
+	list, err := newList(req.ListParams, req.Page, req.Limit, scheduleSortColumns, func(r scheduleRow) interface{} { return r.Id }, "-created_at")
+	if err != nil {
+		return resp, err
+	}
+
+	err = c.db.read(func(t *tables) error {
+		var rows []scheduleRow
+		for _, row := range t.schedules {
+			if !deleted(req.Deleted, row.deletedAt) ||
+				req.Search != "" && !contains(req.Search, row.Group_type) ||
+				req.GroupID != "" && row.Group_id != req.GroupID ||
+				req.BranchID != "" && row.Branch_id != req.BranchID ||
+				req.TeacherID != "" && row.Teacher_id != req.TeacherID ||
+				req.Date != "" && row.Date != req.Date ||
+				!anyOf(row.Branch_id, req.BranchIDs) {
+				continue
+			}
+			rows = append(rows, row)
+		}
+
+		rows, resp.Count, resp.NextCursor, err = list.apply(rows)
+		for _, row := range rows {
+			resp.Schedules = append(resp.Schedules, row.get())
+		}
+		return err
+	})
+
+	return resp, err
+}
+
+func (c *scheduleRepo) GetByID(ctx context.Context, id string) (models.Schedule, error) {
+	var schedule models.Schedule
+
+	err := c.db.read(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.schedules[id]
+		if !ok || row.deletedAt != 0 {
+			return pgx.ErrNoRows
+		}
+		schedule = row.get()
+		return nil
+	})
+
+	return schedule, err
+}
+
+func (c *scheduleRepo) Delete(ctx context.Context, id string, version int) error {
+	return c.db.write(func(t *tables) error {
+		return softDelete(t.schedules, id, version, c.db.now(), func(r *scheduleRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (c *scheduleRepo) Restore(ctx context.Context, id string) error {
+	return c.db.write(func(t *tables) error {
+		return restore(t.schedules, id, func(r *scheduleRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (c *scheduleRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+
+	err := c.db.write(func(t *tables) error {
+		purged = purge(t.schedules, deletedBefore, func(r scheduleRow) (int64, bool) {
+			return r.deletedAt, referenced(t.lessons, func(l lessonRow) bool { return l.ScheduleId == r.Id })
+		})
+		return nil
+	})
+
+	return purged, err
+}
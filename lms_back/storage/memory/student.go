@@ -0,0 +1,363 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/storage"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var studentSortColumns = map[string]column[studentRow]{
+	"full_name":  func(r studentRow) interface{} { return r.Full_Name },
+	"email":      func(r studentRow) interface{} { return r.Email },
+	"login":      func(r studentRow) interface{} { return r.Login },
+	"age":        func(r studentRow) interface{} { return float64(r.Age) },
+	"paid_sum":   func(r studentRow) interface{} { return r.PaidSum },
+	"status":     func(r studentRow) interface{} { return r.Status },
+	"created_at": func(r studentRow) interface{} { return sortTime(r.createdAt) },
+	"updated_at": func(r studentRow) interface{} { return sortTime(r.updatedAt) },
+}
+
+type studentRepo struct {
+	db *db
+}
+
+func (r studentRow) get() models.GetStudent {
+	return models.GetStudent{
+		ID:         r.ID,
+		Full_Name:  r.Full_Name,
+		Email:      r.Email,
+		Age:        r.Age,
+		PaidSum:    r.PaidSum,
+		Status:     r.Status,
+		Login:      r.Login,
+		GroupID:    r.GroupID,
+		Version:    r.Version,
+		Created_At: formatTime(r.createdAt),
+		Updated_At: formatTime(r.updatedAt),
+	}
+}
+
+func (c *studentRepo) Create(ctx context.Context, student models.Student) (models.GetStudent, error) {
+	id := uuid.New().String()
+
+	err := c.db.write(func(t *tables) error {
+		row := studentRow{Student: student}
+		if err := parseUUIDs(&row.GroupID); err != nil {
+			return err
+		}
+
+		now := c.db.now()
+		row.ID, row.PaidSum, row.Deleted_At, row.Version = id, numeric(student.PaidSum), 0, 1
+		row.createdAt, row.updatedAt = now, now
+		t.students[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.GetStudent{}, err
+	}
+
+	return models.GetStudent{
+		ID:         id,
+		Full_Name:  student.Full_Name,
+		Email:      student.Email,
+		Age:        student.Age,
+		PaidSum:    student.PaidSum,
+		Status:     student.Status,
+		Login:      student.Login,
+		GroupID:    student.GroupID,
+		Version:    1,
+		Created_At: student.Created_At,
+		Updated_At: student.Updated_At,
+	}, nil
+}
+
+func (c *studentRepo) Update(ctx context.Context, student models.Student) (models.GetStudent, error) {
+	err := c.db.write(func(t *tables) error {
+		id, groupID := student.ID, student.GroupID
+		if err := parseUUIDs(&id, &groupID); err != nil {
+			return err
+		}
+
+		row, ok := t.students[id]
+		if err := checkVersion(ok && row.deletedAt == 0, row.Version, student.Version); err != nil {
+			return err
+		}
+		row.Full_Name, row.Email, row.Age = student.Full_Name, student.Email, student.Age
+		row.Login, row.GroupID, row.Status = student.Login, groupID, student.Status
+		row.Version++
+		row.updatedAt = c.db.now()
+		t.students[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.GetStudent{}, err
+	}
+
+	return models.GetStudent{
+		ID:         student.ID,
+		Full_Name:  student.Full_Name,
+		Email:      student.Email,
+		Age:        student.Age,
+		PaidSum:    student.PaidSum,
+		Status:     student.Status,
+		Login:      student.Login,
+		GroupID:    student.GroupID,
+		Version:    student.Version + 1,
+		Created_At: student.Created_At,
+		Updated_At: student.Updated_At,
+		Deleted_At: student.Deleted_At,
+	}, nil
+}
+
+func (c *studentRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.GetStudent, error) {
+	values, err := storage.StudentPatch.Decode(patch)
+	if err != nil {
+		return models.GetStudent{}, err
+	}
+
+	current, err := c.GetByID(ctx, id)
+	if err != nil {
+		return models.GetStudent{}, err
+	}
+
+	student := models.Student{}
+	if err = patchModel(current, values, &student); err != nil {
+		return models.GetStudent{}, err
+	}
+	student.Version = version
+	if _, err = c.Update(ctx, student); err != nil {
+		return models.GetStudent{}, err
+	}
+	return c.GetByID(ctx, id)
+}
+
+func (c *studentRepo) UpdatePassword(ctx context.Context, id, password string) error {
+	return c.db.write(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.students[id]
+		if !ok || row.deletedAt != 0 {
+			return nil
+		}
+		row.Password = password
+		row.Version++
+		row.updatedAt = c.db.now()
+		t.students[id] = row
+		return nil
+	})
+}
+
+// AddPaidSum adds amount, which may be negative, to the paid sum of the
+// student, deleted or not.
+func (c *studentRepo) AddPaidSum(ctx context.Context, id string, amount float64) error {
+	return c.db.write(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.students[id]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		row.PaidSum = numeric(row.PaidSum + numeric(amount))
+		row.Version++
+		row.updatedAt = c.db.now()
+		t.students[id] = row
+		return nil
+	})
+}
+
+func (c *studentRepo) SetGroup(ctx context.Context, id, groupID string) error {
+	return c.db.write(func(t *tables) error {
+		if err := parseUUIDs(&id, &groupID); err != nil {
+			return err
+		}
+
+		row, ok := t.students[id]
+		if !ok || row.deletedAt != 0 {
+			return pgx.ErrNoRows
+		}
+		row.GroupID = groupID
+		row.Version++
+		row.updatedAt = c.db.now()
+		t.students[id] = row
+		return nil
+	})
+}
+
+func (c *studentRepo) GetAll(ctx context.Context, req models.GetAllStudentsRequest) (models.GetAllStudentsResponse, error) {
+	resp := models.GetAllStudentsResponse{}
+
+	if err := parseFilterUUIDs(&req.GroupID, &req.EnrolledGroupID, &req.BranchID); err != nil {
+		return resp, err
+	}
+	created, err := dateRange(req.CreatedFrom, req.CreatedTo)
+	if err != nil {
+		return resp, err
+	}
+
+	list, err := newList(req.ListParams, req.Page, req.Limit, studentSortColumns, func(r studentRow) interface{} { return r.ID }, "-created_at")
+	if err != nil {
+		return resp, err
+	}
+
+	err = c.db.read(func(t *tables) error {
+		// the branch of a student is the branch of the group, deleted or not
+		inBranch := func(row studentRow, branchIDs []string) bool {
+			group, ok := t.groups[row.GroupID]
+			return ok && anyOf(group.Branch_id, branchIDs)
+		}
+		enrolled := func(row studentRow) bool {
+			return referenced(t.enrollments, func(e enrollmentRow) bool {
+				return e.StudentID == row.ID && e.GroupID == req.EnrolledGroupID && e.Status == models.EnrollmentActive
+			})
+		}
+
+		var rows []studentRow
+		for _, row := range t.students {
+			if !deleted(req.Deleted, row.deletedAt) ||
+				req.Search != "" && !contains(req.Search, row.Full_Name, row.Email, row.Login) ||
+				req.Status != "" && row.Status != req.Status ||
+				req.GroupID != "" && row.GroupID != req.GroupID ||
+				req.EnrolledGroupID != "" && !enrolled(row) ||
+				!created(row.createdAt) ||
+				req.BranchID != "" && !inBranch(row, []string{req.BranchID}) ||
+				req.BranchIDs != nil && !inBranch(row, req.BranchIDs) {
+				continue
+			}
+			rows = append(rows, row)
+		}
+
+		rows, resp.Count, resp.NextCursor, err = list.apply(rows)
+		for _, row := range rows {
+			resp.Students = append(resp.Students, row.get())
+		}
+		return err
+	})
+
+	return resp, err
+}
+
+func (c *studentRepo) GetByID(ctx context.Context, id string) (models.GetStudent, error) {
+	var student models.GetStudent
+
+	err := c.db.read(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.students[id]
+		if !ok || row.deletedAt != 0 {
+			return pgx.ErrNoRows
+		}
+		student = row.get()
+		return nil
+	})
+
+	return student, err
+}
+
+func (s *studentRepo) GetByLogin(ctx context.Context, login string) (models.Student, error) {
+	var student models.Student
+
+	err := s.db.read(func(t *tables) error {
+		row, ok := studentByLogin(t, login)
+		if !ok {
+			return pgx.ErrNoRows
+		}
+
+		get := row.get()
+		student = models.Student{
+			ID:         get.ID,
+			Full_Name:  get.Full_Name,
+			Email:      get.Email,
+			Age:        get.Age,
+			PaidSum:    get.PaidSum,
+			Status:     get.Status,
+			Login:      get.Login,
+			Password:   row.Password,
+			GroupID:    get.GroupID,
+			Version:    get.Version,
+			Created_At: get.Created_At,
+			Updated_At: get.Updated_At,
+		}
+		return nil
+	})
+
+	return student, err
+}
+
+func (c *studentRepo) GetPassword(ctx context.Context, login string) (string, error) {
+	var password string
+
+	err := c.db.read(func(t *tables) error {
+		row, ok := studentByLogin(t, login)
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		password = row.Password
+		return nil
+	})
+
+	return password, err
+}
+
+func studentByLogin(t *tables, login string) (studentRow, bool) {
+	for _, row := range t.students {
+		if row.Login == login && row.deletedAt == 0 {
+			return row, true
+		}
+	}
+	return studentRow{}, false
+}
+
+func (c *studentRepo) Delete(ctx context.Context, id string, version int) error {
+	return c.db.write(func(t *tables) error {
+		return softDelete(t.students, id, version, c.db.now(), func(r *studentRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (c *studentRepo) Restore(ctx context.Context, id string) error {
+	return c.db.write(func(t *tables) error {
+		return restore(t.students, id, func(r *studentRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (c *studentRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+
+	err := c.db.write(func(t *tables) error {
+		purged = purge(t.students, deletedBefore, func(r studentRow) (int64, bool) {
+			return r.deletedAt, referenced(t.payments, func(p paymentRow) bool { return p.Student_id == r.ID })
+		})
+		// the enrollments, marks and submissions of the purged students are
+		// deleted with them
+		for id, row := range t.enrollments {
+			if _, ok := t.students[row.StudentID]; !ok {
+				delete(t.enrollments, id)
+			}
+		}
+		for key := range t.attendance {
+			if _, ok := t.students[key.studentID]; !ok {
+				delete(t.attendance, key)
+			}
+		}
+		for id, row := range t.submissions {
+			if _, ok := t.students[row.StudentID]; !ok {
+				delete(t.submissions, id)
+			}
+		}
+		return nil
+	})
+
+	return purged, err
+}
@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"sort"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type submissionRepo struct {
+	db *db
+}
+
+func (r submissionRow) get() models.Submission {
+	submission := r.Submission
+	if r.Score != nil {
+		score := *r.Score
+		submission.Score = &score
+	}
+	submission.SubmittedAt = formatTime(r.submittedAt)
+	submission.GradedAt = formatTime(r.gradedAt)
+	submission.CreatedAt = formatTime(r.createdAt)
+	submission.UpdatedAt = formatTime(r.updatedAt)
+	return submission
+}
+
+func (s *submissionRepo) Submit(ctx context.Context, submission models.Submission) (models.Submission, error) {
+	var submitted submissionRow
+
+	err := s.db.write(func(t *tables) error {
+		if err := parseUUIDs(&submission.TaskID, &submission.StudentID); err != nil {
+			return err
+		}
+
+		now := s.db.now()
+		row := submissionRow{Submission: models.Submission{Id: uuid.New().String()}, createdAt: now}
+		for _, existing := range t.submissions {
+			if existing.TaskID == submission.TaskID && existing.StudentID == submission.StudentID {
+				row = existing
+				break
+			}
+		}
+		if row.Score != nil {
+			return pgx.ErrNoRows
+		}
+
+		row.TaskID, row.StudentID = submission.TaskID, submission.StudentID
+		row.Answer, row.Attachment, row.Late = submission.Answer, submission.Attachment, submission.Late
+		row.submittedAt, row.updatedAt = now, now
+		t.submissions[row.Id] = row
+		submitted = row
+		return nil
+	})
+	if err != nil {
+		return models.Submission{}, err
+	}
+
+	return submitted.get(), nil
+}
+
+func (s *submissionRepo) Grade(ctx context.Context, id string, score int, feedback, gradedBy string) (models.Submission, error) {
+	var graded submissionRow
+
+	err := s.db.write(func(t *tables) error {
+		if err := parseUUIDs(&id); err != nil {
+			return err
+		}
+		var err error
+		if gradedBy, err = parseNullUUID(gradedBy); err != nil {
+			return err
+		}
+		if score < 0 {
+			return check("task_submission", "score", strconv.Itoa(score))
+		}
+
+		row, ok := t.submissions[id]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+
+		now := s.db.now()
+		row.Score, row.Feedback, row.GradedBy = &score, feedback, gradedBy
+		row.gradedAt, row.updatedAt = now, now
+		t.submissions[id] = row
+		graded = row
+		return nil
+	})
+	if err != nil {
+		return models.Submission{}, err
+	}
+
+	return graded.get(), nil
+}
+
+func (s *submissionRepo) GetByID(ctx context.Context, id string) (models.Submission, error) {
+	var submission models.Submission
+
+	err := s.db.read(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.submissions[id]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		submission = row.get()
+		return nil
+	})
+
+	return submission, err
+}
+
+func (s *submissionRepo) GetAll(ctx context.Context, req models.GetAllSubmissionsRequest) (models.GetAllSubmissionsResponse, error) {
+	resp := models.GetAllSubmissionsResponse{}
+
+	if err := parseFilterUUIDs(&req.TaskID, &req.StudentID); err != nil {
+		return resp, err
+	}
+
+	err := s.db.read(func(t *tables) error {
+		var rows []submissionRow
+		for _, row := range t.submissions {
+			if req.TaskID != "" && row.TaskID != req.TaskID ||
+				req.StudentID != "" && row.StudentID != req.StudentID {
+				continue
+			}
+			rows = append(rows, row)
+		}
+		sort.Slice(rows, func(i, j int) bool {
+			if !rows[i].submittedAt.Equal(rows[j].submittedAt) {
+				return rows[i].submittedAt.After(rows[j].submittedAt)
+			}
+			return rows[i].Id < rows[j].Id
+		})
+
+		rows, resp.Count = offsetPage(rows, req.Page, req.Limit)
+		for _, row := range rows {
+			resp.Submissions = append(resp.Submissions, row.get())
+		}
+		return nil
+	})
+
+	return resp, err
+}
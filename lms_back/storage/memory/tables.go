@@ -0,0 +1,217 @@
+package memory
+
+import (
+	"lms_back/api/models"
+	"maps"
+	"slices"
+	"time"
+)
+
+// rows keep the columns postgres computes, the timestamps and the deletion
+// time, next to the model.
+type (
+	adminRow struct {
+		models.Admin
+		branchIDs            []string
+		createdAt, updatedAt time.Time
+		deletedAt            int64
+	}
+
+	branchRow struct {
+		models.Branch
+		// updatedAt is zero until the branch is updated, the column has no default
+		createdAt, updatedAt time.Time
+		deletedAt            int64
+	}
+
+	groupRow struct {
+		models.Group
+		createdAt, updatedAt time.Time
+		deletedAt            int64
+	}
+
+	studentRow struct {
+		models.Student
+		createdAt, updatedAt time.Time
+		deletedAt            int64
+	}
+
+	teacherRow struct {
+		models.Teacher
+		createdAt, updatedAt time.Time
+		deletedAt            int64
+	}
+
+	paymentRow struct {
+		models.Payment
+		createdAt, updatedAt time.Time
+		deletedAt            int64
+	}
+
+	scheduleRow struct {
+		models.Schedule
+		createdAt, updatedAt time.Time
+		deletedAt            int64
+	}
+
+	lessonRow struct {
+		models.Lesson
+		from, to             time.Time
+		createdAt, updatedAt time.Time
+		deletedAt            int64
+	}
+
+	taskRow struct {
+		models.Task
+		score                int
+		deadline             time.Time
+		createdAt, updatedAt time.Time
+		deletedAt            int64
+	}
+
+	enrollmentRow struct {
+		models.Enrollment
+		// leftAt is zero while the enrollment is active
+		enrolledAt, leftAt time.Time
+	}
+
+	attendanceRow struct {
+		models.Attendance
+		createdAt, updatedAt time.Time
+	}
+
+	submissionRow struct {
+		models.Submission
+		// gradedAt is zero until the submission is graded
+		submittedAt, gradedAt, createdAt, updatedAt time.Time
+	}
+
+	revokedTokenRow struct {
+		models.RevokedToken
+		expiresAt time.Time
+	}
+
+	passwordResetRow struct {
+		models.PasswordReset
+		expiresAt, usedAt, createdAt time.Time
+	}
+
+	loginAttemptRow struct {
+		models.LoginAttempt
+		createdAt time.Time
+	}
+
+	loginThrottleRow struct {
+		failures               int
+		lockedUntil, updatedAt time.Time
+	}
+
+	twoFactorRow struct {
+		models.TwoFactor
+		createdAt, enabledAt time.Time
+	}
+
+	recoveryCodeRow struct {
+		models.RecoveryCode
+		usedAt, createdAt time.Time
+	}
+
+	auditLogRow struct {
+		models.AuditLog
+		createdAt time.Time
+	}
+
+	idempotencyKeyRow struct {
+		models.IdempotencyKey
+		createdAt time.Time
+	}
+)
+
+// userKey is the key of the tables with a row per user and role.
+type userKey struct {
+	userID, userRole string
+}
+
+// attendanceKey is the primary key of a mark, a student has one per lesson.
+type attendanceKey struct {
+	lessonID, studentID string
+}
+
+// idempotencyID is the primary key of an idempotency key, keys are per user.
+type idempotencyID struct {
+	userID, key string
+}
+
+type tables struct {
+	admins    map[string]adminRow
+	branches  map[string]branchRow
+	groups    map[string]groupRow
+	students  map[string]studentRow
+	teachers  map[string]teacherRow
+	payments  map[string]paymentRow
+	schedules map[string]scheduleRow
+	lessons   map[string]lessonRow
+	tasks     map[string]taskRow
+
+	enrollments     map[string]enrollmentRow
+	attendance      map[attendanceKey]attendanceRow
+	submissions     map[string]submissionRow
+	revokedTokens   map[string]revokedTokenRow
+	passwordResets  map[string]passwordResetRow
+	loginAttempts   []loginAttemptRow
+	loginThrottles  map[string]loginThrottleRow
+	twoFactors      map[userKey]twoFactorRow
+	recoveryCodes   map[string]recoveryCodeRow
+	auditLogs       []auditLogRow
+	idempotencyKeys map[idempotencyID]idempotencyKeyRow
+}
+
+func newTables() *tables {
+	return &tables{
+		admins:          map[string]adminRow{},
+		branches:        map[string]branchRow{},
+		groups:          map[string]groupRow{},
+		students:        map[string]studentRow{},
+		teachers:        map[string]teacherRow{},
+		payments:        map[string]paymentRow{},
+		schedules:       map[string]scheduleRow{},
+		lessons:         map[string]lessonRow{},
+		tasks:           map[string]taskRow{},
+		enrollments:     map[string]enrollmentRow{},
+		attendance:      map[attendanceKey]attendanceRow{},
+		submissions:     map[string]submissionRow{},
+		revokedTokens:   map[string]revokedTokenRow{},
+		passwordResets:  map[string]passwordResetRow{},
+		loginThrottles:  map[string]loginThrottleRow{},
+		twoFactors:      map[userKey]twoFactorRow{},
+		recoveryCodes:   map[string]recoveryCodeRow{},
+		idempotencyKeys: map[idempotencyID]idempotencyKeyRow{},
+	}
+}
+
+// clone copies the tables for a transaction. Rows are values, the slices in
+// them are replaced rather than modified, so copying the maps is enough.
+func (t *tables) clone() *tables {
+	return &tables{
+		admins:          maps.Clone(t.admins),
+		branches:        maps.Clone(t.branches),
+		groups:          maps.Clone(t.groups),
+		students:        maps.Clone(t.students),
+		teachers:        maps.Clone(t.teachers),
+		payments:        maps.Clone(t.payments),
+		schedules:       maps.Clone(t.schedules),
+		lessons:         maps.Clone(t.lessons),
+		tasks:           maps.Clone(t.tasks),
+		enrollments:     maps.Clone(t.enrollments),
+		attendance:      maps.Clone(t.attendance),
+		submissions:     maps.Clone(t.submissions),
+		revokedTokens:   maps.Clone(t.revokedTokens),
+		passwordResets:  maps.Clone(t.passwordResets),
+		loginAttempts:   slices.Clone(t.loginAttempts),
+		loginThrottles:  maps.Clone(t.loginThrottles),
+		twoFactors:      maps.Clone(t.twoFactors),
+		recoveryCodes:   maps.Clone(t.recoveryCodes),
+		auditLogs:       slices.Clone(t.auditLogs),
+		idempotencyKeys: maps.Clone(t.idempotencyKeys),
+	}
+}
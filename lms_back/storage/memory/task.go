@@ -0,0 +1,213 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/storage"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var taskSortColumns = map[string]column[taskRow]{
+	"score":      func(r taskRow) interface{} { return float64(r.score) },
+	"created_at": func(r taskRow) interface{} { return sortTime(r.createdAt) },
+}
+
+type taskRepo struct {
+	db *db
+}
+
+func (r taskRow) get() models.Task {
+	return models.Task{
+		Id:        r.Id,
+		LessonId:  r.LessonId,
+		GroupId:   r.GroupId,
+		Task:      r.Task.Task,
+		Score:     strconv.Itoa(r.score),
+		Deadline:  formatTime(r.deadline),
+		Version:   r.Version,
+		CreatedAt: formatTime(r.createdAt),
+		UpdatedAt: formatTime(r.updatedAt),
+	}
+}
+
+// parseTask returns the row of the task without its timestamps.
+func parseTask(task models.Task) (taskRow, error) {
+	row := taskRow{Task: task}
+	if err := parseUUIDs(&row.LessonId, &row.GroupId); err != nil {
+		return taskRow{}, err
+	}
+
+	var err error
+	if row.score, err = parseInt(task.Score); err != nil {
+		return taskRow{}, err
+	}
+	if task.Deadline != "" {
+		if row.deadline, err = parseTimestamp(task.Deadline); err != nil {
+			return taskRow{}, err
+		}
+	}
+
+	return row, nil
+}
+
+func (c *taskRepo) Create(ctx context.Context, task models.Task) (models.Task, error) {
+	id := uuid.New().String()
+
+	err := c.db.write(func(t *tables) error {
+		row, err := parseTask(task)
+		if err != nil {
+			return err
+		}
+
+		now := c.db.now()
+		row.Id, row.Version = id, 1
+		row.createdAt, row.updatedAt = now, now
+		t.tasks[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	task.Id, task.Version = id, 1
+	return task, nil
+}
+
+func (c *taskRepo) Update(ctx context.Context, task models.Task) (models.Task, error) {
+	err := c.db.write(func(t *tables) error {
+		id, err := parseUUID(task.Id)
+		if err != nil {
+			return err
+		}
+		updated, err := parseTask(task)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.tasks[id]
+		if err = checkVersion(ok && row.deletedAt == 0, row.Version, task.Version); err != nil {
+			return err
+		}
+		updated.Id, updated.Version = id, row.Version+1
+		updated.createdAt, updated.updatedAt, updated.deletedAt = row.createdAt, c.db.now(), row.deletedAt
+		t.tasks[id] = updated
+		return nil
+	})
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	task.Version++
+	return task, nil
+}
+
+func (c *taskRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Task, error) {
+	values, err := storage.TaskPatch.Decode(patch)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	current, err := c.GetByID(ctx, id)
+	if err != nil {
+		return models.Task{}, err
+	}
+
+	task := models.Task{}
+	if err = patchModel(current, values, &task); err != nil {
+		return models.Task{}, err
+	}
+	task.Version = version
+	if _, err = c.Update(ctx, task); err != nil {
+		return models.Task{}, err
+	}
+	return c.GetByID(ctx, id)
+}
+
+func (c *taskRepo) GetAll(ctx context.Context, req models.GetAllTasksRequest) (models.GetAllTasksResponse, error) {
+	resp := models.GetAllTasksResponse{}
+
+	if err := parseFilterUUIDs(&req.LessonID, &req.GroupID); err != nil {
+		return resp, err
+	}
+
+	list, err := newList(req.ListParams, req.Page, req.Limit, taskSortColumns, func(r taskRow) interface{} { return r.Id }, "-created_at")
+	if err != nil {
+		return resp, err
+	}
+
+	err = c.db.read(func(t *tables) error {
+		var rows []taskRow
+		for _, row := range t.tasks {
+			if !deleted(req.Deleted, row.deletedAt) ||
+				req.Search != "" && !contains(req.Search, row.Task.Task) ||
+				req.LessonID != "" && row.LessonId != req.LessonID ||
+				req.GroupID != "" && row.GroupId != req.GroupID {
+				continue
+			}
+			rows = append(rows, row)
+		}
+
+		rows, resp.Count, resp.NextCursor, err = list.apply(rows)
+		for _, row := range rows {
+			resp.Tasks = append(resp.Tasks, row.get())
+		}
+		return err
+	})
+
+	return resp, err
+}
+
+func (c *taskRepo) GetByID(ctx context.Context, id string) (models.Task, error) {
+	var task models.Task
+
+	err := c.db.read(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.tasks[id]
+		if !ok || row.deletedAt != 0 {
+			return pgx.ErrNoRows
+		}
+		task = row.get()
+		return nil
+	})
+
+	return task, err
+}
+
+func (c *taskRepo) Delete(ctx context.Context, id string, version int) error {
+	return c.db.write(func(t *tables) error {
+		return softDelete(t.tasks, id, version, c.db.now(), func(r *taskRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (c *taskRepo) Restore(ctx context.Context, id string) error {
+	return c.db.write(func(t *tables) error {
+		return restore(t.tasks, id, func(r *taskRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (c *taskRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+
+	err := c.db.write(func(t *tables) error {
+		purged = purge(t.tasks, deletedBefore, func(r taskRow) (int64, bool) {
+			return r.deletedAt, false
+		})
+		// the submissions of the purged tasks are deleted with them
+		for id, row := range t.submissions {
+			if _, ok := t.tasks[row.TaskID]; !ok {
+				delete(t.submissions, id)
+			}
+		}
+		return nil
+	})
+
+	return purged, err
+}
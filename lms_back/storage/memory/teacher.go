@@ -0,0 +1,254 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"lms_back/storage"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var teacherSortColumns = map[string]column[teacherRow]{
+	"full_name":  func(r teacherRow) interface{} { return r.Full_name },
+	"email":      func(r teacherRow) interface{} { return r.Email },
+	"login":      func(r teacherRow) interface{} { return r.Login },
+	"age":        func(r teacherRow) interface{} { return float64(r.Age) },
+	"status":     func(r teacherRow) interface{} { return r.Status },
+	"created_at": func(r teacherRow) interface{} { return sortTime(r.createdAt) },
+	"updated_at": func(r teacherRow) interface{} { return sortTime(r.updatedAt) },
+}
+
+type teacherRepo struct {
+	db *db
+}
+
+func (r teacherRow) get() models.GetTeacher {
+	return models.GetTeacher{
+		Id:         r.Id,
+		Full_name:  r.Full_name,
+		Email:      r.Email,
+		Age:        r.Age,
+		Status:     r.Status,
+		Login:      r.Login,
+		Version:    r.Version,
+		Created_at: formatTime(r.createdAt),
+		Updated_at: formatTime(r.updatedAt),
+		Deleted_at: strconv.FormatInt(r.deletedAt, 10),
+	}
+}
+
+func (c *teacherRepo) Create(ctx context.Context, teacher models.Teacher) (models.GetTeacher, error) {
+	id := uuid.New().String()
+
+	err := c.db.write(func(t *tables) error {
+		if err := check("teacher", "status", teacher.Status, models.Statuses...); err != nil {
+			return err
+		}
+
+		now := c.db.now()
+		row := teacherRow{Teacher: teacher, createdAt: now, updatedAt: now}
+		row.Id, row.Version = id, 1
+		t.teachers[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.GetTeacher{}, err
+	}
+
+	return models.GetTeacher{
+		Id:         id,
+		Full_name:  teacher.Full_name,
+		Email:      teacher.Email,
+		Age:        teacher.Age,
+		Status:     teacher.Status,
+		Login:      teacher.Login,
+		Version:    1,
+		Created_at: teacher.Created_at,
+		Updated_at: teacher.Updated_at,
+	}, nil
+}
+
+func (c *teacherRepo) Update(ctx context.Context, teacher models.Teacher) (models.GetTeacher, error) {
+	err := c.db.write(func(t *tables) error {
+		id, err := parseUUID(teacher.Id)
+		if err != nil {
+			return err
+		}
+		if err = check("teacher", "status", teacher.Status, models.Statuses...); err != nil {
+			return err
+		}
+
+		row, ok := t.teachers[id]
+		if err = checkVersion(ok && row.deletedAt == 0, row.Version, teacher.Version); err != nil {
+			return err
+		}
+		row.Full_name, row.Email, row.Age, row.Login, row.Status = teacher.Full_name, teacher.Email, teacher.Age, teacher.Login, teacher.Status
+		row.Version++
+		row.updatedAt = c.db.now()
+		t.teachers[id] = row
+		return nil
+	})
+	if err != nil {
+		return models.GetTeacher{}, err
+	}
+
+	return models.GetTeacher{
+		Id:         teacher.Id,
+		Full_name:  teacher.Full_name,
+		Email:      teacher.Email,
+		Age:        teacher.Age,
+		Status:     teacher.Status,
+		Login:      teacher.Login,
+		Version:    teacher.Version + 1,
+		Created_at: teacher.Created_at,
+		Updated_at: teacher.Updated_at,
+	}, nil
+}
+
+func (c *teacherRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.GetTeacher, error) {
+	values, err := storage.TeacherPatch.Decode(patch)
+	if err != nil {
+		return models.GetTeacher{}, err
+	}
+
+	current, err := c.GetByID(ctx, id)
+	if err != nil {
+		return models.GetTeacher{}, err
+	}
+
+	teacher := models.Teacher{}
+	if err = patchModel(current, values, &teacher); err != nil {
+		return models.GetTeacher{}, err
+	}
+	teacher.Version = version
+	if _, err = c.Update(ctx, teacher); err != nil {
+		return models.GetTeacher{}, err
+	}
+	return c.GetByID(ctx, id)
+}
+
+func (c *teacherRepo) UpdatePassword(ctx context.Context, id, password string) error {
+	return c.db.write(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.teachers[id]
+		if !ok || row.deletedAt != 0 {
+			return nil
+		}
+		row.Password = password
+		row.Version++
+		row.updatedAt = c.db.now()
+		t.teachers[id] = row
+		return nil
+	})
+}
+
+func (c *teacherRepo) GetAll(ctx context.Context, req models.GetAllTeachersRequest) (models.GetAllTeachersResponse, error) {
+	resp := models.GetAllTeachersResponse{}
+
+	list, err := newList(req.ListParams, req.Page, req.Limit, teacherSortColumns, func(r teacherRow) interface{} { return r.Id }, "-created_at")
+	if err != nil {
+		return resp, err
+	}
+
+	err = c.db.read(func(t *tables) error {
+		var rows []teacherRow
+		for _, row := range t.teachers {
+			if !deleted(req.Deleted, row.deletedAt) ||
+				req.Search != "" && !contains(req.Search, row.Full_name, row.Email, row.Login) ||
+				req.Status != "" && row.Status != req.Status {
+				continue
+			}
+			rows = append(rows, row)
+		}
+
+		rows, resp.Count, resp.NextCursor, err = list.apply(rows)
+		for _, row := range rows {
+			resp.Teachers = append(resp.Teachers, row.get())
+		}
+		return err
+	})
+
+	return resp, err
+}
+
+func (c *teacherRepo) GetByID(ctx context.Context, id string) (models.GetTeacher, error) {
+	var teacher models.GetTeacher
+
+	err := c.db.read(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.teachers[id]
+		if !ok || row.deletedAt != 0 {
+			return pgx.ErrNoRows
+		}
+		teacher = row.get()
+		return nil
+	})
+
+	return teacher, err
+}
+
+func (c *teacherRepo) GetByLogin(ctx context.Context, login string) (models.Teacher, error) {
+	var teacher models.Teacher
+
+	err := c.db.read(func(t *tables) error {
+		for _, row := range t.teachers {
+			if row.Login == login && row.deletedAt == 0 {
+				get := row.get()
+				teacher = models.Teacher{
+					Id:         get.Id,
+					Full_name:  get.Full_name,
+					Email:      get.Email,
+					Age:        get.Age,
+					Status:     get.Status,
+					Login:      get.Login,
+					Password:   row.Password,
+					Version:    get.Version,
+					Created_at: get.Created_at,
+					Updated_at: get.Updated_at,
+					Deleted_at: get.Deleted_at,
+				}
+				return nil
+			}
+		}
+		return pgx.ErrNoRows
+	})
+
+	return teacher, err
+}
+
+func (c *teacherRepo) Delete(ctx context.Context, id string, version int) error {
+	return c.db.write(func(t *tables) error {
+		return softDelete(t.teachers, id, version, c.db.now(), func(r *teacherRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (c *teacherRepo) Restore(ctx context.Context, id string) error {
+	return c.db.write(func(t *tables) error {
+		return restore(t.teachers, id, func(r *teacherRow) (*int64, *int) { return &r.deletedAt, &r.Version })
+	})
+}
+
+func (c *teacherRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+
+	err := c.db.write(func(t *tables) error {
+		purged = purge(t.teachers, deletedBefore, func(r teacherRow) (int64, bool) {
+			return r.deletedAt, referenced(t.groups, func(g groupRow) bool { return g.Teacher_id == r.Id }) ||
+				referenced(t.schedules, func(s scheduleRow) bool { return s.Teacher_id == r.Id })
+		})
+		return nil
+	})
+
+	return purged, err
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"time"
+)
+
+type tokenRepo struct {
+	db *db
+}
+
+func (c *tokenRepo) Revoke(ctx context.Context, token models.RevokedToken) (bool, error) {
+	var inserted bool
+
+	err := c.db.write(func(t *tables) error {
+		row := revokedTokenRow{RevokedToken: token, expiresAt: time.Unix(token.ExpiresAt, 0).UTC()}
+		if err := parseUUIDs(&row.JTI, &row.UserID); err != nil {
+			return err
+		}
+
+		if _, ok := t.revokedTokens[row.JTI]; !ok {
+			t.revokedTokens[row.JTI] = row
+			inserted = true
+		}
+
+		now := c.db.now()
+		for jti, revoked := range t.revokedTokens {
+			if revoked.expiresAt.Before(now) {
+				delete(t.revokedTokens, jti)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return inserted, nil
+}
+
+func (c *tokenRepo) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+
+	err := c.db.read(func(t *tables) error {
+		jti, err := parseUUID(jti)
+		if err != nil {
+			return err
+		}
+
+		_, revoked = t.revokedTokens[jti]
+		return nil
+	})
+
+	return revoked, err
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"lms_back/api/models"
+	"lms_back/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// deleted reports whether a row with the deletion time is listed in the
+// deleted mode of filter.Builder.Deleted.
+func deleted(mode string, deletedAt int64) bool {
+	if mode == models.DeletedOnly {
+		return deletedAt != 0
+	}
+	return deletedAt == 0
+}
+
+// checkVersion compares the version of a row with the one a write expects. It
+// returns pgx.ErrNoRows if the row is not found or deleted and a
+// *storage.VersionConflictError if the row has another version.
+func checkVersion(found bool, current, expected int) error {
+	if !found {
+		return pgx.ErrNoRows
+	}
+	if current != expected {
+		return &storage.VersionConflictError{Current: current}
+	}
+	return nil
+}
+
+// softDelete moves the row with the version to the trash. state returns the
+// deletion time and the version of a row.
+func softDelete[T any](table map[string]T, id string, version int, now time.Time, state func(*T) (*int64, *int)) error {
+	id, err := parseUUID(id)
+	if err != nil {
+		return err
+	}
+
+	row, ok := table[id]
+	deletedAt, current := state(&row)
+	if err = checkVersion(ok && *deletedAt == 0, *current, version); err != nil {
+		return err
+	}
+	*deletedAt = epoch(now)
+	*current++
+	table[id] = row
+	return nil
+}
+
+// restore takes the row out of the trash, it returns pgx.ErrNoRows if the row
+// is not in the trash.
+func restore[T any](table map[string]T, id string, state func(*T) (*int64, *int)) error {
+	id, err := parseUUID(id)
+	if err != nil {
+		return err
+	}
+
+	row, ok := table[id]
+	deletedAt, current := state(&row)
+	if !ok || *deletedAt == 0 {
+		return pgx.ErrNoRows
+	}
+	*deletedAt = 0
+	*current++
+	table[id] = row
+	return nil
+}
+
+// purge hard-deletes the rows deleted before deletedBefore, except the ones
+// still referenced. state returns the deletion time of a row and whether it
+// is referenced.
+func purge[T any](table map[string]T, deletedBefore time.Time, state func(T) (int64, bool)) int64 {
+	var purged int64
+	for id, row := range table {
+		deletedAt, keep := state(row)
+		if deletedAt != 0 && deletedAt < deletedBefore.Unix() && !keep {
+			delete(table, id)
+			purged++
+		}
+	}
+	return purged
+}
+
+// referenced reports whether any row of the table matches, deleted or not.
+func referenced[T any](table map[string]T, matches func(T) bool) bool {
+	for _, row := range table {
+		if matches(row) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package memory
+
+import (
+	"context"
+	"lms_back/api/models"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type twoFactorRepo struct {
+	db *db
+}
+
+// parseUserKey parses the primary key of a two_factor row.
+func parseUserKey(userID, userRole string) (userKey, error) {
+	userID, err := parseUUID(userID)
+	if err != nil {
+		return userKey{}, err
+	}
+	return userKey{userID: userID, userRole: userRole}, nil
+}
+
+func (c *twoFactorRepo) Setup(ctx context.Context, twoFactor models.TwoFactor) error {
+	return c.db.write(func(t *tables) error {
+		key, err := parseUserKey(twoFactor.UserID, twoFactor.UserRole)
+		if err != nil {
+			return err
+		}
+		if err = check("two_factor", "user_role", twoFactor.UserRole, "admin", "teacher"); err != nil {
+			return err
+		}
+
+		if row, ok := t.twoFactors[key]; ok && row.Enabled {
+			return nil
+		}
+		t.twoFactors[key] = twoFactorRow{
+			TwoFactor: models.TwoFactor{
+				UserID:   key.userID,
+				UserRole: key.userRole,
+				Secret:   twoFactor.Secret,
+			},
+			createdAt: c.db.now(),
+		}
+		return nil
+	})
+}
+
+func (c *twoFactorRepo) Get(ctx context.Context, userID, userRole string) (models.TwoFactor, error) {
+	var twoFactor models.TwoFactor
+
+	err := c.db.read(func(t *tables) error {
+		key, err := parseUserKey(userID, userRole)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.twoFactors[key]
+		if !ok {
+			return pgx.ErrNoRows
+		}
+		twoFactor = row.TwoFactor
+		twoFactor.CreatedAt = formatTime(row.createdAt)
+		twoFactor.EnabledAt = formatTime(row.enabledAt)
+		return nil
+	})
+
+	return twoFactor, err
+}
+
+func (c *twoFactorRepo) UseStep(ctx context.Context, userID, userRole string, step int64) (bool, error) {
+	var used bool
+
+	err := c.db.write(func(t *tables) error {
+		key, err := parseUserKey(userID, userRole)
+		if err != nil {
+			return err
+		}
+
+		row, ok := t.twoFactors[key]
+		if !ok || row.LastUsedStep >= step {
+			return nil
+		}
+		row.LastUsedStep = step
+		t.twoFactors[key] = row
+		used = true
+		return nil
+	})
+
+	return used, err
+}
+
+func (c *twoFactorRepo) Enable(ctx context.Context, userID, userRole string, recoveryCodeHashes []string) error {
+	return c.db.write(func(t *tables) error {
+		key, err := parseUserKey(userID, userRole)
+		if err != nil {
+			return err
+		}
+
+		now := c.db.now()
+		if row, ok := t.twoFactors[key]; ok {
+			row.Enabled = true
+			row.enabledAt = now
+			t.twoFactors[key] = row
+		}
+
+		t.deleteRecoveryCodes(key)
+		for _, codeHash := range recoveryCodeHashes {
+			id := uuid.New().String()
+			t.recoveryCodes[id] = recoveryCodeRow{
+				RecoveryCode: models.RecoveryCode{
+					Id:       id,
+					UserID:   key.userID,
+					UserRole: key.userRole,
+					CodeHash: codeHash,
+				},
+				createdAt: now,
+			}
+		}
+		return nil
+	})
+}
+
+func (c *twoFactorRepo) Delete(ctx context.Context, userID, userRole string) error {
+	return c.db.write(func(t *tables) error {
+		key, err := parseUserKey(userID, userRole)
+		if err != nil {
+			return err
+		}
+
+		t.deleteRecoveryCodes(key)
+		delete(t.twoFactors, key)
+		return nil
+	})
+}
+
+func (t *tables) deleteRecoveryCodes(key userKey) {
+	for id, code := range t.recoveryCodes {
+		if code.UserID == key.userID && code.UserRole == key.userRole {
+			delete(t.recoveryCodes, id)
+		}
+	}
+}
+
+func (c *twoFactorRepo) GetRecoveryCodes(ctx context.Context, userID, userRole string) ([]models.RecoveryCode, error) {
+	codes := []models.RecoveryCode{}
+
+	err := c.db.read(func(t *tables) error {
+		key, err := parseUserKey(userID, userRole)
+		if err != nil {
+			return err
+		}
+
+		for _, code := range t.recoveryCodes {
+			if code.UserID == key.userID && code.UserRole == key.userRole && code.usedAt.IsZero() {
+				codes = append(codes, code.RecoveryCode)
+			}
+		}
+		sort.Slice(codes, func(i, j int) bool { return codes[i].Id < codes[j].Id })
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+func (c *twoFactorRepo) UseRecoveryCode(ctx context.Context, id string) (bool, error) {
+	var used bool
+
+	err := c.db.write(func(t *tables) error {
+		id, err := parseUUID(id)
+		if err != nil {
+			return err
+		}
+
+		code, ok := t.recoveryCodes[id]
+		if !ok || !code.usedAt.IsZero() {
+			return nil
+		}
+		code.usedAt = c.db.now()
+		t.recoveryCodes[id] = code
+		used = true
+		return nil
+	})
+
+	return used, err
+}
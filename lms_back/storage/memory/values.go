@@ -0,0 +1,289 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"lms_back/storage"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var errSerialization = &pgconn.PgError{
+	Severity: "ERROR",
+	Code:     "40001",
+	Message:  "could not serialize access due to concurrent update",
+}
+
+// invalidInput is translated here, the filters of lists are parsed outside of
+// read.
+func invalidInput(sqlType, value string) error {
+	return storage.DBError(&pgconn.PgError{
+		Severity: "ERROR",
+		Code:     "22P02",
+		Message:  fmt.Sprintf("invalid input syntax for type %s: %q", sqlType, value),
+	})
+}
+
+// check returns the check violation of the constraint unless value is one of allowed.
+func check(table, column, value string, allowed ...string) error {
+	for _, a := range allowed {
+		if value == a {
+			return nil
+		}
+	}
+
+	constraint := table + "_" + column + "_check"
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23514",
+		Message:        fmt.Sprintf("new row for relation %q violates check constraint %q", table, constraint),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+// unique returns the unique violation of the constraint for the key columns
+// and values.
+func unique(table, constraint string, columns, values []string) error {
+	return &pgconn.PgError{
+		Severity:       "ERROR",
+		Code:           "23505",
+		Message:        fmt.Sprintf("duplicate key value violates unique constraint %q", constraint),
+		Detail:         fmt.Sprintf("Key (%s)=(%s) already exists.", strings.Join(columns, ", "), strings.Join(values, ", ")),
+		TableName:      table,
+		ConstraintName: constraint,
+	}
+}
+
+// parseUUID returns the canonical form of a uuid, the form postgres returns it in.
+func parseUUID(s string) (string, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return "", invalidInput("uuid", s)
+	}
+	return id.String(), nil
+}
+
+// parseUUIDs parses every value in place, stopping at the first invalid one.
+func parseUUIDs(values ...*string) error {
+	for _, v := range values {
+		id, err := parseUUID(*v)
+		if err != nil {
+			return err
+		}
+		*v = id
+	}
+	return nil
+}
+
+// parseFilterUUIDs parses the uuids filtered by, empty ones don't filter.
+func parseFilterUUIDs(values ...*string) error {
+	for _, v := range values {
+		if *v == "" {
+			continue
+		}
+		if err := parseUUIDs(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseNullUUID parses a uuid that is stored as NULL when empty.
+func parseNullUUID(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	return parseUUID(s)
+}
+
+func parseInt(s string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, invalidInput("integer", s)
+	}
+	return n, nil
+}
+
+// numeric rounds to the scale of the decimal(10, 2) columns.
+func numeric(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+var dateLayouts = []string{"2006-01-02", "2006-01-02 15:04:05", "2006-01-02T15:04:05", time.RFC3339Nano}
+
+// parseDate parses a date column, a time part is ignored like postgres does.
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, invalidInput("date", s)
+}
+
+// dateRange returns the filter of filter.Builder.Range on column::date with
+// the inclusive date bounds, empty bounds are left open.
+func dateRange(from, to string) (func(time.Time) bool, error) {
+	var bounds [2]time.Time
+	for i, bound := range []string{from, to} {
+		if bound == "" {
+			continue
+		}
+		date, err := parseDate(bound)
+		if err != nil {
+			return nil, err
+		}
+		bounds[i] = date
+	}
+
+	return func(t time.Time) bool {
+		date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return (bounds[0].IsZero() || !date.Before(bounds[0])) && (bounds[1].IsZero() || !date.After(bounds[1]))
+	}, nil
+}
+
+// parseTimestamp parses a value cast to timestamp.
+func parseTimestamp(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, invalidInput("timestamp without time zone", s)
+}
+
+// parseTime parses a time column and formats it the way it is read back.
+func parseTime(s string) (string, error) {
+	for _, layout := range []string{"15:04", "15:04:05", "15:04:05.999999"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("15:04:05.000000"), nil
+		}
+	}
+	return "", invalidInput("time without time zone", s)
+}
+
+// formatTime formats a timestamp the way it is scanned into a sql.NullString,
+// a zero time is NULL.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// sortTime formats a timestamp so that the formatted values sort like the times.
+func sortTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02T15:04:05.000000")
+}
+
+// epoch is the value of extract(epoch from now())::integer.
+func epoch(t time.Time) int64 {
+	return t.Round(time.Second).Unix()
+}
+
+// contains matches like column::text ILIKE '%term%'.
+func contains(term string, values ...string) bool {
+	term = strings.ToLower(term)
+	for _, v := range values {
+		if strings.Contains(strings.ToLower(v), term) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyOf matches like column = ANY(values), a nil slice matches everything.
+func anyOf(value string, values []string) bool {
+	if values == nil {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonb returns the document the way postgres prints a jsonb value, with
+// keys ordered by length and then bytes and a space after colons and commas.
+// An empty document is NULL.
+func jsonb(data []byte) (json.RawMessage, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v interface{}
+	if err := decoder.Decode(&v); err != nil || decoder.More() {
+		return nil, invalidInput("json", string(data))
+	}
+
+	var buf bytes.Buffer
+	writeJSONB(&buf, v)
+	return buf.Bytes(), nil
+}
+
+func writeJSONB(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeJSONB(buf, key)
+			buf.WriteString(": ")
+			writeJSONB(buf, v[key])
+		}
+		buf.WriteByte('}')
+
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			writeJSONB(buf, item)
+		}
+		buf.WriteByte(']')
+
+	case string:
+		encoder := json.NewEncoder(buf)
+		encoder.SetEscapeHTML(false)
+		_ = encoder.Encode(v)
+		// Encode ends the value with a newline
+		buf.Truncate(buf.Len() - 1)
+
+	case json.Number:
+		buf.WriteString(v.String())
+
+	default:
+		data, _ := json.Marshal(v)
+		buf.Write(data)
+	}
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"lms_back/api/models"
+	"lms_back/pkg/errs"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PatchKind is the type a patched value must have.
+type PatchKind int
+
+const (
+	// PatchString is any JSON string.
+	PatchString PatchKind = iota
+	// PatchCount is a non-negative JSON integer.
+	PatchCount
+	// PatchNumber is a non-negative JSON number.
+	PatchNumber
+	// PatchUUID is a JSON string with a uuid.
+	PatchUUID
+	// PatchDate is a JSON string with a date like 2006-01-02.
+	PatchDate
+	// PatchTime is a JSON string with a time of day like 15:04 or 15:04:05.
+	PatchTime
+	// PatchTimestamp is a JSON string with an RFC 3339 time, it is decoded in
+	// UTC.
+	PatchTimestamp
+)
+
+// PatchField is a field of an entity that can be patched.
+type PatchField struct {
+	Column string
+	Kind   PatchKind
+}
+
+// PatchFields are the fields of an entity that can be patched, by their json
+// names. They are the fields the Update of the entity writes.
+type PatchFields map[string]PatchField
+
+// PatchValue is a checked value of a patched field, Value is a string, an int
+// or a float64 by the kind of the field.
+type PatchValue struct {
+	Field  string
+	Column string
+	Value  interface{}
+}
+
+// The fields every entity can be patched in, the Patch of its storage checks them.
+var (
+	AdminPatch = PatchFields{
+		"full_name": {"full_name", PatchString},
+		"email":     {"email", PatchString},
+		"age":       {"age", PatchCount},
+		"status":    {"status", PatchString},
+		"login":     {"login", PatchString},
+		"role":      {"role", PatchString},
+	}
+
+	BranchPatch = PatchFields{
+		"name":    {"name", PatchString},
+		"address": {"address", PatchString},
+	}
+
+	GroupPatch = PatchFields{
+		"type": {"type", PatchString},
+	}
+
+	// StudentPatch leaves out the paid sum, payments keep it.
+	StudentPatch = PatchFields{
+		"full_name": {"full_name", PatchString},
+		"email":     {"email", PatchString},
+		"age":       {"age", PatchCount},
+		"status":    {"status", PatchString},
+		"login":     {"login", PatchString},
+		"group_id":  {"group_id", PatchUUID},
+	}
+
+	TeacherPatch = PatchFields{
+		"full_name": {"full_name", PatchString},
+		"email":     {"email", PatchString},
+		"age":       {"age", PatchCount},
+		"status":    {"status", PatchString},
+		"login":     {"login", PatchString},
+	}
+
+	PaymentPatch = PatchFields{
+		"price":      {"price", PatchNumber},
+		"student_id": {"student_id", PatchUUID},
+		"branch_id":  {"branch_id", PatchUUID},
+		"admin_id":   {"admin_id", PatchUUID},
+	}
+
+	SchedulePatch = PatchFields{
+		"group_id":   {"group_id", PatchUUID},
+		"group_type": {"group_type", PatchString},
+		"start_time": {"start_time", PatchTime},
+		"end_time":   {"end_time", PatchTime},
+		"date":       {"date", PatchString},
+		"branch_id":  {"branch_id", PatchUUID},
+		"teacher_id": {"teacher_id", PatchUUID},
+	}
+
+	LessonPatch = PatchFields{
+		"schedule_id": {"schedule_id", PatchUUID},
+		"group_id":    {"group_id", PatchUUID},
+		"from":        {`"from"`, PatchDate},
+		"to":          {`"to"`, PatchDate},
+		"theme":       {"theme", PatchString},
+	}
+
+	TaskPatch = PatchFields{
+		"lesson_id": {"lesson_id", PatchUUID},
+		"group_id":  {"group_id", PatchUUID},
+		"task":      {"task", PatchString},
+		"score":     {"score", PatchCount},
+		"deadline":  {"deadline", PatchTimestamp},
+	}
+)
+
+// Decode checks every value of the patch and returns them sorted by field. It
+// fails with a validation error for an unknown field, a value of another type
+// and null, the fields that can be patched can't be removed.
+func (f PatchFields) Decode(patch models.Patch) ([]PatchValue, error) {
+	values := make([]PatchValue, 0, len(patch))
+	for name, raw := range patch {
+		field, ok := f[name]
+		if !ok {
+			return nil, errs.Validation("", errs.FieldError{Field: name, Message: "can't be patched"})
+		}
+
+		value, err := field.decode(raw)
+		if err != nil {
+			return nil, errs.Validation("", errs.FieldError{Field: name, Message: err.Error()})
+		}
+		values = append(values, PatchValue{Field: name, Column: field.Column, Value: value})
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i].Field < values[j].Field })
+	return values, nil
+}
+
+type patchReason string
+
+func (r patchReason) Error() string { return string(r) }
+
+func (f PatchField) decode(raw json.RawMessage) (interface{}, error) {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil, patchReason("can't be removed")
+	}
+
+	switch f.Kind {
+	case PatchCount, PatchNumber:
+		var n float64
+		if err := json.Unmarshal(raw, &n); err != nil || n < 0 {
+			return nil, patchReason("must be a non-negative number")
+		}
+		if f.Kind == PatchNumber {
+			return n, nil
+		}
+		if n != math.Trunc(n) || n > math.MaxInt32 {
+			return nil, patchReason("must be a non-negative integer")
+		}
+		return int(n), nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, patchReason("must be a string")
+	}
+
+	switch f.Kind {
+	case PatchUUID:
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, patchReason("must be a uuid")
+		}
+		return id.String(), nil
+	case PatchDate:
+		if _, err := time.Parse(time.DateOnly, s); err != nil {
+			return nil, patchReason("must be a date like 2006-01-02")
+		}
+	case PatchTime:
+		if _, err := time.Parse("15:04", s); err != nil {
+			if _, err = time.Parse(time.TimeOnly, s); err != nil {
+				return nil, patchReason("must be a time like 15:04")
+			}
+		}
+	case PatchTimestamp:
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return nil, patchReason("must be a time like 2006-01-02T15:04:05Z07:00")
+		}
+		return t.UTC().Format(time.RFC3339), nil
+	}
+
+	return s, nil
+}
@@ -3,26 +3,45 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"lms_back/api/models"
 	"lms_back/pkg"
+	"lms_back/storage"
+	"lms_back/storage/postgres/filter"
+	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 
 	"github.com/google/uuid"
 )
 
+// adminBranchIDsColumn selects the ids of the branches assigned to the admin.
+const adminBranchIDsColumn = `COALESCE((SELECT array_agg(ab.branch_id::text) FROM admin_branch ab WHERE ab.admin_id = "admin".id), '{}') AS branch_ids`
+
+// adminSearchColumns are matched by the search query param.
+var adminSearchColumns = []string{"full_name", "email", "login"}
+
+// adminSortColumns are the fields the list can be sorted by.
+var adminSortColumns = map[string]string{
+	"full_name":  "full_name",
+	"email":      "email",
+	"login":      "login",
+	"age":        "age",
+	"status":     "status",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
 type adminRepo struct {
-	db *pgxpool.Pool
+	db DBTX
 }
 
-func NewAdmin(db *pgxpool.Pool) adminRepo {
+func NewAdmin(db DBTX) adminRepo {
 	return adminRepo{
 		db: db,
 	}
 }
 
-func (c *adminRepo) Create(ctx context.Context, admin models.Admin) (models.Admin, error) {
+func (c *adminRepo) Create(ctx context.Context, admin models.Admin) (models.GetAdmin, error) {
 
 	id := uuid.New()
 	query := `INSERT INTO "admin" (
@@ -33,8 +52,9 @@ func (c *adminRepo) Create(ctx context.Context, admin models.Admin) (models.Admi
 		status,
 		login,
 		password,
+		role,
 		created_at)
-		VALUES($1,$2,$3,$4,$5,$6,$7,CURRENT_TIMESTAMP) 
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,CURRENT_TIMESTAMP) 
 	`
 
 	_, err := c.db.Exec(context.Background(), query,
@@ -44,99 +64,142 @@ func (c *adminRepo) Create(ctx context.Context, admin models.Admin) (models.Admi
 		admin.Age,
 		admin.Status,
 		admin.Login,
-		admin.Password)
+		admin.Password,
+		admin.Role)
 
 	if err != nil {
-		return models.Admin{}, err
+		return models.GetAdmin{}, err
 	}
-	return models.Admin{
+	return models.GetAdmin{
 		Id:         id.String(),
 		Full_Name:  admin.Full_Name,
 		Email:      admin.Email,
 		Age:        admin.Age,
 		Status:     admin.Status,
 		Login:      admin.Login,
-		Password:   admin.Password,
+		Role:       admin.Role,
+		BranchIDs:  []string{},
+		Version:    1,
 		Created_at: admin.Created_at,
 		Updated_at: admin.Updated_at,
 	}, nil
 }
 
-func (c *adminRepo) Update(ctx context.Context, admin models.Admin) (models.Admin, error) {
+func (c *adminRepo) Update(ctx context.Context, admin models.Admin) (models.GetAdmin, error) {
 	query := `update "admin" set 
 	full_name=$1,
 	email=$2,
 	age=$3,
 	status=$4,
 	login=$5,
-	password=$6,
+	role=$6,
+	version = version + 1,
 	updated_at=CURRENT_TIMESTAMP
-	WHERE id = $7
+	WHERE id = $7 AND deleted_at = 0 AND version = $8
 	`
-	_, err := c.db.Exec(context.Background(), query,
+	tag, err := c.db.Exec(context.Background(), query,
 		admin.Full_Name,
 		admin.Email,
 		admin.Age,
 		admin.Status,
 		admin.Login,
-		admin.Password,
+		admin.Role,
 		admin.Id,
+		admin.Version,
 	)
 	if err != nil {
-		return models.Admin{}, err
+		return models.GetAdmin{}, err
 	}
-	return models.Admin{
+	if err = checkVersion(ctx, c.db, `"admin"`, admin.Id, tag); err != nil {
+		return models.GetAdmin{}, err
+	}
+	return models.GetAdmin{
 		Id:         admin.Id,
-		Full_Name:  admin.Email,
+		Full_Name:  admin.Full_Name,
+		Email:      admin.Email,
 		Age:        admin.Age,
 		Status:     admin.Status,
 		Login:      admin.Login,
-		Password:   admin.Password,
+		Role:       admin.Role,
+		BranchIDs:  admin.BranchIDs,
+		Version:    admin.Version + 1,
 		Created_at: admin.Created_at,
 		Updated_at: admin.Updated_at,
 	}, nil
 }
 
+func (c *adminRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.GetAdmin, error) {
+	if err := patchRow(ctx, c.db, `"admin"`, id, version, storage.AdminPatch, patch); err != nil {
+		return models.GetAdmin{}, err
+	}
+	return c.GetByID(ctx, id)
+}
+
+func (c *adminRepo) UpdatePassword(ctx context.Context, id, password string) error {
+	query := `update "admin" set 
+	password=$1,
+	version = version + 1,
+	updated_at=CURRENT_TIMESTAMP
+	WHERE id = $2 AND deleted_at = 0
+	`
+	_, err := c.db.Exec(ctx, query, password, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func (c *adminRepo) GetAll(ctx context.Context, req models.GetAllAdminsRequest) (models.GetAllAdminsResponse, error) {
 	var (
-		resp   = models.GetAllAdminsResponse{}
-		filter = ""
+		resp    = models.GetAllAdminsResponse{}
+		cursors []string
 	)
-	offset := (req.Page - 1) * req.Limit
+	where := filter.New().
+		Deleted(req.Deleted).
+		Search(req.Search, adminSearchColumns...).
+		Equal("status", req.Status).
+		Equal("role", req.Role)
 
-	if req.Search != "" {
-		filter += fmt.Sprintf(` and name ILIKE  '%%%v%%' `, req.Search)
+	list, err := filter.NewList(req.ListParams, req.Page, req.Limit, adminSortColumns, "-created_at")
+	if err != nil {
+		return resp, err
 	}
+	list.Apply(where)
 
-	filter += fmt.Sprintf(" OFFSET %v LIMIT %v", offset, req.Limit)
-	fmt.Println("filter: ", filter)
-
-	rows, err := c.db.Query(context.Background(), `select count(id) over(),
+	rows, err := c.db.Query(context.Background(), `select `+list.CountColumn()+`,
         id,
         full_name,
         email,
 		age,
 		status,
 		login,
-		password,
+		role,
+		`+adminBranchIDsColumn+`,
+		version,
         created_at,
-        updated_at
-        FROM "admin"`+filter+``)
+        updated_at,
+        `+list.CursorColumn()+`
+        FROM "admin"`+where.SQL()+list.SQL(), where.Args()...)
 	if err != nil {
 		return resp, err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		var (
-			admin      = models.Admin{}
+			admin      = models.GetAdmin{}
 			Id         sql.NullString
 			full_name  sql.NullString
 			email      sql.NullString
 			age        sql.NullInt16
 			status     sql.NullString
 			login      sql.NullString
-			password   sql.NullString
+			role       sql.NullString
+			branch_ids []string
+			version    int
 			created_at sql.NullString
 			updateAt   sql.NullString
+			cursor     string
 		)
 
 		if err := rows.Scan(
@@ -147,47 +210,110 @@ func (c *adminRepo) GetAll(ctx context.Context, req models.GetAllAdminsRequest)
 			&age,
 			&status,
 			&login,
-			&password,
+			&role,
+			&branch_ids,
+			&version,
 			&created_at,
-			&updateAt); err != nil {
+			&updateAt,
+			&cursor); err != nil {
 			return resp, err
 		}
 		admin.Updated_at = pkg.NullStringToString(updateAt)
-		resp.Admins = append(resp.Admins, models.Admin{
+		resp.Admins = append(resp.Admins, models.GetAdmin{
 			Id:         Id.String,
 			Full_Name:  full_name.String,
 			Email:      email.String,
 			Age:        uint(age.Int16),
 			Status:     status.String,
 			Login:      login.String,
-			Password:   password.String,
+			Role:       role.String,
+			BranchIDs:  branch_ids,
+			Version:    version,
 			Created_at: created_at.String,
 			Updated_at: updateAt.String,
 		})
+		cursors = append(cursors, cursor)
 	}
+
+	if err := rows.Err(); err != nil {
+		return resp, err
+	}
+
+	keep, next := list.Next(cursors)
+	resp.Admins, resp.NextCursor = resp.Admins[:keep], next
+
 	return resp, nil
 }
 
-func (c *adminRepo) GetByID(ctx context.Context, id string) (models.Admin, error) {
-	admin := models.Admin{}
+func (c *adminRepo) GetByID(ctx context.Context, id string) (models.GetAdmin, error) {
+	admin := models.GetAdmin{}
 	var (
 		full_name  sql.NullString
 		email      sql.NullString
 		age        sql.NullInt16
 		status     sql.NullString
 		login      sql.NullString
-		password   sql.NullString
+		role       sql.NullString
+		branch_ids []string
+		version    int
 		created_at sql.NullString
 		updateAt   sql.NullString
 	)
-	if err := c.db.QueryRow(context.Background(), `select id, full_name, email, age, status, login, password, created_at, updated_at from "admin" where id = $1`, id).Scan(
+	if err := c.db.QueryRow(context.Background(), `select id, full_name, email, age, status, login, role, `+adminBranchIDsColumn+`, version, created_at, updated_at from "admin" where id = $1 AND deleted_at = 0`, id).Scan(
 		&admin.Id,
 		&full_name,
 		&email,
 		&age,
 		&status,
 		&login,
+		&role,
+		&branch_ids,
+		&version,
+		&created_at,
+		&updateAt); err != nil {
+		return models.GetAdmin{}, err
+	}
+	return models.GetAdmin{
+		Id:         admin.Id,
+		Full_Name:  full_name.String,
+		Email:      email.String,
+		Age:        uint(age.Int16),
+		Status:     status.String,
+		Login:      login.String,
+		Role:       role.String,
+		BranchIDs:  branch_ids,
+		Version:    version,
+		Created_at: created_at.String,
+		Updated_at: updateAt.String,
+	}, nil
+}
+
+func (c *adminRepo) GetByLogin(ctx context.Context, login string) (models.Admin, error) {
+	admin := models.Admin{}
+	var (
+		full_name  sql.NullString
+		email      sql.NullString
+		age        sql.NullInt16
+		status     sql.NullString
+		loginn     sql.NullString
+		password   sql.NullString
+		role       sql.NullString
+		branch_ids []string
+		version    int
+		created_at sql.NullString
+		updateAt   sql.NullString
+	)
+	if err := c.db.QueryRow(ctx, `select id, full_name, email, age, status, login, password, role, `+adminBranchIDsColumn+`, version, created_at, updated_at from "admin" where login = $1 AND deleted_at = 0`, login).Scan(
+		&admin.Id,
+		&full_name,
+		&email,
+		&age,
+		&status,
+		&loginn,
 		&password,
+		&role,
+		&branch_ids,
+		&version,
 		&created_at,
 		&updateAt); err != nil {
 		return models.Admin{}, err
@@ -198,18 +324,49 @@ func (c *adminRepo) GetByID(ctx context.Context, id string) (models.Admin, error
 		Email:      email.String,
 		Age:        uint(age.Int16),
 		Status:     status.String,
-		Login:      login.String,
+		Login:      loginn.String,
 		Password:   password.String,
+		Role:       role.String,
+		BranchIDs:  branch_ids,
+		Version:    version,
 		Created_at: created_at.String,
 		Updated_at: updateAt.String,
 	}, nil
 }
 
-func (c *adminRepo) Delete(ctx context.Context, id string) error {
-	query := `delete from "admin" where id = $1`
-	_, err := c.db.Exec(context.Background(), query, id)
-	if err != nil {
-		return err
-	}
-	return nil
+func (c *adminRepo) Delete(ctx context.Context, id string, version int) error {
+	return softDelete(ctx, c.db, `"admin"`, id, version)
+}
+
+func (c *adminRepo) Restore(ctx context.Context, id string) error {
+	return restore(ctx, c.db, `"admin"`, id)
+}
+
+func (c *adminRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return purge(ctx, c.db, `"admin"`, `EXISTS (SELECT 1 FROM payment WHERE payment.admin_id = "admin".id)`, deletedBefore)
+}
+
+// SetBranches replaces the branches assigned to the admin.
+func (c *adminRepo) SetBranches(ctx context.Context, adminID string, branchIDs []string) error {
+	return pgx.BeginFunc(ctx, c.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `UPDATE "admin" SET version = version + 1 WHERE id = $1`, adminID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM admin_branch WHERE admin_id = $1`, adminID)
+		if err != nil {
+			return err
+		}
+
+		for _, branchID := range branchIDs {
+			_, err = tx.Exec(ctx, `INSERT INTO admin_branch (admin_id, branch_id, created_at)
+				VALUES($1,$2,CURRENT_TIMESTAMP) ON CONFLICT DO NOTHING`, adminID, branchID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 }
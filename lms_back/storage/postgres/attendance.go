@@ -0,0 +1,192 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"lms_back/api/models"
+	"lms_back/storage/postgres/filter"
+)
+
+type attendanceRepo struct {
+	db DBTX
+}
+
+func NewAttendance(db DBTX) attendanceRepo {
+	return attendanceRepo{
+		db: db,
+	}
+}
+
+func (a *attendanceRepo) Mark(ctx context.Context, lessonID, markedBy string, marks []models.MarkAttendance) ([]models.Attendance, error) {
+	var (
+		group_id    sql.NullString
+		lesson_date sql.NullString
+	)
+	if err := a.db.QueryRow(ctx, `SELECT group_id, "from" FROM "lesson" WHERE id = $1 AND deleted_at = 0`, lessonID).Scan(&group_id, &lesson_date); err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO attendance (
+		lesson_id,
+		student_id,
+		status,
+		note,
+		marked_by,
+		created_at,
+		updated_at)
+		VALUES($1,$2,$3,$4,$5,CURRENT_TIMESTAMP,CURRENT_TIMESTAMP)
+		ON CONFLICT (lesson_id, student_id) DO UPDATE SET
+		status = EXCLUDED.status,
+		note = EXCLUDED.note,
+		marked_by = EXCLUDED.marked_by,
+		updated_at = CURRENT_TIMESTAMP
+		RETURNING lesson_id, student_id, status, note, marked_by, created_at, updated_at
+	`
+
+	attendance := make([]models.Attendance, 0, len(marks))
+	for _, mark := range marks {
+		var (
+			marked     = models.Attendance{GroupID: group_id.String, LessonDate: lesson_date.String}
+			marked_by  sql.NullString
+			created_at sql.NullString
+			updated_at sql.NullString
+		)
+		if err := a.db.QueryRow(ctx, query,
+			lessonID,
+			mark.StudentID,
+			mark.Status,
+			mark.Note,
+			sql.NullString{String: markedBy, Valid: markedBy != ""},
+		).Scan(
+			&marked.LessonID,
+			&marked.StudentID,
+			&marked.Status,
+			&marked.Note,
+			&marked_by,
+			&created_at,
+			&updated_at,
+		); err != nil {
+			return nil, err
+		}
+
+		marked.MarkedBy = marked_by.String
+		marked.CreatedAt = created_at.String
+		marked.UpdatedAt = updated_at.String
+
+		attendance = append(attendance, marked)
+	}
+
+	return attendance, nil
+}
+
+// attendanceFilter filters the marks joined as a with their lessons as l.
+func attendanceFilter(req models.GetAllAttendanceRequest) *filter.Builder {
+	return filter.New().
+		Where("l.deleted_at = 0").
+		Equal("a.lesson_id", req.LessonID).
+		Equal("a.student_id", req.StudentID).
+		Equal("l.group_id", req.GroupID).
+		Range(`l."from"`, req.From, req.To)
+}
+
+func (a *attendanceRepo) GetAll(ctx context.Context, req models.GetAllAttendanceRequest) (models.GetAllAttendanceResponse, error) {
+	var (
+		resp = models.GetAllAttendanceResponse{}
+	)
+	offset := (req.Page - 1) * req.Limit
+
+	where := attendanceFilter(req)
+
+	rows, err := a.db.Query(ctx, `SELECT count(*) OVER(),
+		a.lesson_id,
+		a.student_id,
+		a.status,
+		a.note,
+		a.marked_by,
+		l.group_id,
+		l."from",
+		a.created_at,
+		a.updated_at
+		FROM attendance a JOIN "lesson" l ON l.id = a.lesson_id`+where.SQL()+
+		fmt.Sprintf(` ORDER BY l."from" DESC, a.lesson_id, a.student_id OFFSET %v LIMIT %v`, offset, req.Limit), where.Args()...)
+	if err != nil {
+		return resp, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			attendance  = models.Attendance{}
+			marked_by   sql.NullString
+			group_id    sql.NullString
+			lesson_date sql.NullString
+			created_at  sql.NullString
+			updated_at  sql.NullString
+		)
+		if err := rows.Scan(
+			&resp.Count,
+			&attendance.LessonID,
+			&attendance.StudentID,
+			&attendance.Status,
+			&attendance.Note,
+			&marked_by,
+			&group_id,
+			&lesson_date,
+			&created_at,
+			&updated_at,
+		); err != nil {
+			return resp, err
+		}
+
+		attendance.MarkedBy = marked_by.String
+		attendance.GroupID = group_id.String
+		attendance.LessonDate = lesson_date.String
+		attendance.CreatedAt = created_at.String
+		attendance.UpdatedAt = updated_at.String
+
+		resp.Attendance = append(resp.Attendance, attendance)
+	}
+
+	return resp, rows.Err()
+}
+
+func (a *attendanceRepo) Rates(ctx context.Context, req models.GetAllAttendanceRequest) ([]models.AttendanceRate, error) {
+	where := attendanceFilter(req)
+
+	students := `SELECT student_id FROM marks`
+	if req.GroupID != "" {
+		students += ` UNION SELECT student_id FROM enrollment WHERE group_id = ` + where.Arg(req.GroupID) + ` AND status = 'active'`
+	}
+
+	rows, err := a.db.Query(ctx, `WITH marks AS (SELECT a.student_id,
+		count(*) FILTER (WHERE a.status = 'present') AS present,
+		count(*) FILTER (WHERE a.status = 'late') AS late,
+		count(*) FILTER (WHERE a.status = 'absent') AS absent,
+		count(*) FILTER (WHERE a.status = 'excused') AS excused
+		FROM attendance a JOIN "lesson" l ON l.id = a.lesson_id`+where.SQL()+` GROUP BY a.student_id)
+		SELECT s.id,
+		COALESCE(marks.present, 0),
+		COALESCE(marks.late, 0),
+		COALESCE(marks.absent, 0),
+		COALESCE(marks.excused, 0)
+		FROM (`+students+`) ids
+		JOIN student s ON s.id = ids.student_id AND s.deleted_at = 0
+		LEFT JOIN marks ON marks.student_id = ids.student_id
+		ORDER BY s.id`, where.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []models.AttendanceRate
+	for rows.Next() {
+		var rate models.AttendanceRate
+		if err := rows.Scan(&rate.StudentID, &rate.Present, &rate.Late, &rate.Absent, &rate.Excused); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates, rows.Err()
+}
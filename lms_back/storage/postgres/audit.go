@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"lms_back/api/models"
+	"lms_back/storage/postgres/filter"
+
+	"github.com/google/uuid"
+)
+
+type auditRepo struct {
+	db DBTX
+}
+
+func NewAudit(db DBTX) auditRepo {
+	return auditRepo{
+		db: db,
+	}
+}
+
+func (a *auditRepo) Create(ctx context.Context, log models.AuditLog) error {
+	query := `INSERT INTO audit_log (
+		id,
+		actor_id,
+		actor_role,
+		entity_type,
+		entity_id,
+		branch_id,
+		action,
+		before,
+		after,
+		changes,
+		ip,
+		created_at)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,CURRENT_TIMESTAMP)
+	`
+
+	_, err := a.db.Exec(ctx, query,
+		uuid.New().String(),
+		sql.NullString{String: log.ActorID, Valid: log.ActorID != ""},
+		log.ActorRole,
+		log.EntityType,
+		log.EntityID,
+		sql.NullString{String: log.BranchID, Valid: log.BranchID != ""},
+		log.Action,
+		nullJSON(log.Before),
+		nullJSON(log.After),
+		nullJSON(log.Changes),
+		log.IP,
+	)
+
+	return err
+}
+
+func (a *auditRepo) GetAll(ctx context.Context, req models.GetAllAuditLogsRequest) (models.GetAllAuditLogsResponse, error) {
+	var (
+		resp = models.GetAllAuditLogsResponse{}
+	)
+	offset := (req.Page - 1) * req.Limit
+
+	where := filter.New().
+		Equal("entity_type", req.EntityType).
+		Equal("entity_id", req.EntityID).
+		Equal("actor_id", req.ActorID).
+		Equal("action", req.Action).
+		Any("branch_id", req.BranchIDs, "uuid")
+	if req.From != "" {
+		where.Where("created_at >= ?::timestamp", req.From)
+	}
+	if req.To != "" {
+		where.Where("created_at < ?::timestamp", req.To)
+	}
+
+	rows, err := a.db.Query(ctx, `SELECT count(id) OVER(),
+		id,
+		actor_id,
+		actor_role,
+		entity_type,
+		entity_id,
+		branch_id,
+		action,
+		before,
+		after,
+		changes,
+		ip,
+		created_at
+		FROM audit_log`+where.SQL()+fmt.Sprintf(" ORDER BY created_at DESC OFFSET %v LIMIT %v", offset, req.Limit), where.Args()...)
+	if err != nil {
+		return resp, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			log        = models.AuditLog{}
+			actor_id   sql.NullString
+			branch_id  sql.NullString
+			created_at sql.NullString
+		)
+		if err := rows.Scan(
+			&resp.Count,
+			&log.Id,
+			&actor_id,
+			&log.ActorRole,
+			&log.EntityType,
+			&log.EntityID,
+			&branch_id,
+			&log.Action,
+			&log.Before,
+			&log.After,
+			&log.Changes,
+			&log.IP,
+			&created_at,
+		); err != nil {
+			return resp, err
+		}
+
+		log.ActorID = actor_id.String
+		log.BranchID = branch_id.String
+		log.CreatedAt = created_at.String
+
+		resp.AuditLogs = append(resp.AuditLogs, log)
+	}
+
+	return resp, rows.Err()
+}
+
+// nullJSON stores empty documents as NULL.
+func nullJSON(data []byte) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
@@ -3,19 +3,30 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"lms_back/api/models"
 	"lms_back/pkg"
+	"lms_back/storage"
+	"lms_back/storage/postgres/filter"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// branchSearchColumns are matched by the search query param.
+var branchSearchColumns = []string{"name", "address"}
+
+// branchSortColumns are the fields the list can be sorted by.
+var branchSortColumns = map[string]string{
+	"name":       "name",
+	"address":    "address",
+	"created_at": "created_at",
+}
+
 type branchRepo struct {
-	db *pgxpool.Pool
+	db DBTX
 }
 
-func NewBranch(db *pgxpool.Pool) branchRepo {
+func NewBranch(db DBTX) branchRepo {
 	return branchRepo{
 		db: db,
 	}
@@ -41,9 +52,10 @@ func (c *branchRepo) Create(ctx context.Context, branch models.Branch) (models.B
 		return models.Branch{}, err
 	}
 	return models.Branch{
-		Id:        branch.Id,
+		Id:        id.String(),
 		Name:      branch.Name,
 		Address:   branch.Address,
+		Version:   1,
 		CreatedAt: branch.CreatedAt,
 	}, nil
 }
@@ -52,68 +64,90 @@ func (c *branchRepo) Update(ctx context.Context, branch models.Branch) (models.B
 	query := `update branches set 
 	name=$1,
 	address=$2,
+	version = version + 1,
 	updated_at=CURRENT_TIMESTAMP
-	WHERE id = $3 AND deleted_at = 0
+	WHERE id = $3 AND deleted_at = 0 AND version = $4
 	`
-	_, err := c.db.Exec(context.Background(), query,
+	tag, err := c.db.Exec(context.Background(), query,
 		branch.Name,
 		branch.Address,
 		branch.Id,
+		branch.Version,
 	)
 	if err != nil {
 		return models.Branch{}, err
 	}
+	if err = checkVersion(ctx, c.db, "branches", branch.Id, tag); err != nil {
+		return models.Branch{}, err
+	}
 	return models.Branch{
 		Id:        branch.Id,
 		Name:      branch.Name,
 		Address:   branch.Address,
+		Version:   branch.Version + 1,
 		CreatedAt: branch.CreatedAt,
 		UpdatedAt: branch.UpdatedAt,
 	}, nil
 }
 
+func (c *branchRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Branch, error) {
+	if err := patchRow(ctx, c.db, "branches", id, version, storage.BranchPatch, patch); err != nil {
+		return models.Branch{}, err
+	}
+	return c.GetByID(ctx, id)
+}
+
 func (c *branchRepo) GetAll(ctx context.Context, req models.GetAllBranchesRequest) (models.GetAllBranchesResponse, error) {
 	var (
-		resp   = models.GetAllBranchesResponse{}
-		filter = ""
+		resp    = models.GetAllBranchesResponse{}
+		cursors []string
 	)
-	offset := (req.Page - 1) * req.Limit
+	where := filter.New().
+		Deleted(req.Deleted).
+		Search(req.Search, branchSearchColumns...)
 
-	if req.Search != "" {
-		filter += fmt.Sprintf(` and name ILIKE  '%%%v%%' `, req.Search)
+	list, err := filter.NewList(req.ListParams, req.Page, req.Limit, branchSortColumns, "-created_at")
+	if err != nil {
+		return resp, err
 	}
+	list.Apply(where)
 
-	filter += fmt.Sprintf(" OFFSET %v LIMIT %v", offset, req.Limit)
-	fmt.Println("filter: ", filter)
-
-	rows, err := c.db.Query(context.Background(), `select count(id) over(),
+	rows, err := c.db.Query(context.Background(), `select `+list.CountColumn()+`,
         id,
         name,
         address,
+        version,
         created_at,
         updated_at,
-        deleted_at FROM branches WHERE deleted_at = 0`+filter+``)
+        deleted_at,
+        `+list.CursorColumn()+` FROM branches`+where.SQL()+list.SQL(), where.Args()...)
 	if err != nil {
 		return resp, err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		var (
 			branch     = models.Branch{}
 			id         sql.NullString
 			name       sql.NullString
 			address    sql.NullString
+			version    int
 			created_at sql.NullString
 			updateAt   sql.NullString
 			deleted_at sql.NullString
+			cursor     string
 		)
 		if err := rows.Scan(
 			&resp.Count,
 			&id,
 			&name,
 			&address,
+			&version,
 			&created_at,
 			&updateAt,
-			&deleted_at); err != nil {
+			&deleted_at,
+			&cursor); err != nil {
 			return resp, err
 		}
 		branch.UpdatedAt = pkg.NullStringToString(updateAt)
@@ -121,11 +155,21 @@ func (c *branchRepo) GetAll(ctx context.Context, req models.GetAllBranchesReques
 			Id:        id.String,
 			Name:      name.String,
 			Address:   address.String,
+			Version:   version,
 			CreatedAt: created_at.String,
 			UpdatedAt: updateAt.String,
 			DeletedAt: deleted_at.String,
 		})
+		cursors = append(cursors, cursor)
 	}
+
+	if err := rows.Err(); err != nil {
+		return resp, err
+	}
+
+	keep, next := list.Next(cursors)
+	resp.Branches, resp.NextCursor = resp.Branches[:keep], next
+
 	return resp, nil
 }
 
@@ -135,15 +179,17 @@ func (c *branchRepo) GetByID(ctx context.Context, id string) (models.Branch, err
 		branch     = models.Branch{}
 		name       sql.NullString
 		address    sql.NullString
+		version    int
 		created_at sql.NullString
 		updateAt   sql.NullString
 		deleted_at sql.NullString
 	)
 
-	if err := c.db.QueryRow(context.Background(), `select id, name, address, created_at, updated_at, deleted_at from branches where id = $1`, id).Scan(
+	if err := c.db.QueryRow(context.Background(), `select id, name, address, version, created_at, updated_at, deleted_at from branches where id = $1 AND deleted_at = 0`, id).Scan(
 		&branch.Id,
 		&name,
 		&address,
+		&version,
 		&created_at,
 		&updateAt,
 		&deleted_at); err != nil {
@@ -153,17 +199,24 @@ func (c *branchRepo) GetByID(ctx context.Context, id string) (models.Branch, err
 		Id:        branch.Id,
 		Name:      name.String,
 		Address:   address.String,
+		Version:   version,
 		CreatedAt: created_at.String,
 		UpdatedAt: updateAt.String,
 		DeletedAt: deleted_at.String,
 	}, nil
 }
 
-func (c *branchRepo) Delete(ctx context.Context, id string) error {
-	query := `delete from branches where id = $1`
-	_, err := c.db.Exec(context.Background(), query, id)
-	if err != nil {
-		return err
-	}
-	return nil
+func (c *branchRepo) Delete(ctx context.Context, id string, version int) error {
+	return softDelete(ctx, c.db, "branches", id, version)
+}
+
+func (c *branchRepo) Restore(ctx context.Context, id string) error {
+	return restore(ctx, c.db, "branches", id)
+}
+
+func (c *branchRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return purge(ctx, c.db, "branches", `(EXISTS (SELECT 1 FROM "group" WHERE "group".branch_id = branches.id)
+		OR EXISTS (SELECT 1 FROM payment WHERE payment.branch_id = branches.id)
+		OR EXISTS (SELECT 1 FROM schedule WHERE schedule.branch_id = branches.id)
+		OR EXISTS (SELECT 1 FROM login_attempt WHERE login_attempt.branch_id = branches.id))`, deletedBefore)
 }
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"lms_back/storage"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DBTX is implemented by both *pgxpool.Pool and pgx.Tx, so the repositories
+// run their queries the same way inside and outside of a transaction.
+type DBTX interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// errorDB runs the queries on db and translates their errors with
+// storage.DBError, so every repository returns domain errors.
+type errorDB struct {
+	db DBTX
+}
+
+func (d errorDB) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	tag, err := d.db.Exec(ctx, sql, arguments...)
+	return tag, storage.DBError(err)
+}
+
+func (d errorDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	rows, err := d.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, storage.DBError(err)
+	}
+	return errorRows{rows}, nil
+}
+
+func (d errorDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return errorRow{d.db.QueryRow(ctx, sql, args...)}
+}
+
+func (d errorDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return d.db.Begin(ctx)
+}
+
+type errorRow struct {
+	row pgx.Row
+}
+
+func (r errorRow) Scan(dest ...any) error {
+	return storage.DBError(r.row.Scan(dest...))
+}
+
+// errorRows translates the error of the query, rows report it after the
+// last row.
+type errorRows struct {
+	pgx.Rows
+}
+
+func (r errorRows) Err() error {
+	return storage.DBError(r.Rows.Err())
+}
@@ -0,0 +1,132 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"lms_back/api/models"
+	"lms_back/storage/postgres/filter"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type enrollmentRepo struct {
+	db DBTX
+}
+
+func NewEnrollment(db DBTX) enrollmentRepo {
+	return enrollmentRepo{
+		db: db,
+	}
+}
+
+func (e *enrollmentRepo) Create(ctx context.Context, enrollment models.Enrollment) (models.Enrollment, error) {
+	query := `INSERT INTO enrollment (
+		id,
+		student_id,
+		group_id,
+		status,
+		reason,
+		enrolled_at)
+		VALUES($1,$2,$3,$4,$5,CURRENT_TIMESTAMP)
+		RETURNING id, student_id, group_id, enrolled_at, left_at, status, reason
+	`
+
+	return scanEnrollment(e.db.QueryRow(ctx, query,
+		uuid.New().String(),
+		enrollment.StudentID,
+		enrollment.GroupID,
+		models.EnrollmentActive,
+		enrollment.Reason,
+	))
+}
+
+func (e *enrollmentRepo) Close(ctx context.Context, studentID, groupID, status, reason string) (models.Enrollment, error) {
+	query := `UPDATE enrollment SET
+		status = $3,
+		reason = $4,
+		left_at = CURRENT_TIMESTAMP
+		WHERE student_id = $1 AND group_id = $2 AND status = 'active'
+		RETURNING id, student_id, group_id, enrolled_at, left_at, status, reason
+	`
+
+	return scanEnrollment(e.db.QueryRow(ctx, query, studentID, groupID, status, reason))
+}
+
+func (e *enrollmentRepo) GetAll(ctx context.Context, req models.GetAllEnrollmentsRequest) (models.GetAllEnrollmentsResponse, error) {
+	var (
+		resp = models.GetAllEnrollmentsResponse{}
+	)
+	offset := (req.Page - 1) * req.Limit
+
+	where := filter.New().
+		Equal("student_id", req.StudentID).
+		Equal("group_id", req.GroupID).
+		Equal("status", req.Status)
+
+	rows, err := e.db.Query(ctx, `SELECT count(id) OVER(),
+		id,
+		student_id,
+		group_id,
+		enrolled_at,
+		left_at,
+		status,
+		reason
+		FROM enrollment`+where.SQL()+fmt.Sprintf(" ORDER BY enrolled_at DESC, id OFFSET %v LIMIT %v", offset, req.Limit), where.Args()...)
+	if err != nil {
+		return resp, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			enrollment  = models.Enrollment{}
+			enrolled_at sql.NullString
+			left_at     sql.NullString
+		)
+		if err := rows.Scan(
+			&resp.Count,
+			&enrollment.Id,
+			&enrollment.StudentID,
+			&enrollment.GroupID,
+			&enrolled_at,
+			&left_at,
+			&enrollment.Status,
+			&enrollment.Reason,
+		); err != nil {
+			return resp, err
+		}
+
+		enrollment.EnrolledAt = enrolled_at.String
+		enrollment.LeftAt = left_at.String
+
+		resp.Enrollments = append(resp.Enrollments, enrollment)
+	}
+
+	return resp, rows.Err()
+}
+
+func scanEnrollment(row pgx.Row) (models.Enrollment, error) {
+	var (
+		enrollment  = models.Enrollment{}
+		enrolled_at sql.NullString
+		left_at     sql.NullString
+	)
+	if err := row.Scan(
+		&enrollment.Id,
+		&enrollment.StudentID,
+		&enrollment.GroupID,
+		&enrolled_at,
+		&left_at,
+		&enrollment.Status,
+		&enrollment.Reason,
+	); err != nil {
+		return models.Enrollment{}, err
+	}
+
+	enrollment.EnrolledAt = enrolled_at.String
+	enrollment.LeftAt = left_at.String
+
+	return enrollment, nil
+}
@@ -0,0 +1,134 @@
+// Package filter builds parameterized WHERE clauses for the list queries of
+// the postgres storage. Column names and conditions come from the code, every
+// value from the request is passed as a query argument.
+package filter
+
+import (
+	"fmt"
+	"lms_back/api/models"
+	"strings"
+)
+
+type Builder struct {
+	conditions []string
+	args       []interface{}
+}
+
+func New() *Builder {
+	return &Builder{}
+}
+
+// Arg adds a query argument and returns its placeholder.
+func (b *Builder) Arg(value interface{}) string {
+	b.args = append(b.args, value)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// Where adds a condition, every ? in it is replaced by the placeholder of the
+// next value.
+func (b *Builder) Where(condition string, values ...interface{}) *Builder {
+	parts := strings.Split(condition, "?")
+	if len(parts)-1 != len(values) {
+		panic(fmt.Sprintf("filter: condition %q has %d placeholders for %d values", condition, len(parts)-1, len(values)))
+	}
+
+	var sql strings.Builder
+	sql.WriteString(parts[0])
+	for i, value := range values {
+		sql.WriteString(b.Arg(value))
+		sql.WriteString(parts[i+1])
+	}
+
+	b.conditions = append(b.conditions, sql.String())
+	return b
+}
+
+// Equal filters by the column unless value is empty.
+func (b *Builder) Equal(column, value string) *Builder {
+	if value == "" {
+		return b
+	}
+	return b.Where(column+" = ?", value)
+}
+
+// Any filters by the column being one of values. A nil slice doesn't filter,
+// an empty one matches nothing.
+func (b *Builder) Any(column string, values []string, sqlType string) *Builder {
+	if values == nil {
+		return b
+	}
+	return b.Where(column+" = ANY(?::"+sqlType+"[])", values)
+}
+
+// Range filters the column by inclusive bounds, zero bounds are left open.
+func (b *Builder) Range(column string, from, to interface{}) *Builder {
+	if !isZero(from) {
+		b.Where(column+" >= ?", from)
+	}
+	if !isZero(to) {
+		b.Where(column+" <= ?", to)
+	}
+	return b
+}
+
+// Search matches term as a substring of any of the columns, case insensitive.
+func (b *Builder) Search(term string, columns ...string) *Builder {
+	if term == "" || len(columns) == 0 {
+		return b
+	}
+
+	placeholder := b.Arg(Contains(term))
+
+	matches := make([]string, 0, len(columns))
+	for _, column := range columns {
+		matches = append(matches, column+"::text ILIKE "+placeholder)
+	}
+
+	b.conditions = append(b.conditions, "("+strings.Join(matches, " OR ")+")")
+	return b
+}
+
+// Deleted hides soft deleted rows, or lists only them for models.DeletedOnly.
+func (b *Builder) Deleted(mode string) *Builder {
+	if mode == models.DeletedOnly {
+		return b.Where("deleted_at <> 0")
+	}
+	return b.Where("deleted_at = 0")
+}
+
+// SQL returns the WHERE clause with a leading space, or an empty string
+// without conditions.
+func (b *Builder) SQL() string {
+	if len(b.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(b.conditions, " AND ")
+}
+
+func (b *Builder) Args() []interface{} {
+	return b.args
+}
+
+// Contains returns the ILIKE pattern matching term as a substring.
+func Contains(term string) string {
+	return "%" + escapeLike(term) + "%"
+}
+
+// escapeLike makes the wildcards of LIKE match themselves.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func isZero(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case int:
+		return v == 0
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package filter
+
+import (
+	"lms_back/api/models"
+	"reflect"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	b := New().
+		Search("50%_off", "full_name", "email").
+		Equal("status", "active").
+		Equal("group_id", "").
+		Any("branch_id", []string{"b1"}, "uuid").
+		Range("price", 100.0, 0.0)
+
+	wantSQL := ` WHERE (full_name::text ILIKE $1 OR email::text ILIKE $1) AND status = $2 AND branch_id = ANY($3::uuid[]) AND price >= $4`
+	if got := b.SQL(); got != wantSQL {
+		t.Errorf("SQL() = %q, want %q", got, wantSQL)
+	}
+
+	wantArgs := []interface{}{`%50\%\_off%`, "active", []string{"b1"}, 100.0}
+	if got := b.Args(); !reflect.DeepEqual(got, wantArgs) {
+		t.Errorf("Args() = %v, want %v", got, wantArgs)
+	}
+
+	if got := b.Arg(10); got != "$5" {
+		t.Errorf("Arg() = %q, want $5", got)
+	}
+}
+
+func TestBuilder_Empty(t *testing.T) {
+	b := New().Search("").Any("branch_id", nil, "uuid").Range("created_at", "", "")
+
+	if got := b.SQL(); got != "" {
+		t.Errorf("SQL() = %q, want no WHERE clause", got)
+	}
+	if got := b.Args(); len(got) != 0 {
+		t.Errorf("Args() = %v, want none", got)
+	}
+}
+
+func TestBuilder_AnyEmptyMatchesNothing(t *testing.T) {
+	b := New().Any("branch_id", []string{}, "uuid")
+
+	if got, want := b.SQL(), " WHERE branch_id = ANY($1::uuid[])"; got != want {
+		t.Errorf("SQL() = %q, want %q", got, want)
+	}
+}
+
+func TestBuilder_Deleted(t *testing.T) {
+	if got, want := New().Deleted("").SQL(), " WHERE deleted_at = 0"; got != want {
+		t.Errorf("SQL() = %q, want %q", got, want)
+	}
+	if got, want := New().Deleted(models.DeletedOnly).SQL(), " WHERE deleted_at <> 0"; got != want {
+		t.Errorf("SQL() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,178 @@
+package filter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"lms_back/api/models"
+	"lms_back/storage"
+	"strings"
+)
+
+// Order is a column of the ORDER BY clause.
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// List orders and paginates a list query. It pages with OFFSET or, in cursor
+// mode, with a keyset condition on the sort columns of the last row, which
+// stays stable when rows are inserted while paging.
+type List struct {
+	params      models.ListParams
+	page, limit uint64
+	orders      []Order
+	after       []interface{}
+}
+
+// NewList parses the sort of params against columns, which maps the sort
+// fields of the API to table columns. The id column is always the last order,
+// so rows with equal sort values keep a stable order. The keyset condition
+// doesn't match NULL, nullable columns have to be mapped with a COALESCE.
+func NewList(params models.ListParams, page, limit uint64, columns map[string]string, defaultSort string) (*List, error) {
+	if params.Sort == "" {
+		params.Sort = defaultSort
+	}
+
+	list := &List{params: params, page: page, limit: limit}
+	for _, field := range strings.Split(params.Sort, ",") {
+		field = strings.TrimSpace(field)
+		desc := strings.HasPrefix(field, "-")
+		column, ok := columns[strings.TrimPrefix(field, "-")]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", storage.ErrInvalidSort, field)
+		}
+		list.orders = append(list.orders, Order{Column: column, Desc: desc})
+	}
+	list.orders = append(list.orders, Order{Column: "id"})
+
+	if params.UseCursor && params.Cursor != "" {
+		after, err := decodeCursor(params.Cursor, params.Sort)
+		if err != nil || len(after) != len(list.orders) {
+			return nil, storage.ErrInvalidCursor
+		}
+		list.after = after
+	}
+
+	return list, nil
+}
+
+// Apply adds the keyset condition of the cursor to b.
+func (l *List) Apply(b *Builder) {
+	if l.after == nil {
+		return
+	}
+
+	// (c1 > v1) OR (c1 = v1 AND c2 > v2) OR ... with < for descending columns
+	placeholders := make([]string, len(l.after))
+	for i, value := range l.after {
+		placeholders[i] = b.Arg(value)
+	}
+
+	terms := make([]string, 0, len(l.orders))
+	for i, order := range l.orders {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, l.orders[j].Column+" = "+placeholders[j])
+		}
+		operator := " > "
+		if order.Desc {
+			operator = " < "
+		}
+		parts = append(parts, order.Column+operator+placeholders[i])
+		terms = append(terms, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	b.conditions = append(b.conditions, "("+strings.Join(terms, " OR ")+")")
+}
+
+// CountColumn selects the total count of rows. It is not computed in cursor
+// mode, as it costs a scan of every matching row.
+func (l *List) CountColumn() string {
+	if l.params.UseCursor {
+		return "0"
+	}
+	return "count(id) OVER()"
+}
+
+// CursorColumn selects the sort values of a row, which make up the cursor
+// pointing after it.
+func (l *List) CursorColumn() string {
+	if !l.params.UseCursor {
+		return "''"
+	}
+
+	columns := make([]string, len(l.orders))
+	for i, order := range l.orders {
+		columns[i] = order.Column
+	}
+	return "json_build_array(" + strings.Join(columns, ", ") + ")::text"
+}
+
+// SQL returns the ORDER BY clause and the pagination of the query. In cursor
+// mode it asks for one more row than the limit to know if there is a next page.
+func (l *List) SQL() string {
+	orders := make([]string, len(l.orders))
+	for i, order := range l.orders {
+		orders[i] = order.Column + " ASC"
+		if order.Desc {
+			orders[i] = order.Column + " DESC"
+		}
+	}
+	sql := " ORDER BY " + strings.Join(orders, ", ")
+
+	if l.params.UseCursor {
+		return sql + fmt.Sprintf(" LIMIT %d", l.limit+1)
+	}
+	return sql + fmt.Sprintf(" OFFSET %d LIMIT %d", (l.page-1)*l.limit, l.limit)
+}
+
+// Next takes the cursor columns of the fetched rows and returns how many of
+// them belong to the page and the cursor of the next page, if there is one.
+func (l *List) Next(cursors []string) (int, string) {
+	if !l.params.UseCursor || uint64(len(cursors)) <= l.limit {
+		return len(cursors), ""
+	}
+
+	keep := int(l.limit)
+	return keep, encodeCursor(cursors[keep-1], l.params.Sort)
+}
+
+type cursor struct {
+	Sort   string          `json:"s"`
+	Values json.RawMessage `json:"v"`
+}
+
+func encodeCursor(values, sort string) string {
+	data, _ := json.Marshal(cursor{Sort: sort, Values: json.RawMessage(values)})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the sort values of the cursor. A cursor is only valid
+// with the sort it was issued for.
+func decodeCursor(value, sort string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var c cursor
+	if err = json.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if c.Sort != sort {
+		return nil, fmt.Errorf("cursor was issued for sort %q", c.Sort)
+	}
+
+	var values []interface{}
+	if err = json.Unmarshal(c.Values, &values); err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		if v == nil {
+			return nil, fmt.Errorf("cursor has a null value")
+		}
+	}
+
+	return values, nil
+}
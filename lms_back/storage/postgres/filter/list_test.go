@@ -0,0 +1,67 @@
+package filter
+
+import (
+	"errors"
+	"lms_back/api/models"
+	"lms_back/storage"
+	"reflect"
+	"testing"
+)
+
+var testSortColumns = map[string]string{"full_name": "full_name", "created_at": "created_at"}
+
+func TestList_Offset(t *testing.T) {
+	list, err := NewList(models.ListParams{Sort: "full_name,-created_at"}, 3, 10, testSortColumns, "-created_at")
+	if err != nil {
+		t.Fatalf("NewList() error = %v", err)
+	}
+
+	want := " ORDER BY full_name ASC, created_at DESC, id ASC OFFSET 20 LIMIT 10"
+	if got := list.SQL(); got != want {
+		t.Errorf("SQL() = %q, want %q", got, want)
+	}
+
+	if _, err = NewList(models.ListParams{Sort: "password"}, 1, 10, testSortColumns, "-created_at"); !errors.Is(err, storage.ErrInvalidSort) {
+		t.Errorf("NewList() error = %v, want %v", err, storage.ErrInvalidSort)
+	}
+}
+
+func TestList_Cursor(t *testing.T) {
+	first, err := NewList(models.ListParams{UseCursor: true}, 1, 2, testSortColumns, "-created_at")
+	if err != nil {
+		t.Fatalf("NewList() error = %v", err)
+	}
+	if got, want := first.SQL(), " ORDER BY created_at DESC, id ASC LIMIT 3"; got != want {
+		t.Errorf("SQL() = %q, want %q", got, want)
+	}
+
+	keep, cursor := first.Next([]string{`["2024-01-03T00:00:00", "a"]`, `["2024-01-02T00:00:00", "b"]`, `["2024-01-01T00:00:00", "c"]`})
+	if keep != 2 || cursor == "" {
+		t.Fatalf("Next() = %d, %q, want 2 rows and a cursor", keep, cursor)
+	}
+
+	second, err := NewList(models.ListParams{UseCursor: true, Cursor: cursor}, 1, 2, testSortColumns, "-created_at")
+	if err != nil {
+		t.Fatalf("NewList() error = %v", err)
+	}
+	where := New().Equal("status", "active")
+	second.Apply(where)
+
+	wantSQL := ` WHERE status = $1 AND ((created_at < $2) OR (created_at = $2 AND id > $3))`
+	if got := where.SQL(); got != wantSQL {
+		t.Errorf("SQL() = %q, want %q", got, wantSQL)
+	}
+	wantArgs := []interface{}{"active", "2024-01-02T00:00:00", "b"}
+	if got := where.Args(); !reflect.DeepEqual(got, wantArgs) {
+		t.Errorf("Args() = %v, want %v", got, wantArgs)
+	}
+
+	if keep, next := second.Next([]string{`["2024-01-01T00:00:00", "c"]`}); keep != 1 || next != "" {
+		t.Errorf("Next() = %d, %q, want the last page", keep, next)
+	}
+
+	// a cursor is bound to the sort it was issued for
+	if _, err = NewList(models.ListParams{Sort: "full_name", UseCursor: true, Cursor: cursor}, 1, 2, testSortColumns, "-created_at"); !errors.Is(err, storage.ErrInvalidCursor) {
+		t.Errorf("NewList() error = %v, want %v", err, storage.ErrInvalidCursor)
+	}
+}
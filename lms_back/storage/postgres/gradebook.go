@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"lms_back/api/models"
+	"lms_back/storage/postgres/filter"
+)
+
+type gradebookRepo struct {
+	db DBTX
+}
+
+func NewGradebook(db DBTX) gradebookRepo {
+	return gradebookRepo{
+		db: db,
+	}
+}
+
+func (g *gradebookRepo) Students(ctx context.Context, groupID string) ([]models.GradebookStudent, error) {
+	rows, err := g.db.Query(ctx, `SELECT s.id, s.full_name
+		FROM enrollment e JOIN student s ON s.id = e.student_id AND s.deleted_at = 0
+		WHERE e.group_id = $1 AND e.status = 'active'
+		ORDER BY s.full_name, s.id`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var students []models.GradebookStudent
+	for rows.Next() {
+		var student models.GradebookStudent
+		if err := rows.Scan(&student.StudentID, &student.FullName); err != nil {
+			return nil, err
+		}
+		students = append(students, student)
+	}
+
+	return students, rows.Err()
+}
+
+func (g *gradebookRepo) Lessons(ctx context.Context, req models.GradebookRequest) ([]models.GradebookLesson, error) {
+	where := filter.New().
+		Where("deleted_at = 0").
+		Equal("group_id", req.GroupID).
+		Range(`"from"`, req.From, req.To)
+
+	rows, err := g.db.Query(ctx, `SELECT id, theme, "from" FROM "lesson"`+where.SQL()+` ORDER BY "from", id`, where.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lessons []models.GradebookLesson
+	for rows.Next() {
+		var (
+			lesson models.GradebookLesson
+			date   sql.NullString
+		)
+		if err := rows.Scan(&lesson.LessonID, &lesson.Theme, &date); err != nil {
+			return nil, err
+		}
+		lesson.Date = date.String
+		lessons = append(lessons, lesson)
+	}
+
+	return lessons, rows.Err()
+}
+
+// gradebookTaskFilter filters the tasks joined as t with their lessons as l.
+func gradebookTaskFilter(req models.GradebookRequest) *filter.Builder {
+	return filter.New().
+		Where("t.deleted_at = 0").
+		Where("l.deleted_at = 0").
+		Equal("t.group_id", req.GroupID).
+		Range(`l."from"`, req.From, req.To)
+}
+
+func (g *gradebookRepo) Tasks(ctx context.Context, req models.GradebookRequest) ([]models.GradebookTask, error) {
+	where := gradebookTaskFilter(req)
+
+	rows, err := g.db.Query(ctx, `SELECT t.id, t.lesson_id, t.task, t.score, t.deadline
+		FROM "task" t JOIN "lesson" l ON l.id = t.lesson_id`+where.SQL()+`
+		ORDER BY l."from", l.id, t.created_at, t.id`, where.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []models.GradebookTask
+	for rows.Next() {
+		var (
+			task     models.GradebookTask
+			deadline sql.NullString
+		)
+		if err := rows.Scan(&task.TaskID, &task.LessonID, &task.Task, &task.MaxScore, &deadline); err != nil {
+			return nil, err
+		}
+		task.Deadline = deadline.String
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+func (g *gradebookRepo) Scores(ctx context.Context, req models.GradebookRequest) ([]models.GradebookScore, error) {
+	where := gradebookTaskFilter(req)
+
+	rows, err := g.db.Query(ctx, `SELECT ts.task_id, ts.student_id, ts.score, ts.late
+		FROM task_submission ts
+		JOIN "task" t ON t.id = ts.task_id
+		JOIN "lesson" l ON l.id = t.lesson_id`+where.SQL()+`
+		ORDER BY ts.task_id, ts.student_id`, where.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []models.GradebookScore
+	for rows.Next() {
+		var (
+			score  models.GradebookScore
+			graded sql.NullInt32
+		)
+		if err := rows.Scan(&score.TaskID, &score.StudentID, &graded, &score.Late); err != nil {
+			return nil, err
+		}
+		if graded.Valid {
+			value := int(graded.Int32)
+			score.Score = &value
+		}
+		scores = append(scores, score)
+	}
+
+	return scores, rows.Err()
+}
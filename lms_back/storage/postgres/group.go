@@ -3,21 +3,31 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"lms_back/api/models"
 	"lms_back/pkg"
+	"lms_back/storage"
+	"lms_back/storage/postgres/filter"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
-
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// groupSearchColumns are matched by the search query param.
+var groupSearchColumns = []string{"group_id"}
+
+// groupSortColumns are the fields the list can be sorted by.
+var groupSortColumns = map[string]string{
+	"group_id":   "group_id",
+	"type":       "type",
+	"created_at": `COALESCE(created_at, '-infinity')`,
+}
+
 type GroupRepo struct {
-	db *pgxpool.Pool
+	db DBTX
 }
 
-func NewGroup(db *pgxpool.Pool) GroupRepo {
+func NewGroup(db DBTX) GroupRepo {
 	return GroupRepo{
 		db: db,
 	}
@@ -53,9 +63,10 @@ func (g *GroupRepo) Create(ctx context.Context, group models.Group) (models.Grou
 		VALUES($1,$2,$3,$4,$5,CURRENT_TIMESTAMP)
 		`
 
+	groupID := "Gr-" + pkg.GetSerialId(digit)
 	_, err = g.db.Exec(context.Background(), query,
 		id.String(),
-		"Gr-"+pkg.GetSerialId(digit),
+		groupID,
 		group.Branch_id,
 		group.Teacher_id,
 		group.Type)
@@ -63,11 +74,12 @@ func (g *GroupRepo) Create(ctx context.Context, group models.Group) (models.Grou
 		return models.Group{}, err
 	}
 	return models.Group{
-		Id:         group.Id,
-		Group_id:   group.Group_id,
+		Id:         id.String(),
+		Group_id:   groupID,
 		Branch_id:  group.Branch_id,
 		Teacher_id: group.Teacher_id,
 		Type:       group.Type,
+		Version:    1,
 		Created_at: group.Created_at,
 	}, nil
 }
@@ -75,12 +87,16 @@ func (g *GroupRepo) Create(ctx context.Context, group models.Group) (models.Grou
 func (g *GroupRepo) Update(ctx context.Context, group models.Group) (models.Group, error) {
 	query := `UPDATE "group" SET
 		type=$1,
+		version = version + 1,
 		updated_at=CURRENT_TIMESTAMP
-		WHERE id=$2`
+		WHERE id=$2 AND deleted_at = 0 AND version = $3`
 
-	_, err := g.db.Exec(context.Background(), query, group.Type, group.Id)
+	tag, err := g.db.Exec(context.Background(), query, group.Type, group.Id, group.Version)
 	if err != nil {
-		return models.Group{}, nil
+		return models.Group{}, err
+	}
+	if err = checkVersion(ctx, g.db, `"group"`, group.Id, tag); err != nil {
+		return models.Group{}, err
 	}
 	return models.Group{
 		Id:         group.Id,
@@ -88,38 +104,55 @@ func (g *GroupRepo) Update(ctx context.Context, group models.Group) (models.Grou
 		Branch_id:  group.Branch_id,
 		Teacher_id: group.Teacher_id,
 		Type:       group.Type,
+		Version:    group.Version + 1,
 		Created_at: group.Created_at,
 		Updated_at: group.Updated_at,
 	}, nil
 }
 
+func (g *GroupRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Group, error) {
+	if err := patchRow(ctx, g.db, `"group"`, id, version, storage.GroupPatch, patch); err != nil {
+		return models.Group{}, err
+	}
+	return g.GetByID(ctx, id)
+}
+
 func (g *GroupRepo) GetAll(ctx context.Context, req models.GetAllGroupsRequest) (models.GetAllGroupsResponse, error) {
 	var (
-		resp   = models.GetAllGroupsResponse{}
-		filter = ""
+		resp    = models.GetAllGroupsResponse{}
+		cursors []string
 	)
-	offset := (req.Page - 1) * req.Limit
-
-	if req.Search != "" {
-		filter += fmt.Sprintf(` and name ILIKE  '%%%v%%' `, req.Search)
+	where := filter.New().
+		Deleted(req.Deleted).
+		Search(req.Search, groupSearchColumns...).
+		Equal("branch_id", req.BranchID).
+		Equal("teacher_id", req.TeacherID).
+		Equal("type", req.Type).
+		Any("branch_id", req.BranchIDs, "uuid")
+
+	list, err := filter.NewList(req.ListParams, req.Page, req.Limit, groupSortColumns, "-created_at")
+	if err != nil {
+		return resp, err
 	}
+	list.Apply(where)
 
-	filter += fmt.Sprintf(" OFFSET %v LIMIT %v", offset, req.Limit)
-	fmt.Println("filter: ", filter)
-
-	rows, err := g.db.Query(context.Background(), `SELECT count (id) OVER(),
+	rows, err := g.db.Query(context.Background(), `SELECT `+list.CountColumn()+`,
         id,
         group_id,
         branch_id,
         teacher_id,
         type,
+        version,
         created_at,
-        updated_at FROM "group"`+filter+``)
+        updated_at,
+        `+list.CursorColumn()+` FROM "group"`+where.SQL()+list.SQL(), where.Args()...)
 
 	if err != nil {
 		return resp, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 		var (
 			group      = models.Group{}
@@ -127,8 +160,10 @@ func (g *GroupRepo) GetAll(ctx context.Context, req models.GetAllGroupsRequest)
 			branch_id  sql.NullString
 			teacher_id sql.NullString
 			Type       sql.NullString
+			version    int
 			created_at sql.NullString
 			updateAt   sql.NullString
+			cursor     string
 		)
 		if err := rows.Scan(
 			&resp.Count,
@@ -137,8 +172,10 @@ func (g *GroupRepo) GetAll(ctx context.Context, req models.GetAllGroupsRequest)
 			&branch_id,
 			&teacher_id,
 			&Type,
+			&version,
 			&created_at,
-			&updateAt); err != nil {
+			&updateAt,
+			&cursor); err != nil {
 			return resp, err
 		}
 		group.Updated_at = pkg.NullStringToString(updateAt)
@@ -148,10 +185,20 @@ func (g *GroupRepo) GetAll(ctx context.Context, req models.GetAllGroupsRequest)
 			Branch_id:  branch_id.String,
 			Teacher_id: teacher_id.String,
 			Type:       Type.String,
+			Version:    version,
 			Created_at: created_at.String,
 			Updated_at: updateAt.String,
 		})
+		cursors = append(cursors, cursor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return resp, err
 	}
+
+	keep, next := list.Next(cursors)
+	resp.Groups, resp.NextCursor = resp.Groups[:keep], next
+
 	return resp, nil
 }
 
@@ -162,34 +209,45 @@ func (g *GroupRepo) GetByID(ctx context.Context, id string) (models.Group, error
 		branch_id  sql.NullString
 		teacher_id sql.NullString
 		Type       sql.NullString
+		version    int
 		created_at sql.NullString
 		updateAt   sql.NullString
 	)
-	if err := g.db.QueryRow(context.Background(), `SELECT id, group_id, branch_id, teacher_id, type, created_at, updated_at FROM "group" WHERE id = $1`, id).Scan(
+	if err := g.db.QueryRow(context.Background(), `SELECT id, group_id, branch_id, teacher_id, type, version, created_at, updated_at FROM "group" WHERE id = $1 AND deleted_at = 0`, id).Scan(
 		&group.Id,
 		&group_id,
 		&branch_id,
 		&teacher_id,
 		&Type,
+		&version,
 		&created_at,
 		&updateAt); err != nil {
 		return models.Group{}, err
 	}
 	return models.Group{
+		Id:         group.Id,
 		Group_id:   group_id.String,
 		Branch_id:  branch_id.String,
 		Teacher_id: teacher_id.String,
 		Type:       Type.String,
+		Version:    version,
 		Created_at: created_at.String,
 		Updated_at: updateAt.String,
 	}, nil
 }
 
-func (g *GroupRepo) Delete(ctx context.Context, id string) error {
-	query := `delete from "group" where id = $1`
-	_, err := g.db.Exec(context.Background(), query, id)
-	if err != nil {
-		return err
-	}
-	return nil
+func (g *GroupRepo) Delete(ctx context.Context, id string, version int) error {
+	return softDelete(ctx, g.db, `"group"`, id, version)
+}
+
+func (g *GroupRepo) Restore(ctx context.Context, id string) error {
+	return restore(ctx, g.db, `"group"`, id)
+}
+
+func (g *GroupRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return purge(ctx, g.db, `"group"`, `(EXISTS (SELECT 1 FROM student WHERE student.group_id = "group".id)
+		OR EXISTS (SELECT 1 FROM schedule WHERE schedule.group_id = "group".id)
+		OR EXISTS (SELECT 1 FROM lesson WHERE lesson.group_id = "group".id)
+		OR EXISTS (SELECT 1 FROM "task" WHERE "task".group_id = "group".id)
+		OR EXISTS (SELECT 1 FROM enrollment WHERE enrollment.group_id = "group".id))`, deletedBefore)
 }
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"lms_back/api/models"
+	"time"
+)
+
+type idempotencyRepo struct {
+	db DBTX
+}
+
+func NewIdempotency(db DBTX) idempotencyRepo {
+	return idempotencyRepo{
+		db: db,
+	}
+}
+
+// Create claims the key for the request. It returns false if the key is
+// already claimed, keys older than ttl and claims of requests still running
+// after lease are claimed again.
+func (i *idempotencyRepo) Create(ctx context.Context, key models.IdempotencyKey, ttl, lease time.Duration) (bool, error) {
+	query := `INSERT INTO idempotency_key (
+		key,
+		user_id,
+		method,
+		path,
+		request_hash,
+		created_at)
+		VALUES($1,$2,$3,$4,$5,CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id, key) DO UPDATE SET
+		method = EXCLUDED.method,
+		path = EXCLUDED.path,
+		request_hash = EXCLUDED.request_hash,
+		status_code = NULL,
+		content_type = '',
+		etag = '',
+		response = NULL,
+		created_at = CURRENT_TIMESTAMP
+		WHERE idempotency_key.created_at < CURRENT_TIMESTAMP - $6 * interval '1 second'
+		OR (idempotency_key.status_code IS NULL AND idempotency_key.created_at < CURRENT_TIMESTAMP - $7 * interval '1 second')
+	`
+
+	tag, err := i.db.Exec(ctx, query,
+		key.Key,
+		key.UserID,
+		key.Method,
+		key.Path,
+		key.RequestHash,
+		ttl.Seconds(),
+		lease.Seconds(),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+func (i *idempotencyRepo) Get(ctx context.Context, userID, key string) (models.IdempotencyKey, error) {
+	var (
+		idempotencyKey = models.IdempotencyKey{}
+		statusCode     sql.NullInt64
+		createdAt      sql.NullString
+	)
+
+	row := i.db.QueryRow(ctx, `SELECT key, user_id, method, path, request_hash, status_code, content_type, etag, response, created_at
+		FROM idempotency_key WHERE user_id = $1 AND key = $2`, userID, key)
+	if err := row.Scan(
+		&idempotencyKey.Key,
+		&idempotencyKey.UserID,
+		&idempotencyKey.Method,
+		&idempotencyKey.Path,
+		&idempotencyKey.RequestHash,
+		&statusCode,
+		&idempotencyKey.ContentType,
+		&idempotencyKey.ETag,
+		&idempotencyKey.Response,
+		&createdAt,
+	); err != nil {
+		return models.IdempotencyKey{}, err
+	}
+
+	idempotencyKey.StatusCode = int(statusCode.Int64)
+	idempotencyKey.CreatedAt = createdAt.String
+
+	return idempotencyKey, nil
+}
+
+// Complete stores the response of the request that claimed the key. A key
+// completed by a retry that took over the lease keeps the response of the retry.
+func (i *idempotencyRepo) Complete(ctx context.Context, key models.IdempotencyKey) error {
+	_, err := i.db.Exec(ctx, `UPDATE idempotency_key SET status_code = $1, content_type = $2, etag = $3, response = $4
+		WHERE user_id = $5 AND key = $6 AND status_code IS NULL`, key.StatusCode, key.ContentType, key.ETag, key.Response, key.UserID, key.Key)
+
+	return err
+}
+
+// Delete releases the key, so the request can be retried with it.
+func (i *idempotencyRepo) Delete(ctx context.Context, userID, key string) error {
+	_, err := i.db.Exec(ctx, `DELETE FROM idempotency_key WHERE user_id = $1 AND key = $2`, userID, key)
+
+	return err
+}
@@ -3,19 +3,30 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"lms_back/api/models"
 	"lms_back/pkg"
+	"lms_back/storage"
+	"lms_back/storage/postgres/filter"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// lessonSearchColumns are matched by the search query param.
+var lessonSearchColumns = []string{"theme"}
+
+// lessonSortColumns are the fields the list can be sorted by.
+var lessonSortColumns = map[string]string{
+	"theme":      "theme",
+	"from":       `COALESCE("from", '-infinity')`,
+	"created_at": `COALESCE(created_at, '-infinity')`,
+}
+
 type lessonRepo struct {
-	db *pgxpool.Pool
+	db DBTX
 }
 
-func NewLesson(db *pgxpool.Pool) lessonRepo {
+func NewLesson(db DBTX) lessonRepo {
 	return lessonRepo{
 		db: db,
 	}
@@ -28,8 +39,8 @@ func (c *lessonRepo) Create(ctx context.Context, lesson models.Lesson) (models.L
 		id,
 		schedule_id,
 		group_id,
-		from,
-		to,
+		"from",
+		"to",
 		theme,
 		created_at)
 		VALUES($1,$2,$3,$4,$5,$6,CURRENT_TIMESTAMP) 
@@ -48,12 +59,13 @@ func (c *lessonRepo) Create(ctx context.Context, lesson models.Lesson) (models.L
 		return models.Lesson{}, err
 	}
 	return models.Lesson{
-		Id:         lesson.Id,
+		Id:         id.String(),
 		ScheduleId: lesson.ScheduleId,
 		GroupId:    lesson.GroupId,
 		From:       lesson.From,
 		To:         lesson.To,
 		Theme:      lesson.Theme,
+		Version:    1,
 		Created_at: lesson.Created_at,
 		Updated_at: lesson.Updated_at,
 	}, nil
@@ -63,23 +75,28 @@ func (c *lessonRepo) Update(ctx context.Context, lesson models.Lesson) (models.L
 	query := `update "lesson" set 
 	schedule_id=$1,
 	group_id=$2,
-	from=$3,
-	to=$4,
+	"from"=$3,
+	"to"=$4,
 	theme=$5,
+	version = version + 1,
 	updated_at=CURRENT_TIMESTAMP
-	WHERE id = $6
+	WHERE id = $6 AND deleted_at = 0 AND version = $7
 	`
-	_, err := c.db.Exec(context.Background(), query,
+	tag, err := c.db.Exec(context.Background(), query,
 		lesson.ScheduleId,
 		lesson.GroupId,
 		lesson.From,
 		lesson.To,
 		lesson.Theme,
 		lesson.Id,
+		lesson.Version,
 	)
 	if err != nil {
 		return models.Lesson{}, err
 	}
+	if err = checkVersion(ctx, c.db, `"lesson"`, lesson.Id, tag); err != nil {
+		return models.Lesson{}, err
+	}
 	return models.Lesson{
 		Id:         lesson.Id,
 		ScheduleId: lesson.ScheduleId,
@@ -87,38 +104,54 @@ func (c *lessonRepo) Update(ctx context.Context, lesson models.Lesson) (models.L
 		From:       lesson.From,
 		To:         lesson.To,
 		Theme:      lesson.Theme,
+		Version:    lesson.Version + 1,
 		Created_at: lesson.Created_at,
 		Updated_at: lesson.Updated_at,
 	}, nil
 }
 
+func (c *lessonRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Lesson, error) {
+	if err := patchRow(ctx, c.db, `"lesson"`, id, version, storage.LessonPatch, patch); err != nil {
+		return models.Lesson{}, err
+	}
+	return c.GetByID(ctx, id)
+}
+
 func (c *lessonRepo) GetAll(ctx context.Context, req models.GetAllLessonsRequest) (models.GetAllLessonsResponse, error) {
 	var (
-		resp   = models.GetAllLessonsResponse{}
-		filter = ""
+		resp    = models.GetAllLessonsResponse{}
+		cursors []string
 	)
-	offset := (req.Page - 1) * req.Limit
-
-	if req.Search != "" {
-		filter += fmt.Sprintf(` and name ILIKE  '%%%v%%' `, req.Search)
+	where := filter.New().
+		Deleted(req.Deleted).
+		Search(req.Search, lessonSearchColumns...).
+		Equal("group_id", req.GroupID).
+		Equal("schedule_id", req.ScheduleID).
+		Range(`"from"`, req.FromDate, req.ToDate)
+
+	list, err := filter.NewList(req.ListParams, req.Page, req.Limit, lessonSortColumns, "-created_at")
+	if err != nil {
+		return resp, err
 	}
+	list.Apply(where)
 
-	filter += fmt.Sprintf(" OFFSET %v LIMIT %v", offset, req.Limit)
-	fmt.Println("filter: ", filter)
-
-	rows, err := c.db.Query(context.Background(), `select count(id) over(),
+	rows, err := c.db.Query(context.Background(), `select `+list.CountColumn()+`,
         id,
         schedule_id,
         group_id,
-		from,
-		to,
+		"from",
+		"to",
 		theme,
+		version,
         created_at,
-        updated_at
-        FROM "lesson"`+filter+``)
+        updated_at,
+        `+list.CursorColumn()+`
+        FROM "lesson"`+where.SQL()+list.SQL(), where.Args()...)
 	if err != nil {
 		return resp, err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		var (
 			lesson      = models.Lesson{}
@@ -127,8 +160,10 @@ func (c *lessonRepo) GetAll(ctx context.Context, req models.GetAllLessonsRequest
 			from        sql.NullString
 			to          sql.NullString
 			theme       sql.NullString
+			version     int
 			created_at  sql.NullString
 			updateAt    sql.NullString
+			cursor      string
 		)
 		if err := rows.Scan(
 			&resp.Count,
@@ -138,20 +173,34 @@ func (c *lessonRepo) GetAll(ctx context.Context, req models.GetAllLessonsRequest
 			&from,
 			&to,
 			&theme,
+			&version,
 			&created_at,
-			&updateAt); err != nil {
+			&updateAt,
+			&cursor); err != nil {
 			return resp, err
 		}
 		lesson.Updated_at = pkg.NullStringToString(updateAt)
 		resp.Lessons = append(resp.Lessons, models.Lesson{
+			Id:         lesson.Id,
 			ScheduleId: schedule_id.String,
 			GroupId:    group_id.String,
 			From:       from.String,
 			To:         to.String,
+			Theme:      theme.String,
+			Version:    version,
 			Created_at: created_at.String,
 			Updated_at: updateAt.String,
 		})
+		cursors = append(cursors, cursor)
 	}
+
+	if err := rows.Err(); err != nil {
+		return resp, err
+	}
+
+	keep, next := list.Next(cursors)
+	resp.Lessons, resp.NextCursor = resp.Lessons[:keep], next
+
 	return resp, nil
 }
 
@@ -163,36 +212,44 @@ func (c *lessonRepo) GetByID(ctx context.Context, id string) (models.Lesson, err
 		from        sql.NullString
 		to          sql.NullString
 		theme       sql.NullString
+		version     int
 		created_at  sql.NullString
 		updateAt    sql.NullString
 	)
 
-	if err := c.db.QueryRow(context.Background(), `select id, schedule_id, group_id, from, to, theme, created_at, updated_at from "lesson" where id = $1`, id).Scan(
+	if err := c.db.QueryRow(context.Background(), `select id, schedule_id, group_id, "from", "to", theme, version, created_at, updated_at from "lesson" where id = $1 AND deleted_at = 0`, id).Scan(
 		&lesson.Id,
 		&schedule_id,
 		&group_id,
 		&from,
 		&to,
 		&theme,
+		&version,
 		&created_at,
 		&updateAt); err != nil {
 		return models.Lesson{}, err
 	}
 	return models.Lesson{
+		Id:         lesson.Id,
 		ScheduleId: schedule_id.String,
 		GroupId:    group_id.String,
 		From:       from.String,
 		To:         to.String,
+		Theme:      theme.String,
+		Version:    version,
 		Created_at: created_at.String,
 		Updated_at: updateAt.String,
 	}, nil
 }
 
-func (c *lessonRepo) Delete(ctx context.Context, id string) error {
-	query := `delete from "lesson" where id = $1`
-	_, err := c.db.Exec(context.Background(), query, id)
-	if err != nil {
-		return err
-	}
-	return nil
+func (c *lessonRepo) Delete(ctx context.Context, id string, version int) error {
+	return softDelete(ctx, c.db, `"lesson"`, id, version)
+}
+
+func (c *lessonRepo) Restore(ctx context.Context, id string) error {
+	return restore(ctx, c.db, `"lesson"`, id)
+}
+
+func (c *lessonRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return purge(ctx, c.db, `"lesson"`, `EXISTS (SELECT 1 FROM "task" WHERE "task".lesson_id = "lesson".id)`, deletedBefore)
 }
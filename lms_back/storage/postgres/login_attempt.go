@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"lms_back/api/models"
+	"lms_back/storage/postgres/filter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type loginAttemptRepo struct {
+	db DBTX
+}
+
+func NewLoginAttempt(db DBTX) loginAttemptRepo {
+	return loginAttemptRepo{
+		db: db,
+	}
+}
+
+// Create records a login attempt. The branch of students is taken from their
+// group, teachers get the branch of one of the groups they teach.
+func (l *loginAttemptRepo) Create(ctx context.Context, attempt models.LoginAttempt) error {
+	query := `INSERT INTO login_attempt (
+		id,
+		login,
+		user_role,
+		user_id,
+		branch_id,
+		ip,
+		success,
+		created_at)
+		VALUES($1,$2,$3,$4,
+		CASE $3
+			WHEN 'student' THEN (SELECT g.branch_id FROM student s JOIN "group" g ON g.id = s.group_id WHERE s.id = $4)
+			WHEN 'teacher' THEN (SELECT g.branch_id FROM "group" g WHERE g.teacher_id = $4 LIMIT 1)
+		END,
+		$5,$6,CURRENT_TIMESTAMP)
+	`
+
+	_, err := l.db.Exec(ctx, query,
+		uuid.New().String(),
+		attempt.Login,
+		attempt.UserRole,
+		sql.NullString{String: attempt.UserID, Valid: attempt.UserID != ""},
+		attempt.IP,
+		attempt.Success,
+	)
+
+	return err
+}
+
+func (l *loginAttemptRepo) GetAll(ctx context.Context, req models.GetAllLoginAttemptsRequest) (models.GetAllLoginAttemptsResponse, error) {
+	var (
+		resp = models.GetAllLoginAttemptsResponse{}
+	)
+	offset := (req.Page - 1) * req.Limit
+
+	where := filter.New().
+		Equal("login", req.Login).
+		Equal("user_role", req.UserRole).
+		Equal("branch_id", req.BranchID).
+		Equal("ip", req.IP).
+		Any("branch_id", req.BranchIDs, "uuid")
+	if req.Success != "" {
+		where.Where("success = ?", req.Success == "true")
+	}
+
+	rows, err := l.db.Query(ctx, `SELECT count(id) OVER(),
+		id,
+		login,
+		user_role,
+		user_id,
+		branch_id,
+		ip,
+		success,
+		created_at
+		FROM login_attempt`+where.SQL()+fmt.Sprintf(" ORDER BY created_at DESC OFFSET %v LIMIT %v", offset, req.Limit), where.Args()...)
+	if err != nil {
+		return resp, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			attempt    = models.LoginAttempt{}
+			user_id    sql.NullString
+			branch_id  sql.NullString
+			created_at sql.NullString
+		)
+		if err := rows.Scan(
+			&resp.Count,
+			&attempt.Id,
+			&attempt.Login,
+			&attempt.UserRole,
+			&user_id,
+			&branch_id,
+			&attempt.IP,
+			&attempt.Success,
+			&created_at,
+		); err != nil {
+			return resp, err
+		}
+
+		attempt.UserID = user_id.String
+		attempt.BranchID = branch_id.String
+		attempt.CreatedAt = created_at.String
+
+		resp.LoginAttempts = append(resp.LoginAttempts, attempt)
+	}
+
+	return resp, rows.Err()
+}
+
+// GetThrottle returns the failure counter of the key, a key without failures gives an empty counter.
+func (l *loginAttemptRepo) GetThrottle(ctx context.Context, key string) (models.LoginThrottle, error) {
+	throttle := models.LoginThrottle{Key: key}
+
+	query := `SELECT failures,
+		COALESCE(CEIL(GREATEST(EXTRACT(EPOCH FROM locked_until - CURRENT_TIMESTAMP), 0)), 0)::int
+		FROM login_throttle WHERE key = $1`
+
+	err := l.db.QueryRow(ctx, query, key).Scan(&throttle.Failures, &throttle.RetryAfter)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return models.LoginThrottle{}, err
+	}
+
+	return throttle, nil
+}
+
+// RegisterFailure increments the failure counter of the key and returns it.
+// Failures older than window are forgotten.
+func (l *loginAttemptRepo) RegisterFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	var failures int
+
+	query := `INSERT INTO login_throttle (key, failures, updated_at)
+		VALUES($1, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (key) DO UPDATE SET
+		failures = CASE
+			WHEN login_throttle.updated_at < CURRENT_TIMESTAMP - $2 * interval '1 second' THEN 1
+			ELSE login_throttle.failures + 1
+		END,
+		updated_at = CURRENT_TIMESTAMP
+		RETURNING failures`
+
+	if err := l.db.QueryRow(ctx, query, key, window.Seconds()).Scan(&failures); err != nil {
+		return 0, err
+	}
+
+	return failures, nil
+}
+
+// Lock rejects the attempts of the key for the given duration.
+func (l *loginAttemptRepo) Lock(ctx context.Context, key string, duration time.Duration) error {
+	query := `UPDATE login_throttle SET locked_until = CURRENT_TIMESTAMP + $2 * interval '1 second' WHERE key = $1`
+
+	_, err := l.db.Exec(ctx, query, key, duration.Seconds())
+	return err
+}
+
+func (l *loginAttemptRepo) ResetThrottle(ctx context.Context, key string) error {
+	_, err := l.db.Exec(ctx, `DELETE FROM login_throttle WHERE key = $1`, key)
+	return err
+}
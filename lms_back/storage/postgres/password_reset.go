@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"lms_back/api/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type passwordResetRepo struct {
+	db DBTX
+}
+
+func NewPasswordReset(db DBTX) passwordResetRepo {
+	return passwordResetRepo{
+		db: db,
+	}
+}
+
+// Create stores a new reset code and invalidates the previous unused codes of the user.
+func (p *passwordResetRepo) Create(ctx context.Context, reset models.PasswordReset, ttl time.Duration) (models.PasswordReset, error) {
+	id := uuid.New()
+
+	_, err := p.db.Exec(ctx, `UPDATE password_reset SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND user_role = $2 AND used_at IS NULL`, reset.UserID, reset.UserRole)
+	if err != nil {
+		return models.PasswordReset{}, err
+	}
+
+	query := `INSERT INTO password_reset (
+		id,
+		user_id,
+		user_role,
+		code_hash,
+		expires_at,
+		created_at)
+		VALUES($1,$2,$3,$4,CURRENT_TIMESTAMP + $5 * interval '1 second',CURRENT_TIMESTAMP)
+	`
+
+	_, err = p.db.Exec(ctx, query,
+		id.String(),
+		reset.UserID,
+		reset.UserRole,
+		reset.CodeHash,
+		ttl.Seconds(),
+	)
+	if err != nil {
+		return models.PasswordReset{}, err
+	}
+
+	return models.PasswordReset{
+		Id:       id.String(),
+		UserID:   reset.UserID,
+		UserRole: reset.UserRole,
+		CodeHash: reset.CodeHash,
+	}, nil
+}
+
+// GetActive returns the latest unused and not expired reset code of the user.
+func (p *passwordResetRepo) GetActive(ctx context.Context, userID, userRole string) (models.PasswordReset, error) {
+	var (
+		reset      = models.PasswordReset{}
+		expires_at sql.NullString
+		created_at sql.NullString
+	)
+
+	query := `SELECT id, user_id, user_role, code_hash, attempts, expires_at, created_at
+		FROM password_reset
+		WHERE user_id = $1 AND user_role = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	if err := p.db.QueryRow(ctx, query, userID, userRole).Scan(
+		&reset.Id,
+		&reset.UserID,
+		&reset.UserRole,
+		&reset.CodeHash,
+		&reset.Attempts,
+		&expires_at,
+		&created_at,
+	); err != nil {
+		return models.PasswordReset{}, err
+	}
+
+	reset.ExpiresAt = expires_at.String
+	reset.CreatedAt = created_at.String
+
+	return reset, nil
+}
+
+func (p *passwordResetRepo) IncrementAttempts(ctx context.Context, id string) error {
+	_, err := p.db.Exec(ctx, `UPDATE password_reset SET attempts = attempts + 1 WHERE id = $1`, id)
+	return err
+}
+
+// MarkUsed consumes the code, it reports false if the code was already used.
+func (p *passwordResetRepo) MarkUsed(ctx context.Context, id string) (bool, error) {
+	tag, err := p.db.Exec(ctx, `UPDATE password_reset SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
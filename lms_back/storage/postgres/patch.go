@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"lms_back/api/models"
+	"lms_back/storage"
+	"strings"
+)
+
+// patchRow writes the fields of the patch to the row with the version. It sets
+// only the columns of the patched fields, an empty patch still increments the
+// version of the row.
+func patchRow(ctx context.Context, db DBTX, table, id string, version int, fields storage.PatchFields, patch models.Patch) error {
+	values, err := fields.Decode(patch)
+	if err != nil {
+		return err
+	}
+
+	set := make([]string, 0, len(values)+2)
+	args := make([]interface{}, 0, len(values)+2)
+	for _, value := range values {
+		args = append(args, value.Value)
+		set = append(set, fmt.Sprintf("%s = $%d", value.Column, len(args)))
+	}
+	set = append(set, "version = version + 1", "updated_at = CURRENT_TIMESTAMP")
+	args = append(args, id, version)
+
+	query := fmt.Sprintf(`UPDATE %s SET %s WHERE id = $%d AND deleted_at = 0 AND version = $%d`,
+		table, strings.Join(set, ", "), len(args)-1, len(args))
+	tag, err := db.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return checkVersion(ctx, db, table, id, tag)
+}
@@ -3,20 +3,27 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"lms_back/api/models"
+	"lms_back/storage"
+	"lms_back/storage/postgres/filter"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// paymentSortColumns are the fields the list can be sorted by.
+var paymentSortColumns = map[string]string{
+	"price":      "price",
+	"created_at": `COALESCE(created_at, '-infinity')`,
+}
+
 type paymentRepo struct {
-	db     *pgxpool.Pool
+	db DBTX
 }
 
-func NewPayment(db *pgxpool.Pool) paymentRepo {
+func NewPayment(db DBTX) paymentRepo {
 	return paymentRepo{
-		db:     db,
+		db: db,
 	}
 }
 
@@ -37,27 +44,39 @@ func (p *paymentRepo) Create(ctx context.Context, payment models.CreatePayment)
 		Student_id: payment.Student_id,
 		Branch_id:  payment.Branch_id,
 		Admin_id:   payment.Admin_id,
+		Version:    1,
 	}, nil
 }
 
 func (p *paymentRepo) GetAll(ctx context.Context, req models.GetAllPaymentsRequest) (models.GetAllPaymentsResponse, error) {
 	var (
-		resp   = models.GetAllPaymentsResponse{}
-		filter = ""
+		resp    = models.GetAllPaymentsResponse{}
+		cursors []string
 	)
-	offset := (req.Page - 1) * req.Limit
-
+	where := filter.New().
+		Deleted(req.Deleted).
+		Equal("student_id", req.StudentID).
+		Equal("branch_id", req.BranchID).
+		Equal("admin_id", req.AdminID).
+		Range("price", req.MinPrice, req.MaxPrice).
+		Range("created_at::date", req.CreatedFrom, req.CreatedTo).
+		Any("branch_id", req.BranchIDs, "uuid")
 	if req.Search != "" {
-		filter += fmt.Sprintf(` and name ILIKE  '%%%v%%' `, req.Search)
+		where.Where(`student_id IN (SELECT id FROM student WHERE full_name ILIKE ? OR login ILIKE ?)`, filter.Contains(req.Search), filter.Contains(req.Search))
 	}
 
-	filter += fmt.Sprintf(" OFFSET %v LIMIT %v", offset, req.Limit)
-	fmt.Println("filter: ", filter)
+	list, err := filter.NewList(req.ListParams, req.Page, req.Limit, paymentSortColumns, "-created_at")
+	if err != nil {
+		return resp, err
+	}
+	list.Apply(where)
 
-	rows, err := p.db.Query(context.Background(), `SELECT id, price, student_id, branch_id, admin_id, created_at, updated_at FROM payment`)
+	rows, err := p.db.Query(context.Background(), `SELECT `+list.CountColumn()+`, id, price, student_id, branch_id, admin_id, version, created_at, updated_at, `+list.CursorColumn()+` FROM payment`+where.SQL()+list.SQL(), where.Args()...)
 	if err != nil {
 		return resp, err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		var (
 			payment    = models.Payment{}
@@ -65,17 +84,22 @@ func (p *paymentRepo) GetAll(ctx context.Context, req models.GetAllPaymentsReque
 			student_id sql.NullString
 			branch_id  sql.NullString
 			admin_id   sql.NullString
+			version    int
 			created_at sql.NullString
 			updated_at sql.NullString
+			cursor     string
 		)
 		if err := rows.Scan(
+			&resp.Count,
 			&payment.Id,
 			&price,
 			&student_id,
 			&branch_id,
 			&admin_id,
+			&version,
 			&created_at,
 			&updated_at,
+			&cursor,
 		); err != nil {
 			return resp, err
 		}
@@ -85,31 +109,53 @@ func (p *paymentRepo) GetAll(ctx context.Context, req models.GetAllPaymentsReque
 			Student_id: student_id.String,
 			Branch_id:  branch_id.String,
 			Admin_id:   admin_id.String,
+			Version:    version,
 			CreatedAt:  created_at.String,
 			UpdatedAt:  updated_at.String,
 		})
+		cursors = append(cursors, cursor)
 	}
+
+	if err := rows.Err(); err != nil {
+		return resp, err
+	}
+
+	keep, next := list.Next(cursors)
+	resp.Payments, resp.NextCursor = resp.Payments[:keep], next
+
 	return resp, nil
 }
 
 func (p *paymentRepo) GetByID(ctx context.Context, id string) (models.Payment, error) {
+	return p.getByID(ctx, `SELECT id, price, student_id, branch_id, admin_id, version, created_at, updated_at FROM payment WHERE id = $1 AND deleted_at = 0`, id)
+}
+
+// GetByIDForUpdate locks the payment until the end of the transaction, so its
+// price and student can't change while the student balance is corrected.
+func (p *paymentRepo) GetByIDForUpdate(ctx context.Context, id string) (models.Payment, error) {
+	return p.getByID(ctx, `SELECT id, price, student_id, branch_id, admin_id, version, created_at, updated_at FROM payment WHERE id = $1 AND deleted_at = 0 FOR UPDATE`, id)
+}
+
+func (p *paymentRepo) getByID(ctx context.Context, query, id string) (models.Payment, error) {
 	var (
 		payment    = models.Payment{}
 		price      sql.NullFloat64
 		student_id sql.NullString
 		branch_id  sql.NullString
 		admin_id   sql.NullString
+		version    int
 		created_at sql.NullString
 		updated_at sql.NullString
 	)
 
-	row := p.db.QueryRow(context.Background(), `SELECT id, price, student_id, branch_id, admin_id, created_at, updated_at FROM payment WHERE id = $1`, id)
+	row := p.db.QueryRow(ctx, query, id)
 	if err := row.Scan(
 		&payment.Id,
 		&price,
 		&student_id,
 		&branch_id,
 		&admin_id,
+		&version,
 		&created_at,
 		&updated_at,
 	); err != nil {
@@ -121,33 +167,49 @@ func (p *paymentRepo) GetByID(ctx context.Context, id string) (models.Payment, e
 		Student_id: student_id.String,
 		Branch_id:  branch_id.String,
 		Admin_id:   admin_id.String,
+		Version:    version,
 		CreatedAt:  created_at.String,
 		UpdatedAt:  updated_at.String,
 	}, nil
 }
 
 func (p *paymentRepo) Update(ctx context.Context, payment models.Payment) (models.Payment, error) {
-	query := `UPDATE payment SET price=$1, student_id=$2, branch_id=$3, admin_id=$4, updated_at=CURRENT_TIMESTAMP WHERE id=$5`
+	query := `UPDATE payment SET price=$1, student_id=$2, branch_id=$3, admin_id=$4, version = version + 1, updated_at=CURRENT_TIMESTAMP WHERE id=$5 AND deleted_at = 0 AND version = $6`
 
-	_, err := p.db.Exec(context.Background(), query, payment.Price, payment.Student_id, payment.Branch_id, payment.Admin_id, payment.Id)
+	tag, err := p.db.Exec(context.Background(), query, payment.Price, payment.Student_id, payment.Branch_id, payment.Admin_id, payment.Id, payment.Version)
 	if err != nil {
 		return models.Payment{}, err
 	}
+	if err = checkVersion(ctx, p.db, "payment", payment.Id, tag); err != nil {
+		return models.Payment{}, err
+	}
 	return models.Payment{
 		Id:         payment.Id,
 		Price:      payment.Price,
 		Student_id: payment.Student_id,
 		Branch_id:  payment.Branch_id,
 		Admin_id:   payment.Admin_id,
+		Version:    payment.Version + 1,
 		CreatedAt:  payment.CreatedAt,
 		UpdatedAt:  payment.UpdatedAt,
 	}, nil
 }
 
-func (p *paymentRepo) Delete(ctx context.Context, id string) error {
-	_, err := p.db.Exec(context.Background(), `DELETE FROM payment WHERE id = $1`, id)
-	if err != nil {
-		return err
+func (p *paymentRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Payment, error) {
+	if err := patchRow(ctx, p.db, "payment", id, version, storage.PaymentPatch, patch); err != nil {
+		return models.Payment{}, err
 	}
-	return nil
+	return p.GetByID(ctx, id)
+}
+
+func (p *paymentRepo) Delete(ctx context.Context, id string, version int) error {
+	return softDelete(ctx, p.db, "payment", id, version)
+}
+
+func (p *paymentRepo) Restore(ctx context.Context, id string) error {
+	return restore(ctx, p.db, "payment", id)
+}
+
+func (p *paymentRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return purge(ctx, p.db, "payment", "", deletedBefore)
 }
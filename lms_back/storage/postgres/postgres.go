@@ -8,15 +8,38 @@ import (
 
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/lib/pq"
 )
 
 type Store struct {
 	Pool   *pgxpool.Pool
+	// db is the pool, or the transaction inside WithTx
+	db   DBTX
+	inTx bool
 }
 
 func New(ctx context.Context, cfg config.Config) (storage.IStorage, error) {
+	newPool, err := Connect(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStore(newPool), nil
+}
+
+// NewStore returns the storage of the pool. Its repositories return the
+// errors of the database as domain errors.
+func NewStore(pool *pgxpool.Pool) Store {
+	return Store{
+		Pool: pool,
+		db:   errorDB{pool},
+	}
+}
+
+// Connect opens the connection pool of the database in cfg.
+func Connect(ctx context.Context, cfg config.Config) (*pgxpool.Pool, error) {
 	url := fmt.Sprintf(`host=%s port=%v user=%s password=%s database=%s sslmode=disable`,
 		cfg.PostgresHost, cfg.PostgresPort, cfg.PostgresUser, cfg.PostgresPassword, cfg.PostgresDatabase)
 
@@ -28,76 +51,145 @@ func New(ctx context.Context, cfg config.Config) (storage.IStorage, error) {
 	pgPoolConfig.MaxConns = 100
 	pgPoolConfig.MaxConnLifetime = time.Hour
 
-	newPool, err := pgxpool.NewWithConfig(context.Background(), pgPoolConfig)
+	newPool, err := pgxpool.NewWithConfig(ctx, pgPoolConfig)
 	if err != nil {
 		fmt.Println("error while connecting to db", err.Error())
 		return nil, err
 	}
 
-	return Store{
-		Pool:   newPool,
-	}, nil
+	return newPool, nil
 }
 
 func (s Store) CloseDB() {
 	s.Pool.Close()
 }
 
+func (s Store) WithTx(ctx context.Context, fn func(storage.IStorage) error) error {
+	// nested calls join the outer transaction
+	if s.inTx {
+		return fn(s)
+	}
+
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		return fn(Store{Pool: s.Pool, db: errorDB{tx}, inTx: true})
+	})
+}
+
 func (s Store) AdminReport() storage.IAdminReportStorage {
-	AdminReport := AdminPayment(s.Pool)
+	AdminReport := AdminPayment(s.db)
 	return &AdminReport
 }
 
 func (s Store) Admin() storage.IAdminStorage {
-	NewAdmin := NewAdmin(s.Pool)
+	NewAdmin := NewAdmin(s.db)
 
 	return &NewAdmin
 }
 
 func (s Store) Branch() storage.IBranchStorage {
-	NewBranch := NewBranch(s.Pool)
+	NewBranch := NewBranch(s.db)
 
 	return &NewBranch
 }
 
 func (s Store) Group() storage.IGroupStorage {
-	NewGroup := NewGroup(s.Pool)
+	NewGroup := NewGroup(s.db)
 
 	return &NewGroup
 }
 
 func (s Store) Lesson() storage.ILessonStorage {
-	NewLesson := NewLesson(s.Pool)
+	NewLesson := NewLesson(s.db)
 
 	return &NewLesson
 }
 
+func (s Store) Enrollment() storage.IEnrollmentStorage {
+	NewEnrollment := NewEnrollment(s.db)
+
+	return &NewEnrollment
+}
+
+func (s Store) Attendance() storage.IAttendanceStorage {
+	NewAttendance := NewAttendance(s.db)
+
+	return &NewAttendance
+}
+
+func (s Store) Submission() storage.ISubmissionStorage {
+	NewSubmission := NewSubmission(s.db)
+
+	return &NewSubmission
+}
+
+func (s Store) Gradebook() storage.IGradebookStorage {
+	NewGradebook := NewGradebook(s.db)
+
+	return &NewGradebook
+}
+
 func (s Store) Payment() storage.IPaymentStorage {
-	NewPayment := NewPayment(s.Pool)
+	NewPayment := NewPayment(s.db)
 
 	return &NewPayment
 }
 
 func (s Store) Schedule() storage.IScheduleStorage {
-	NewSchedule := NewSchedule(s.Pool)
+	NewSchedule := NewSchedule(s.db)
 
 	return &NewSchedule
 }
 
 func (s Store) Student() storage.IStudentStorage {
-	NewStudent := NewStudent(s.Pool)
+	NewStudent := NewStudent(s.db)
 
 	return &NewStudent
 }
 
 func (s Store) Task() storage.ITaskStorage {
-	NewTask := NewTask(s.Pool)
+	NewTask := NewTask(s.db)
 
 	return &NewTask
 }
 
 func (s Store) Teacher() storage.ITeacherStorage {
-	NewTeacher := NewTeacher(s.Pool)
+	NewTeacher := NewTeacher(s.db)
 
 	return &NewTeacher
 }
+
+func (s Store) Token() storage.ITokenStorage {
+	NewToken := NewToken(s.db)
+
+	return &NewToken
+}
+
+func (s Store) PasswordReset() storage.IPasswordResetStorage {
+	NewPasswordReset := NewPasswordReset(s.db)
+
+	return &NewPasswordReset
+}
+
+func (s Store) LoginAttempt() storage.ILoginAttemptStorage {
+	NewLoginAttempt := NewLoginAttempt(s.db)
+
+	return &NewLoginAttempt
+}
+
+func (s Store) TwoFactor() storage.ITwoFactorStorage {
+	NewTwoFactor := NewTwoFactor(s.db)
+
+	return &NewTwoFactor
+}
+
+func (s Store) Audit() storage.IAuditStorage {
+	NewAudit := NewAudit(s.db)
+
+	return &NewAudit
+}
+
+func (s Store) Idempotency() storage.IIdempotencyStorage {
+	NewIdempotency := NewIdempotency(s.db)
+
+	return &NewIdempotency
+}
@@ -5,15 +5,13 @@ import (
 	"time"
 
 	"lms_back/api/models"
-
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type adminReportRepo struct {
-	db *pgxpool.Pool
+	db DBTX
 }
 
-func AdminPayment(db *pgxpool.Pool) adminReportRepo {
+func AdminPayment(db DBTX) adminReportRepo {
 	return adminReportRepo{
 		db: db,
 	}
@@ -43,7 +41,7 @@ func (c *adminReportRepo) GetByIDAdminPayment(ctx context.Context, req models.Ad
 	defer rows.Close()
 
 	for rows.Next() {
-		var admin models.Admin
+		var admin models.GetAdmin
 		var payment models.Payment
 		var CreatedAt time.Time
 		var Updated_At time.Time
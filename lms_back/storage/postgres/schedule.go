@@ -3,19 +3,29 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"lms_back/api/models"
 	"lms_back/pkg"
+	"lms_back/storage"
+	"lms_back/storage/postgres/filter"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// scheduleSearchColumns are matched by the search query param.
+var scheduleSearchColumns = []string{"group_type"}
+
+// scheduleSortColumns are the fields the list can be sorted by.
+var scheduleSortColumns = map[string]string{
+	"date":       `COALESCE(date, '')`,
+	"created_at": `COALESCE(created_at, '-infinity')`,
+}
+
 type ScheduleRepo struct {
-	db *pgxpool.Pool
+	db DBTX
 }
 
-func NewSchedule(db *pgxpool.Pool) ScheduleRepo {
+func NewSchedule(db DBTX) ScheduleRepo {
 	return ScheduleRepo{
 		db: db,
 	}
@@ -34,7 +44,7 @@ func (c *ScheduleRepo) Create(ctx context.Context, schedule models.Schedule) (mo
 		branch_id,
 		teacher_id,
 		created_at)
-		VALUES($1,$2,$3,$4,$5,$6,$7,CURRENT_TIMESTAMP) 
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,CURRENT_TIMESTAMP) 
 	`
 
 	_, err := c.db.Exec(context.Background(), query,
@@ -46,14 +56,13 @@ func (c *ScheduleRepo) Create(ctx context.Context, schedule models.Schedule) (mo
 		schedule.Date,
 		schedule.Branch_id,
 		schedule.Teacher_id,
-		schedule.Created_at,
 	)
 
 	if err != nil {
 		return models.Schedule{}, err
 	}
 	return models.Schedule{
-		Id:         schedule.Id,
+		Id:         id.String(),
 		Group_id:   schedule.Group_id,
 		Group_type: schedule.Group_type,
 		Start_time: schedule.Start_time,
@@ -61,6 +70,7 @@ func (c *ScheduleRepo) Create(ctx context.Context, schedule models.Schedule) (mo
 		Date:       schedule.Date,
 		Branch_id:  schedule.Branch_id,
 		Teacher_id: schedule.Teacher_id,
+		Version:    1,
 		Created_at: schedule.Created_at,
 		Updated_at: schedule.Updated_at,
 	}, nil
@@ -75,10 +85,11 @@ func (c *ScheduleRepo) Update(ctx context.Context, schedule models.Schedule) (mo
 	date=$5,
 	branch_id=$6,
 	teacher_id=$7,
+	version = version + 1,
 	updated_at = CURRENT_TIMESTAMP
-	WHERE id = $8
+	WHERE id = $8 AND deleted_at = 0 AND version = $9
 	`
-	_, err := c.db.Exec(context.Background(), query,
+	tag, err := c.db.Exec(context.Background(), query,
 		schedule.Group_id,
 		schedule.Group_type,
 		schedule.Start_time,
@@ -86,12 +97,15 @@ func (c *ScheduleRepo) Update(ctx context.Context, schedule models.Schedule) (mo
 		schedule.Date,
 		schedule.Branch_id,
 		schedule.Teacher_id,
-		schedule.Created_at,
 		schedule.Id,
+		schedule.Version,
 	)
 	if err != nil {
 		return models.Schedule{}, err
 	}
+	if err = checkVersion(ctx, c.db, "schedule", schedule.Id, tag); err != nil {
+		return models.Schedule{}, err
+	}
 	return models.Schedule{
 		Id:         schedule.Id,
 		Group_id:   schedule.Group_id,
@@ -101,26 +115,40 @@ func (c *ScheduleRepo) Update(ctx context.Context, schedule models.Schedule) (mo
 		Date:       schedule.Date,
 		Branch_id:  schedule.Branch_id,
 		Teacher_id: schedule.Teacher_id,
+		Version:    schedule.Version + 1,
 		Created_at: schedule.Created_at,
 		Updated_at: schedule.Updated_at,
 	}, nil
 }
 
+func (c *ScheduleRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Schedule, error) {
+	if err := patchRow(ctx, c.db, "schedule", id, version, storage.SchedulePatch, patch); err != nil {
+		return models.Schedule{}, err
+	}
+	return c.GetByID(ctx, id)
+}
+
 func (c *ScheduleRepo) GetAll(ctx context.Context, req models.GetAllSchedulesRequest) (models.GetAllSchedulesResponse, error) {
 	var (
-		resp   = models.GetAllSchedulesResponse{}
-		filter = ""
+		resp    = models.GetAllSchedulesResponse{}
+		cursors []string
 	)
-	offset := (req.Page - 1) * req.Limit
+	where := filter.New().
+		Deleted(req.Deleted).
+		Search(req.Search, scheduleSearchColumns...).
+		Equal("group_id", req.GroupID).
+		Equal("branch_id", req.BranchID).
+		Equal("teacher_id", req.TeacherID).
+		Equal("date", req.Date).
+		Any("branch_id", req.BranchIDs, "uuid")
 
-	if req.Search != "" {
-		filter += fmt.Sprintf(` and name ILIKE  '%%%v%%' `, req.Search)
+	list, err := filter.NewList(req.ListParams, req.Page, req.Limit, scheduleSortColumns, "-created_at")
+	if err != nil {
+		return resp, err
 	}
+	list.Apply(where)
 
-	filter += fmt.Sprintf(" OFFSET %v LIMIT %v", offset, req.Limit)
-	fmt.Println("filter: ", filter)
-
-	rows, err := c.db.Query(context.Background(), `select count(id) over(),
+	rows, err := c.db.Query(context.Background(), `select `+list.CountColumn()+`,
         id,
 		group_id,
 		group_type,
@@ -129,13 +157,17 @@ func (c *ScheduleRepo) GetAll(ctx context.Context, req models.GetAllSchedulesReq
 		date,
 		branch_id,
 		teacher_id,
+		version,
 		created_at,
-        updated_at FROM schedule`+filter+``)
+        updated_at,
+        `+list.CursorColumn()+` FROM schedule`+where.SQL()+list.SQL(), where.Args()...)
 
 	if err != nil {
 		return resp, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 		var (
 			schedule   = models.Schedule{}
@@ -146,8 +178,10 @@ func (c *ScheduleRepo) GetAll(ctx context.Context, req models.GetAllSchedulesReq
 			date       sql.NullString
 			branch_id  sql.NullString
 			teacher_id sql.NullString
+			version    int
 			created_at sql.NullString
 			updateAt   sql.NullString
+			cursor     string
 		)
 		if err := rows.Scan(
 			&resp.Count,
@@ -159,23 +193,36 @@ func (c *ScheduleRepo) GetAll(ctx context.Context, req models.GetAllSchedulesReq
 			&date,
 			&branch_id,
 			&teacher_id,
+			&version,
 			&created_at,
-			&updateAt); err != nil {
+			&updateAt,
+			&cursor); err != nil {
 			return resp, err
 		}
 		schedule.Updated_at = pkg.NullStringToString(updateAt)
 		resp.Schedules = append(resp.Schedules, models.Schedule{
+			Id:         schedule.Id,
 			Group_id:   group_id.String,
-			Group_type: group_id.String,
+			Group_type: group_type.String,
 			Start_time: start_time.String,
 			End_time:   end_time.String,
 			Date:       date.String,
 			Branch_id:  branch_id.String,
 			Teacher_id: teacher_id.String,
+			Version:    version,
 			Created_at: created_at.String,
 			Updated_at: updateAt.String,
 		})
+		cursors = append(cursors, cursor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return resp, err
 	}
+
+	keep, next := list.Next(cursors)
+	resp.Schedules, resp.NextCursor = resp.Schedules[:keep], next
+
 	return resp, nil
 }
 
@@ -189,12 +236,13 @@ func (c *ScheduleRepo) GetByID(ctx context.Context, id string) (models.Schedule,
 		date       sql.NullString
 		branch_id  sql.NullString
 		teacher_id sql.NullString
+		version    int
 		created_at sql.NullString
 		updateAt   sql.NullString
 	)
 	if err := c.db.QueryRow(context.Background(), `select id, group_id, group_type, start_time,
-			end_time, date, branch_id, teacher_id, created_at,
-			updated_at from schedule where id = $1`, id).Scan(
+			end_time, date, branch_id, teacher_id, version, created_at,
+			updated_at from schedule where id = $1 AND deleted_at = 0`, id).Scan(
 		&schedule.Id,
 		&group_id,
 		&group_type,
@@ -203,29 +251,35 @@ func (c *ScheduleRepo) GetByID(ctx context.Context, id string) (models.Schedule,
 		&date,
 		&branch_id,
 		&teacher_id,
+		&version,
 		&created_at,
 		&updateAt,
 	); err != nil {
 		return models.Schedule{}, err
 	}
 	return models.Schedule{
+		Id:         schedule.Id,
 		Group_id:   group_id.String,
-		Group_type: group_id.String,
+		Group_type: group_type.String,
 		Start_time: start_time.String,
 		End_time:   end_time.String,
 		Date:       date.String,
 		Branch_id:  branch_id.String,
 		Teacher_id: teacher_id.String,
+		Version:    version,
 		Created_at: created_at.String,
 		Updated_at: updateAt.String,
 	}, nil
 }
 
-func (c *ScheduleRepo) Delete(ctx context.Context, id string) error {
-	query := `delete from schedule where id = $1`
-	_, err := c.db.Exec(context.Background(), query, id)
-	if err != nil {
-		return err
-	}
-	return nil
+func (c *ScheduleRepo) Delete(ctx context.Context, id string, version int) error {
+	return softDelete(ctx, c.db, "schedule", id, version)
+}
+
+func (c *ScheduleRepo) Restore(ctx context.Context, id string) error {
+	return restore(ctx, c.db, "schedule", id)
+}
+
+func (c *ScheduleRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return purge(ctx, c.db, "schedule", `EXISTS (SELECT 1 FROM lesson WHERE lesson.schedule_id = schedule.id)`, deletedBefore)
 }
@@ -4,26 +4,42 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"lms_back/api/models"
+	"lms_back/storage"
 	"lms_back/pkg"
+	"lms_back/storage/postgres/filter"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// studentSearchColumns are matched by the search query param.
+var studentSearchColumns = []string{"full_name", "email", "login"}
+
+// studentSortColumns are the fields the list can be sorted by.
+var studentSortColumns = map[string]string{
+	"full_name":  "full_name",
+	"email":      "email",
+	"login":      "login",
+	"age":        "age",
+	"paid_sum":   "paid_sum",
+	"status":     "status",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
 type StudentRepo struct {
-	db *pgxpool.Pool
+	db DBTX
 }
 
-func NewStudent(db *pgxpool.Pool) StudentRepo {
+func NewStudent(db DBTX) StudentRepo {
 	return StudentRepo{
 		db: db,
 	}
 }
 
-func (c *StudentRepo) Login(ctx context.Context, login models.StudentLoginRequest) (string, error) {
+func (c *StudentRepo) Login(ctx context.Context, login models.LoginRequest) (string, error) {
 	var hashedPass string
 
 	query := `SELECT password
@@ -53,42 +69,48 @@ func (s *StudentRepo) GetByLogin(ctx context.Context, login string) (models.Stud
 	student := models.Student{}
 	var (
 		full_name sql.NullString
-		paid_sum  sql.NullFloat64
-		age       sql.NullInt16
 		email     sql.NullString
+		age       sql.NullInt16
+		paid_sum  sql.NullFloat64
 		status    sql.NullString
-		group_id  sql.NullString
 		loginn    sql.NullString
+		password  sql.NullString
+		group_id  sql.NullString
+		version   int
 		createdat sql.NullString
 		updatedat sql.NullString
 	)
 
 	query := `SELECT 
 		id, 
-		full_name 
+		full_name,
 		email,
 		age,
-		status,
 		paid_sum,
+		status,
 		login,
 		password,
+		group_id,
+		version,
 		created_at, 
 		updated_at
-		FROM customers WHERE login = $1 AND deleted_at = 0`
+		FROM student WHERE login = $1 AND deleted_at = 0`
 
 	row := s.db.QueryRow(ctx, query, login)
 
 	err := row.Scan(
 		&student.ID,
-		&paid_sum,
-		&age,
+		&full_name,
 		&email,
+		&age,
+		&paid_sum,
 		&status,
-		&group_id,
 		&loginn,
+		&password,
+		&group_id,
+		&version,
 		&createdat,
 		&updatedat,
-		&student.Password,
 	)
 
 	if err != nil {
@@ -101,12 +123,16 @@ func (s *StudentRepo) GetByLogin(ctx context.Context, login string) (models.Stud
 	student.PaidSum = paid_sum.Float64
 	student.Status = status.String
 	student.Login = loginn.String
+	student.Password = password.String
 	student.GroupID = group_id.String
+	student.Version = version
+	student.Created_At = createdat.String
+	student.Updated_At = updatedat.String
 
 	return student, nil
 }
 
-func (c *StudentRepo) Create(ctx context.Context, student models.Student) (models.Student, error) {
+func (c *StudentRepo) Create(ctx context.Context, student models.Student) (models.GetStudent, error) {
 
 	id := uuid.New()
 	query := `INSERT INTO student (
@@ -136,80 +162,155 @@ func (c *StudentRepo) Create(ctx context.Context, student models.Student) (model
 	)
 
 	if err != nil {
-		return models.Student{}, err
+		return models.GetStudent{}, err
 	}
 
-	return models.Student{
+	return models.GetStudent{
 		ID:         id.String(),
 		Full_Name:  student.Full_Name,
 		Email:      student.Email,
 		Age:        student.Age,
 		PaidSum:    student.PaidSum,
+		Status:     student.Status,
 		Login:      student.Login,
-		Password:   student.Password,
 		GroupID:    student.GroupID,
+		Version:    1,
 		Created_At: student.Created_At,
 		Updated_At: student.Updated_At,
 	}, nil
 }
 
-func (c *StudentRepo) Update(ctx context.Context, student models.Student) (models.Student, error) {
+func (c *StudentRepo) Update(ctx context.Context, student models.Student) (models.GetStudent, error) {
 	query := `UPDATE "student" set 
 		full_name=$1,
 		email=$2,
 		age=$3,
-		paid_sum=$4,
-		login=$5,
-		password=$6,
-		group_id=$7,
-		status=$8,
+		login=$4,
+		group_id=$5,
+		status=$6,
+		version = version + 1,
 		updated_at = CURRENT_TIMESTAMP
-		WHERE id =$9
+		WHERE id =$7 AND deleted_at = 0 AND version = $8
 	`
-	_, err := c.db.Exec(context.Background(), query,
+	tag, err := c.db.Exec(context.Background(), query,
 		student.Full_Name,
 		student.Email,
 		student.Age,
-		student.PaidSum,
 		student.Login,
-		student.Password,
 		student.GroupID,
 		student.Status,
 		student.ID,
+		student.Version,
 	)
 	if err != nil {
-		return models.Student{}, err
+		return models.GetStudent{}, err
+	}
+	if err = checkVersion(ctx, c.db, "student", student.ID, tag); err != nil {
+		return models.GetStudent{}, err
 	}
-	fmt.Println(student.ID, "________423553453534524#%$^$%^#^@$%4324")
-	return models.Student{
+	return models.GetStudent{
 		ID:         student.ID,
 		Full_Name:  student.Full_Name,
 		Email:      student.Email,
 		Age:        student.Age,
 		PaidSum:    student.PaidSum,
+		Status:     student.Status,
 		Login:      student.Login,
-		Password:   student.Password,
+		GroupID:    student.GroupID,
+		Version:    student.Version + 1,
 		Created_At: student.Created_At,
 		Updated_At: student.Updated_At,
 		Deleted_At: student.Deleted_At,
 	}, nil
 }
 
+func (c *StudentRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.GetStudent, error) {
+	if err := patchRow(ctx, c.db, "student", id, version, storage.StudentPatch, patch); err != nil {
+		return models.GetStudent{}, err
+	}
+	return c.GetByID(ctx, id)
+}
+
+func (c *StudentRepo) UpdatePassword(ctx context.Context, id, password string) error {
+	query := `UPDATE "student" set 
+		password=$1,
+		version = version + 1,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE id =$2 AND deleted_at = 0
+	`
+	_, err := c.db.Exec(ctx, query, password, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// AddPaidSum adds amount, which may be negative, to the paid sum of the student
+// in the database, so concurrent payments don't overwrite each other.
+func (c *StudentRepo) AddPaidSum(ctx context.Context, id string, amount float64) error {
+	query := `UPDATE "student" set 
+		paid_sum = paid_sum + $1,
+		version = version + 1,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`
+	tag, err := c.db.Exec(ctx, query, amount, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// SetGroup sets the primary group of the student, enrollments move students
+// between groups without a version.
+func (c *StudentRepo) SetGroup(ctx context.Context, id, groupID string) error {
+	query := `UPDATE "student" set 
+		group_id = $1,
+		version = version + 1,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND deleted_at = 0
+	`
+	tag, err := c.db.Exec(ctx, query, groupID, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
 func (c *StudentRepo) GetAll(ctx context.Context, req models.GetAllStudentsRequest) (models.GetAllStudentsResponse, error) {
 	var (
-		resp   = models.GetAllStudentsResponse{}
-		filter = ""
+		resp    = models.GetAllStudentsResponse{}
+		cursors []string
 	)
-	offset := (req.Page - 1) * req.Limit
-
-	if req.Search != "" {
-		filter += fmt.Sprintf(` and name ILIKE  '%%%v%%' `, req.Search)
+	where := filter.New().
+		Deleted(req.Deleted).
+		Search(req.Search, studentSearchColumns...).
+		Equal("status", req.Status).
+		Equal("group_id", req.GroupID).
+		Range("created_at::date", req.CreatedFrom, req.CreatedTo)
+	if req.EnrolledGroupID != "" {
+		where.Where(`id IN (SELECT student_id FROM enrollment WHERE group_id = ? AND status = 'active')`, req.EnrolledGroupID)
+	}
+	if req.BranchID != "" {
+		where.Where(`group_id IN (SELECT id FROM "group" WHERE branch_id = ?)`, req.BranchID)
+	}
+	if req.BranchIDs != nil {
+		where.Where(`group_id IN (SELECT id FROM "group" WHERE branch_id = ANY(?::uuid[]))`, req.BranchIDs)
 	}
 
-	filter += fmt.Sprintf(" OFFSET %v LIMIT %v", offset, req.Limit)
-	fmt.Println("filter: ", filter)
+	list, err := filter.NewList(req.ListParams, req.Page, req.Limit, studentSortColumns, "-created_at")
+	if err != nil {
+		return resp, err
+	}
+	list.Apply(where)
 
-	rows, err := c.db.Query(context.Background(), `select count(id) over(),
+	rows, err := c.db.Query(context.Background(), `select `+list.CountColumn()+`,
         id,
         full_name,
 		email,
@@ -217,27 +318,31 @@ func (c *StudentRepo) GetAll(ctx context.Context, req models.GetAllStudentsReque
 		paid_sum,
 		status,
         login,
-		password,
 		group_id,
+		version,
         created_at,
-        updated_at FROM student`+filter+``)
+        updated_at,
+        `+list.CursorColumn()+` FROM student`+where.SQL()+list.SQL(), where.Args()...)
 	if err != nil {
 		return resp, err
 	}
 
+	defer rows.Close()
+
 	for rows.Next() {
 		var (
-			student    = models.Student{}
+			student    = models.GetStudent{}
 			full_name  sql.NullString
 			email      sql.NullString
 			age        sql.NullInt64
 			paid_sum   sql.NullFloat64
 			status     sql.NullString
 			login      sql.NullString
-			password   sql.NullString
 			group_id   sql.NullString
+			version    int
 			created_at sql.NullString
 			updated_at sql.NullString
+			cursor     string
 		)
 		if err := rows.Scan(
 			&resp.Count,
@@ -248,14 +353,15 @@ func (c *StudentRepo) GetAll(ctx context.Context, req models.GetAllStudentsReque
 			&paid_sum,
 			&status,
 			&login,
-			&password,
 			&group_id,
+			&version,
 			&created_at,
-			&updated_at); err != nil {
+			&updated_at,
+			&cursor); err != nil {
 			return resp, err
 		}
 		student.Updated_At = pkg.NullStringToString(updated_at)
-		resp.Students = append(resp.Students, models.Student{
+		resp.Students = append(resp.Students, models.GetStudent{
 			ID:         student.ID,
 			Full_Name:  full_name.String,
 			Email:      email.String,
@@ -263,30 +369,39 @@ func (c *StudentRepo) GetAll(ctx context.Context, req models.GetAllStudentsReque
 			PaidSum:    paid_sum.Float64,
 			Status:     status.String,
 			Login:      login.String,
-			Password:   password.String,
 			GroupID:    group_id.String,
+			Version:    version,
 			Created_At: created_at.String,
 			Updated_At: updated_at.String,
 		})
+		cursors = append(cursors, cursor)
 	}
+
+	if err := rows.Err(); err != nil {
+		return resp, err
+	}
+
+	keep, next := list.Next(cursors)
+	resp.Students, resp.NextCursor = resp.Students[:keep], next
+
 	return resp, nil
 }
 
-func (c *StudentRepo) GetByID(ctx context.Context, id string) (models.Student, error) {
+func (c *StudentRepo) GetByID(ctx context.Context, id string) (models.GetStudent, error) {
 	var (
-		student    = models.Student{}
+		student    = models.GetStudent{}
 		full_name  sql.NullString
 		email      sql.NullString
 		age        sql.NullInt64
 		paid_sum   sql.NullFloat64
 		status     sql.NullString
 		login      sql.NullString
-		password   sql.NullString
 		group_id   sql.NullString
+		version    int
 		created_at sql.NullString
 		updated_at sql.NullString
 	)
-	if err := c.db.QueryRow(context.Background(), `select id, full_name, email, age, paid_sum, status, login, password, group_id, created_at, updated_at from student where id = $1`, id).Scan(
+	if err := c.db.QueryRow(context.Background(), `select id, full_name, email, age, paid_sum, status, login, group_id, version, created_at, updated_at from student where id = $1 AND deleted_at = 0`, id).Scan(
 		&student.ID,
 		&full_name,
 		&email,
@@ -294,14 +409,14 @@ func (c *StudentRepo) GetByID(ctx context.Context, id string) (models.Student, e
 		&paid_sum,
 		&status,
 		&login,
-		&password,
 		&group_id,
+		&version,
 		&created_at,
 		&updated_at,
 	); err != nil {
-		return models.Student{}, err
+		return models.GetStudent{}, err
 	}
-	return models.Student{
+	return models.GetStudent{
 		ID:         student.ID,
 		Full_Name:  full_name.String,
 		Email:      email.String,
@@ -309,20 +424,23 @@ func (c *StudentRepo) GetByID(ctx context.Context, id string) (models.Student, e
 		PaidSum:    paid_sum.Float64,
 		Status:     status.String,
 		Login:      login.String,
-		Password:   password.String,
 		GroupID:    group_id.String,
+		Version:    version,
 		Created_At: created_at.String,
 		Updated_At: updated_at.String,
 	}, nil
 }
 
-func (c *StudentRepo) Delete(ctx context.Context, id string) error {
-	query := `delete from student where id = $1`
-	_, err := c.db.Exec(context.Background(), query, id)
-	if err != nil {
-		return err
-	}
-	return nil
+func (c *StudentRepo) Delete(ctx context.Context, id string, version int) error {
+	return softDelete(ctx, c.db, "student", id, version)
+}
+
+func (c *StudentRepo) Restore(ctx context.Context, id string) error {
+	return restore(ctx, c.db, "student", id)
+}
+
+func (c *StudentRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return purge(ctx, c.db, "student", `EXISTS (SELECT 1 FROM payment WHERE payment.student_id = student.id)`, deletedBefore)
 }
 
 func (c *StudentRepo) GetPassword(ctx context.Context, login string) (string, error) {
@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"lms_back/api/models"
+	"lms_back/storage/postgres/filter"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const submissionColumns = `id,
+		task_id,
+		student_id,
+		answer,
+		attachment,
+		submitted_at,
+		late,
+		score,
+		feedback,
+		graded_by,
+		graded_at,
+		created_at,
+		updated_at`
+
+type submissionRepo struct {
+	db DBTX
+}
+
+func NewSubmission(db DBTX) submissionRepo {
+	return submissionRepo{
+		db: db,
+	}
+}
+
+func (s *submissionRepo) Submit(ctx context.Context, submission models.Submission) (models.Submission, error) {
+	query := `INSERT INTO task_submission (
+		id,
+		task_id,
+		student_id,
+		answer,
+		attachment,
+		late,
+		submitted_at,
+		created_at,
+		updated_at)
+		VALUES($1,$2,$3,$4,$5,$6,CURRENT_TIMESTAMP,CURRENT_TIMESTAMP,CURRENT_TIMESTAMP)
+		ON CONFLICT (task_id, student_id) DO UPDATE SET
+		answer = EXCLUDED.answer,
+		attachment = EXCLUDED.attachment,
+		late = EXCLUDED.late,
+		submitted_at = CURRENT_TIMESTAMP,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE task_submission.score IS NULL
+		RETURNING ` + submissionColumns
+
+	return scanSubmission(s.db.QueryRow(ctx, query,
+		uuid.New().String(),
+		submission.TaskID,
+		submission.StudentID,
+		submission.Answer,
+		submission.Attachment,
+		submission.Late,
+	))
+}
+
+func (s *submissionRepo) Grade(ctx context.Context, id string, score int, feedback, gradedBy string) (models.Submission, error) {
+	query := `UPDATE task_submission SET
+		score = $2,
+		feedback = $3,
+		graded_by = $4,
+		graded_at = CURRENT_TIMESTAMP,
+		updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + submissionColumns
+
+	return scanSubmission(s.db.QueryRow(ctx, query, id, score, feedback, sql.NullString{String: gradedBy, Valid: gradedBy != ""}))
+}
+
+func (s *submissionRepo) GetByID(ctx context.Context, id string) (models.Submission, error) {
+	return scanSubmission(s.db.QueryRow(ctx, `SELECT `+submissionColumns+` FROM task_submission WHERE id = $1`, id))
+}
+
+func (s *submissionRepo) GetAll(ctx context.Context, req models.GetAllSubmissionsRequest) (models.GetAllSubmissionsResponse, error) {
+	var (
+		resp = models.GetAllSubmissionsResponse{}
+	)
+	offset := (req.Page - 1) * req.Limit
+
+	where := filter.New().
+		Equal("task_id", req.TaskID).
+		Equal("student_id", req.StudentID)
+
+	rows, err := s.db.Query(ctx, `SELECT count(id) OVER(), `+submissionColumns+`
+		FROM task_submission`+where.SQL()+fmt.Sprintf(" ORDER BY submitted_at DESC, id OFFSET %v LIMIT %v", offset, req.Limit), where.Args()...)
+	if err != nil {
+		return resp, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		submission, err := scanSubmission(rows, &resp.Count)
+		if err != nil {
+			return resp, err
+		}
+		resp.Submissions = append(resp.Submissions, submission)
+	}
+
+	return resp, rows.Err()
+}
+
+// scanSubmission scans the submissionColumns of the row after the columns
+// selected before them into prefix.
+func scanSubmission(row pgx.Row, prefix ...interface{}) (models.Submission, error) {
+	var (
+		submission   = models.Submission{}
+		submitted_at sql.NullString
+		score        sql.NullInt32
+		graded_by    sql.NullString
+		graded_at    sql.NullString
+		created_at   sql.NullString
+		updated_at   sql.NullString
+	)
+	if err := row.Scan(append(prefix,
+		&submission.Id,
+		&submission.TaskID,
+		&submission.StudentID,
+		&submission.Answer,
+		&submission.Attachment,
+		&submitted_at,
+		&submission.Late,
+		&score,
+		&submission.Feedback,
+		&graded_by,
+		&graded_at,
+		&created_at,
+		&updated_at,
+	)...); err != nil {
+		return models.Submission{}, err
+	}
+
+	submission.SubmittedAt = submitted_at.String
+	if score.Valid {
+		graded := int(score.Int32)
+		submission.Score = &graded
+	}
+	submission.GradedBy = graded_by.String
+	submission.GradedAt = graded_at.String
+	submission.CreatedAt = created_at.String
+	submission.UpdatedAt = updated_at.String
+
+	return submission, nil
+}
@@ -3,19 +3,29 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"lms_back/api/models"
 	"lms_back/pkg"
+	"lms_back/storage"
+	"lms_back/storage/postgres/filter"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// taskSearchColumns are matched by the search query param.
+var taskSearchColumns = []string{"task"}
+
+// taskSortColumns are the fields the list can be sorted by.
+var taskSortColumns = map[string]string{
+	"score":      "score",
+	"created_at": `COALESCE(created_at, '-infinity')`,
+}
+
 type TaskRepo struct {
-	db *pgxpool.Pool
+	db DBTX
 }
 
-func NewTask(db *pgxpool.Pool) TaskRepo {
+func NewTask(db DBTX) TaskRepo {
 	return TaskRepo{
 		db: db,
 	}
@@ -26,28 +36,35 @@ func (c *TaskRepo) Create(ctx context.Context, task models.Task) (models.Task, e
 	id := uuid.New()
 	query := `INSERT INTO "task" (
 		id,
-		lesson_id
+		lesson_id,
 		group_id,
+		task,
 		score,
+		deadline,
 		created_at)
-		VALUES($1,$2,$3,$4,CURRENT_TIMESTAMP) 
+		VALUES($1,$2,$3,$4,$5,$6,CURRENT_TIMESTAMP) 
 	`
 
 	_, err := c.db.Exec(context.Background(), query,
 		id.String(),
 		task.LessonId,
 		task.GroupId,
+		task.Task,
 		task.Score,
+		deadlineArg(task.Deadline),
 	)
 
 	if err != nil {
 		return models.Task{}, err
 	}
 	return models.Task{
-		Id:        task.Id,
+		Id:        id.String(),
 		LessonId:  task.LessonId,
 		GroupId:   task.GroupId,
+		Task:      task.Task,
 		Score:     task.Score,
+		Deadline:  task.Deadline,
+		Version:   1,
 		CreatedAt: task.CreatedAt,
 		UpdatedAt: task.UpdatedAt,
 	}, nil
@@ -57,79 +74,130 @@ func (c *TaskRepo) Update(ctx context.Context, task models.Task) (models.Task, e
 	query := `UPDATE "task" set
 	lesson_id=$1, 
 	group_id=$2,
-    score=$3,
+	task=$3,
+    score=$4,
+	deadline=$5,
+	version = version + 1,
 	updated_at=CURRENT_TIMESTAMP
-	WHERE id = $4
+	WHERE id = $6 AND deleted_at = 0 AND version = $7
 	`
-	_, err := c.db.Exec(context.Background(), query,
+	tag, err := c.db.Exec(context.Background(), query,
 		task.LessonId,
 		task.GroupId,
+		task.Task,
 		task.Score,
+		deadlineArg(task.Deadline),
 		task.Id,
+		task.Version,
 	)
 	if err != nil {
 		return models.Task{}, err
 	}
+	if err = checkVersion(ctx, c.db, `"task"`, task.Id, tag); err != nil {
+		return models.Task{}, err
+	}
 	return models.Task{
 		Id:        task.Id,
 		LessonId:  task.LessonId,
 		GroupId:   task.GroupId,
+		Task:      task.Task,
 		Score:     task.Score,
+		Deadline:  task.Deadline,
+		Version:   task.Version + 1,
 		CreatedAt: task.CreatedAt,
 		UpdatedAt: task.UpdatedAt,
 	}, nil
 }
 
+func (c *TaskRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Task, error) {
+	if err := patchRow(ctx, c.db, `"task"`, id, version, storage.TaskPatch, patch); err != nil {
+		return models.Task{}, err
+	}
+	return c.GetByID(ctx, id)
+}
+
 func (c *TaskRepo) GetAll(ctx context.Context, req models.GetAllTasksRequest) (models.GetAllTasksResponse, error) {
 	var (
-		resp   = models.GetAllTasksResponse{}
-		filter = ""
+		resp    = models.GetAllTasksResponse{}
+		cursors []string
 	)
-	offset := (req.Page - 1) * req.Limit
+	where := filter.New().
+		Deleted(req.Deleted).
+		Search(req.Search, taskSearchColumns...).
+		Equal("lesson_id", req.LessonID).
+		Equal("group_id", req.GroupID)
 
-	if req.Search != "" {
-		filter += fmt.Sprintf(` and name ILIKE  '%%%v%%' `, req.Search)
+	list, err := filter.NewList(req.ListParams, req.Page, req.Limit, taskSortColumns, "-created_at")
+	if err != nil {
+		return resp, err
 	}
+	list.Apply(where)
 
-	filter += fmt.Sprintf(" OFFSET %v LIMIT %v", offset, req.Limit)
-	fmt.Println("filter: ", filter)
-
-	rows, err := c.db.Query(context.Background(), `select count(id) over(),
+	rows, err := c.db.Query(context.Background(), `select `+list.CountColumn()+`,
         id,
 		lesson_id,
         group_id,
+		task,
 		score,
+		deadline,
+		version,
         created_at,
-        updated_at
-        FROM "task"`+filter+``)
+        updated_at,
+        `+list.CursorColumn()+`
+        FROM "task"`+where.SQL()+list.SQL(), where.Args()...)
 	if err != nil {
 		return resp, err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		var (
 			task       = models.Task{}
 			lesson_id  sql.NullString
 			group_id   sql.NullString
+			score      sql.NullString
+			deadline   sql.NullString
+			version    int
 			created_at sql.NullString
 			updated_at sql.NullString
+			cursor     string
 		)
 		if err := rows.Scan(
 			&resp.Count,
 			&task.Id,
 			&lesson_id,
 			&group_id,
+			&task.Task,
+			&score,
+			&deadline,
+			&version,
 			&created_at,
-			&updated_at); err != nil {
+			&updated_at,
+			&cursor); err != nil {
 			return resp, err
 		}
 		task.UpdatedAt = pkg.NullStringToString(updated_at)
 		resp.Tasks = append(resp.Tasks, models.Task{
+			Id:        task.Id,
 			LessonId:  lesson_id.String,
 			GroupId:   group_id.String,
+			Task:      task.Task,
+			Score:     score.String,
+			Deadline:  deadline.String,
+			Version:   version,
 			CreatedAt: created_at.String,
 			UpdatedAt: updated_at.String,
 		})
+		cursors = append(cursors, cursor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return resp, err
 	}
+
+	keep, next := list.Next(cursors)
+	resp.Tasks, resp.NextCursor = resp.Tasks[:keep], next
+
 	return resp, nil
 }
 
@@ -138,31 +206,54 @@ func (c *TaskRepo) GetByID(ctx context.Context, id string) (models.Task, error)
 		task       = models.Task{}
 		lesson_id  sql.NullString
 		group_id   sql.NullString
+		score      sql.NullString
+		deadline   sql.NullString
+		version    int
 		created_at sql.NullString
 		updated_at sql.NullString
 	)
 
-	if err := c.db.QueryRow(context.Background(), `select id, lesson_id, group_id, score, created_at, updated_at from "task" where id = $1`, id).Scan(
+	if err := c.db.QueryRow(context.Background(), `select id, lesson_id, group_id, task, score, deadline, version, created_at, updated_at from "task" where id = $1 AND deleted_at = 0`, id).Scan(
 		&task.Id,
 		&lesson_id,
 		&group_id,
+		&task.Task,
+		&score,
+		&deadline,
+		&version,
 		&created_at,
 		&updated_at); err != nil {
 		return models.Task{}, err
 	}
 	return models.Task{
+		Id:        task.Id,
 		LessonId:  lesson_id.String,
 		GroupId:   group_id.String,
+		Task:      task.Task,
+		Score:     score.String,
+		Deadline:  deadline.String,
+		Version:   version,
 		CreatedAt: created_at.String,
 		UpdatedAt: updated_at.String,
 	}, nil
 }
 
-func (c *TaskRepo) Delete(ctx context.Context, id string) error {
-	query := `delete from "task" where id = $1`
-	_, err := c.db.Exec(context.Background(), query, id)
-	if err != nil {
-		return err
+// deadlineArg writes the RFC 3339 deadline in UTC, the column has no time zone.
+func deadlineArg(deadline string) sql.NullString {
+	if t, err := time.Parse(time.RFC3339, deadline); err == nil {
+		deadline = t.UTC().Format(time.RFC3339)
 	}
-	return nil
+	return sql.NullString{String: deadline, Valid: deadline != ""}
+}
+
+func (c *TaskRepo) Delete(ctx context.Context, id string, version int) error {
+	return softDelete(ctx, c.db, `"task"`, id, version)
+}
+
+func (c *TaskRepo) Restore(ctx context.Context, id string) error {
+	return restore(ctx, c.db, `"task"`, id)
+}
+
+func (c *TaskRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return purge(ctx, c.db, `"task"`, "", deletedBefore)
 }
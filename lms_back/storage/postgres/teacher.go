@@ -3,24 +3,39 @@ package postgres
 import (
 	"context"
 	"database/sql"
-	"fmt"
 	"lms_back/api/models"
+	"lms_back/storage"
+	"lms_back/storage/postgres/filter"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// teacherSearchColumns are matched by the search query param.
+var teacherSearchColumns = []string{"full_name", "email", "login"}
+
+// teacherSortColumns are the fields the list can be sorted by.
+var teacherSortColumns = map[string]string{
+	"full_name":  "full_name",
+	"email":      "email",
+	"login":      "login",
+	"age":        "age",
+	"status":     "status",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
 type TeacherRepo struct {
-	db *pgxpool.Pool
+	db DBTX
 }
 
-func NewTeacher(db *pgxpool.Pool) TeacherRepo {
+func NewTeacher(db DBTX) TeacherRepo {
 	return TeacherRepo{
 		db: db,
 	}
 }
 
-func (c *TeacherRepo) Create(ctx context.Context, teacher models.Teacher) (models.Teacher, error) {
+func (c *TeacherRepo) Create(ctx context.Context, teacher models.Teacher) (models.GetTeacher, error) {
 
 	id := uuid.New()
 	query := `INSERT INTO teacher (
@@ -45,96 +60,128 @@ func (c *TeacherRepo) Create(ctx context.Context, teacher models.Teacher) (model
 	)
 
 	if err != nil {
-		return models.Teacher{}, err
+		return models.GetTeacher{}, err
 	}
 
-	return models.Teacher{
+	return models.GetTeacher{
 		Id:         id.String(),
+		Full_name:  teacher.Full_name,
 		Email:      teacher.Email,
 		Age:        teacher.Age,
 		Status:     teacher.Status,
 		Login:      teacher.Login,
-		Password:   teacher.Password,
+		Version:    1,
 		Created_at: teacher.Created_at,
 		Updated_at: teacher.Updated_at,
 	}, nil
 }
 
-func (c *TeacherRepo) Update(ctx context.Context, teacher models.Teacher) (models.Teacher, error) {
+func (c *TeacherRepo) Update(ctx context.Context, teacher models.Teacher) (models.GetTeacher, error) {
 	query := `update teacher set 
 	full_name=$1,
 	email=$2,
 	age=$3,
 	login=$4,
-	password=$5,
-	status=$6,
+	status=$5,
+	version = version + 1,
 	updated_at = CURRENT_TIMESTAMP
-	WHERE id = $7 AND deleted_at = 0
+	WHERE id = $6 AND deleted_at = 0 AND version = $7
 	`
-	_, err := c.db.Exec(context.Background(), query,
+	tag, err := c.db.Exec(context.Background(), query,
 		teacher.Full_name,
 		teacher.Email,
 		teacher.Age,
 		teacher.Login,
-		teacher.Password,
 		teacher.Status,
 		teacher.Id,
+		teacher.Version,
 	)
 	if err != nil {
-		return models.Teacher{}, err
+		return models.GetTeacher{}, err
 	}
-	return models.Teacher{
+	if err = checkVersion(ctx, c.db, "teacher", teacher.Id, tag); err != nil {
+		return models.GetTeacher{}, err
+	}
+	return models.GetTeacher{
 		Id:         teacher.Id,
+		Full_name:  teacher.Full_name,
 		Email:      teacher.Email,
 		Age:        teacher.Age,
 		Status:     teacher.Status,
 		Login:      teacher.Login,
-		Password:   teacher.Password,
+		Version:    teacher.Version + 1,
 		Created_at: teacher.Created_at,
 		Updated_at: teacher.Updated_at,
 	}, nil
 }
 
+func (c *TeacherRepo) Patch(ctx context.Context, id string, version int, patch models.Patch) (models.GetTeacher, error) {
+	if err := patchRow(ctx, c.db, "teacher", id, version, storage.TeacherPatch, patch); err != nil {
+		return models.GetTeacher{}, err
+	}
+	return c.GetByID(ctx, id)
+}
+
+func (c *TeacherRepo) UpdatePassword(ctx context.Context, id, password string) error {
+	query := `update teacher set 
+	password=$1,
+	version = version + 1,
+	updated_at = CURRENT_TIMESTAMP
+	WHERE id = $2 AND deleted_at = 0
+	`
+	_, err := c.db.Exec(ctx, query, password, id)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
 func (c *TeacherRepo) GetAll(ctx context.Context, req models.GetAllTeachersRequest) (models.GetAllTeachersResponse, error) {
 	var (
-		resp   = models.GetAllTeachersResponse{}
-		filter = ""
+		resp    = models.GetAllTeachersResponse{}
+		cursors []string
 	)
-	offset := (req.Page - 1) * req.Limit
+	where := filter.New().
+		Deleted(req.Deleted).
+		Search(req.Search, teacherSearchColumns...).
+		Equal("status", req.Status)
 
-	if req.Search != "" {
-		filter += fmt.Sprintf(` and name ILIKE  '%%%v%%' `, req.Search)
+	list, err := filter.NewList(req.ListParams, req.Page, req.Limit, teacherSortColumns, "-created_at")
+	if err != nil {
+		return resp, err
 	}
+	list.Apply(where)
 
-	filter += fmt.Sprintf(" OFFSET %v LIMIT %v", offset, req.Limit)
-	fmt.Println("filter: ", filter)
-
-	rows, err := c.db.Query(context.Background(), `select count(id) over(),
+	rows, err := c.db.Query(context.Background(), `select `+list.CountColumn()+`,
         id,
         full_name,
 		email,
 		age,
         login,
-		password,
 		status,
+		version,
         created_at,
         updated_at,
-        deleted_at FROM teacher WHERE deleted_at = 0`+filter+``)
+        deleted_at,
+        `+list.CursorColumn()+` FROM teacher`+where.SQL()+list.SQL(), where.Args()...)
 	if err != nil {
 		return resp, err
 	}
+	defer rows.Close()
+
 	for rows.Next() {
 		var (
-			teacher    = models.Teacher{}
+			teacher    = models.GetTeacher{}
 			full_name  sql.NullString
 			email      sql.NullString
 			age        sql.NullInt16
 			login      sql.NullString
-			password   sql.NullString
 			status     sql.NullString
+			version    int
 			created_at sql.NullString
 			updated_at sql.NullString
 			deleted_at sql.NullString
+			cursor     string
 		)
 		if err := rows.Scan(
 			&resp.Count,
@@ -143,51 +190,105 @@ func (c *TeacherRepo) GetAll(ctx context.Context, req models.GetAllTeachersReque
 			&email,
 			&age,
 			&login,
-			&password,
 			&status,
+			&version,
 			&created_at,
 			&updated_at,
-			&deleted_at); err != nil {
+			&deleted_at,
+			&cursor); err != nil {
 			return resp, err
 		}
-		resp.Teachers = append(resp.Teachers, models.Teacher{
+		resp.Teachers = append(resp.Teachers, models.GetTeacher{
 			Id:         teacher.Id,
 			Full_name:  full_name.String,
 			Email:      email.String,
 			Age:        int(age.Int16),
 			Login:      login.String,
-			Password:   password.String,
 			Status:     status.String,
+			Version:    version,
 			Created_at: created_at.String,
 			Updated_at: updated_at.String,
 			Deleted_at: deleted_at.String,
 		})
+		cursors = append(cursors, cursor)
+	}
+
+	if err := rows.Err(); err != nil {
+		return resp, err
 	}
+
+	keep, next := list.Next(cursors)
+	resp.Teachers, resp.NextCursor = resp.Teachers[:keep], next
+
 	return resp, nil
 }
 
-func (c *TeacherRepo) GetByID(ctx context.Context, id string) (models.Teacher, error) {
-	teacher := models.Teacher{}
+func (c *TeacherRepo) GetByID(ctx context.Context, id string) (models.GetTeacher, error) {
+	teacher := models.GetTeacher{}
 	var (
 		full_name  sql.NullString
 		email      sql.NullString
 		age        sql.NullInt16
 		login      sql.NullString
-		password   sql.NullString
 		status     sql.NullString
+		version    int
 		created_at sql.NullString
 		updated_at sql.NullString
 		deleted_at sql.NullString
 	)
 
-	if err := c.db.QueryRow(context.Background(), `select id, full_name, email, age, login, password, status, created_at, updated_at, deleted_at from teacher where id = $1`, id).Scan(
+	if err := c.db.QueryRow(context.Background(), `select id, full_name, email, age, login, status, version, created_at, updated_at, deleted_at from teacher where id = $1 AND deleted_at = 0`, id).Scan(
 		&teacher.Id,
 		&full_name,
 		&email,
 		&age,
 		&login,
+		&status,
+		&version,
+		&created_at,
+		&updated_at,
+		&deleted_at,
+	); err != nil {
+		return models.GetTeacher{}, err
+	}
+	return models.GetTeacher{
+		Id:         teacher.Id,
+		Full_name:  full_name.String,
+		Email:      email.String,
+		Age:        int(age.Int16),
+		Login:      login.String,
+		Status:     status.String,
+		Version:    version,
+		Created_at: created_at.String,
+		Updated_at: updated_at.String,
+		Deleted_at: deleted_at.String,
+	}, nil
+}
+
+func (c *TeacherRepo) GetByLogin(ctx context.Context, login string) (models.Teacher, error) {
+	teacher := models.Teacher{}
+	var (
+		full_name  sql.NullString
+		email      sql.NullString
+		age        sql.NullInt16
+		loginn     sql.NullString
+		password   sql.NullString
+		status     sql.NullString
+		version    int
+		created_at sql.NullString
+		updated_at sql.NullString
+		deleted_at sql.NullString
+	)
+
+	if err := c.db.QueryRow(ctx, `select id, full_name, email, age, login, password, status, version, created_at, updated_at, deleted_at from teacher where login = $1 AND deleted_at = 0`, login).Scan(
+		&teacher.Id,
+		&full_name,
+		&email,
+		&age,
+		&loginn,
 		&password,
 		&status,
+		&version,
 		&created_at,
 		&updated_at,
 		&deleted_at,
@@ -199,20 +300,25 @@ func (c *TeacherRepo) GetByID(ctx context.Context, id string) (models.Teacher, e
 		Full_name:  full_name.String,
 		Email:      email.String,
 		Age:        int(age.Int16),
-		Login:      login.String,
+		Login:      loginn.String,
 		Password:   password.String,
 		Status:     status.String,
+		Version:    version,
 		Created_at: created_at.String,
 		Updated_at: updated_at.String,
 		Deleted_at: deleted_at.String,
 	}, nil
 }
 
-func (c *TeacherRepo) Delete(ctx context.Context, id string) error {
-	query := `delete from teacher where id = $1`
-	_, err := c.db.Exec(context.Background(), query, id)
-	if err != nil {
-		return err
-	}
-	return nil
+func (c *TeacherRepo) Delete(ctx context.Context, id string, version int) error {
+	return softDelete(ctx, c.db, "teacher", id, version)
+}
+
+func (c *TeacherRepo) Restore(ctx context.Context, id string) error {
+	return restore(ctx, c.db, "teacher", id)
+}
+
+func (c *TeacherRepo) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return purge(ctx, c.db, "teacher", `(EXISTS (SELECT 1 FROM "group" WHERE "group".teacher_id = teacher.id)
+		OR EXISTS (SELECT 1 FROM schedule WHERE schedule.teacher_id = teacher.id))`, deletedBefore)
 }
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"lms_back/api/models"
+)
+
+type tokenRepo struct {
+	db DBTX
+}
+
+func NewToken(db DBTX) tokenRepo {
+	return tokenRepo{
+		db: db,
+	}
+}
+
+// Revoke stores the token id and reports whether it was not revoked before.
+func (t *tokenRepo) Revoke(ctx context.Context, token models.RevokedToken) (bool, error) {
+	query := `INSERT INTO revoked_token (
+		jti,
+		user_id,
+		expires_at,
+		revoked_at)
+		VALUES($1,$2,to_timestamp($3),CURRENT_TIMESTAMP)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	tag, err := t.db.Exec(ctx, query, token.JTI, token.UserID, token.ExpiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = t.db.Exec(ctx, `DELETE FROM revoked_token WHERE expires_at < CURRENT_TIMESTAMP`)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (t *tokenRepo) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var revoked bool
+
+	query := `SELECT EXISTS(SELECT 1 FROM revoked_token WHERE jti = $1)`
+	if err := t.db.QueryRow(ctx, query, jti).Scan(&revoked); err != nil {
+		return false, err
+	}
+
+	return revoked, nil
+}
@@ -0,0 +1,63 @@
+package postgres
+
+import (
+	"context"
+	"lms_back/storage"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// softDelete moves the row with the version to the trash.
+func softDelete(ctx context.Context, db DBTX, table, id string, version int) error {
+	tag, err := db.Exec(ctx, `UPDATE `+table+` SET deleted_at = extract(epoch from now())::integer, version = version + 1
+		WHERE id = $1 AND deleted_at = 0 AND version = $2`, id, version)
+	if err != nil {
+		return err
+	}
+	return checkVersion(ctx, db, table, id, tag)
+}
+
+// checkVersion explains a write of a row with an expected version. If the
+// write changed no row it returns storage.ErrNotFound for a missing or deleted row
+// and a *storage.VersionConflictError for a row with another version.
+func checkVersion(ctx context.Context, db DBTX, table, id string, tag pgconn.CommandTag) error {
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	var current int
+	if err := db.QueryRow(ctx, `SELECT version FROM `+table+` WHERE id = $1 AND deleted_at = 0`, id).Scan(&current); err != nil {
+		return err
+	}
+	return &storage.VersionConflictError{Current: current}
+}
+
+// restore takes the row out of the trash, it returns storage.ErrNotFound if the row
+// is not in the trash.
+func restore(ctx context.Context, db DBTX, table, id string) error {
+	tag, err := db.Exec(ctx, `UPDATE `+table+` SET deleted_at = 0, version = version + 1 WHERE id = $1 AND deleted_at <> 0`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return storage.ErrNotFound
+	}
+	return nil
+}
+
+// purge hard-deletes the rows deleted before deletedBefore. Rows still
+// referenced by other rows are kept until the referencing rows are purged,
+// keep is the condition that finds them.
+func purge(ctx context.Context, db DBTX, table, keep string, deletedBefore time.Time) (int64, error) {
+	query := `DELETE FROM ` + table + ` WHERE deleted_at <> 0 AND deleted_at < $1`
+	if keep != "" {
+		query += ` AND NOT ` + keep
+	}
+
+	tag, err := db.Exec(ctx, query, deletedBefore.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"lms_back/api/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type twoFactorRepo struct {
+	db DBTX
+}
+
+func NewTwoFactor(db DBTX) twoFactorRepo {
+	return twoFactorRepo{
+		db: db,
+	}
+}
+
+// Setup stores a new not yet enabled secret of the user, replacing the previous pending one.
+func (t *twoFactorRepo) Setup(ctx context.Context, twoFactor models.TwoFactor) error {
+	query := `INSERT INTO two_factor (
+		user_id,
+		user_role,
+		secret,
+		enabled,
+		last_used_step,
+		created_at)
+		VALUES($1,$2,$3,false,0,CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id, user_role) DO UPDATE SET
+		secret = EXCLUDED.secret,
+		last_used_step = 0,
+		created_at = CURRENT_TIMESTAMP
+		WHERE two_factor.enabled = false
+	`
+
+	_, err := t.db.Exec(ctx, query, twoFactor.UserID, twoFactor.UserRole, twoFactor.Secret)
+	return err
+}
+
+func (t *twoFactorRepo) Get(ctx context.Context, userID, userRole string) (models.TwoFactor, error) {
+	var (
+		twoFactor  = models.TwoFactor{}
+		created_at sql.NullString
+		enabled_at sql.NullString
+	)
+
+	query := `SELECT user_id, user_role, secret, enabled, last_used_step, created_at, enabled_at
+		FROM two_factor WHERE user_id = $1 AND user_role = $2`
+
+	if err := t.db.QueryRow(ctx, query, userID, userRole).Scan(
+		&twoFactor.UserID,
+		&twoFactor.UserRole,
+		&twoFactor.Secret,
+		&twoFactor.Enabled,
+		&twoFactor.LastUsedStep,
+		&created_at,
+		&enabled_at,
+	); err != nil {
+		return models.TwoFactor{}, err
+	}
+
+	twoFactor.CreatedAt = created_at.String
+	twoFactor.EnabledAt = enabled_at.String
+
+	return twoFactor, nil
+}
+
+// UseStep records the time step of an accepted code. It reports false if the
+// step or a later one was already used, so a code can't be replayed.
+func (t *twoFactorRepo) UseStep(ctx context.Context, userID, userRole string, step int64) (bool, error) {
+	query := `UPDATE two_factor SET last_used_step = $3
+		WHERE user_id = $1 AND user_role = $2 AND last_used_step < $3`
+
+	tag, err := t.db.Exec(ctx, query, userID, userRole, step)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// Enable turns on two-factor authentication and replaces the recovery codes of the user.
+func (t *twoFactorRepo) Enable(ctx context.Context, userID, userRole string, recoveryCodeHashes []string) error {
+	return pgx.BeginFunc(ctx, t.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `UPDATE two_factor SET enabled = true, enabled_at = CURRENT_TIMESTAMP
+			WHERE user_id = $1 AND user_role = $2`, userID, userRole)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM recovery_code WHERE user_id = $1 AND user_role = $2`, userID, userRole)
+		if err != nil {
+			return err
+		}
+
+		for _, codeHash := range recoveryCodeHashes {
+			_, err = tx.Exec(ctx, `INSERT INTO recovery_code (id, user_id, user_role, code_hash, created_at)
+				VALUES($1,$2,$3,$4,CURRENT_TIMESTAMP)`, uuid.New().String(), userID, userRole, codeHash)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (t *twoFactorRepo) Delete(ctx context.Context, userID, userRole string) error {
+	return pgx.BeginFunc(ctx, t.db, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM recovery_code WHERE user_id = $1 AND user_role = $2`, userID, userRole)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, `DELETE FROM two_factor WHERE user_id = $1 AND user_role = $2`, userID, userRole)
+		return err
+	})
+}
+
+// GetRecoveryCodes returns the unused recovery codes of the user.
+func (t *twoFactorRepo) GetRecoveryCodes(ctx context.Context, userID, userRole string) ([]models.RecoveryCode, error) {
+	rows, err := t.db.Query(ctx, `SELECT id, user_id, user_role, code_hash FROM recovery_code
+		WHERE user_id = $1 AND user_role = $2 AND used_at IS NULL`, userID, userRole)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := []models.RecoveryCode{}
+	for rows.Next() {
+		code := models.RecoveryCode{}
+		if err = rows.Scan(&code.Id, &code.UserID, &code.UserRole, &code.CodeHash); err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, rows.Err()
+}
+
+// UseRecoveryCode consumes the recovery code, it reports false if it was already used.
+func (t *twoFactorRepo) UseRecoveryCode(ctx context.Context, id string) (bool, error) {
+	tag, err := t.db.Exec(ctx, `UPDATE recovery_code SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
@@ -3,10 +3,22 @@ package storage
 import (
 	"context"
 	"lms_back/api/models"
+	"time"
 )
 
+// IStorage is the storage of the api. The Update and Delete methods of the
+// entities only write the row if it still has the version they are given, in
+// Version of the model or the version argument, and fail with a
+// *VersionConflictError otherwise, or with ErrNotFound if the row doesn't
+// exist or is deleted. Every write of a row increments its version. Patch
+// writes only the fields in the patch, checked by the PatchFields of the
+// entity, and fails with a validation error for a field it can't write.
+// Errors of the database are returned as the domain errors of DBError.
 type IStorage interface {
 	CloseDB()
+	// WithTx runs fn in a transaction. The storage passed to fn runs every query
+	// in it, the transaction is committed if fn returns nil and rolled back otherwise.
+	WithTx(ctx context.Context, fn func(IStorage) error) error
 	Admin() IAdminStorage
 	Branch() IBranchStorage
 	Group() IGroupStorage
@@ -16,15 +28,31 @@ type IStorage interface {
 	Schedule() IScheduleStorage
 	Task() ITaskStorage
 	Lesson() ILessonStorage
+	Enrollment() IEnrollmentStorage
+	Attendance() IAttendanceStorage
+	Submission() ISubmissionStorage
+	Gradebook() IGradebookStorage
 	AdminReport() IAdminReportStorage
+	Token() ITokenStorage
+	PasswordReset() IPasswordResetStorage
+	LoginAttempt() ILoginAttemptStorage
+	TwoFactor() ITwoFactorStorage
+	Audit() IAuditStorage
+	Idempotency() IIdempotencyStorage
 }
 
 type IAdminStorage interface {
-	Create(context.Context, models.Admin) (models.Admin, error)
+	Create(context.Context, models.Admin) (models.GetAdmin, error)
 	GetAll(ctx context.Context, request models.GetAllAdminsRequest) (models.GetAllAdminsResponse, error)
-	GetByID(ctx context.Context, id string) (models.Admin, error)
-	Update(context.Context, models.Admin) (models.Admin, error)
-	Delete(context.Context, string) error
+	GetByID(ctx context.Context, id string) (models.GetAdmin, error)
+	Update(context.Context, models.Admin) (models.GetAdmin, error)
+	Patch(ctx context.Context, id string, version int, patch models.Patch) (models.GetAdmin, error)
+	UpdatePassword(ctx context.Context, id, password string) error
+	Delete(ctx context.Context, id string, version int) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetByLogin(context.Context, string) (models.Admin, error)
+	SetBranches(ctx context.Context, adminID string, branchIDs []string) error
 }
 
 type IBranchStorage interface {
@@ -32,7 +60,10 @@ type IBranchStorage interface {
 	GetAll(ctx context.Context, request models.GetAllBranchesRequest) (models.GetAllBranchesResponse, error)
 	GetByID(ctx context.Context, id string) (models.Branch, error)
 	Update(context.Context, models.Branch) (models.Branch, error)
-	Delete(context.Context, string) error
+	Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Branch, error)
+	Delete(ctx context.Context, id string, version int) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
 }
 
 type IGroupStorage interface {
@@ -40,7 +71,10 @@ type IGroupStorage interface {
 	GetAll(ctx context.Context, request models.GetAllGroupsRequest) (models.GetAllGroupsResponse, error)
 	GetByID(ctx context.Context, id string) (models.Group, error)
 	Update(context.Context, models.Group) (models.Group, error)
-	Delete(context.Context, string) error
+	Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Group, error)
+	Delete(ctx context.Context, id string, version int) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
 }
 
 type ILessonStorage interface {
@@ -48,7 +82,10 @@ type ILessonStorage interface {
 	GetAll(ctx context.Context,req models.GetAllLessonsRequest) (models.GetAllLessonsResponse, error)
 	GetByID(ctx context.Context, id string) (models.Lesson, error)
 	Update(context.Context, models.Lesson) (models.Lesson, error)
-	Delete(context.Context, string) error
+	Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Lesson, error)
+	Delete(ctx context.Context, id string, version int) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
 }
 
 type IPaymentStorage interface {
@@ -56,25 +93,40 @@ type IPaymentStorage interface {
 	GetAll(ctx context.Context, request models.GetAllPaymentsRequest) (models.GetAllPaymentsResponse, error)
 	GetByID(ctx context.Context, id string) (models.Payment, error)
 	Update(context.Context, models.Payment) (models.Payment, error)
-	Delete(context.Context, string) error
+	Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Payment, error)
+	Delete(ctx context.Context, id string, version int) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetByIDForUpdate(ctx context.Context, id string) (models.Payment, error)
 }
 
 type IStudentStorage interface {
-	Create(context.Context,models.Student) (models.Student, error)
+	Create(context.Context, models.Student) (models.GetStudent, error)
 	GetAll(ctx context.Context, request models.GetAllStudentsRequest) (models.GetAllStudentsResponse, error)
-	GetByID(ctx context.Context,id string) (models.Student, error)
-	Update(context.Context, models.Student) (models.Student, error)
-	Delete(context.Context, string) error
+	GetByID(ctx context.Context, id string) (models.GetStudent, error)
+	Update(context.Context, models.Student) (models.GetStudent, error)
+	Patch(ctx context.Context, id string, version int, patch models.Patch) (models.GetStudent, error)
+	UpdatePassword(ctx context.Context, id, password string) error
+	AddPaidSum(ctx context.Context, id string, amount float64) error
+	SetGroup(ctx context.Context, id, groupID string) error
+	Delete(ctx context.Context, id string, version int) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
 	GetPassword(ctx context.Context, login string) (string, error)
 	GetByLogin(context.Context, string) (models.Student, error)
 }
 
 type ITeacherStorage interface {
-	Create(context.Context,models.Teacher) (models.Teacher, error)
+	Create(context.Context, models.Teacher) (models.GetTeacher, error)
 	GetAll(ctx context.Context, request models.GetAllTeachersRequest) (models.GetAllTeachersResponse, error)
-	GetByID(ctx context.Context, id string) (models.Teacher, error)
-	Update(context.Context, models.Teacher) (models.Teacher, error)
-	Delete(context.Context, string) error
+	GetByID(ctx context.Context, id string) (models.GetTeacher, error)
+	Update(context.Context, models.Teacher) (models.GetTeacher, error)
+	Patch(ctx context.Context, id string, version int, patch models.Patch) (models.GetTeacher, error)
+	UpdatePassword(ctx context.Context, id, password string) error
+	Delete(ctx context.Context, id string, version int) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetByLogin(context.Context, string) (models.Teacher, error)
 }
 
 type IScheduleStorage interface {
@@ -82,7 +134,10 @@ type IScheduleStorage interface {
 	GetAll(ctx context.Context, request models.GetAllSchedulesRequest) (models.GetAllSchedulesResponse, error)
 	GetByID(ctx context.Context, id string) (models.Schedule, error)
 	Update(context.Context, models.Schedule) (models.Schedule, error)
-	Delete(context.Context, string) error
+	Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Schedule, error)
+	Delete(ctx context.Context, id string, version int) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
 }
 
 type ITaskStorage interface {
@@ -90,9 +145,105 @@ type ITaskStorage interface {
 	GetAll(ctx context.Context, req models.GetAllTasksRequest) (models.GetAllTasksResponse, error)
 	GetByID(ctx context.Context, id string) (models.Task, error)
 	Update(context.Context, models.Task) (models.Task, error)
-	Delete(context.Context, string) error
+	Patch(ctx context.Context, id string, version int, patch models.Patch) (models.Task, error)
+	Delete(ctx context.Context, id string, version int) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+// IEnrollmentStorage keeps the groups students are and were in. A student
+// has at most one active enrollment per group, Create fails with a conflict
+// for another one.
+type IEnrollmentStorage interface {
+	Create(context.Context, models.Enrollment) (models.Enrollment, error)
+	// Close ends the active enrollment of the student in the group with the
+	// status and reason, it returns ErrNotFound if there is none.
+	Close(ctx context.Context, studentID, groupID, status, reason string) (models.Enrollment, error)
+	GetAll(ctx context.Context, req models.GetAllEnrollmentsRequest) (models.GetAllEnrollmentsResponse, error)
+}
+
+// IAttendanceStorage keeps a mark per lesson and student. Lists and rates
+// leave out the marks of deleted lessons.
+type IAttendanceStorage interface {
+	// Mark writes the marks of the lesson, replacing the earlier marks of the
+	// students. It returns ErrNotFound if the lesson doesn't exist.
+	Mark(ctx context.Context, lessonID, markedBy string, marks []models.MarkAttendance) ([]models.Attendance, error)
+	GetAll(ctx context.Context, req models.GetAllAttendanceRequest) (models.GetAllAttendanceResponse, error)
+	// Rates counts the marks of req per student, without the rate and ignoring
+	// the page. Filtered by a group it counts the students actively enrolled
+	// in the group without marks too.
+	Rates(ctx context.Context, req models.GetAllAttendanceRequest) ([]models.AttendanceRate, error)
+}
+
+// ISubmissionStorage keeps one submission per task and student.
+type ISubmissionStorage interface {
+	// Submit writes the answer of the student to the task with the current
+	// time, replacing an earlier ungraded answer. It returns pgx.ErrNoRows if
+	// the earlier answer is graded, the grade stays with the answer it was
+	// given for.
+	Submit(context.Context, models.Submission) (models.Submission, error)
+	// Grade sets the score and feedback of the submission, it returns
+	// ErrNotFound if there is no such submission.
+	Grade(ctx context.Context, id string, score int, feedback, gradedBy string) (models.Submission, error)
+	GetByID(ctx context.Context, id string) (models.Submission, error)
+	GetAll(ctx context.Context, req models.GetAllSubmissionsRequest) (models.GetAllSubmissionsResponse, error)
+}
+
+// IGradebookStorage reads what the gradebook of a group is made of. Lessons
+// and tasks are those of the group in the date range of the request, deleted
+// ones left out, ordered by lesson date.
+type IGradebookStorage interface {
+	// Students returns the students actively enrolled in the group by name.
+	Students(ctx context.Context, groupID string) ([]models.GradebookStudent, error)
+	Lessons(ctx context.Context, req models.GradebookRequest) ([]models.GradebookLesson, error)
+	Tasks(ctx context.Context, req models.GradebookRequest) ([]models.GradebookTask, error)
+	// Scores returns the submissions to the tasks of req by any student.
+	Scores(ctx context.Context, req models.GradebookRequest) ([]models.GradebookScore, error)
 }
 
 type IAdminReportStorage interface {
 	GetByIDAdminPayment(ctx context.Context, req models.AdminKey) ([]models.AdminPayment, error)
-}
\ No newline at end of file
+}
+
+type ITokenStorage interface {
+	Revoke(context.Context, models.RevokedToken) (bool, error)
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type IPasswordResetStorage interface {
+	Create(ctx context.Context, reset models.PasswordReset, ttl time.Duration) (models.PasswordReset, error)
+	GetActive(ctx context.Context, userID, userRole string) (models.PasswordReset, error)
+	IncrementAttempts(ctx context.Context, id string) error
+	MarkUsed(ctx context.Context, id string) (bool, error)
+}
+
+type ILoginAttemptStorage interface {
+	Create(ctx context.Context, attempt models.LoginAttempt) error
+	GetAll(ctx context.Context, request models.GetAllLoginAttemptsRequest) (models.GetAllLoginAttemptsResponse, error)
+	GetThrottle(ctx context.Context, key string) (models.LoginThrottle, error)
+	RegisterFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	Lock(ctx context.Context, key string, duration time.Duration) error
+	ResetThrottle(ctx context.Context, key string) error
+}
+
+type ITwoFactorStorage interface {
+	Setup(ctx context.Context, twoFactor models.TwoFactor) error
+	Get(ctx context.Context, userID, userRole string) (models.TwoFactor, error)
+	UseStep(ctx context.Context, userID, userRole string, step int64) (bool, error)
+	Enable(ctx context.Context, userID, userRole string, recoveryCodeHashes []string) error
+	Delete(ctx context.Context, userID, userRole string) error
+	GetRecoveryCodes(ctx context.Context, userID, userRole string) ([]models.RecoveryCode, error)
+	UseRecoveryCode(ctx context.Context, id string) (bool, error)
+}
+
+type IAuditStorage interface {
+	Create(ctx context.Context, log models.AuditLog) error
+	GetAll(ctx context.Context, request models.GetAllAuditLogsRequest) (models.GetAllAuditLogsResponse, error)
+}
+
+type IIdempotencyStorage interface {
+	Create(ctx context.Context, key models.IdempotencyKey, ttl, lease time.Duration) (bool, error)
+	Get(ctx context.Context, userID, key string) (models.IdempotencyKey, error)
+	Complete(ctx context.Context, key models.IdempotencyKey) error
+	Delete(ctx context.Context, userID, key string) error
+}
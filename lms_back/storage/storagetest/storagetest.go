@@ -0,0 +1,1238 @@
+// Package storagetest is the conformance suite of storage.IStorage. Every
+// implementation runs it, so the in-memory storage used by tests can't drift
+// from the postgres one.
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lms_back/api/models"
+	"lms_back/pkg/errs"
+	"lms_back/storage"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Run runs the suite, newStorage returns an empty storage for every test.
+func Run(t *testing.T, newStorage func(t *testing.T) storage.IStorage) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s storage.IStorage)
+	}{
+		{"Branch", testBranch},
+		{"Admin", testAdmin},
+		{"Group", testGroup},
+		{"Student", testStudent},
+		{"Teacher", testTeacher},
+		{"Payment", testPayment},
+		{"ScheduleLessonTask", testScheduleLessonTask},
+		{"Enrollment", testEnrollment},
+		{"Attendance", testAttendance},
+		{"Submission", testSubmission},
+		{"Gradebook", testGradebook},
+		{"Trash", testTrash},
+		{"Version", testVersion},
+		{"Patch", testPatch},
+		{"List", testList},
+		{"Errors", testErrors},
+		{"WithTx", testWithTx},
+		{"AdminReport", testAdminReport},
+		{"Token", testToken},
+		{"PasswordReset", testPasswordReset},
+		{"LoginAttempt", testLoginAttempt},
+		{"TwoFactor", testTwoFactor},
+		{"Audit", testAudit},
+		{"Idempotency", testIdempotency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStorage(t))
+		})
+	}
+}
+
+var ctx = context.Background()
+
+func check(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pgCode returns the code of a *pgconn.PgError, or "" for other errors.
+func pgCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+// fixture is a branch with a teacher, a group and a student of the group.
+type fixture struct {
+	branch  models.Branch
+	teacher models.GetTeacher
+	group   models.Group
+	student models.GetStudent
+	admin   models.GetAdmin
+}
+
+func newFixture(t *testing.T, s storage.IStorage, name string) fixture {
+	t.Helper()
+
+	var (
+		f   fixture
+		err error
+	)
+
+	f.branch, err = s.Branch().Create(ctx, models.Branch{Name: name, Address: name + " street"})
+	check(t, err)
+
+	f.teacher, err = s.Teacher().Create(ctx, models.Teacher{
+		Full_name: name + " Teacher",
+		Email:     name + "@teacher.uz",
+		Age:       30,
+		Status:    "active",
+		Login:     name + "-teacher",
+		Password:  "hash",
+	})
+	check(t, err)
+
+	f.group, err = s.Group().Create(ctx, models.Group{Branch_id: f.branch.Id, Teacher_id: f.teacher.Id, Type: "backend"})
+	check(t, err)
+
+	f.student, err = s.Student().Create(ctx, models.Student{
+		Full_Name: name + " Student",
+		Email:     name + "@student.uz",
+		Age:       20,
+		Status:    "active",
+		Login:     name + "-student",
+		Password:  "hash",
+		GroupID:   f.group.Id,
+	})
+	check(t, err)
+
+	f.admin, err = s.Admin().Create(ctx, models.Admin{
+		Full_Name: name + " Admin",
+		Email:     name + "@admin.uz",
+		Age:       40,
+		Status:    "active",
+		Login:     name + "-admin",
+		Password:  "hash",
+		Role:      "admin",
+	})
+	check(t, err)
+
+	return f
+}
+
+func testBranch(t *testing.T, s storage.IStorage) {
+	created, err := s.Branch().Create(ctx, models.Branch{Name: "Chilonzor", Address: "Bunyodkor 1"})
+	check(t, err)
+	if _, err = uuid.Parse(created.Id); err != nil {
+		t.Fatalf("Create() id = %q", created.Id)
+	}
+
+	got, err := s.Branch().GetByID(ctx, created.Id)
+	check(t, err)
+	if got.Name != "Chilonzor" || got.Address != "Bunyodkor 1" || got.CreatedAt == "" || got.UpdatedAt != "" || got.DeletedAt != "0" {
+		t.Errorf("GetByID() = %+v", got)
+	}
+
+	_, err = s.Branch().Update(ctx, models.Branch{Id: created.Id, Name: "Yunusobod", Address: "Amir Temur 2", Version: created.Version})
+	check(t, err)
+	got, err = s.Branch().GetByID(ctx, created.Id)
+	check(t, err)
+	if got.Name != "Yunusobod" || got.Address != "Amir Temur 2" || got.UpdatedAt == "" {
+		t.Errorf("GetByID() after Update() = %+v", got)
+	}
+}
+
+func testAdmin(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "admin")
+
+	if len(f.admin.BranchIDs) != 0 || f.admin.BranchIDs == nil {
+		t.Errorf("Create() branch ids = %#v, want empty", f.admin.BranchIDs)
+	}
+
+	admin, err := s.Admin().GetByLogin(ctx, "admin-admin")
+	check(t, err)
+	if admin.Id != f.admin.Id || admin.Password != "hash" || admin.Role != "admin" {
+		t.Errorf("GetByLogin() = %+v", admin)
+	}
+
+	check(t, s.Admin().SetBranches(ctx, f.admin.Id, []string{f.branch.Id, f.branch.Id}))
+	got, err := s.Admin().GetByID(ctx, f.admin.Id)
+	check(t, err)
+	if len(got.BranchIDs) != 1 || got.BranchIDs[0] != f.branch.Id {
+		t.Errorf("GetByID() branch ids = %v, want [%s]", got.BranchIDs, f.branch.Id)
+	}
+
+	check(t, s.Admin().UpdatePassword(ctx, f.admin.Id, "new hash"))
+	admin, err = s.Admin().GetByLogin(ctx, "admin-admin")
+	check(t, err)
+	if admin.Password != "new hash" {
+		t.Errorf("GetByLogin() password = %q after UpdatePassword()", admin.Password)
+	}
+
+	_, err = s.Admin().Create(ctx, models.Admin{Full_Name: "Bad", Status: "active", Login: "bad", Role: "root"})
+	if code := pgCode(err); code != "23514" {
+		t.Errorf("Create() with an unknown role error = %v, want a check violation", err)
+	}
+}
+
+func testGroup(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "group")
+	if f.group.Group_id != "Gr-0000001" {
+		t.Errorf("first Create() group_id = %q, want Gr-0000001", f.group.Group_id)
+	}
+
+	second, err := s.Group().Create(ctx, models.Group{Branch_id: f.branch.Id, Teacher_id: f.teacher.Id, Type: "frontend"})
+	check(t, err)
+	if second.Group_id != "Gr-0000002" {
+		t.Errorf("second Create() group_id = %q, want Gr-0000002", second.Group_id)
+	}
+
+	_, err = s.Group().Create(ctx, models.Group{Branch_id: f.branch.Id, Teacher_id: f.teacher.Id, Type: "sales"})
+	if code := pgCode(err); code != "23514" {
+		t.Errorf("Create() with an unknown type error = %v, want a check violation", err)
+	}
+
+	resp, err := s.Group().GetAll(ctx, models.GetAllGroupsRequest{Type: "frontend", Page: 1, Limit: 10})
+	check(t, err)
+	if resp.Count != 1 || len(resp.Groups) != 1 || resp.Groups[0].Id != second.Id {
+		t.Errorf("GetAll() by type = %+v", resp)
+	}
+}
+
+func testStudent(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "student")
+	other := newFixture(t, s, "other")
+
+	check(t, s.Student().AddPaidSum(ctx, f.student.ID, 150.5))
+	check(t, s.Student().AddPaidSum(ctx, f.student.ID, 49.5))
+	got, err := s.Student().GetByID(ctx, f.student.ID)
+	check(t, err)
+	if got.PaidSum != 200 || got.GroupID != f.group.Id {
+		t.Errorf("GetByID() = %+v, want paid sum 200", got)
+	}
+
+	if err = s.Student().AddPaidSum(ctx, uuid.New().String(), 1); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("AddPaidSum() of an unknown student error = %v, want pgx.ErrNoRows", err)
+	}
+
+	// only payments move the paid sum
+	_, err = s.Student().Update(ctx, models.Student{ID: got.ID, Full_Name: "Renamed", Email: got.Email, Age: got.Age, PaidSum: 1, Status: got.Status, Login: got.Login, GroupID: got.GroupID, Version: got.Version})
+	check(t, err)
+	if got, err = s.Student().GetByID(ctx, f.student.ID); err != nil || got.PaidSum != 200 || got.Full_Name != "Renamed" {
+		t.Errorf("GetByID() after Update() = %+v, %v, want paid sum 200", got, err)
+	}
+
+	password, err := s.Student().GetPassword(ctx, "student-student")
+	check(t, err)
+	if password != "hash" {
+		t.Errorf("GetPassword() = %q", password)
+	}
+
+	resp, err := s.Student().GetAll(ctx, models.GetAllStudentsRequest{BranchID: other.branch.Id, Page: 1, Limit: 10})
+	check(t, err)
+	if resp.Count != 1 || resp.Students[0].ID != other.student.ID {
+		t.Errorf("GetAll() by branch = %+v", resp)
+	}
+
+	resp, err = s.Student().GetAll(ctx, models.GetAllStudentsRequest{BranchIDs: []string{f.branch.Id}, Page: 1, Limit: 10})
+	check(t, err)
+	if resp.Count != 1 || resp.Students[0].ID != f.student.ID {
+		t.Errorf("GetAll() in the branches of an admin = %+v", resp)
+	}
+}
+
+func testTeacher(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "teacher")
+
+	_, err := s.Teacher().Update(ctx, models.Teacher{
+		Id:        f.teacher.Id,
+		Full_name: "Renamed",
+		Email:     f.teacher.Email,
+		Age:       31,
+		Status:    "inactive",
+		Login:     f.teacher.Login,
+		Version:   f.teacher.Version,
+	})
+	check(t, err)
+
+	teacher, err := s.Teacher().GetByLogin(ctx, "teacher-teacher")
+	check(t, err)
+	if teacher.Full_name != "Renamed" || teacher.Age != 31 || teacher.Status != "inactive" || teacher.Password != "hash" || teacher.Deleted_at != "0" {
+		t.Errorf("GetByLogin() after Update() = %+v", teacher)
+	}
+
+	resp, err := s.Teacher().GetAll(ctx, models.GetAllTeachersRequest{Status: "active", Page: 1, Limit: 10})
+	check(t, err)
+	if resp.Count != 0 || resp.Teachers != nil {
+		t.Errorf("GetAll() of active teachers = %+v, want none", resp)
+	}
+}
+
+func testPayment(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "payment")
+
+	var ids []string
+	for _, price := range []float64{100, 250.25, 400} {
+		payment, err := s.Payment().Create(ctx, models.CreatePayment{
+			Price:      price,
+			Student_id: f.student.ID,
+			Branch_id:  f.branch.Id,
+			Admin_id:   f.admin.Id,
+		})
+		check(t, err)
+		ids = append(ids, payment.Id)
+	}
+
+	payment, err := s.Payment().GetByIDForUpdate(ctx, ids[1])
+	check(t, err)
+	if payment.Price != 250.25 || payment.Student_id != f.student.ID || payment.CreatedAt == "" {
+		t.Errorf("GetByIDForUpdate() = %+v", payment)
+	}
+
+	resp, err := s.Payment().GetAll(ctx, models.GetAllPaymentsRequest{
+		MinPrice:   200,
+		Search:     "PAYMENT stud",
+		ListParams: models.ListParams{Sort: "-price"},
+		Page:       1,
+		Limit:      10,
+	})
+	check(t, err)
+	if resp.Count != 2 || resp.Payments[0].Id != ids[2] || resp.Payments[1].Id != ids[1] {
+		t.Errorf("GetAll() = %+v, want the two larger payments by descending price", resp)
+	}
+
+	today := time.Now().UTC().Format("2006-01-02")
+	resp, err = s.Payment().GetAll(ctx, models.GetAllPaymentsRequest{CreatedFrom: today, CreatedTo: today, Page: 1, Limit: 10})
+	check(t, err)
+	if resp.Count != 3 {
+		t.Errorf("GetAll() created today count = %d, want 3", resp.Count)
+	}
+}
+
+func testScheduleLessonTask(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "lesson")
+
+	schedule, err := s.Schedule().Create(ctx, models.Schedule{
+		Group_id:   f.group.Id,
+		Group_type: "backend",
+		Start_time: "09:00",
+		End_time:   "11:00",
+		Date:       "monday",
+		Branch_id:  f.branch.Id,
+		Teacher_id: f.teacher.Id,
+	})
+	check(t, err)
+	gotSchedule, err := s.Schedule().GetByID(ctx, schedule.Id)
+	check(t, err)
+	if gotSchedule.Start_time != "09:00:00.000000" || gotSchedule.End_time != "11:00:00.000000" || gotSchedule.Date != "monday" {
+		t.Errorf("Schedule().GetByID() = %+v", gotSchedule)
+	}
+
+	lesson, err := s.Lesson().Create(ctx, models.Lesson{
+		ScheduleId: schedule.Id,
+		GroupId:    f.group.Id,
+		From:       "2024-03-01",
+		To:         "2024-03-31",
+		Theme:      "Goroutines",
+	})
+	check(t, err)
+	gotLesson, err := s.Lesson().GetByID(ctx, lesson.Id)
+	check(t, err)
+	if gotLesson.Id != lesson.Id || gotLesson.Theme != "Goroutines" || gotLesson.From != "2024-03-01T00:00:00Z" || gotLesson.To != "2024-03-31T00:00:00Z" {
+		t.Errorf("Lesson().GetByID() = %+v", gotLesson)
+	}
+
+	lessons, err := s.Lesson().GetAll(ctx, models.GetAllLessonsRequest{FromDate: "2024-02-01", ToDate: "2024-02-29", Page: 1, Limit: 10})
+	check(t, err)
+	if lessons.Count != 0 {
+		t.Errorf("Lesson().GetAll() in february = %+v, want none", lessons)
+	}
+
+	task, err := s.Task().Create(ctx, models.Task{LessonId: lesson.Id, GroupId: f.group.Id, Task: "Write a worker pool", Score: "5", Deadline: "2024-03-10T23:00:00+05:00"})
+	check(t, err)
+	gotTask, err := s.Task().GetByID(ctx, task.Id)
+	check(t, err)
+	if gotTask.Id != task.Id || gotTask.LessonId != lesson.Id || gotTask.GroupId != f.group.Id || gotTask.Task != "Write a worker pool" || gotTask.Score != "5" || gotTask.Deadline != "2024-03-10T18:00:00Z" {
+		t.Errorf("Task().GetByID() = %+v", gotTask)
+	}
+	tasks, err := s.Task().GetAll(ctx, models.GetAllTasksRequest{Search: "worker", Page: 1, Limit: 10})
+	check(t, err)
+	if tasks.Count != 1 || tasks.Tasks[0].Id != task.Id {
+		t.Errorf("Task().GetAll() searching the task text = %+v", tasks)
+	}
+
+	_, err = s.Task().Create(ctx, models.Task{LessonId: lesson.Id, GroupId: f.group.Id, Score: "five"})
+	if code := pgCode(err); code != "22P02" {
+		t.Errorf("Task().Create() with a text score error = %v, want invalid input", err)
+	}
+}
+
+func testEnrollment(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "enrollment")
+	second, err := s.Group().Create(ctx, models.Group{Branch_id: f.branch.Id, Teacher_id: f.teacher.Id, Type: "frontend"})
+	check(t, err)
+
+	first, err := s.Enrollment().Create(ctx, models.Enrollment{StudentID: f.student.ID, GroupID: f.group.Id})
+	check(t, err)
+	if first.Status != models.EnrollmentActive || first.EnrolledAt == "" || first.LeftAt != "" {
+		t.Errorf("Create() = %+v", first)
+	}
+	if _, err = s.Enrollment().Create(ctx, models.Enrollment{StudentID: f.student.ID, GroupID: f.group.Id}); errs.KindOf(err) != errs.KindConflict {
+		t.Errorf("Create() of a second active enrollment error = %v, want a conflict", err)
+	}
+	_, err = s.Enrollment().Create(ctx, models.Enrollment{StudentID: f.student.ID, GroupID: second.Id})
+	check(t, err)
+
+	students, err := s.Student().GetAll(ctx, models.GetAllStudentsRequest{EnrolledGroupID: second.Id, Page: 1, Limit: 10})
+	check(t, err)
+	if students.Count != 1 || students.Students[0].ID != f.student.ID {
+		t.Errorf("Student().GetAll() of the enrolled group = %+v", students)
+	}
+
+	closed, err := s.Enrollment().Close(ctx, f.student.ID, f.group.Id, models.EnrollmentWithdrawn, "moved away")
+	check(t, err)
+	if closed.Id != first.Id || closed.Status != models.EnrollmentWithdrawn || closed.Reason != "moved away" || closed.LeftAt == "" {
+		t.Errorf("Close() = %+v", closed)
+	}
+	if _, err = s.Enrollment().Close(ctx, f.student.ID, f.group.Id, models.EnrollmentWithdrawn, ""); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Close() of a closed enrollment error = %v, want storage.ErrNotFound", err)
+	}
+	// the student can join the group it left again
+	_, err = s.Enrollment().Create(ctx, models.Enrollment{StudentID: f.student.ID, GroupID: f.group.Id})
+	check(t, err)
+
+	history, err := s.Enrollment().GetAll(ctx, models.GetAllEnrollmentsRequest{StudentID: f.student.ID, Page: 1, Limit: 10})
+	check(t, err)
+	if history.Count != 3 || len(history.Enrollments) != 3 {
+		t.Errorf("GetAll() of the student = %+v", history)
+	}
+	active, err := s.Enrollment().GetAll(ctx, models.GetAllEnrollmentsRequest{GroupID: f.group.Id, Status: models.EnrollmentActive, Page: 1, Limit: 10})
+	check(t, err)
+	if active.Count != 1 || active.Enrollments[0].Id == first.Id {
+		t.Errorf("GetAll() of the active enrollments of the group = %+v", active)
+	}
+
+	check(t, s.Student().SetGroup(ctx, f.student.ID, second.Id))
+	student, err := s.Student().GetByID(ctx, f.student.ID)
+	check(t, err)
+	if student.GroupID != second.Id || student.Version != f.student.Version+1 {
+		t.Errorf("GetByID() after SetGroup() = %+v", student)
+	}
+
+	// the enrollments keep the groups from being purged but not the student
+	check(t, s.Student().Delete(ctx, f.student.ID, student.Version))
+	check(t, s.Group().Delete(ctx, f.group.Id, f.group.Version))
+	purged, err := s.Group().Purge(ctx, time.Now().Add(time.Hour))
+	check(t, err)
+	if purged != 0 {
+		t.Errorf("Group().Purge() = %d, want the enrolled group kept", purged)
+	}
+	purged, err = s.Student().Purge(ctx, time.Now().Add(time.Hour))
+	check(t, err)
+	history, err = s.Enrollment().GetAll(ctx, models.GetAllEnrollmentsRequest{StudentID: f.student.ID, Page: 1, Limit: 10})
+	check(t, err)
+	if purged != 1 || history.Count != 0 {
+		t.Errorf("Student().Purge() = %d and left %d enrollments, want the student purged with its enrollments", purged, history.Count)
+	}
+}
+
+func testAttendance(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "attendance")
+	schedule, err := s.Schedule().Create(ctx, models.Schedule{
+		Group_id:   f.group.Id,
+		Group_type: "backend",
+		Start_time: "09:00",
+		End_time:   "11:00",
+		Date:       "monday",
+		Branch_id:  f.branch.Id,
+		Teacher_id: f.teacher.Id,
+	})
+	check(t, err)
+	var lessons []models.Lesson
+	for _, date := range []string{"2024-03-04", "2024-03-11", "2024-04-01"} {
+		lesson, err := s.Lesson().Create(ctx, models.Lesson{ScheduleId: schedule.Id, GroupId: f.group.Id, From: date, To: date, Theme: "Lesson " + date})
+		check(t, err)
+		lessons = append(lessons, lesson)
+	}
+	absentee, err := s.Student().Create(ctx, models.Student{Full_Name: "Absent Student", Status: "active", Login: "attendance-absent", GroupID: f.group.Id})
+	check(t, err)
+	newcomer, err := s.Student().Create(ctx, models.Student{Full_Name: "New Student", Status: "active", Login: "attendance-new", GroupID: f.group.Id})
+	check(t, err)
+	for _, id := range []string{f.student.ID, absentee.ID, newcomer.ID} {
+		_, err = s.Enrollment().Create(ctx, models.Enrollment{StudentID: id, GroupID: f.group.Id})
+		check(t, err)
+	}
+
+	marked, err := s.Attendance().Mark(ctx, lessons[0].Id, f.teacher.Id, []models.MarkAttendance{
+		{StudentID: f.student.ID, Status: models.AttendanceAbsent},
+		{StudentID: absentee.ID, Status: models.AttendanceAbsent},
+	})
+	check(t, err)
+	if len(marked) != 2 || marked[0].GroupID != f.group.Id || marked[0].MarkedBy != f.teacher.Id || marked[0].LessonDate == "" || marked[0].CreatedAt == "" {
+		t.Errorf("Mark() = %+v", marked)
+	}
+	// marking the student again replaces the mark
+	remarked, err := s.Attendance().Mark(ctx, lessons[0].Id, "", []models.MarkAttendance{{StudentID: f.student.ID, Status: models.AttendanceLate, Note: "bus"}})
+	check(t, err)
+	if len(remarked) != 1 || remarked[0].Status != models.AttendanceLate || remarked[0].Note != "bus" || remarked[0].MarkedBy != "" || remarked[0].CreatedAt != marked[0].CreatedAt {
+		t.Errorf("Mark() of a marked student = %+v, want the mark replaced", remarked)
+	}
+	for _, lesson := range lessons[1:] {
+		_, err = s.Attendance().Mark(ctx, lesson.Id, f.teacher.Id, []models.MarkAttendance{
+			{StudentID: f.student.ID, Status: models.AttendancePresent},
+			{StudentID: absentee.ID, Status: models.AttendanceExcused},
+		})
+		check(t, err)
+	}
+
+	if _, err = s.Attendance().Mark(ctx, uuid.New().String(), "", []models.MarkAttendance{{StudentID: f.student.ID, Status: models.AttendancePresent}}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Mark() of an unknown lesson error = %v, want storage.ErrNotFound", err)
+	}
+	_, err = s.Attendance().Mark(ctx, lessons[0].Id, "", []models.MarkAttendance{{StudentID: f.student.ID, Status: "asleep"}})
+	if e, ok := errs.As(err); !ok || e.Kind != errs.KindValidation || len(e.Fields) != 1 || e.Fields[0].Field != "status" {
+		t.Errorf("Mark() with an unknown status error = %v, want a validation error of status", err)
+	}
+
+	list, err := s.Attendance().GetAll(ctx, models.GetAllAttendanceRequest{StudentID: f.student.ID, Page: 1, Limit: 2})
+	check(t, err)
+	if list.Count != 3 || len(list.Attendance) != 2 || list.Attendance[0].LessonID != lessons[2].Id || list.Attendance[1].LessonID != lessons[1].Id {
+		t.Errorf("GetAll() of the student = %+v, want the latest lessons first", list)
+	}
+	march, err := s.Attendance().GetAll(ctx, models.GetAllAttendanceRequest{GroupID: f.group.Id, From: "2024-03-01", To: "2024-03-31", Page: 1, Limit: 10})
+	check(t, err)
+	if march.Count != 4 {
+		t.Errorf("GetAll() of the group in march = %+v, want 4 marks", march)
+	}
+
+	rates, err := s.Attendance().Rates(ctx, models.GetAllAttendanceRequest{GroupID: f.group.Id, From: "2024-03-01", To: "2024-03-31"})
+	check(t, err)
+	counts := map[string]models.AttendanceRate{}
+	for _, rate := range rates {
+		counts[rate.StudentID] = rate
+	}
+	want := map[string]models.AttendanceRate{
+		f.student.ID: {StudentID: f.student.ID, Present: 1, Late: 1},
+		absentee.ID:  {StudentID: absentee.ID, Absent: 1, Excused: 1},
+		newcomer.ID:  {StudentID: newcomer.ID},
+	}
+	if len(rates) != 3 || counts[f.student.ID] != want[f.student.ID] || counts[absentee.ID] != want[absentee.ID] || counts[newcomer.ID] != want[newcomer.ID] {
+		t.Errorf("Rates() of the group in march = %+v, want %+v", rates, want)
+	}
+
+	// the marks of deleted lessons are left out
+	check(t, s.Lesson().Delete(ctx, lessons[2].Id, lessons[2].Version))
+	rates, err = s.Attendance().Rates(ctx, models.GetAllAttendanceRequest{StudentID: f.student.ID})
+	check(t, err)
+	if len(rates) != 1 || rates[0].Present != 1 || rates[0].Late != 1 {
+		t.Errorf("Rates() of the student after deleting a lesson = %+v", rates)
+	}
+}
+
+func testSubmission(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "submission")
+	schedule, err := s.Schedule().Create(ctx, models.Schedule{
+		Group_id:   f.group.Id,
+		Group_type: "backend",
+		Start_time: "09:00",
+		End_time:   "11:00",
+		Date:       "monday",
+		Branch_id:  f.branch.Id,
+		Teacher_id: f.teacher.Id,
+	})
+	check(t, err)
+	lesson, err := s.Lesson().Create(ctx, models.Lesson{ScheduleId: schedule.Id, GroupId: f.group.Id, From: "2024-03-04", To: "2024-03-04", Theme: "Channels"})
+	check(t, err)
+	task, err := s.Task().Create(ctx, models.Task{LessonId: lesson.Id, GroupId: f.group.Id, Task: "Fan in", Score: "10"})
+	check(t, err)
+	other, err := s.Student().Create(ctx, models.Student{Full_Name: "Other Student", Status: "active", Login: "submission-other", GroupID: f.group.Id})
+	check(t, err)
+
+	first, err := s.Submission().Submit(ctx, models.Submission{TaskID: task.Id, StudentID: f.student.ID, Answer: "draft"})
+	check(t, err)
+	if first.Id == "" || first.Answer != "draft" || first.Late || first.Score != nil || first.SubmittedAt == "" || first.GradedAt != "" {
+		t.Errorf("Submit() = %+v", first)
+	}
+	// submitting again replaces the answer of the same submission
+	second, err := s.Submission().Submit(ctx, models.Submission{TaskID: task.Id, StudentID: f.student.ID, Answer: "final", Attachment: "https://git.example.com/fan-in", Late: true})
+	check(t, err)
+	if second.Id != first.Id || second.Answer != "final" || second.Attachment == "" || !second.Late || second.CreatedAt != first.CreatedAt {
+		t.Errorf("Submit() of a submitted task = %+v, want %s replaced", second, first.Id)
+	}
+	_, err = s.Submission().Submit(ctx, models.Submission{TaskID: task.Id, StudentID: other.ID, Answer: "mine"})
+	check(t, err)
+
+	graded, err := s.Submission().Grade(ctx, first.Id, 8, "good", f.teacher.Id)
+	check(t, err)
+	if graded.Score == nil || *graded.Score != 8 || graded.Feedback != "good" || graded.GradedBy != f.teacher.Id || graded.GradedAt == "" || graded.Answer != "final" {
+		t.Errorf("Grade() = %+v", graded)
+	}
+	if _, err = s.Submission().Grade(ctx, uuid.New().String(), 8, "", ""); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Grade() of an unknown submission error = %v, want storage.ErrNotFound", err)
+	}
+	_, err = s.Submission().Grade(ctx, first.Id, -1, "", "")
+	if e, ok := errs.As(err); !ok || e.Kind != errs.KindValidation || len(e.Fields) != 1 || e.Fields[0].Field != "score" {
+		t.Errorf("Grade() with a negative score error = %v, want a validation error of score", err)
+	}
+	if _, err = s.Submission().Submit(ctx, models.Submission{TaskID: task.Id, StudentID: f.student.ID, Answer: "after the grade"}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Submit() of a graded task error = %v, want pgx.ErrNoRows", err)
+	}
+	got, err := s.Submission().GetByID(ctx, first.Id)
+	check(t, err)
+	if got.Score == nil || *got.Score != 8 || got.Answer != "final" {
+		t.Errorf("GetByID() = %+v, want the grade of the final answer", got)
+	}
+
+	all, err := s.Submission().GetAll(ctx, models.GetAllSubmissionsRequest{TaskID: task.Id, Page: 1, Limit: 10})
+	check(t, err)
+	own, err := s.Submission().GetAll(ctx, models.GetAllSubmissionsRequest{TaskID: task.Id, StudentID: other.ID, Page: 1, Limit: 10})
+	check(t, err)
+	if all.Count != 2 || own.Count != 1 || own.Submissions[0].StudentID != other.ID {
+		t.Errorf("GetAll() = %d of the task and %+v of the student", all.Count, own)
+	}
+
+	// the submissions are purged with their task
+	check(t, s.Task().Delete(ctx, task.Id, task.Version))
+	_, err = s.Task().Purge(ctx, time.Now().Add(time.Hour))
+	check(t, err)
+	all, err = s.Submission().GetAll(ctx, models.GetAllSubmissionsRequest{TaskID: task.Id, Page: 1, Limit: 10})
+	check(t, err)
+	if all.Count != 0 {
+		t.Errorf("GetAll() after purging the task = %+v", all)
+	}
+}
+
+func testGradebook(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "gradebook")
+	schedule, err := s.Schedule().Create(ctx, models.Schedule{
+		Group_id:   f.group.Id,
+		Group_type: "backend",
+		Start_time: "09:00",
+		End_time:   "11:00",
+		Date:       "monday",
+		Branch_id:  f.branch.Id,
+		Teacher_id: f.teacher.Id,
+	})
+	check(t, err)
+	var lessons []models.Lesson
+	for _, date := range []string{"2024-03-11", "2024-03-04", "2024-04-01"} {
+		lesson, err := s.Lesson().Create(ctx, models.Lesson{ScheduleId: schedule.Id, GroupId: f.group.Id, From: date, To: date, Theme: "Lesson " + date})
+		check(t, err)
+		lessons = append(lessons, lesson)
+	}
+	var tasks []models.Task
+	for i, lesson := range []models.Lesson{lessons[0], lessons[1], lessons[0], lessons[2]} {
+		task, err := s.Task().Create(ctx, models.Task{LessonId: lesson.Id, GroupId: f.group.Id, Task: fmt.Sprintf("Task %d", i), Score: "10"})
+		check(t, err)
+		tasks = append(tasks, task)
+	}
+	otherGroup, err := s.Group().Create(ctx, models.Group{Branch_id: f.branch.Id, Teacher_id: f.teacher.Id, Type: "frontend"})
+	check(t, err)
+	_, err = s.Task().Create(ctx, models.Task{LessonId: lessons[0].Id, GroupId: otherGroup.Id, Task: "Not ours", Score: "10"})
+	check(t, err)
+
+	classmate, err := s.Student().Create(ctx, models.Student{Full_Name: "Aziz Classmate", Status: "active", Login: "gradebook-classmate", GroupID: f.group.Id})
+	check(t, err)
+	_, err = s.Student().Create(ctx, models.Student{Full_Name: "Not Enrolled", Status: "active", Login: "gradebook-outsider", GroupID: f.group.Id})
+	check(t, err)
+	for _, id := range []string{f.student.ID, classmate.ID} {
+		_, err = s.Enrollment().Create(ctx, models.Enrollment{StudentID: id, GroupID: f.group.Id})
+		check(t, err)
+	}
+
+	students, err := s.Gradebook().Students(ctx, f.group.Id)
+	check(t, err)
+	if len(students) != 2 || students[0].StudentID != classmate.ID || students[1].StudentID != f.student.ID || students[0].FullName != "Aziz Classmate" {
+		t.Errorf("Students() = %+v, want the enrolled students by name", students)
+	}
+
+	march := models.GradebookRequest{GroupID: f.group.Id, From: "2024-03-01", To: "2024-03-31"}
+	gotLessons, err := s.Gradebook().Lessons(ctx, march)
+	check(t, err)
+	if len(gotLessons) != 2 || gotLessons[0].LessonID != lessons[1].Id || gotLessons[1].LessonID != lessons[0].Id || gotLessons[0].Theme != "Lesson 2024-03-04" || gotLessons[0].Date == "" {
+		t.Errorf("Lessons() in march = %+v, want the lessons by date", gotLessons)
+	}
+	gotTasks, err := s.Gradebook().Tasks(ctx, march)
+	check(t, err)
+	want := []string{tasks[1].Id, tasks[0].Id, tasks[2].Id}
+	if len(gotTasks) != len(want) || gotTasks[0].MaxScore != 10 || gotTasks[0].LessonID != lessons[1].Id || gotTasks[0].Task != "Task 1" {
+		t.Fatalf("Tasks() in march = %+v, want the tasks of the group by lesson", gotTasks)
+	}
+	for i, task := range gotTasks {
+		if task.TaskID != want[i] {
+			t.Errorf("Tasks()[%d] = %s, want %s", i, task.TaskID, want[i])
+		}
+	}
+
+	submission, err := s.Submission().Submit(ctx, models.Submission{TaskID: tasks[0].Id, StudentID: f.student.ID, Answer: "done", Late: true})
+	check(t, err)
+	_, err = s.Submission().Grade(ctx, submission.Id, 7, "", f.teacher.Id)
+	check(t, err)
+	_, err = s.Submission().Submit(ctx, models.Submission{TaskID: tasks[1].Id, StudentID: classmate.ID, Answer: "done"})
+	check(t, err)
+	_, err = s.Submission().Submit(ctx, models.Submission{TaskID: tasks[3].Id, StudentID: classmate.ID, Answer: "april"})
+	check(t, err)
+
+	scores, err := s.Gradebook().Scores(ctx, march)
+	check(t, err)
+	got := map[string]models.GradebookScore{}
+	for _, score := range scores {
+		got[score.StudentID+score.TaskID] = score
+	}
+	graded, ungraded := got[f.student.ID+tasks[0].Id], got[classmate.ID+tasks[1].Id]
+	if len(scores) != 2 || graded.Score == nil || *graded.Score != 7 || !graded.Late || ungraded.TaskID == "" || ungraded.Score != nil {
+		t.Errorf("Scores() in march = %+v", scores)
+	}
+}
+
+func testTrash(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "trash")
+
+	check(t, s.Student().Delete(ctx, f.student.ID, f.student.Version))
+	if _, err := s.Student().GetByID(ctx, f.student.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetByID() of a deleted student error = %v, want pgx.ErrNoRows", err)
+	}
+
+	trash, err := s.Student().GetAll(ctx, models.GetAllStudentsRequest{ListParams: models.ListParams{Deleted: models.DeletedOnly}, Page: 1, Limit: 10})
+	check(t, err)
+	if trash.Count != 1 || trash.Students[0].ID != f.student.ID {
+		t.Errorf("GetAll() of the trash = %+v", trash)
+	}
+
+	check(t, s.Student().Restore(ctx, f.student.ID))
+	if err = s.Student().Restore(ctx, f.student.ID); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Restore() of a student not in the trash error = %v, want pgx.ErrNoRows", err)
+	}
+	if _, err = s.Student().GetByID(ctx, f.student.ID); err != nil {
+		t.Errorf("GetByID() after Restore() error = %v", err)
+	}
+
+	// the student still references the group
+	check(t, s.Group().Delete(ctx, f.group.Id, f.group.Version))
+	purged, err := s.Group().Purge(ctx, time.Now().Add(time.Hour))
+	check(t, err)
+	if purged != 0 {
+		t.Errorf("Group().Purge() = %d, want the referenced group kept", purged)
+	}
+
+	check(t, s.Branch().Delete(ctx, f.branch.Id, f.branch.Version))
+	extra, err := s.Branch().Create(ctx, models.Branch{Name: "Extra"})
+	check(t, err)
+	check(t, s.Admin().SetBranches(ctx, f.admin.Id, []string{extra.Id}))
+	check(t, s.Branch().Delete(ctx, extra.Id, extra.Version))
+
+	purged, err = s.Branch().Purge(ctx, time.Now().Add(time.Hour))
+	check(t, err)
+	if purged != 1 {
+		t.Errorf("Branch().Purge() = %d, want only the unreferenced branch purged", purged)
+	}
+	admin, err := s.Admin().GetByID(ctx, f.admin.Id)
+	check(t, err)
+	if len(admin.BranchIDs) != 0 {
+		t.Errorf("admin branch ids = %v after the branch was purged", admin.BranchIDs)
+	}
+}
+
+func testVersion(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "version")
+	if f.group.Version != 1 {
+		t.Errorf("Create() version = %d, want 1", f.group.Version)
+	}
+
+	stale := models.Group{Id: f.group.Id, Type: "frontend", Version: f.group.Version}
+	updated, err := s.Group().Update(ctx, stale)
+	check(t, err)
+	if updated.Version != 2 {
+		t.Errorf("Update() version = %d, want 2", updated.Version)
+	}
+
+	var conflict *storage.VersionConflictError
+	if _, err = s.Group().Update(ctx, stale); !errors.As(err, &conflict) || conflict.Current != 2 {
+		t.Errorf("Update() with a stale version error = %v, want a conflict with version 2", err)
+	}
+	if err = s.Group().Delete(ctx, f.group.Id, stale.Version); !errors.As(err, &conflict) {
+		t.Errorf("Delete() with a stale version error = %v, want a conflict", err)
+	}
+	got, err := s.Group().GetByID(ctx, f.group.Id)
+	check(t, err)
+	if got.Type != "frontend" || got.Version != 2 {
+		t.Errorf("GetByID() after the conflicts = %+v", got)
+	}
+
+	check(t, s.Student().AddPaidSum(ctx, f.student.ID, 10))
+	if err = s.Student().Delete(ctx, f.student.ID, f.student.Version); !errors.As(err, &conflict) || conflict.Current != 2 {
+		t.Errorf("Delete() after AddPaidSum() error = %v, want a conflict with version 2", err)
+	}
+	check(t, s.Student().Delete(ctx, f.student.ID, 2))
+	if err = s.Student().Delete(ctx, f.student.ID, 3); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Delete() of a deleted student error = %v, want pgx.ErrNoRows", err)
+	}
+	if _, err = s.Branch().Update(ctx, models.Branch{Id: uuid.New().String(), Name: "Unknown", Version: 1}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Update() of an unknown branch error = %v, want pgx.ErrNoRows", err)
+	}
+}
+
+func testPatch(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "patch")
+
+	student, err := s.Student().Patch(ctx, f.student.ID, f.student.Version, models.Patch{
+		"full_name": json.RawMessage(`"Patched Student"`),
+		"age":       json.RawMessage(`21`),
+	})
+	check(t, err)
+	if student.Full_Name != "Patched Student" || student.Age != 21 || student.Email != f.student.Email ||
+		student.GroupID != f.group.Id || student.Version != 2 {
+		t.Errorf("Student().Patch() = %+v, want the other fields kept", student)
+	}
+
+	var conflict *storage.VersionConflictError
+	if _, err = s.Student().Patch(ctx, f.student.ID, f.student.Version, models.Patch{"age": json.RawMessage(`22`)}); !errors.As(err, &conflict) || conflict.Current != 2 {
+		t.Errorf("Student().Patch() with a stale version error = %v, want a conflict with version 2", err)
+	}
+	if _, err = s.Branch().Patch(ctx, uuid.New().String(), 1, models.Patch{"name": json.RawMessage(`"Unknown"`)}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Branch().Patch() of an unknown branch error = %v, want pgx.ErrNoRows", err)
+	}
+
+	for _, patch := range []models.Patch{
+		{"paid_sum": json.RawMessage(`100`)},
+		{"age": json.RawMessage(`"old"`)},
+		{"age": json.RawMessage(`-1`)},
+		{"group_id": json.RawMessage(`"group"`)},
+		{"email": json.RawMessage(`null`)},
+	} {
+		_, err = s.Student().Patch(ctx, f.student.ID, student.Version, patch)
+		if e, ok := errs.As(err); !ok || e.Kind != errs.KindValidation || len(e.Fields) != 1 {
+			t.Errorf("Student().Patch(%s) error = %v, want a validation error of the field", patch, err)
+		}
+	}
+
+	schedule, err := s.Schedule().Create(ctx, models.Schedule{
+		Group_id:   f.group.Id,
+		Group_type: "backend",
+		Start_time: "09:00",
+		End_time:   "11:00",
+		Date:       "monday",
+		Branch_id:  f.branch.Id,
+		Teacher_id: f.teacher.Id,
+	})
+	check(t, err)
+	lesson, err := s.Lesson().Create(ctx, models.Lesson{ScheduleId: schedule.Id, GroupId: f.group.Id, From: "2024-03-01", To: "2024-03-31", Theme: "Maps"})
+	check(t, err)
+	lesson, err = s.Lesson().Patch(ctx, lesson.Id, lesson.Version, models.Patch{"from": json.RawMessage(`"2024-03-15"`)})
+	check(t, err)
+	if lesson.From != "2024-03-15T00:00:00Z" || lesson.To != "2024-03-31T00:00:00Z" || lesson.Theme != "Maps" {
+		t.Errorf("Lesson().Patch() = %+v", lesson)
+	}
+
+	task, err := s.Task().Create(ctx, models.Task{LessonId: lesson.Id, GroupId: f.group.Id, Score: "5"})
+	check(t, err)
+	task, err = s.Task().Patch(ctx, task.Id, task.Version, models.Patch{"score": json.RawMessage(`4`), "deadline": json.RawMessage(`"2024-03-20T12:00:00Z"`)})
+	check(t, err)
+	if task.Score != "4" || task.Deadline != "2024-03-20T12:00:00Z" || task.LessonId != lesson.Id || task.Version != 2 {
+		t.Errorf("Task().Patch() = %+v", task)
+	}
+}
+
+func testList(t *testing.T, s storage.IStorage) {
+	for _, name := range []string{"Beta", "Alpha", "Delta", "Gamma", "Epsilon"} {
+		_, err := s.Branch().Create(ctx, models.Branch{Name: name, Address: "Yunusobod"})
+		check(t, err)
+	}
+
+	resp, err := s.Branch().GetAll(ctx, models.GetAllBranchesRequest{Search: "TA", ListParams: models.ListParams{Sort: "name"}, Page: 2, Limit: 1})
+	check(t, err)
+	if resp.Count != 2 || len(resp.Branches) != 1 || resp.Branches[0].Name != "Delta" {
+		t.Errorf("GetAll() second page = %+v", resp)
+	}
+
+	var names []string
+	params := models.ListParams{Sort: "-name", UseCursor: true}
+	for {
+		resp, err = s.Branch().GetAll(ctx, models.GetAllBranchesRequest{ListParams: params, Limit: 2})
+		check(t, err)
+		for _, branch := range resp.Branches {
+			names = append(names, branch.Name)
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		params.Cursor = resp.NextCursor
+	}
+	if want := []string{"Gamma", "Epsilon", "Delta", "Beta", "Alpha"}; !equal(names, want) {
+		t.Errorf("cursor walk = %v, want %v", names, want)
+	}
+
+	_, err = s.Branch().GetAll(ctx, models.GetAllBranchesRequest{ListParams: models.ListParams{Sort: "password"}, Page: 1, Limit: 10})
+	if !errors.Is(err, storage.ErrInvalidSort) {
+		t.Errorf("GetAll() sorted by an unknown field error = %v, want storage.ErrInvalidSort", err)
+	}
+
+	_, err = s.Branch().GetAll(ctx, models.GetAllBranchesRequest{ListParams: models.ListParams{Sort: "name", Cursor: "not a cursor", UseCursor: true}, Limit: 10})
+	if !errors.Is(err, storage.ErrInvalidCursor) {
+		t.Errorf("GetAll() with a forged cursor error = %v, want storage.ErrInvalidCursor", err)
+	}
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testErrors(t *testing.T, s storage.IStorage) {
+	_, err := s.Teacher().GetByID(ctx, uuid.New().String())
+	if !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetByID() of an unknown id error = %v, want pgx.ErrNoRows", err)
+	}
+	if errs.KindOf(err) != errs.KindNotFound {
+		t.Errorf("GetByID() of an unknown id error = %v, want a not found error", err)
+	}
+
+	_, err = s.Teacher().GetByID(ctx, "42")
+	if pgCode(err) != "22P02" {
+		t.Errorf("GetByID() of an invalid uuid error = %v, want invalid input", err)
+	}
+	if errs.KindOf(err) != errs.KindValidation {
+		t.Errorf("GetByID() of an invalid uuid error = %v, want a validation error", err)
+	}
+	if _, err = s.Admin().GetByLogin(ctx, "nobody"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetByLogin() of an unknown login error = %v, want pgx.ErrNoRows", err)
+	}
+
+	f := newFixture(t, s, "errors")
+	_, err = s.Group().Create(ctx, models.Group{Branch_id: f.branch.Id, Teacher_id: f.teacher.Id, Type: "chess"})
+	if e, ok := errs.As(err); !ok || e.Kind != errs.KindValidation || len(e.Fields) != 1 || e.Fields[0].Field != "type" {
+		t.Errorf("Group().Create() with an unknown type error = %v, want a validation error of type", err)
+	}
+}
+
+func testWithTx(t *testing.T, s storage.IStorage) {
+	rollback := errors.New("rollback")
+
+	var id string
+	err := s.WithTx(ctx, func(tx storage.IStorage) error {
+		branch, err := tx.Branch().Create(ctx, models.Branch{Name: "Rolled back"})
+		if err != nil {
+			return err
+		}
+		id = branch.Id
+		if _, err = tx.Branch().GetByID(ctx, id); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("WithTx() error = %v, want the error of fn", err)
+	}
+	if _, err = s.Branch().GetByID(ctx, id); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetByID() of a rolled back branch error = %v, want pgx.ErrNoRows", err)
+	}
+
+	err = s.WithTx(ctx, func(tx storage.IStorage) error {
+		branch, err := tx.Branch().Create(ctx, models.Branch{Name: "Committed"})
+		id = branch.Id
+		return err
+	})
+	check(t, err)
+	if _, err = s.Branch().GetByID(ctx, id); err != nil {
+		t.Errorf("GetByID() of a committed branch error = %v", err)
+	}
+}
+
+func testAdminReport(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "report")
+
+	report, err := s.AdminReport().GetByIDAdminPayment(ctx, models.AdminKey{Id: f.admin.Id})
+	check(t, err)
+	if report != nil {
+		t.Errorf("GetByIDAdminPayment() without payments = %+v, want nil", report)
+	}
+
+	payment, err := s.Payment().Create(ctx, models.CreatePayment{Price: 75, Student_id: f.student.ID, Branch_id: f.branch.Id, Admin_id: f.admin.Id})
+	check(t, err)
+
+	report, err = s.AdminReport().GetByIDAdminPayment(ctx, models.AdminKey{Id: f.admin.Id})
+	check(t, err)
+	if len(report) != 1 || report[0].Admin.Full_Name != "report Admin" || report[0].Payment.Id != payment.Id || report[0].Payment.Price != 75 {
+		t.Errorf("GetByIDAdminPayment() = %+v", report)
+	}
+}
+
+func testToken(t *testing.T, s storage.IStorage) {
+	token := models.RevokedToken{JTI: uuid.New().String(), UserID: uuid.New().String(), ExpiresAt: time.Now().Add(time.Hour).Unix()}
+
+	for i, want := range []bool{true, false} {
+		revoked, err := s.Token().Revoke(ctx, token)
+		check(t, err)
+		if revoked != want {
+			t.Errorf("Revoke() #%d = %v, want %v", i+1, revoked, want)
+		}
+	}
+
+	revoked, err := s.Token().IsRevoked(ctx, token.JTI)
+	check(t, err)
+	if !revoked {
+		t.Errorf("IsRevoked() = false for a revoked token")
+	}
+	revoked, err = s.Token().IsRevoked(ctx, uuid.New().String())
+	check(t, err)
+	if revoked {
+		t.Errorf("IsRevoked() = true for an unknown token")
+	}
+}
+
+func testPasswordReset(t *testing.T, s storage.IStorage) {
+	userID := uuid.New().String()
+
+	first, err := s.PasswordReset().Create(ctx, models.PasswordReset{UserID: userID, UserRole: "student", CodeHash: "first"}, time.Hour)
+	check(t, err)
+	second, err := s.PasswordReset().Create(ctx, models.PasswordReset{UserID: userID, UserRole: "student", CodeHash: "second"}, time.Hour)
+	check(t, err)
+
+	active, err := s.PasswordReset().GetActive(ctx, userID, "student")
+	check(t, err)
+	if active.Id != second.Id || active.CodeHash != "second" || active.ExpiresAt == "" {
+		t.Errorf("GetActive() = %+v, want the second code", active)
+	}
+
+	used, err := s.PasswordReset().MarkUsed(ctx, first.Id)
+	check(t, err)
+	if used {
+		t.Errorf("MarkUsed() = true for a code replaced by a newer one")
+	}
+
+	check(t, s.PasswordReset().IncrementAttempts(ctx, second.Id))
+	active, err = s.PasswordReset().GetActive(ctx, userID, "student")
+	check(t, err)
+	if active.Attempts != 1 {
+		t.Errorf("GetActive() attempts = %d, want 1", active.Attempts)
+	}
+
+	used, err = s.PasswordReset().MarkUsed(ctx, second.Id)
+	check(t, err)
+	if !used {
+		t.Errorf("MarkUsed() = false for the active code")
+	}
+	if _, err = s.PasswordReset().GetActive(ctx, userID, "student"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("GetActive() after MarkUsed() error = %v, want pgx.ErrNoRows", err)
+	}
+
+	_, err = s.PasswordReset().Create(ctx, models.PasswordReset{UserID: userID, UserRole: "guest", CodeHash: "x"}, time.Hour)
+	if pgCode(err) != "23514" {
+		t.Errorf("Create() for an unknown role error = %v, want a check violation", err)
+	}
+}
+
+func testLoginAttempt(t *testing.T, s storage.IStorage) {
+	f := newFixture(t, s, "login")
+
+	attempts := []models.LoginAttempt{
+		{Login: "login-student", UserRole: "student", UserID: f.student.ID, IP: "10.0.0.1", Success: true},
+		{Login: "login-teacher", UserRole: "teacher", UserID: f.teacher.Id, IP: "10.0.0.2", Success: false},
+		{Login: "nobody", UserRole: "admin", IP: "10.0.0.3", Success: false},
+	}
+	for _, attempt := range attempts {
+		check(t, s.LoginAttempt().Create(ctx, attempt))
+	}
+
+	resp, err := s.LoginAttempt().GetAll(ctx, models.GetAllLoginAttemptsRequest{BranchID: f.branch.Id, Page: 1, Limit: 10})
+	check(t, err)
+	if resp.Count != 2 {
+		t.Errorf("GetAll() in the branch = %+v, want the student and teacher attempts", resp)
+	}
+
+	resp, err = s.LoginAttempt().GetAll(ctx, models.GetAllLoginAttemptsRequest{Success: "false", Page: 1, Limit: 1})
+	check(t, err)
+	if resp.Count != 2 || len(resp.LoginAttempts) != 1 {
+		t.Errorf("GetAll() of failures = %+v, want one page of two", resp)
+	}
+
+	resp, err = s.LoginAttempt().GetAll(ctx, models.GetAllLoginAttemptsRequest{Login: "nobody", Page: 1, Limit: 10})
+	check(t, err)
+	if resp.Count != 1 || resp.LoginAttempts[0].UserID != "" || resp.LoginAttempts[0].BranchID != "" || resp.LoginAttempts[0].CreatedAt == "" {
+		t.Errorf("GetAll() of an unknown login = %+v", resp)
+	}
+
+	key := "login:student:login-student"
+	for want := 1; want <= 3; want++ {
+		failures, err := s.LoginAttempt().RegisterFailure(ctx, key, time.Hour)
+		check(t, err)
+		if failures != want {
+			t.Errorf("RegisterFailure() = %d, want %d", failures, want)
+		}
+	}
+
+	check(t, s.LoginAttempt().Lock(ctx, key, time.Minute))
+	throttle, err := s.LoginAttempt().GetThrottle(ctx, key)
+	check(t, err)
+	if throttle.Failures != 3 || throttle.RetryAfter < 59 || throttle.RetryAfter > 60 {
+		t.Errorf("GetThrottle() = %+v, want 3 failures locked for a minute", throttle)
+	}
+
+	check(t, s.LoginAttempt().ResetThrottle(ctx, key))
+	throttle, err = s.LoginAttempt().GetThrottle(ctx, key)
+	check(t, err)
+	if throttle != (models.LoginThrottle{Key: key}) {
+		t.Errorf("GetThrottle() after ResetThrottle() = %+v", throttle)
+	}
+}
+
+func testTwoFactor(t *testing.T, s storage.IStorage) {
+	userID := uuid.New().String()
+
+	if _, err := s.TwoFactor().Get(ctx, userID, "admin"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Get() before Setup() error = %v, want pgx.ErrNoRows", err)
+	}
+
+	check(t, s.TwoFactor().Setup(ctx, models.TwoFactor{UserID: userID, UserRole: "admin", Secret: "first"}))
+	check(t, s.TwoFactor().Setup(ctx, models.TwoFactor{UserID: userID, UserRole: "admin", Secret: "second"}))
+	check(t, s.TwoFactor().Enable(ctx, userID, "admin", []string{"a", "b"}))
+	check(t, s.TwoFactor().Setup(ctx, models.TwoFactor{UserID: userID, UserRole: "admin", Secret: "third"}))
+
+	twoFactor, err := s.TwoFactor().Get(ctx, userID, "admin")
+	check(t, err)
+	if !twoFactor.Enabled || twoFactor.Secret != "second" || twoFactor.EnabledAt == "" {
+		t.Errorf("Get() = %+v, want the second secret enabled", twoFactor)
+	}
+
+	for i, want := range []bool{true, false, true} {
+		used, err := s.TwoFactor().UseStep(ctx, userID, "admin", []int64{10, 10, 11}[i])
+		check(t, err)
+		if used != want {
+			t.Errorf("UseStep() #%d = %v, want %v", i+1, used, want)
+		}
+	}
+
+	codes, err := s.TwoFactor().GetRecoveryCodes(ctx, userID, "admin")
+	check(t, err)
+	if len(codes) != 2 {
+		t.Fatalf("GetRecoveryCodes() = %+v, want 2 codes", codes)
+	}
+	for i, want := range []bool{true, false} {
+		used, err := s.TwoFactor().UseRecoveryCode(ctx, codes[0].Id)
+		check(t, err)
+		if used != want {
+			t.Errorf("UseRecoveryCode() #%d = %v, want %v", i+1, used, want)
+		}
+	}
+	codes, err = s.TwoFactor().GetRecoveryCodes(ctx, userID, "admin")
+	check(t, err)
+	if len(codes) != 1 {
+		t.Errorf("GetRecoveryCodes() after a code was used = %+v, want 1 code", codes)
+	}
+
+	check(t, s.TwoFactor().Delete(ctx, userID, "admin"))
+	codes, err = s.TwoFactor().GetRecoveryCodes(ctx, userID, "admin")
+	check(t, err)
+	if codes == nil || len(codes) != 0 {
+		t.Errorf("GetRecoveryCodes() after Delete() = %#v, want an empty slice", codes)
+	}
+}
+
+func testAudit(t *testing.T, s storage.IStorage) {
+	// the audit log can't be emptied between tests, the unique entity id keeps
+	// the rows of other runs out
+	entityID := uuid.New().String()
+	branchID := uuid.New().String()
+
+	logs := []models.AuditLog{
+		{EntityType: "student", EntityID: entityID, Action: "create", After: json.RawMessage(`{"name":"Ali","age":20}`)},
+		{EntityType: "student", EntityID: entityID, BranchID: branchID, Action: "update", Changes: json.RawMessage(`{"age":{"before":20,"after":21}}`)},
+	}
+	for _, log := range logs {
+		check(t, s.Audit().Create(ctx, log))
+	}
+
+	resp, err := s.Audit().GetAll(ctx, models.GetAllAuditLogsRequest{EntityID: entityID, Page: 1, Limit: 10})
+	check(t, err)
+	if resp.Count != 2 {
+		t.Fatalf("GetAll() = %+v, want 2 logs", resp)
+	}
+
+	var create models.AuditLog
+	for _, log := range resp.AuditLogs {
+		if log.Action == "create" {
+			create = log
+		}
+	}
+	if string(create.After) != `{"age": 20, "name": "Ali"}` || create.Before != nil || create.ActorID != "" {
+		t.Errorf("created log = %+v, after = %s", create, create.After)
+	}
+
+	resp, err = s.Audit().GetAll(ctx, models.GetAllAuditLogsRequest{EntityID: entityID, BranchIDs: []string{branchID}, Page: 1, Limit: 10})
+	check(t, err)
+	if resp.Count != 1 || resp.AuditLogs[0].Action != "update" {
+		t.Errorf("GetAll() in the branch = %+v", resp)
+	}
+
+	tomorrow := time.Now().UTC().Add(24 * time.Hour).Format("2006-01-02 15:04:05")
+	resp, err = s.Audit().GetAll(ctx, models.GetAllAuditLogsRequest{EntityID: entityID, From: tomorrow, Page: 1, Limit: 10})
+	check(t, err)
+	if resp.Count != 0 {
+		t.Errorf("GetAll() from tomorrow = %+v, want none", resp)
+	}
+}
+
+func testIdempotency(t *testing.T, s storage.IStorage) {
+	key := models.IdempotencyKey{Key: "key-1", UserID: "user-1", Method: "POST", Path: "/branch", RequestHash: "hash"}
+
+	for i, want := range []bool{true, false} {
+		created, err := s.Idempotency().Create(ctx, key, time.Hour, time.Hour)
+		check(t, err)
+		if created != want {
+			t.Errorf("Create() #%d = %v, want %v", i+1, created, want)
+		}
+	}
+
+	// the request holding the key died, a retry after the lease takes it over
+	time.Sleep(10 * time.Millisecond)
+	created, err := s.Idempotency().Create(ctx, key, time.Hour, time.Millisecond)
+	check(t, err)
+	if !created {
+		t.Errorf("Create() after the lease of a running request = false, want true")
+	}
+
+	got, err := s.Idempotency().Get(ctx, "user-1", "key-1")
+	check(t, err)
+	if got.StatusCode != 0 || got.Path != "/branch" || got.Response != nil {
+		t.Errorf("Get() of a running request = %+v", got)
+	}
+
+	key.StatusCode, key.ContentType, key.ETag, key.Response = 201, "application/json", `"1"`, []byte(`{"id":"1"}`)
+	check(t, s.Idempotency().Complete(ctx, key))
+	got, err = s.Idempotency().Get(ctx, "user-1", "key-1")
+	check(t, err)
+	if got.StatusCode != 201 || got.ContentType != "application/json" || got.ETag != `"1"` || string(got.Response) != `{"id":"1"}` {
+		t.Errorf("Get() after Complete() = %+v", got)
+	}
+
+	// a completed response is kept for the ttl, past the lease
+	time.Sleep(10 * time.Millisecond)
+	if created, err = s.Idempotency().Create(ctx, key, time.Hour, time.Millisecond); err != nil || created {
+		t.Errorf("Create() of a completed key = %v, %v, want false", created, err)
+	}
+
+	check(t, s.Idempotency().Delete(ctx, "user-1", "key-1"))
+	if _, err = s.Idempotency().Get(ctx, "user-1", "key-1"); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Get() after Delete() error = %v, want pgx.ErrNoRows", err)
+	}
+}